@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"online-subs-api/services"
+	"unicode/utf8"
+)
+
+// runImportCommand implements "online-subs-api import", it exits with 1 when the file
+// has invalid rows or could not be imported
+func runImportCommand(service *services.SubsService, args []string){
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	file := flags.String("file", "", "CSV file to import")
+	mapping := flags.String("map", "", "field=Column pairs separated by commas, e.g. service_name=Service,price=Cost")
+	delimiter := flags.String("delimiter", ",", "column delimiter")
+	dryRun := flags.Bool("dry-run", false, "validate the file without importing it")
+	userID := flags.String("user-id", "", "user ID for rows without one")
+	tenantID := flags.String("tenant-id", "", "tenant ID for rows without one")
+	flags.Parse(args)
+
+	if *file == "" || utf8.RuneCountInString(*delimiter) != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	opts := services.ImportOptions{DryRun: *dryRun, DefaultUserID: *userID, DefaultTenantID: *tenantID}
+	opts.Delimiter, _ = utf8.DecodeRuneInString(*delimiter)
+	var err error
+	if opts.Mapping, err = services.ParseImportMapping(*mapping); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	f, err := os.Open(*file)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer f.Close()
+
+	report, err := service.ImportCSVService(f, opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "import failed:", err)
+		os.Exit(1)
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(report)
+	if report.Invalid > 0 {
+		os.Exit(1)
+	}
+}
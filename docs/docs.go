@@ -15,9 +15,9 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/subs/create": {
+        "/admin/catalog/plans/create": {
             "post": {
-                "description": "Create a subscription for a user",
+                "description": "Admin: add a plan with default price and billing period to a provider",
                 "consumes": [
                     "application/json"
                 ],
@@ -25,17 +25,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "subscriptions"
+                    "catalog-admin"
                 ],
-                "summary": "Create a new subscription",
+                "summary": "Create a catalog plan",
                 "parameters": [
                     {
-                        "description": "Subscription Request",
-                        "name": "sub",
+                        "type": "string",
+                        "description": "Admin token",
+                        "name": "X-Admin-Token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Plan",
+                        "name": "plan",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handlers.JSONSubRequest"
+                            "$ref": "#/definitions/handlers.JSONPlanRequest"
                         }
                     }
                 ],
@@ -43,7 +50,7 @@ const docTemplate = `{
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/models.Sub"
+                            "$ref": "#/definitions/models.Plan"
                         }
                     },
                     "400": {
@@ -51,24 +58,34 @@ const docTemplate = `{
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    "403": {
+                        "description": "forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
                     }
                 }
             }
         },
-        "/subs/delete": {
+        "/admin/catalog/plans/delete": {
             "delete": {
-                "description": "Delete a subscription by ID",
-                "produces": [
-                    "application/json"
-                ],
+                "description": "Admin: delete a plan by ID, subscriptions on it are unlinked from the plan",
                 "tags": [
-                    "subscriptions"
+                    "catalog-admin"
                 ],
-                "summary": "Delete a subscription",
+                "summary": "Delete a catalog plan",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Subscription ID",
+                        "description": "Admin token",
+                        "name": "X-Admin-Token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Plan ID",
                         "name": "id",
                         "in": "query",
                         "required": true
@@ -87,8 +104,8 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
-                    "404": {
-                        "description": "subscription not found",
+                    "403": {
+                        "description": "forbidden",
                         "schema": {
                             "type": "string"
                         }
@@ -96,40 +113,59 @@ const docTemplate = `{
                 }
             }
         },
-        "/subs/getById": {
-            "get": {
-                "description": "Retrieve a subscription using its ID",
+        "/admin/catalog/plans/update": {
+            "put": {
+                "description": "Admin: replace a plan's name, aliases, default price and billing period",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "subscriptions"
+                    "catalog-admin"
                 ],
-                "summary": "Get subscription by ID",
+                "summary": "Update a catalog plan",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Subscription ID",
+                        "description": "Admin token",
+                        "name": "X-Admin-Token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Plan ID",
                         "name": "id",
                         "in": "query",
                         "required": true
+                    },
+                    {
+                        "description": "Plan",
+                        "name": "plan",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.JSONPlanRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Sub"
+                            "$ref": "#/definitions/models.Plan"
                         }
                     },
                     "400": {
-                        "description": "missing or invalid id",
+                        "description": "invalid request body or failed update",
                         "schema": {
                             "type": "string"
                         }
                     },
-                    "404": {
-                        "description": "subscription not found",
+                    "403": {
+                        "description": "forbidden",
                         "schema": {
                             "type": "string"
                         }
@@ -137,9 +173,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/subs/listAll": {
-            "get": {
-                "description": "Get all subscriptions for a user or service",
+        "/admin/catalog/providers/create": {
+            "post": {
+                "description": "Admin: add a provider with canonical name, aliases and category",
                 "consumes": [
                     "application/json"
                 ],
@@ -147,27 +183,147 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "subscriptions"
+                    "catalog-admin"
+                ],
+                "summary": "Create a catalog provider",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin token",
+                        "name": "X-Admin-Token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Provider",
+                        "name": "provider",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.JSONProviderRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Provider"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid request body or failed to create",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/catalog/providers/delete": {
+            "delete": {
+                "description": "Admin: delete a provider together with its plans, subscriptions referencing them keep their name and price but are unlinked from the catalog",
+                "tags": [
+                    "catalog-admin"
+                ],
+                "summary": "Delete a catalog provider",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin token",
+                        "name": "X-Admin-Token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Provider ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "missing id",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/catalog/providers/update": {
+            "put": {
+                "description": "Admin: replace a provider's name, aliases and category",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "catalog-admin"
+                ],
+                "summary": "Update a catalog provider",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin token",
+                        "name": "X-Admin-Token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Provider ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Provider",
+                        "name": "provider",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.JSONProviderRequest"
+                        }
+                    }
                 ],
-                "summary": "List all subscriptions",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.Sub"
-                            }
+                            "$ref": "#/definitions/models.Provider"
                         }
                     },
                     "400": {
-                        "description": "invalid request body",
+                        "description": "invalid request body or failed update",
                         "schema": {
                             "type": "string"
                         }
                     },
-                    "500": {
-                        "description": "failed to list subscriptions",
+                    "403": {
+                        "description": "forbidden",
                         "schema": {
                             "type": "string"
                         }
@@ -175,33 +331,70 @@ const docTemplate = `{
                 }
             }
         },
-        "/subs/total-cost": {
+        "/admin/outbox/status": {
             "get": {
-                "description": "Returns the total subscription cost in a given date range, optionally filtered by user_id and service_name",
-                "consumes": [
+                "description": "Seq of the newest outbox event and the last seq each sink has published",
+                "produces": [
                     "application/json"
                 ],
+                "tags": [
+                    "outbox"
+                ],
+                "summary": "Outbox relay status",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin token",
+                        "name": "X-Admin-Token",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/services.OutboxStatus"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/reconciliation/run": {
+            "post": {
+                "description": "Reconcile payments with the expected charges now instead of waiting for the daily run. Without start/end the trailing RECONCILE_MONTHS months up to the current month are checked.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "subscriptions"
+                    "payments"
                 ],
-                "summary": "Get total subscription cost",
+                "summary": "Run the reconciliation job",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Start date in YYYY-MM-DD format (write - 01 for DD as it is set like that in GORM by default) - like YYYY-MM-01",
-                        "name": "start",
-                        "in": "query",
+                        "description": "Admin token",
+                        "name": "X-Admin-Token",
+                        "in": "header",
                         "required": true
                     },
                     {
                         "type": "string",
-                        "description": "End date in YYYY-MM-DD format (write - 01 for DD as it is set like that in GORM by default) - like YYYY-MM-01",
+                        "description": "First month in MM-YYYY format",
+                        "name": "start",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Last month in MM-YYYY format",
                         "name": "end",
-                        "in": "query",
-                        "required": true
+                        "in": "query"
                     },
                     {
                         "type": "string",
@@ -214,14 +407,19 @@ const docTemplate = `{
                         "description": "Service name",
                         "name": "service_name",
                         "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "tenant_id",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Total cost response",
+                        "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/services.ReconciliationRun"
                         }
                     },
                     "400": {
@@ -229,98 +427,6542 @@ const docTemplate = `{
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    "403": {
+                        "description": "forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
                     }
                 }
             }
         },
-        "/subs/update": {
-            "put": {
-                "description": "Update subscription details",
-                "consumes": [
-                    "application/json"
-                ],
+        "/admin/reminders/run": {
+            "post": {
+                "description": "Create and deliver the due reminders now instead of waiting for the next scheduler pass",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "subscriptions"
+                    "reminders"
                 ],
-                "summary": "Update a subscription",
+                "summary": "Run the reminder scheduler",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Subscription ID",
-                        "name": "id",
-                        "in": "query",
+                        "description": "Admin token",
+                        "name": "X-Admin-Token",
+                        "in": "header",
                         "required": true
-                    },
-                    {
-                        "description": "Updated Subscription",
-                        "name": "sub",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/handlers.JSONSubRequest"
-                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Sub"
+                            "$ref": "#/definitions/services.ReminderRun"
                         }
                     },
-                    "400": {
-                        "description": "invalid request body or failed update",
+                    "403": {
+                        "description": "forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "scheduler pass failed",
                         "schema": {
                             "type": "string"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "handlers.JSONSubRequest": {
-            "type": "object",
-            "properties": {
-                "end_date": {
-                    "type": "string"
-                },
-                "price": {
-                    "type": "integer"
-                },
-                "service_name": {
-                    "type": "string"
-                },
+        },
+        "/budgets/alerts": {
+            "get": {
+                "description": "Get the alerts raised when budgets crossed 80% or 100% of their amount, newest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "budgets"
+                ],
+                "summary": "List budget alerts",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Budget ID",
+                        "name": "budget_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Month in YYYY-MM format",
+                        "name": "period",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.BudgetAlert"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "invalid budget_id",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/budgets/create": {
+            "post": {
+                "description": "Create a monthly budget for a user (scope_id is the user_id), a category or a tenant",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "budgets"
+                ],
+                "summary": "Create a budget",
+                "parameters": [
+                    {
+                        "description": "Budget",
+                        "name": "budget",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.JSONBudgetRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Budget"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid request body or failed to create",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/budgets/delete": {
+            "delete": {
+                "description": "Delete a budget and its alerts",
+                "tags": [
+                    "budgets"
+                ],
+                "summary": "Delete a budget",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Budget ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "missing id",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/budgets/getById": {
+            "get": {
+                "description": "Get a budget with its spend in the current month",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "budgets"
+                ],
+                "summary": "Get a budget",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Budget ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/services.BudgetStatus"
+                        }
+                    },
+                    "404": {
+                        "description": "budget not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/budgets/listAll": {
+            "get": {
+                "description": "Get all budgets with their spend in the current month",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "budgets"
+                ],
+                "summary": "List budgets",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "user, category or tenant",
+                        "name": "scope",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/services.BudgetStatus"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "failed to list budgets",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/budgets/update": {
+            "put": {
+                "description": "Change the monthly amount of a budget",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "budgets"
+                ],
+                "summary": "Update a budget",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Budget ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "New amount",
+                        "name": "budget",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.JSONBudgetAmountRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Budget"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid request body or failed update",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/catalog/listAll": {
+            "get": {
+                "description": "Get all catalog providers with their plans, optionally filtered by category",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "catalog"
+                ],
+                "summary": "List the service catalog",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Category",
+                        "name": "category",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Provider"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "failed to list catalog",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/catalog/providers/getById": {
+            "get": {
+                "description": "Retrieve a catalog provider and its plans",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "catalog"
+                ],
+                "summary": "Get catalog provider by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Provider ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Provider"
+                        }
+                    },
+                    "400": {
+                        "description": "missing id",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "provider not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/categories/create": {
+            "post": {
+                "description": "Create a spending category, names are lowercased",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "categories"
+                ],
+                "summary": "Create a category",
+                "parameters": [
+                    {
+                        "description": "Category",
+                        "name": "category",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.JSONCategoryRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Category"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid request body or failed to create",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/categories/delete": {
+            "delete": {
+                "description": "Delete a category, subscriptions using it are left without a category",
+                "tags": [
+                    "categories"
+                ],
+                "summary": "Delete a category",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Category name",
+                        "name": "name",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "missing name",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "category not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/categories/listAll": {
+            "get": {
+                "description": "Get all categories",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "categories"
+                ],
+                "summary": "List categories",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Category"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "failed to list categories",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/feeds/calendar.ics": {
+            "get": {
+                "description": "Renewals, trial ends and end dates of the feed's subscriptions, for calendar apps to subscribe to",
+                "produces": [
+                    "text/calendar"
+                ],
+                "tags": [
+                    "feeds"
+                ],
+                "summary": "iCalendar feed",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Feed token",
+                        "name": "token",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "iCalendar feed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "feed not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/feeds/create": {
+            "post": {
+                "description": "Create the .ics feed of a user's or a tenant's subscriptions. The returned token is shown only once.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "feeds"
+                ],
+                "summary": "Create a calendar feed",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID (UUID format)",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "tenant_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/services.FeedToken"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid scope or feed already exists",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/feeds/delete": {
+            "delete": {
+                "description": "Revoke the feed of a user or tenant",
+                "tags": [
+                    "feeds"
+                ],
+                "summary": "Delete a calendar feed",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID (UUID format)",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "tenant_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid scope",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/feeds/rotate": {
+            "post": {
+                "description": "Issue a new token for the feed, the old feed URL stops working",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "feeds"
+                ],
+                "summary": "Rotate a calendar feed token",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID (UUID format)",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "tenant_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/services.FeedToken"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid scope",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "feed not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/imports/apps": {
+            "get": {
+                "description": "Names of the apps whose exports can be imported",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "imports"
+                ],
+                "summary": "List importable apps",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/imports/commit": {
+            "post": {
+                "description": "Import the rows of a preview in one transaction. Without a body every valid row that is not a duplicate is imported; rows picks the rows and include_duplicates imports duplicates too. A preview can only be committed once.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "imports"
+                ],
+                "summary": "Commit an import preview",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Preview ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Rows to import",
+                        "name": "commit",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/services.AppImportCommit"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/services.AppImportResult"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid request body or failed to commit",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/imports/getById": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "imports"
+                ],
+                "summary": "Get an import preview",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Preview ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/services.AppImportPreview"
+                        }
+                    },
+                    "404": {
+                        "description": "import preview not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/imports/preview": {
+            "post": {
+                "description": "Parse the export of another subscription app (bobby, trackmysubs, subscriptions_manager) and map it to subscriptions of the user. Rows are validated and checked for duplicates against the user's subscriptions, nothing is imported until the preview is committed. Previews expire after 24 hours.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "imports"
+                ],
+                "summary": "Preview an app import",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "Export file of the app",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "bobby, trackmysubs or subscriptions_manager",
+                        "name": "app",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "User the subscriptions are imported for",
+                        "name": "user_id",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "tenant_id",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Currency prices are kept in, e.g. RUB",
+                        "name": "currency",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "JSON object of exchange rates into currency, e.g. {\\",
+                        "name": "rates",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/services.AppImportPreview"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid upload or failed to parse",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/reminders/listAll": {
+            "get": {
+                "description": "Get the renewal, trial end and end reminders with their delivery status",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reminders"
+                ],
+                "summary": "List reminders",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "sub_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "pending, sent or failed",
+                        "name": "status",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Reminder"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "invalid filter",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/reports/discrepancies": {
+            "get": {
+                "description": "Discrepancies found by the reconciliation job between the expected charges and the recorded payments: missed, double, amount_mismatch, after_end and unexpected. Discrepancies that are no longer found on a later run are resolved.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reports"
+                ],
+                "summary": "Payment discrepancy report",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID (UUID format)",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "sub_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "tenant_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "missed, double, amount_mismatch, after_end or unexpected",
+                        "name": "kind",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "open (default) or resolved, all for both",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "First month in MM-YYYY format",
+                        "name": "start",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Last month in MM-YYYY format",
+                        "name": "end",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Discrepancy"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/reports/forecast": {
+            "post": {
+                "description": "Projects month-by-month charges of the active subscriptions per user and service, taking billing periods, trials and scheduled price changes into account.\nOverrides add what-if scenarios (cancel, add, change_price), the baseline totals show the forecast without them. Months and the overall total break the amounts down into net, tax and gross.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reports"
+                ],
+                "summary": "Spending forecast",
+                "parameters": [
+                    {
+                        "description": "Start month (MM-YYYY, default next month), number of months (default 12) and what-if overrides",
+                        "name": "forecast",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/services.ForecastRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID (UUID format), counts their share of shared subscriptions",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Service name",
+                        "name": "service_name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Category",
+                        "name": "category",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tag name",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "tenant_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/services.Forecast"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/reports/settlement": {
+            "get": {
+                "description": "Who owes whom for the shared subscriptions charged between start and end (inclusive). The owner of a subscription pays its charges and each member owes their share, balances are netted into as few transfers as possible.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reports"
+                ],
+                "summary": "Settle shared subscriptions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Start month in MM-YYYY format",
+                        "name": "start",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "End month in MM-YYYY format",
+                        "name": "end",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only subscriptions the user owns or is a member of",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Service name",
+                        "name": "service_name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "tenant_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/services.Settlement"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/reports/spending": {
+            "get": {
+                "description": "Time series of spend per month or year between start and end (inclusive), grouped by any combination of user, service and category.\nEach subscription is counted in the months it is billed in, according to its start date and billing period.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reports"
+                ],
+                "summary": "Spending breakdown report",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Start month in MM-YYYY format",
+                        "name": "start",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "End month in MM-YYYY format",
+                        "name": "end",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "month (default) or year",
+                        "name": "interval",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated list of user, service, category",
+                        "name": "group_by",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID (UUID format), counts their share of shared subscriptions",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Service name",
+                        "name": "service_name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Category",
+                        "name": "category",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tag name",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "tenant_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/services.SpendingReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/reports/vat": {
+            "get": {
+                "description": "Net, tax and gross of the year's expected charges (after discounts) per jurisdiction and rate, for the year and per quarter, at the rate in effect in the month of each charge. Charges of subscriptions without tax settings are summed as untaxed.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reports"
+                ],
+                "summary": "Yearly VAT summary",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Year in YYYY format",
+                        "name": "year",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID (UUID format)",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Service name",
+                        "name": "service_name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Category",
+                        "name": "category",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tag name",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "tenant_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/services.VATSummary"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/reports/vat/export": {
+            "get": {
+                "description": "One row per expected charge of a taxed subscription in the year, with its jurisdiction, rate, whether prices include tax and the net, tax and gross amounts, as CSV, NDJSON or XLSX (format parameter or Accept header, CSV by default).",
+                "produces": [
+                    "text/csv",
+                    "application/x-ndjson",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+                ],
+                "tags": [
+                    "reports"
+                ],
+                "summary": "Export the yearly VAT lines",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Year in YYYY format",
+                        "name": "year",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "csv, ndjson or xlsx",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID (UUID format)",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Service name",
+                        "name": "service_name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Category",
+                        "name": "category",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tag name",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "tenant_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "406": {
+                        "description": "unsupported format",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/statements/candidates": {
+            "get": {
+                "description": "Candidates found in the user's bank statements, highest confidence first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "statements"
+                ],
+                "summary": "List subscription candidates",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "user_id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only candidates of this statement import",
+                        "name": "import_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "pending, accepted or dismissed",
+                        "name": "status",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.StatementCandidate"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "invalid filter",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/statements/candidates/accept": {
+            "post": {
+                "description": "Create subscriptions from the listed candidates in one transaction. Inactive series end with their last charge. Candidates that are not pending, already tracked (unless include_tracked) or fail validation are reported as skipped.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "statements"
+                ],
+                "summary": "Accept subscription candidates",
+                "parameters": [
+                    {
+                        "description": "Candidates to accept",
+                        "name": "accept",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/services.StatementAccept"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/services.StatementAcceptResult"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid request body or failed to accept",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/statements/candidates/dismiss": {
+            "post": {
+                "description": "Only pending candidates can be dismissed, accepted ones keep their subscription",
+                "tags": [
+                    "statements"
+                ],
+                "summary": "Dismiss a subscription candidate",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Candidate ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "404": {
+                        "description": "pending candidate not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/statements/import": {
+            "post": {
+                "description": "Upload a bank statement (CSV, OFX/QFX or CAMT.053). Debits are grouped by merchant and recurring amounts at a weekly, monthly, quarterly or yearly cadence are stored as candidate subscriptions with a confidence score. Candidates the user already tracks carry matched_sub_id. The transactions themselves are not stored.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "statements"
+                ],
+                "summary": "Find subscriptions in a bank statement",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "Bank statement",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "User the statement belongs to",
+                        "name": "user_id",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "tenant_id",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "csv, ofx, qfx or camt053, detected from the content when empty",
+                        "name": "format",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Lowest confidence to propose, 0-1 (default 0.6)",
+                        "name": "min_confidence",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/services.StatementAnalysis"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid upload or failed to parse",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/category/set": {
+            "put": {
+                "description": "Set the category of a subscription, an empty category clears it",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Set subscription category",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Category name",
+                        "name": "category",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Sub"
+                        }
+                    },
+                    "400": {
+                        "description": "missing id or unknown category",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/create": {
+            "post": {
+                "description": "Create a subscription for a user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Create a new subscription",
+                "parameters": [
+                    {
+                        "description": "Subscription Request",
+                        "name": "sub",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.JSONSubRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Sub"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid request body or failed to create",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/delete": {
+            "delete": {
+                "description": "Delete a subscription by ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Delete a subscription",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "missing id",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "subscription not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/discounts/create": {
+            "post": {
+                "description": "Keep the real price and record the discount on top of it: a percentage of the charge or a fixed amount, for the first charge from start_date (MM-YYYY, default the current month) on (\"once\"), for a number of charges (\"repeating\" with periods) or \"forever\". Discounts are taken off every charge they cover, never below zero.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "discounts"
+                ],
+                "summary": "Add a discount to a subscription",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "sub_id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Discount",
+                        "name": "discount",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.JSONDiscountRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Discount"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid request body or failed to create",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/discounts/delete": {
+            "delete": {
+                "tags": [
+                    "discounts"
+                ],
+                "summary": "Delete a discount",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "sub_id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Discount ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "discount not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/discounts/listAll": {
+            "get": {
+                "description": "Get the discounts of a subscription, by start date",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "discounts"
+                ],
+                "summary": "List discounts",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "sub_id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Discount"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "missing or invalid sub_id",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/export": {
+            "get": {
+                "description": "Stream the subscriptions matching the list filters as CSV, NDJSON or XLSX, picked by the format parameter or the Accept header (CSV by default). Rows are read from the database one at a time. The first row holds the column names, select them with columns (id, service_name, service_name_input, name_confidence, price, billing_period, user_id, start_date, end_date, trial_end_date, next_charge_date, category, tags, tenant_id, provider_id, plan_id, metadata, metadata.\u003ckey\u003e).",
+                "produces": [
+                    "text/csv",
+                    "application/x-ndjson",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Export subscriptions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "csv, ndjson or xlsx",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated columns, e.g. service_name,price,metadata.email",
+                        "name": "columns",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID (UUID format)",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Service name",
+                        "name": "service_name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Catalog provider ID",
+                        "name": "provider_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Catalog plan ID",
+                        "name": "plan_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Category",
+                        "name": "category",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tag name",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "tenant_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Metadata value filter, use meta.\u003ckey\u003e=\u003cvalue\u003e for any key",
+                        "name": "meta.key",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only subscriptions whose metadata has this key",
+                        "name": "meta_has",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid filter or column",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "406": {
+                        "description": "unsupported format",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/getById": {
+            "get": {
+                "description": "Retrieve a subscription using its ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Get subscription by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Sub"
+                        }
+                    },
+                    "400": {
+                        "description": "missing or invalid id",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "subscription not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/import": {
+            "post": {
+                "description": "Import a CSV file of subscriptions. \"mapping\" maps fields (service_name, price, user_id, start_date, end_date, trial_end_date, billing_period, category, tags, tenant_id, provider_id, plan_id, metadata.\u003ckey\u003e) to CSV column headers, unmapped fields use the column of the same name. Every row is validated first; with dry_run, or if any row is invalid, nothing is written and the report lists the errors per row. Otherwise all rows are created in one transaction.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Import subscriptions from CSV",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "CSV file with a header row",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "JSON object of field to column, e.g. {\\",
+                        "name": "mapping",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Column delimiter, defaults to ,",
+                        "name": "delimiter",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Validate only",
+                        "name": "dry_run",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID for rows without one",
+                        "name": "user_id",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tenant ID for rows without one",
+                        "name": "tenant_id",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "dry run report",
+                        "schema": {
+                            "$ref": "#/definitions/services.ImportReport"
+                        }
+                    },
+                    "201": {
+                        "description": "imported",
+                        "schema": {
+                            "$ref": "#/definitions/services.ImportReport"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid upload or mapping",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "some rows are invalid, nothing was imported",
+                        "schema": {
+                            "$ref": "#/definitions/services.ImportReport"
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/listAll": {
+            "get": {
+                "description": "Get all subscriptions, optionally filtered by user, service, catalog provider, plan, category, tag, tenant or metadata",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "List all subscriptions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID (UUID format)",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Service name",
+                        "name": "service_name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Catalog provider ID",
+                        "name": "provider_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Catalog plan ID",
+                        "name": "plan_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Category",
+                        "name": "category",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tag name",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "tenant_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Metadata value filter, use meta.\u003ckey\u003e=\u003cvalue\u003e for any key",
+                        "name": "meta.key",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only subscriptions whose metadata has this key",
+                        "name": "meta_has",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Sub"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "invalid request body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "failed to list subscriptions",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/members/delete": {
+            "delete": {
+                "description": "Remove all members, the owner pays the full price again",
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Stop sharing a subscription",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "sub_id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "subscription is not shared",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/members/get": {
+            "get": {
+                "description": "Split rule, members and the share of each user at the current price",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Get the members of a shared subscription",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "sub_id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/services.SubSplitView"
+                        }
+                    },
+                    "404": {
+                        "description": "subscription is not shared",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/members/set": {
+            "put": {
+                "description": "Set the members of a shared subscription and how its price is split: equal, percentage (share is 1-100) or fixed (share is an amount). The owner (user_id of the subscription) pays it and takes the rest; the owner can be listed to set their own share. Replaces the previous members.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Share a subscription",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "sub_id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Split rule and members",
+                        "name": "split",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/services.SplitRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/services.SubSplitView"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid request body or failed to share",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/payments/create": {
+            "post": {
+                "description": "Add an actual charge of a subscription to its payments ledger, date in YYYY-MM-DD format. Status is paid (default), refunded, partially_refunded (with refunded_amount) or disputed.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "payments"
+                ],
+                "summary": "Record a payment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "sub_id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Payment",
+                        "name": "payment",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.JSONPaymentRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Payment"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid request body or failed to create",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/payments/delete": {
+            "delete": {
+                "tags": [
+                    "payments"
+                ],
+                "summary": "Delete a payment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "sub_id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Payment ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "payment not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/payments/import": {
+            "post": {
+                "description": "Upload a CSV with date (YYYY-MM-DD), amount, reference and sub_id columns, optionally currency, method, status and refunded_amount. The sub_id column can be left out when sub_id is given as a form field. Nothing is stored unless every row is valid.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "payments"
+                ],
+                "summary": "Import payments",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "CSV file",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Subscription of rows without a sub_id column",
+                        "name": "sub_id",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/services.PaymentImportReport"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid upload",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/services.PaymentImportReport"
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/payments/listAll": {
+            "get": {
+                "description": "Get the payments ledger of a subscription, oldest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "payments"
+                ],
+                "summary": "List payments",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "sub_id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Payment"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "missing or invalid sub_id",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/payments/update": {
+            "put": {
+                "description": "Change the details of a payment, e.g. to mark it refunded, partially_refunded (with refunded_amount) or disputed. Fields that are left out are kept.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "payments"
+                ],
+                "summary": "Update a payment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "sub_id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Payment ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Payment",
+                        "name": "payment",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.JSONPaymentRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Payment"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid request body or failed to update",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/plan-change": {
+            "post": {
+                "description": "Move a subscription to a new price or catalog plan from a day (YYYY-MM-DD). The old price segment is closed and a new one opened according to the proration policy: immediate (credit the rest of the period at the old price and charge it at the new one, by day, with the next charge, new price from the next charge), next_renewal (new price and catalog plan from the next charge) or none (new price for the whole current period). The default policy is PRORATION_POLICY (immediate).",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Change plan mid-period",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "sub_id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only compute the credits and charges",
+                        "name": "dry_run",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Plan change",
+                        "name": "change",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/services.PlanChangeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "dry run",
+                        "schema": {
+                            "$ref": "#/definitions/services.PlanChange"
+                        }
+                    },
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/services.PlanChange"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid request body or failed to change plan",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/price-changes/create": {
+            "post": {
+                "description": "Schedule a new price for a subscription, effective from the given month (MM-YYYY)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Schedule a price change",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "sub_id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Price change",
+                        "name": "change",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.JSONPriceChangeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.PriceChange"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid request body or failed to create",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/price-changes/delete": {
+            "delete": {
+                "description": "Delete a scheduled price change by ID",
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Delete a price change",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Price change ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "missing id",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/price-changes/listAll": {
+            "get": {
+                "description": "Get the scheduled price changes of a subscription, oldest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "List price changes",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "sub_id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.PriceChange"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "missing or invalid sub_id",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/pricing/delete": {
+            "delete": {
+                "description": "The subscription costs its price again, recorded usage is kept",
+                "tags": [
+                    "usage"
+                ],
+                "summary": "Remove the pricing model of a subscription",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "sub_id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "pricing not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/pricing/get": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "usage"
+                ],
+                "summary": "Get the pricing model of a subscription",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "sub_id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SubPricing"
+                        }
+                    },
+                    "400": {
+                        "description": "missing or invalid sub_id",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/pricing/set": {
+            "put": {
+                "description": "Make the subscription usage-based: every billing period costs base_fee plus the usage recorded in it, priced per_unit, tiered (each tier prices its own units, e.g. the first 1000 free) or volume (the tier the total falls into prices all units). Unit prices are per unit_size units. Recorded usage is repriced; model \"flat\" goes back to the subscription price.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "usage"
+                ],
+                "summary": "Set the pricing model of a subscription",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "sub_id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Pricing model",
+                        "name": "pricing",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/services.PricingRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SubPricing"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid request body or pricing",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/resolve": {
+            "get": {
+                "description": "Returns the canonical catalog name for a free-text service name together with a confidence score, and the closest catalog names as suggestions when it is not resolved",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Resolve a service name",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Service name as typed by the user",
+                        "name": "name",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/services.Resolution"
+                        }
+                    },
+                    "400": {
+                        "description": "missing name",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/seats/assign": {
+            "post": {
+                "description": "Track who uses a seat of the subscription from the given day (YYYY-MM-DD, default today). The assignee is free text such as an email, user_id optionally links a user.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "seats"
+                ],
+                "summary": "Assign a seat",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "sub_id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Assignment",
+                        "name": "assignment",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.JSONSeatAssignRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.SeatAssignment"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid request body or failed to assign",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/seats/assignments": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "seats"
+                ],
+                "summary": "List seat assignments",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "sub_id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.SeatAssignment"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "missing or invalid sub_id",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/seats/create": {
+            "post": {
+                "description": "Set the number of seats from the given day (YYYY-MM-DD) on. The first seat change makes the subscription seat-based: its price is then the price of one seat. A prorated change inside a billing period is charged for the rest of the period with the next charge, otherwise it counts from the next charge.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "seats"
+                ],
+                "summary": "Change the seats of a subscription",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "sub_id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Seat change",
+                        "name": "change",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.JSONSeatChangeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.SeatChange"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid request body or failed to create",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/seats/delete": {
+            "delete": {
+                "tags": [
+                    "seats"
+                ],
+                "summary": "Delete a seat change",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "sub_id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Seat change ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "seat change not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/seats/listAll": {
+            "get": {
+                "description": "Get the seat history of a subscription, oldest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "seats"
+                ],
+                "summary": "List seat changes",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "sub_id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.SeatChange"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "missing or invalid sub_id",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/seats/unassign": {
+            "post": {
+                "description": "Free the seat from the given day (YYYY-MM-DD, default today) on",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "seats"
+                ],
+                "summary": "Unassign a seat",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "sub_id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Seat assignment ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Day the seat is freed",
+                        "name": "date",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SeatAssignment"
+                        }
+                    },
+                    "400": {
+                        "description": "failed to unassign",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/seats/utilization": {
+            "get": {
+                "description": "Seats, assigned users, unused seats and their monthly cost at the end of each month between start and end (MM-YYYY)",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "seats"
+                ],
+                "summary": "Seat utilization",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "sub_id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Start month in MM-YYYY format",
+                        "name": "start",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "End month in MM-YYYY format",
+                        "name": "end",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/services.SeatUtilization"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/stream": {
+            "get": {
+                "description": "Server-Sent Events stream of subscription.created, subscription.updated and subscription.deleted events, and of the budget.alert events of user and tenant budgets.\nThe SSE id is the event seq; reconnect with the Last-Event-ID header (or last_event_id) to replay the missed events. A comment line is sent every 15 seconds as heartbeat.",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Stream subscription changes",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID (UUID format)",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Service name",
+                        "name": "service_name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "tenant_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Resume after this event id, for clients that can't send Last-Event-ID",
+                        "name": "last_event_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Resume after this event id",
+                        "name": "Last-Event-ID",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "event stream",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid filter",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/suggest": {
+            "get": {
+                "description": "Autocomplete for service names: matches the query against catalog names and aliases (English/Russian, transliterated, typo tolerant)",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Suggest service names",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Partial service name",
+                        "name": "q",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Max suggestions (default 10)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/services.Resolution"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "missing q",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/tags/set": {
+            "put": {
+                "description": "Replace the tags of a subscription, unknown tag names are created",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Set subscription tags",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Tag names",
+                        "name": "tags",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.JSONSubTagsRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Sub"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid request body or failed update",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/tax/delete": {
+            "delete": {
+                "description": "The subscription is no longer taxed",
+                "tags": [
+                    "taxes"
+                ],
+                "summary": "Remove the tax of a subscription",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "sub_id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "tax settings not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/tax/get": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "taxes"
+                ],
+                "summary": "Get the tax of a subscription",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "sub_id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SubTax"
+                        }
+                    },
+                    "404": {
+                        "description": "subscription has no tax settings",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/tax/set": {
+            "put": {
+                "description": "Tax the subscription at the rate of its jurisdiction, or at its own rate (in percent) when given. With inclusive (the default) the price already contains the tax, with inclusive false the tax is added on top of it.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "taxes"
+                ],
+                "summary": "Set the tax of a subscription",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "sub_id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Tax settings",
+                        "name": "tax",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/services.SubTaxRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SubTax"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid request body or tax settings",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/total-cost": {
+            "get": {
+                "description": "Returns the total subscription cost in a given date range, optionally filtered by user_id, service_name, catalog provider, plan, category or tag and grouped by category or tag\nbasis=expected (default) sums the list prices after discounts, with the gross_cost before them and a tax_breakdown of the total into net, tax and gross, basis=actual sums the recorded payments after refunds, with paid, refunded and disputed amounts per currency",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Get total subscription cost",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Start date in YYYY-MM-DD format (write - 01 for DD as it is set like that in GORM by default) - like YYYY-MM-01",
+                        "name": "start",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "End date in YYYY-MM-DD format (write - 01 for DD as it is set like that in GORM by default) - like YYYY-MM-01",
+                        "name": "end",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID (UUID format)",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Service name",
+                        "name": "service_name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Catalog provider ID",
+                        "name": "provider_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Catalog plan ID",
+                        "name": "plan_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Category",
+                        "name": "category",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tag name",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "tenant_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Break the total down by category or tag",
+                        "name": "group_by",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "expected (default) or actual",
+                        "name": "basis",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Total cost response",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/upcoming": {
+            "get": {
+                "description": "Charges due within a window, sorted by date and summed per day, with their tax breakdown. Returns an iCalendar file of the gross amounts charged with format=ics or Accept: text/calendar.",
+                "produces": [
+                    "application/json",
+                    "text/calendar"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "List upcoming charges",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "First day of the window in YYYY-MM-DD format, defaults to today",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Window length in days (default 14, max 366)",
+                        "name": "days",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "json (default) or ics",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID (UUID format), lists their share of shared subscriptions",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Service name",
+                        "name": "service_name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Category",
+                        "name": "category",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tag name",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "tenant_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/services.UpcomingCharges"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/update": {
+            "put": {
+                "description": "Update subscription details",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Update a subscription",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Updated Subscription",
+                        "name": "sub",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.JSONSubRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Sub"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid request body or failed update",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/usage/listAll": {
+            "get": {
+                "description": "Usage records of a subscription between two days (YYYY-MM-DD, inclusive), the current month by default",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "usage"
+                ],
+                "summary": "List usage records",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "sub_id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "First day in YYYY-MM-DD format",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Last day in YYYY-MM-DD format",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.UsageRecord"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/usage/record": {
+            "post": {
+                "description": "Ingest up to 1000 usage records of a usage-based subscription. Timestamps are RFC 3339 or YYYY-MM-DD; a record whose idempotency_key was already recorded for the subscription is skipped, so batches can be retried safely.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "usage"
+                ],
+                "summary": "Record usage",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "sub_id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Usage records",
+                        "name": "usage",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.JSONUsageRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/services.UsageIngestResult"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid request body or records",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/usage/summary": {
+            "get": {
+                "description": "Base fee, units and usage priced per tier for each billing period starting between start and end (MM-YYYY, the last 12 months by default)",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "usage"
+                ],
+                "summary": "Usage charges per billing period",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "sub_id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Start month in MM-YYYY format",
+                        "name": "start",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "End month in MM-YYYY format",
+                        "name": "end",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/services.UsageSummary"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/tags/create": {
+            "post": {
+                "description": "Create a user-defined tag, names are lowercased",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tags"
+                ],
+                "summary": "Create a tag",
+                "parameters": [
+                    {
+                        "description": "Tag",
+                        "name": "tag",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.JSONTagRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Tag"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid request body or failed to create",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/tags/delete": {
+            "delete": {
+                "description": "Delete a tag and remove it from all subscriptions",
+                "tags": [
+                    "tags"
+                ],
+                "summary": "Delete a tag",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tag ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "missing id",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/tags/listAll": {
+            "get": {
+                "description": "Get all tags",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tags"
+                ],
+                "summary": "List tags",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Tag"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "failed to list tags",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/taxes/rates/create": {
+            "post": {
+                "description": "Set the tax rate (in percent) of a country or jurisdiction such as DE or US-CA from valid_from (MM-YYYY) on, a later rate of the same jurisdiction replaces it. Requires the X-Admin-Token header.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "taxes"
+                ],
+                "summary": "Add a tax rate",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin token",
+                        "name": "X-Admin-Token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Tax rate",
+                        "name": "rate",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.JSONTaxRateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.TaxRate"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid request body or failed to create",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/taxes/rates/delete": {
+            "delete": {
+                "description": "Requires the X-Admin-Token header",
+                "tags": [
+                    "taxes"
+                ],
+                "summary": "Delete a tax rate",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin token",
+                        "name": "X-Admin-Token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tax rate ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "tax rate not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/taxes/rates/listAll": {
+            "get": {
+                "description": "Get the tax rates of all jurisdictions, oldest first per jurisdiction",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "taxes"
+                ],
+                "summary": "List tax rates",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.TaxRate"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "failed to list tax rates",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/metadata-schema/delete": {
+            "delete": {
+                "description": "Remove the schema, the tenant's metadata is no longer validated",
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Delete a tenant's metadata schema",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "tenant_id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "missing tenant_id",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/metadata-schema/get": {
+            "get": {
+                "description": "Retrieve the JSON Schema used to validate the tenant's subscription metadata",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Get a tenant's metadata schema",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "tenant_id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.TenantSchema"
+                        }
+                    },
+                    "400": {
+                        "description": "missing tenant_id",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "schema not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/metadata-schema/set": {
+            "put": {
+                "description": "Create or replace the JSON Schema that the metadata of the tenant's subscriptions must satisfy",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Set a tenant's metadata schema",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "tenant_id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "JSON Schema",
+                        "name": "schema",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.TenantSchema"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid schema",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/create": {
+            "post": {
+                "description": "Register an endpoint for the subscription events of a tenant. event_types filters the events (subscription.created, subscription.updated, subscription.deleted, subscription.renewed, subscription.ended, budget.alert), empty means all.\nThe signing secret is returned only once.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Register a webhook endpoint",
+                "parameters": [
+                    {
+                        "description": "Webhook endpoint",
+                        "name": "endpoint",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.JSONWebhookRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/services.WebhookEndpointSecret"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid request body or failed to create",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/delete": {
+            "delete": {
+                "description": "Delete an endpoint and its delivery log",
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Delete a webhook endpoint",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Webhook endpoint ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "missing id",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/deliveries": {
+            "get": {
+                "description": "Get the latest 200 deliveries with their status, attempts and last error",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Webhook delivery log",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Webhook endpoint ID",
+                        "name": "endpoint_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "pending, delivered or dead",
+                        "name": "status",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.WebhookDelivery"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "invalid filter",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/deliveries/redeliver": {
+            "post": {
+                "description": "Queue a delivery again with a fresh set of attempts, e.g. a dead one",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Redeliver a webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Delivery ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/models.WebhookDelivery"
+                        }
+                    },
+                    "404": {
+                        "description": "delivery not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/listAll": {
+            "get": {
+                "description": "Get the registered webhook endpoints, optionally of one tenant",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "List webhook endpoints",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "tenant_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.WebhookEndpoint"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "invalid tenant_id",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/update": {
+            "put": {
+                "description": "Change the url, event types or active flag of an endpoint",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Update a webhook endpoint",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Webhook endpoint ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Webhook endpoint",
+                        "name": "endpoint",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.JSONWebhookRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.WebhookEndpoint"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid request body or failed update",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "handlers.JSONBudgetAmountRequest": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.JSONBudgetRequest": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "integer"
+                },
+                "scope": {
+                    "type": "string"
+                },
+                "scope_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.JSONCategoryRequest": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.JSONDiscountRequest": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "integer"
+                },
+                "code": {
+                    "type": "string"
+                },
+                "duration": {
+                    "type": "string"
+                },
+                "kind": {
+                    "type": "string"
+                },
+                "percentage": {
+                    "type": "number"
+                },
+                "periods": {
+                    "type": "integer"
+                },
+                "start_date": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.JSONPaymentRequest": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "integer"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "date": {
+                    "type": "string"
+                },
+                "method": {
+                    "type": "string"
+                },
+                "reference": {
+                    "type": "string"
+                },
+                "refunded_amount": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.JSONPlanRequest": {
+            "type": "object",
+            "properties": {
+                "aliases": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "billing_period": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "price": {
+                    "type": "integer"
+                },
+                "provider_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.JSONPriceChangeRequest": {
+            "type": "object",
+            "properties": {
+                "effective_date": {
+                    "type": "string"
+                },
+                "price": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.JSONProviderRequest": {
+            "type": "object",
+            "properties": {
+                "aliases": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "category": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.JSONSeatAssignRequest": {
+            "type": "object",
+            "properties": {
+                "assignee": {
+                    "type": "string"
+                },
+                "date": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.JSONSeatChangeRequest": {
+            "type": "object",
+            "properties": {
+                "effective_date": {
+                    "type": "string"
+                },
+                "prorate": {
+                    "type": "boolean"
+                },
+                "seats": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.JSONSubRequest": {
+            "type": "object",
+            "properties": {
+                "billing_period": {
+                    "type": "string"
+                },
+                "category": {
+                    "type": "string"
+                },
+                "end_date": {
+                    "type": "string"
+                },
+                "metadata": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "plan_id": {
+                    "type": "string"
+                },
+                "price": {
+                    "type": "integer"
+                },
+                "provider_id": {
+                    "type": "string"
+                },
+                "service_name": {
+                    "type": "string"
+                },
+                "start_date": {
+                    "type": "string"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "tenant_id": {
+                    "type": "string"
+                },
+                "trial_end_date": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.JSONSubTagsRequest": {
+            "type": "object",
+            "properties": {
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "handlers.JSONTagRequest": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.JSONTaxRateRequest": {
+            "type": "object",
+            "properties": {
+                "jurisdiction": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "rate": {
+                    "type": "number"
+                },
+                "valid_from": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.JSONUsageRequest": {
+            "type": "object",
+            "properties": {
+                "records": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/services.UsageInput"
+                    }
+                }
+            }
+        },
+        "handlers.JSONWebhookRequest": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "type": "boolean"
+                },
+                "event_types": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "tenant_id": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Budget": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "scope": {
+                    "type": "string"
+                },
+                "scope_id": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.BudgetAlert": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "integer"
+                },
+                "budget_id": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "period": {
+                    "type": "string"
+                },
+                "scope": {
+                    "type": "string"
+                },
+                "scope_id": {
+                    "type": "string"
+                },
+                "spend": {
+                    "type": "integer"
+                },
+                "threshold": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.CalendarFeed": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "rotated_at": {
+                    "type": "string"
+                },
+                "scope": {
+                    "type": "string"
+                },
+                "scope_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Category": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Discount": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "integer"
+                },
+                "code": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "duration": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "kind": {
+                    "type": "string"
+                },
+                "percentage": {
+                    "type": "number"
+                },
+                "periods": {
+                    "type": "integer"
+                },
+                "start_date": {
+                    "type": "string"
+                },
+                "sub_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Discrepancy": {
+            "type": "object",
+            "properties": {
+                "actual": {
+                    "type": "integer"
+                },
+                "detected_at": {
+                    "type": "string"
+                },
+                "expected": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "kind": {
+                    "type": "string"
+                },
+                "month": {
+                    "type": "string"
+                },
+                "payment_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "resolved_at": {
+                    "type": "string"
+                },
+                "service_name": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "sub_id": {
+                    "type": "string"
+                },
+                "tenant_id": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.JSONMap": {
+            "type": "object",
+            "additionalProperties": true
+        },
+        "models.OutboxCursor": {
+            "type": "object",
+            "properties": {
+                "seq": {
+                    "type": "integer"
+                },
+                "sink": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Payment": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "date": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "method": {
+                    "type": "string"
+                },
+                "reference": {
+                    "type": "string"
+                },
+                "refunded_amount": {
+                    "type": "integer"
+                },
+                "source": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "sub_id": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Plan": {
+            "type": "object",
+            "properties": {
+                "aliases": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "billing_period": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "price": {
+                    "type": "integer"
+                },
+                "provider_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.PriceChange": {
+            "type": "object",
+            "properties": {
+                "adjustment_month": {
+                    "type": "string"
+                },
+                "changed_at": {
+                    "type": "string"
+                },
+                "charge": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "credit": {
+                    "type": "integer"
+                },
+                "effective_date": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "plan_applied": {
+                    "type": "boolean"
+                },
+                "plan_from": {
+                    "type": "string"
+                },
+                "plan_id": {
+                    "type": "string"
+                },
+                "previous_price": {
+                    "type": "integer"
+                },
+                "price": {
+                    "type": "integer"
+                },
+                "proration_policy": {
+                    "type": "string"
+                },
+                "sub_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.PricingTier": {
+            "type": "object",
+            "properties": {
+                "flat_fee": {
+                    "type": "integer"
+                },
+                "unit_price": {
+                    "type": "integer"
+                },
+                "up_to": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Provider": {
+            "type": "object",
+            "properties": {
+                "aliases": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "category": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "plans": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Plan"
+                    }
+                }
+            }
+        },
+        "models.Reminder": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "channel": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "event_date": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "kind": {
+                    "type": "string"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "next_attempt_at": {
+                    "type": "string"
+                },
+                "price": {
+                    "type": "integer"
+                },
+                "sent_at": {
+                    "type": "string"
+                },
+                "service_name": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "sub_id": {
+                    "type": "string"
+                },
+                "tenant_id": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.SeatAssignment": {
+            "type": "object",
+            "properties": {
+                "assigned_at": {
+                    "type": "string"
+                },
+                "assignee": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "sub_id": {
+                    "type": "string"
+                },
+                "unassigned_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.SeatChange": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "effective_date": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "prorate": {
+                    "type": "boolean"
+                },
+                "seats": {
+                    "type": "integer"
+                },
+                "sub_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.StatementCandidate": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "type": "boolean"
+                },
+                "amount": {
+                    "type": "number"
+                },
+                "billing_period": {
+                    "type": "string"
+                },
+                "cadence": {
+                    "type": "string"
+                },
+                "category": {
+                    "type": "string"
+                },
+                "charges": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.StatementCharge"
+                    }
+                },
+                "confidence": {
+                    "type": "number"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "first_date": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "import_id": {
+                    "type": "string"
+                },
+                "last_date": {
+                    "type": "string"
+                },
+                "matched_sub_id": {
+                    "type": "string"
+                },
+                "merchant": {
+                    "type": "string"
+                },
+                "next_expected": {
+                    "type": "string"
+                },
+                "occurrences": {
+                    "type": "integer"
+                },
+                "price": {
+                    "type": "integer"
+                },
+                "provider_id": {
+                    "type": "string"
+                },
+                "service_name": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "sub_id": {
+                    "type": "string"
+                },
+                "tenant_id": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.StatementCharge": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "date": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.StatementImport": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "format": {
+                    "type": "string"
+                },
+                "from_date": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "tenant_id": {
+                    "type": "string"
+                },
+                "to_date": {
+                    "type": "string"
+                },
+                "transactions": {
+                    "type": "integer"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Sub": {
+            "type": "object",
+            "properties": {
+                "billing_period": {
+                    "type": "string"
+                },
+                "category": {
+                    "type": "string"
+                },
+                "discounts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Discount"
+                    }
+                },
+                "end_date": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "metadata": {
+                    "$ref": "#/definitions/models.JSONMap"
+                },
+                "name_confidence": {
+                    "type": "number"
+                },
+                "next_charge_date": {
+                    "type": "string"
+                },
+                "plan_id": {
+                    "type": "string"
+                },
+                "price": {
+                    "type": "integer"
+                },
+                "pricing": {
+                    "$ref": "#/definitions/models.SubPricing"
+                },
+                "provider_id": {
+                    "type": "string"
+                },
+                "seat_changes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.SeatChange"
+                    }
+                },
+                "service_name": {
+                    "type": "string"
+                },
+                "service_name_input": {
+                    "type": "string"
+                },
+                "start_date": {
+                    "type": "string"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Tag"
+                    }
+                },
+                "tax": {
+                    "$ref": "#/definitions/models.SubTax"
+                },
+                "tenant_id": {
+                    "type": "string"
+                },
+                "trial_end_date": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.SubMember": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "share": {
+                    "type": "integer"
+                },
+                "sub_id": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.SubPricing": {
+            "type": "object",
+            "properties": {
+                "base_fee": {
+                    "type": "integer"
+                },
+                "model": {
+                    "type": "string"
+                },
+                "sub_id": {
+                    "type": "string"
+                },
+                "tiers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.PricingTier"
+                    }
+                },
+                "unit": {
+                    "type": "string"
+                },
+                "unit_price": {
+                    "type": "integer"
+                },
+                "unit_size": {
+                    "type": "integer"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.SubTax": {
+            "type": "object",
+            "properties": {
+                "inclusive": {
+                    "type": "boolean"
+                },
+                "jurisdiction": {
+                    "type": "string"
+                },
+                "rate": {
+                    "type": "number"
+                },
+                "sub_id": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Tag": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.TaxRate": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "jurisdiction": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "rate": {
+                    "type": "number"
+                },
+                "valid_from": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.TenantSchema": {
+            "type": "object",
+            "properties": {
+                "schema": {
+                    "$ref": "#/definitions/models.JSONMap"
+                },
+                "tenant_id": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.UsageRecord": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "idempotency_key": {
+                    "type": "string"
+                },
+                "quantity": {
+                    "type": "integer"
+                },
+                "sub_id": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.WebhookDelivery": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "delivered_at": {
+                    "type": "string"
+                },
+                "endpoint_id": {
+                    "type": "string"
+                },
+                "event_id": {
+                    "type": "string"
+                },
+                "event_type": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "last_status_code": {
+                    "type": "integer"
+                },
+                "next_attempt_at": {
+                    "type": "string"
+                },
+                "payload": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.WebhookEndpoint": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "type": "boolean"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "event_types": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "tenant_id": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "repo.TaxBreakdown": {
+            "type": "object",
+            "properties": {
+                "gross": {
+                    "type": "integer"
+                },
+                "net": {
+                    "type": "integer"
+                },
+                "tax": {
+                    "type": "integer"
+                }
+            }
+        },
+        "services.AppImportCommit": {
+            "type": "object",
+            "properties": {
+                "include_duplicates": {
+                    "type": "boolean"
+                },
+                "rows": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "services.AppImportPreview": {
+            "type": "object",
+            "properties": {
+                "app": {
+                    "type": "string"
+                },
+                "committed_at": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "duplicates": {
+                    "type": "integer"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "invalid": {
+                    "type": "integer"
+                },
+                "rows": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/services.AppImportRow"
+                    }
+                },
+                "tenant_id": {
+                    "type": "string"
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "user_id": {
+                    "type": "string"
+                },
+                "valid": {
+                    "type": "integer"
+                }
+            }
+        },
+        "services.AppImportResult": {
+            "type": "object",
+            "properties": {
+                "imported": {
+                    "type": "integer"
+                },
+                "preview_id": {
+                    "type": "string"
+                },
+                "skipped": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/services.AppImportSkip"
+                    }
+                },
+                "subscriptions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Sub"
+                    }
+                }
+            }
+        },
+        "services.AppImportRow": {
+            "type": "object",
+            "properties": {
+                "duplicate_of": {
+                    "type": "string"
+                },
+                "duplicate_row": {
+                    "type": "integer"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "row": {
+                    "type": "integer"
+                },
+                "source": {
+                    "$ref": "#/definitions/services.AppRecord"
+                },
+                "subscription": {
+                    "$ref": "#/definitions/models.Sub"
+                },
+                "warnings": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "services.AppImportSkip": {
+            "type": "object",
+            "properties": {
+                "reason": {
+                    "type": "string"
+                },
+                "row": {
+                    "type": "integer"
+                }
+            }
+        },
+        "services.AppRecord": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "cancelled": {
+                    "type": "boolean"
+                },
+                "category": {
+                    "type": "string"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "cycle_count": {
+                    "type": "integer"
+                },
+                "cycle_unit": {
+                    "type": "string"
+                },
+                "end_date": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "notes": {
+                    "type": "string"
+                },
                 "start_date": {
                     "type": "string"
+                }
+            }
+        },
+        "services.BudgetStatus": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "percent": {
+                    "type": "integer"
+                },
+                "period": {
+                    "type": "string"
+                },
+                "scope": {
+                    "type": "string"
+                },
+                "scope_id": {
+                    "type": "string"
+                },
+                "spend": {
+                    "type": "integer"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "services.FeedToken": {
+            "type": "object",
+            "properties": {
+                "feed": {
+                    "$ref": "#/definitions/models.CalendarFeed"
+                },
+                "token": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "services.Forecast": {
+            "type": "object",
+            "properties": {
+                "baseline_total": {
+                    "type": "integer"
+                },
+                "end": {
+                    "type": "string"
+                },
+                "gross_total": {
+                    "type": "integer"
+                },
+                "months": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/services.ForecastMonth"
+                    }
+                },
+                "overrides": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/services.ForecastOverride"
+                    }
+                },
+                "start": {
+                    "type": "string"
+                },
+                "tax_breakdown": {
+                    "$ref": "#/definitions/repo.TaxBreakdown"
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "totals": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/services.ForecastLine"
+                    }
+                }
+            }
+        },
+        "services.ForecastLine": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "integer"
+                },
+                "service_name": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "services.ForecastMonth": {
+            "type": "object",
+            "properties": {
+                "baseline_total": {
+                    "type": "integer"
+                },
+                "gross_total": {
+                    "type": "integer"
+                },
+                "lines": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/services.ForecastLine"
+                    }
+                },
+                "month": {
+                    "type": "string"
+                },
+                "tax_breakdown": {
+                    "$ref": "#/definitions/repo.TaxBreakdown"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "services.ForecastOverride": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "billing_period": {
+                    "type": "string"
+                },
+                "from": {
+                    "type": "string"
+                },
+                "price": {
+                    "type": "integer"
+                },
+                "quantity": {
+                    "type": "integer"
+                },
+                "service_name": {
+                    "type": "string"
+                },
+                "sub_id": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "services.ForecastRequest": {
+            "type": "object",
+            "properties": {
+                "months": {
+                    "type": "integer"
+                },
+                "overrides": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/services.ForecastOverride"
+                    }
+                },
+                "start": {
+                    "type": "string"
+                }
+            }
+        },
+        "services.ImportReport": {
+            "type": "object",
+            "properties": {
+                "dry_run": {
+                    "type": "boolean"
+                },
+                "imported": {
+                    "type": "integer"
+                },
+                "invalid": {
+                    "type": "integer"
+                },
+                "rows": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/services.ImportRowResult"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "valid": {
+                    "type": "integer"
+                }
+            }
+        },
+        "services.ImportRowResult": {
+            "type": "object",
+            "properties": {
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "row": {
+                    "type": "integer"
+                },
+                "subscription": {
+                    "$ref": "#/definitions/models.Sub"
+                }
+            }
+        },
+        "services.MemberShare": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "integer"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "services.OutboxStatus": {
+            "type": "object",
+            "properties": {
+                "cursors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.OutboxCursor"
+                    }
+                },
+                "last_seq": {
+                    "type": "integer"
+                }
+            }
+        },
+        "services.PaymentImportReport": {
+            "type": "object",
+            "properties": {
+                "imported": {
+                    "type": "integer"
+                },
+                "invalid": {
+                    "type": "integer"
+                },
+                "rows": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/services.PaymentImportRow"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "services.PaymentImportRow": {
+            "type": "object",
+            "properties": {
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "payment": {
+                    "$ref": "#/definitions/models.Payment"
+                },
+                "row": {
+                    "type": "integer"
+                }
+            }
+        },
+        "services.PlanChange": {
+            "type": "object",
+            "properties": {
+                "change": {
+                    "$ref": "#/definitions/models.PriceChange"
+                },
+                "dry_run": {
+                    "type": "boolean"
+                },
+                "net": {
+                    "type": "integer"
+                },
+                "period_days": {
+                    "type": "integer"
+                },
+                "period_end": {
+                    "type": "string"
+                },
+                "period_start": {
+                    "type": "string"
+                },
+                "remaining_days": {
+                    "type": "integer"
+                }
+            }
+        },
+        "services.PlanChangeRequest": {
+            "type": "object",
+            "properties": {
+                "date": {
+                    "type": "string"
+                },
+                "plan_id": {
+                    "type": "string"
+                },
+                "price": {
+                    "type": "integer"
+                },
+                "proration_policy": {
+                    "type": "string"
+                }
+            }
+        },
+        "services.PricingRequest": {
+            "type": "object",
+            "properties": {
+                "base_fee": {
+                    "type": "integer"
+                },
+                "model": {
+                    "type": "string"
+                },
+                "tiers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.PricingTier"
+                    }
+                },
+                "unit": {
+                    "type": "string"
+                },
+                "unit_price": {
+                    "type": "integer"
+                },
+                "unit_size": {
+                    "type": "integer"
+                }
+            }
+        },
+        "services.ProjectedCharge": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "integer"
+                },
+                "date": {
+                    "type": "string"
+                },
+                "gross": {
+                    "type": "integer"
+                },
+                "service_name": {
+                    "type": "string"
+                },
+                "sub_id": {
+                    "type": "string"
+                },
+                "tax_breakdown": {
+                    "description": "TaxBreakdown splits Amount into net, tax and the gross that is charged",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/repo.TaxBreakdown"
+                        }
+                    ]
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "services.ReconciliationRun": {
+            "type": "object",
+            "properties": {
+                "by_kind": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                },
+                "end": {
+                    "type": "string"
+                },
+                "open": {
+                    "type": "integer"
+                },
+                "payments": {
+                    "type": "integer"
+                },
+                "start": {
+                    "type": "string"
+                },
+                "subs": {
+                    "type": "integer"
+                }
+            }
+        },
+        "services.ReminderRun": {
+            "type": "object",
+            "properties": {
+                "created": {
+                    "type": "integer"
+                },
+                "failed": {
+                    "type": "integer"
+                },
+                "retrying": {
+                    "type": "integer"
+                },
+                "sent": {
+                    "type": "integer"
+                }
+            }
+        },
+        "services.ReportGroup": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "integer"
+                },
+                "category": {
+                    "type": "string"
+                },
+                "gross": {
+                    "type": "integer"
+                },
+                "service_name": {
+                    "type": "string"
+                },
+                "tax_breakdown": {
+                    "$ref": "#/definitions/repo.TaxBreakdown"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "services.ReportPeriod": {
+            "type": "object",
+            "properties": {
+                "gross_subtotal": {
+                    "type": "integer"
+                },
+                "groups": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/services.ReportGroup"
+                    }
+                },
+                "period": {
+                    "type": "string"
+                },
+                "subtotal": {
+                    "type": "integer"
+                },
+                "tax_subtotal": {
+                    "$ref": "#/definitions/repo.TaxBreakdown"
+                }
+            }
+        },
+        "services.Resolution": {
+            "type": "object",
+            "properties": {
+                "canonical_name": {
+                    "type": "string"
+                },
+                "category": {
+                    "type": "string"
+                },
+                "confidence": {
+                    "type": "number"
+                },
+                "input": {
+                    "type": "string"
+                },
+                "match_type": {
+                    "type": "string"
+                },
+                "plan_id": {
+                    "type": "string"
+                },
+                "plan_name": {
+                    "type": "string"
+                },
+                "provider_id": {
+                    "type": "string"
+                },
+                "suggestions": {
+                    "description": "close catalog names when the name is not resolved, best first",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/services.Resolution"
+                    }
+                }
+            }
+        },
+        "services.SeatMonth": {
+            "type": "object",
+            "properties": {
+                "assigned": {
+                    "type": "integer"
+                },
+                "month": {
+                    "type": "string"
+                },
+                "seats": {
+                    "type": "integer"
+                },
+                "unused": {
+                    "type": "integer"
+                },
+                "unused_cost": {
+                    "type": "integer"
+                },
+                "utilization": {
+                    "type": "number"
+                }
+            }
+        },
+        "services.SeatUtilization": {
+            "type": "object",
+            "properties": {
+                "assignments": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.SeatAssignment"
+                    }
+                },
+                "months": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/services.SeatMonth"
+                    }
+                },
+                "service_name": {
+                    "type": "string"
+                },
+                "sub_id": {
+                    "type": "string"
+                },
+                "unit_price": {
+                    "type": "integer"
+                }
+            }
+        },
+        "services.Settlement": {
+            "type": "object",
+            "properties": {
+                "balances": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/services.SettlementBalance"
+                    }
+                },
+                "end": {
+                    "type": "string"
+                },
+                "start": {
+                    "type": "string"
+                },
+                "subs": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/services.SettlementSub"
+                    }
+                },
+                "transfers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/services.SettlementTransfer"
+                    }
+                }
+            }
+        },
+        "services.SettlementBalance": {
+            "type": "object",
+            "properties": {
+                "balance": {
+                    "type": "integer"
+                },
+                "paid": {
+                    "type": "integer"
+                },
+                "share": {
+                    "type": "integer"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "services.SettlementSub": {
+            "type": "object",
+            "properties": {
+                "charged": {
+                    "type": "integer"
+                },
+                "paid_by": {
+                    "type": "string"
+                },
+                "service_name": {
+                    "type": "string"
+                },
+                "shares": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/services.MemberShare"
+                    }
+                },
+                "split_rule": {
+                    "type": "string"
+                },
+                "sub_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "services.SettlementTransfer": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "integer"
+                },
+                "from": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                }
+            }
+        },
+        "services.SpendingReport": {
+            "type": "object",
+            "properties": {
+                "end": {
+                    "type": "string"
+                },
+                "grand_total": {
+                    "type": "integer"
+                },
+                "gross_total": {
+                    "type": "integer"
+                },
+                "group_by": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "group_totals": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/services.ReportGroup"
+                    }
+                },
+                "interval": {
+                    "type": "string"
+                },
+                "series": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/services.ReportPeriod"
+                    }
+                },
+                "start": {
+                    "type": "string"
+                },
+                "tax_total": {
+                    "$ref": "#/definitions/repo.TaxBreakdown"
+                }
+            }
+        },
+        "services.SplitMember": {
+            "type": "object",
+            "properties": {
+                "share": {
+                    "type": "integer"
                 },
                 "user_id": {
                     "type": "string"
                 }
             }
         },
-        "models.Sub": {
+        "services.SplitRequest": {
             "type": "object",
             "properties": {
-                "end_date": {
+                "members": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/services.SplitMember"
+                    }
+                },
+                "split_rule": {
                     "type": "string"
+                }
+            }
+        },
+        "services.StatementAccept": {
+            "type": "object",
+            "properties": {
+                "ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "include_tracked": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "services.StatementAcceptResult": {
+            "type": "object",
+            "properties": {
+                "skipped": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/services.StatementSkip"
+                    }
+                },
+                "subscriptions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Sub"
+                    }
+                }
+            }
+        },
+        "services.StatementAnalysis": {
+            "type": "object",
+            "properties": {
+                "candidates": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.StatementCandidate"
+                    }
                 },
+                "import": {
+                    "$ref": "#/definitions/models.StatementImport"
+                }
+            }
+        },
+        "services.StatementSkip": {
+            "type": "object",
+            "properties": {
                 "id": {
                     "type": "string"
                 },
+                "reason": {
+                    "type": "string"
+                }
+            }
+        },
+        "services.SubSplitView": {
+            "type": "object",
+            "properties": {
+                "members": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.SubMember"
+                    }
+                },
                 "price": {
                     "type": "integer"
                 },
+                "shares": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/services.MemberShare"
+                    }
+                },
+                "split_rule": {
+                    "type": "string"
+                },
+                "sub_id": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "services.SubTaxRequest": {
+            "type": "object",
+            "properties": {
+                "inclusive": {
+                    "type": "boolean"
+                },
+                "jurisdiction": {
+                    "type": "string"
+                },
+                "rate": {
+                    "type": "number"
+                }
+            }
+        },
+        "services.TierUsage": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "integer"
+                },
+                "flat_fee": {
+                    "type": "integer"
+                },
+                "from": {
+                    "type": "integer"
+                },
+                "unit_price": {
+                    "type": "integer"
+                },
+                "units": {
+                    "type": "integer"
+                },
+                "up_to": {
+                    "type": "integer"
+                }
+            }
+        },
+        "services.UpcomingCharges": {
+            "type": "object",
+            "properties": {
+                "days": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/services.UpcomingDay"
+                    }
+                },
+                "from": {
+                    "type": "string"
+                },
+                "tax_breakdown": {
+                    "$ref": "#/definitions/repo.TaxBreakdown"
+                },
+                "to": {
+                    "type": "string"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "services.UpcomingDay": {
+            "type": "object",
+            "properties": {
+                "charges": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/services.ProjectedCharge"
+                    }
+                },
+                "date": {
+                    "type": "string"
+                },
+                "tax_breakdown": {
+                    "$ref": "#/definitions/repo.TaxBreakdown"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "services.UsageIngestResult": {
+            "type": "object",
+            "properties": {
+                "duplicates": {
+                    "type": "integer"
+                },
+                "received": {
+                    "type": "integer"
+                },
+                "recorded": {
+                    "type": "integer"
+                }
+            }
+        },
+        "services.UsageInput": {
+            "type": "object",
+            "properties": {
+                "idempotency_key": {
+                    "type": "string"
+                },
+                "quantity": {
+                    "type": "integer"
+                },
+                "timestamp": {
+                    "type": "string"
+                }
+            }
+        },
+        "services.UsagePeriod": {
+            "type": "object",
+            "properties": {
+                "base_fee": {
+                    "type": "integer"
+                },
+                "period_end": {
+                    "type": "string"
+                },
+                "period_start": {
+                    "type": "string"
+                },
+                "tiers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/services.TierUsage"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "units": {
+                    "type": "integer"
+                },
+                "usage_amount": {
+                    "type": "integer"
+                }
+            }
+        },
+        "services.UsageSummary": {
+            "type": "object",
+            "properties": {
+                "periods": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/services.UsagePeriod"
+                    }
+                },
+                "pricing": {
+                    "$ref": "#/definitions/models.SubPricing"
+                },
                 "service_name": {
                     "type": "string"
                 },
-                "start_date": {
+                "sub_id": {
                     "type": "string"
                 },
-                "user_id": {
+                "total": {
+                    "type": "integer"
+                },
+                "units": {
+                    "type": "integer"
+                }
+            }
+        },
+        "services.VATLine": {
+            "type": "object",
+            "properties": {
+                "charges": {
+                    "type": "integer"
+                },
+                "gross": {
+                    "type": "integer"
+                },
+                "jurisdiction": {
+                    "type": "string"
+                },
+                "net": {
+                    "type": "integer"
+                },
+                "rate": {
+                    "type": "number"
+                },
+                "tax": {
+                    "type": "integer"
+                }
+            }
+        },
+        "services.VATQuarter": {
+            "type": "object",
+            "properties": {
+                "lines": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/services.VATLine"
+                    }
+                },
+                "quarter": {
+                    "type": "string"
+                },
+                "total": {
+                    "$ref": "#/definitions/repo.TaxBreakdown"
+                }
+            }
+        },
+        "services.VATSummary": {
+            "type": "object",
+            "properties": {
+                "lines": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/services.VATLine"
+                    }
+                },
+                "quarters": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/services.VATQuarter"
+                    }
+                },
+                "total": {
+                    "$ref": "#/definitions/repo.TaxBreakdown"
+                },
+                "untaxed": {
+                    "type": "integer"
+                },
+                "year": {
+                    "type": "integer"
+                }
+            }
+        },
+        "services.WebhookEndpointSecret": {
+            "type": "object",
+            "properties": {
+                "endpoint": {
+                    "$ref": "#/definitions/models.WebhookEndpoint"
+                },
+                "secret": {
                     "type": "string"
                 }
             }
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"online-subs-api/services"
+	"online-subs-api/utils"
+)
+
+type AppImportHandler struct{
+	appImportService *services.AppImportService
+}
+
+func NewAppImportHandler(appImportService *services.AppImportService) *AppImportHandler{
+	return &AppImportHandler{appImportService: appImportService}
+}
+
+// ListAppsHandler godoc
+// @Summary List importable apps
+// @Description Names of the apps whose exports can be imported
+// @Tags imports
+// @Produce json
+// @Success 200 {array} string
+// @Router /imports/apps [get]
+func (h *AppImportHandler) ListAppsHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("ListAppsHandler called")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(services.AppParserNames())
+}
+
+// PreviewImportHandler godoc
+// @Summary Preview an app import
+// @Description Parse the export of another subscription app (bobby, trackmysubs, subscriptions_manager) and map it to subscriptions of the user. Rows are validated and checked for duplicates against the user's subscriptions, nothing is imported until the preview is committed. Previews expire after 24 hours.
+// @Tags imports
+// @Accept mpfd
+// @Produce json
+// @Param file formData file true "Export file of the app"
+// @Param app formData string true "bobby, trackmysubs or subscriptions_manager"
+// @Param user_id formData string true "User the subscriptions are imported for"
+// @Param tenant_id formData string false "Tenant ID"
+// @Param currency formData string false "Currency prices are kept in, e.g. RUB"
+// @Param rates formData string false "JSON object of exchange rates into currency, e.g. {\"USD\": 92.5}"
+// @Success 201 {object} services.AppImportPreview
+// @Failure 400 {string} string "invalid upload or failed to parse"
+// @Router /imports/preview [post]
+func (h *AppImportHandler) PreviewImportHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("PreviewImportHandler called")
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportUpload)
+	if err := r.ParseMultipartForm(maxImportUpload); err != nil {
+		utils.ErrorLogger.Printf("Failed to parse upload: %v", err)
+		http.Error(w, "invalid upload, expected multipart/form-data", http.StatusBadRequest)
+		return
+	}
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		utils.WarningLogger.Println("Missing file in import request")
+		http.Error(w, "missing file paramter", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	opts := services.AppImportOptions{
+		App: r.FormValue("app"),
+		UserID: r.FormValue("user_id"),
+		TenantID: r.FormValue("tenant_id"),
+		Currency: r.FormValue("currency"),
+	}
+	if rates := r.FormValue("rates"); rates != "" {
+		if err := json.Unmarshal([]byte(rates), &opts.Rates); err != nil {
+			utils.ErrorLogger.Printf("Invalid rates: %v", err)
+			http.Error(w, "invalid rates, expected a JSON object", http.StatusBadRequest)
+			return
+		}
+	}
+
+	preview, err := h.appImportService.PreviewService(file, opts)
+	if err != nil {
+		utils.ErrorLogger.Printf("Failed to preview import: %v", err)
+		http.Error(w, "failed to preview import: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(preview)
+}
+
+// GetImportPreviewHandler godoc
+// @Summary Get an import preview
+// @Tags imports
+// @Produce json
+// @Param id query string true "Preview ID"
+// @Success 200 {object} services.AppImportPreview
+// @Failure 404 {string} string "import preview not found"
+// @Router /imports/getById [get]
+func (h *AppImportHandler) GetImportPreviewHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("GetImportPreviewHandler called")
+	id := r.URL.Query().Get("id")
+	if id == ""{
+		utils.WarningLogger.Println("Missing id parameter in request")
+		http.Error(w, "missing id paramter", http.StatusBadRequest)
+		return
+	}
+
+	preview, err := h.appImportService.GetPreviewService(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(preview)
+}
+
+// CommitImportHandler godoc
+// @Summary Commit an import preview
+// @Description Import the rows of a preview in one transaction. Without a body every valid row that is not a duplicate is imported; rows picks the rows and include_duplicates imports duplicates too. A preview can only be committed once.
+// @Tags imports
+// @Accept json
+// @Produce json
+// @Param id query string true "Preview ID"
+// @Param commit body services.AppImportCommit false "Rows to import"
+// @Success 201 {object} services.AppImportResult
+// @Failure 400 {string} string "invalid request body or failed to commit"
+// @Router /imports/commit [post]
+func (h *AppImportHandler) CommitImportHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("CommitImportHandler called")
+	id := r.URL.Query().Get("id")
+	if id == ""{
+		utils.WarningLogger.Println("Missing id parameter in request")
+		http.Error(w, "missing id paramter", http.StatusBadRequest)
+		return
+	}
+
+	var req services.AppImportCommit
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		utils.ErrorLogger.Printf("Failed to decode request body: %v", err)
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.appImportService.CommitService(id, req)
+	if err != nil {
+		utils.ErrorLogger.Printf("Failed to commit import %s: %v", id, err)
+		http.Error(w, "failed to commit import: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(result)
+}
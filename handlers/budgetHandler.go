@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"online-subs-api/models"
+	"online-subs-api/services"
+	"online-subs-api/utils"
+)
+
+type JSONBudgetRequest struct {
+	Scope   string `json:"scope"`
+	ScopeID string `json:"scope_id"`
+	Amount  int    `json:"amount"`
+}
+
+type JSONBudgetAmountRequest struct {
+	Amount int `json:"amount"`
+}
+
+type BudgetHandler struct{
+	budgetService *services.BudgetService
+}
+
+func NewBudgetHandler(budgetService *services.BudgetService) *BudgetHandler{
+	return &BudgetHandler{budgetService: budgetService}
+}
+
+// CreateBudgetHandler godoc
+// @Summary Create a budget
+// @Description Create a monthly budget for a user (scope_id is the user_id), a category or a tenant
+// @Tags budgets
+// @Accept json
+// @Produce json
+// @Param budget body JSONBudgetRequest true "Budget"
+// @Success 201 {object} models.Budget
+// @Failure 400 {string} string "invalid request body or failed to create"
+// @Router /budgets/create [post]
+func (h *BudgetHandler) CreateBudgetHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("CreateBudgetHandler called")
+
+	var req JSONBudgetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorLogger.Printf("Failed to decode request body: %v", err)
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	budget := &models.Budget{Scope: req.Scope, ScopeID: req.ScopeID, Amount: req.Amount}
+	if err := h.budgetService.CreateBudgetService(budget); err != nil {
+		utils.ErrorLogger.Printf("Failed to create budget: %v", err)
+		http.Error(w, "failed to create budget: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(budget)
+}
+
+// GetBudgetHandler godoc
+// @Summary Get a budget
+// @Description Get a budget with its spend in the current month
+// @Tags budgets
+// @Produce json
+// @Param id query string true "Budget ID"
+// @Success 200 {object} services.BudgetStatus
+// @Failure 404 {string} string "budget not found"
+// @Router /budgets/getById [get]
+func (h *BudgetHandler) GetBudgetHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("GetBudgetHandler called")
+	id := r.URL.Query().Get("id")
+	if id == ""{
+		utils.WarningLogger.Println("Missing id parameter in request")
+		http.Error(w, "missing id paramter", http.StatusBadRequest)
+		return
+	}
+
+	status, err := h.budgetService.GetBudgetService(id)
+	if err != nil {
+		utils.ErrorLogger.Printf("Budget not found for id=%s: %v", id, err)
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// ListBudgetsHandler godoc
+// @Summary List budgets
+// @Description Get all budgets with their spend in the current month
+// @Tags budgets
+// @Produce json
+// @Param scope query string false "user, category or tenant"
+// @Success 200 {array} services.BudgetStatus
+// @Failure 500 {string} string "failed to list budgets"
+// @Router /budgets/listAll [get]
+func (h *BudgetHandler) ListBudgetsHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("ListBudgetsHandler called")
+
+	statuses, err := h.budgetService.ListBudgetsService(r.URL.Query().Get("scope"))
+	if err != nil {
+		utils.ErrorLogger.Printf("Failed to list budgets: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(statuses)
+}
+
+// UpdateBudgetHandler godoc
+// @Summary Update a budget
+// @Description Change the monthly amount of a budget
+// @Tags budgets
+// @Accept json
+// @Produce json
+// @Param id query string true "Budget ID"
+// @Param budget body JSONBudgetAmountRequest true "New amount"
+// @Success 200 {object} models.Budget
+// @Failure 400 {string} string "invalid request body or failed update"
+// @Router /budgets/update [put]
+func (h *BudgetHandler) UpdateBudgetHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("UpdateBudgetHandler called")
+	id := r.URL.Query().Get("id")
+	if id == ""{
+		utils.WarningLogger.Println("Missing id parameter in request")
+		http.Error(w, "missing id paramter", http.StatusBadRequest)
+		return
+	}
+
+	var req JSONBudgetAmountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorLogger.Printf("Failed to decode request body: %v", err)
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	budget, err := h.budgetService.UpdateBudgetService(id, req.Amount)
+	if err != nil {
+		utils.ErrorLogger.Printf("Failed to update budget id=%s: %v", id, err)
+		http.Error(w, "failed to update budget: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(budget)
+}
+
+// DeleteBudgetHandler godoc
+// @Summary Delete a budget
+// @Description Delete a budget and its alerts
+// @Tags budgets
+// @Param id query string true "Budget ID"
+// @Success 204 {string} string "No Content"
+// @Failure 400 {string} string "missing id"
+// @Router /budgets/delete [delete]
+func (h *BudgetHandler) DeleteBudgetHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("DeleteBudgetHandler called")
+	id := r.URL.Query().Get("id")
+	if id == ""{
+		utils.WarningLogger.Println("Missing id parameter in request")
+		http.Error(w, "missing id paramter", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.budgetService.DeleteBudgetService(id); err != nil {
+		utils.ErrorLogger.Printf("Failed to delete budget id=%s: %v", id, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListBudgetAlertsHandler godoc
+// @Summary List budget alerts
+// @Description Get the alerts raised when budgets crossed 80% or 100% of their amount, newest first
+// @Tags budgets
+// @Produce json
+// @Param budget_id query string false "Budget ID"
+// @Param period query string false "Month in YYYY-MM format"
+// @Success 200 {array} models.BudgetAlert
+// @Failure 400 {string} string "invalid budget_id"
+// @Router /budgets/alerts [get]
+func (h *BudgetHandler) ListBudgetAlertsHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("ListBudgetAlertsHandler called")
+	q := r.URL.Query()
+
+	alerts, err := h.budgetService.ListAlertsService(q.Get("budget_id"), q.Get("period"))
+	if err != nil {
+		utils.ErrorLogger.Printf("Failed to list budget alerts: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(alerts)
+}
@@ -0,0 +1,350 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"online-subs-api/models"
+	"online-subs-api/services"
+	"online-subs-api/utils"
+	"os"
+)
+
+type JSONProviderRequest struct {
+	Name     string   `json:"name"`
+	Aliases  []string `json:"aliases"`
+	Category string   `json:"category"`
+}
+
+type JSONPlanRequest struct {
+	ProviderID    string   `json:"provider_id"`
+	Name          string   `json:"name"`
+	Aliases       []string `json:"aliases"`
+	Price         int      `json:"price"`
+	BillingPeriod string   `json:"billing_period"`
+}
+
+type CatalogHandler struct{
+	catalogService *services.CatalogService
+}
+
+func NewCatalogHandler(catalogService *services.CatalogService) *CatalogHandler{
+	return &CatalogHandler{catalogService: catalogService}
+}
+
+// authorizeAdmin checks the X-Admin-Token header against ADMIN_TOKEN, admin routes are
+// closed while ADMIN_TOKEN is not set
+func authorizeAdmin(w http.ResponseWriter, r *http.Request) bool{
+	token := os.Getenv("ADMIN_TOKEN")
+	if token != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Admin-Token")), []byte(token)) == 1 {
+		return true
+	}
+	utils.WarningLogger.Println("Rejected admin request without a valid token")
+	http.Error(w, "forbidden", http.StatusForbidden)
+	return false
+}
+
+// ListCatalogHandler godoc
+// @Summary List the service catalog
+// @Description Get all catalog providers with their plans, optionally filtered by category
+// @Tags catalog
+// @Produce json
+// @Param category query string false "Category"
+// @Success 200 {array} models.Provider
+// @Failure 500 {string} string "failed to list catalog"
+// @Router /catalog/listAll [get]
+func (h *CatalogHandler) ListCatalogHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("ListCatalogHandler called")
+
+	providers, err := h.catalogService.ListProvidersService(r.URL.Query().Get("category"))
+	if err != nil{
+		utils.ErrorLogger.Printf("Failed to list catalog: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(providers)
+}
+
+// GetProviderHandler godoc
+// @Summary Get catalog provider by ID
+// @Description Retrieve a catalog provider and its plans
+// @Tags catalog
+// @Produce json
+// @Param id query string true "Provider ID"
+// @Success 200 {object} models.Provider
+// @Failure 400 {string} string "missing id"
+// @Failure 404 {string} string "provider not found"
+// @Router /catalog/providers/getById [get]
+func (h *CatalogHandler) GetProviderHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("GetProviderHandler called")
+	id := r.URL.Query().Get("id")
+	if id == ""{
+		utils.WarningLogger.Println("Missing id parameter in request")
+		http.Error(w, "missing id paramter", http.StatusBadRequest)
+		return
+	}
+
+	provider, err := h.catalogService.GetProviderService(id)
+	if err != nil{
+		utils.ErrorLogger.Printf("Provider not found for id=%s: %v", id, err)
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(provider)
+}
+
+// CreateProviderHandler godoc
+// @Summary Create a catalog provider
+// @Description Admin: add a provider with canonical name, aliases and category
+// @Tags catalog-admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Token header string true "Admin token"
+// @Param provider body JSONProviderRequest true "Provider"
+// @Success 201 {object} models.Provider
+// @Failure 400 {string} string "invalid request body or failed to create"
+// @Failure 403 {string} string "forbidden"
+// @Router /admin/catalog/providers/create [post]
+func (h *CatalogHandler) CreateProviderHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("CreateProviderHandler called")
+	if !authorizeAdmin(w, r) {
+		return
+	}
+
+	var req JSONProviderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorLogger.Printf("Failed to decode request body: %v\n", err)
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	provider := &models.Provider{
+		Name: req.Name,
+		Aliases: req.Aliases,
+		Category: req.Category,
+	}
+
+	if err := h.catalogService.CreateProviderService(provider); err != nil {
+		utils.ErrorLogger.Printf("Failed to create provider: %v\n", err)
+		http.Error(w, "failed to create provider: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(provider)
+}
+
+// UpdateProviderHandler godoc
+// @Summary Update a catalog provider
+// @Description Admin: replace a provider's name, aliases and category
+// @Tags catalog-admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Token header string true "Admin token"
+// @Param id query string true "Provider ID"
+// @Param provider body JSONProviderRequest true "Provider"
+// @Success 200 {object} models.Provider
+// @Failure 400 {string} string "invalid request body or failed update"
+// @Failure 403 {string} string "forbidden"
+// @Router /admin/catalog/providers/update [put]
+func (h *CatalogHandler) UpdateProviderHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("UpdateProviderHandler called")
+	if !authorizeAdmin(w, r) {
+		return
+	}
+
+	id := r.URL.Query().Get("id")
+	if id == ""{
+		utils.WarningLogger.Println("Missing id parameter in request")
+		http.Error(w, "missing id paramter", http.StatusBadRequest)
+		return
+	}
+
+	var req JSONProviderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorLogger.Printf("Failed to decode request body: %v", err)
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	provider := &models.Provider{
+		ID: id,
+		Name: req.Name,
+		Aliases: req.Aliases,
+		Category: req.Category,
+	}
+
+	if err := h.catalogService.UpdateProviderService(provider); err != nil {
+		utils.ErrorLogger.Printf("Failed to update provider: %v", err)
+		http.Error(w, "failed to update provider: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(provider)
+}
+
+// DeleteProviderHandler godoc
+// @Summary Delete a catalog provider
+// @Description Admin: delete a provider together with its plans, subscriptions referencing them keep their name and price but are unlinked from the catalog
+// @Tags catalog-admin
+// @Param X-Admin-Token header string true "Admin token"
+// @Param id query string true "Provider ID"
+// @Success 204 {string} string "No Content"
+// @Failure 400 {string} string "missing id"
+// @Failure 403 {string} string "forbidden"
+// @Router /admin/catalog/providers/delete [delete]
+func (h *CatalogHandler) DeleteProviderHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("DeleteProviderHandler called")
+	if !authorizeAdmin(w, r) {
+		return
+	}
+
+	id := r.URL.Query().Get("id")
+	if id == ""{
+		utils.WarningLogger.Println("Missing id parameter in request")
+		http.Error(w, "missing id paramter", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.catalogService.DeleteProviderService(id); err != nil{
+		utils.ErrorLogger.Printf("Failed to delete provider id=%s: %v", id, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CreatePlanHandler godoc
+// @Summary Create a catalog plan
+// @Description Admin: add a plan with default price and billing period to a provider
+// @Tags catalog-admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Token header string true "Admin token"
+// @Param plan body JSONPlanRequest true "Plan"
+// @Success 201 {object} models.Plan
+// @Failure 400 {string} string "invalid request body or failed to create"
+// @Failure 403 {string} string "forbidden"
+// @Router /admin/catalog/plans/create [post]
+func (h *CatalogHandler) CreatePlanHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("CreatePlanHandler called")
+	if !authorizeAdmin(w, r) {
+		return
+	}
+
+	var req JSONPlanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorLogger.Printf("Failed to decode request body: %v\n", err)
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	plan := &models.Plan{
+		ProviderID: req.ProviderID,
+		Name: req.Name,
+		Aliases: req.Aliases,
+		Price: req.Price,
+		BillingPeriod: req.BillingPeriod,
+	}
+
+	if err := h.catalogService.CreatePlanService(plan); err != nil {
+		utils.ErrorLogger.Printf("Failed to create plan: %v\n", err)
+		http.Error(w, "failed to create plan: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(plan)
+}
+
+// UpdatePlanHandler godoc
+// @Summary Update a catalog plan
+// @Description Admin: replace a plan's name, aliases, default price and billing period
+// @Tags catalog-admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Token header string true "Admin token"
+// @Param id query string true "Plan ID"
+// @Param plan body JSONPlanRequest true "Plan"
+// @Success 200 {object} models.Plan
+// @Failure 400 {string} string "invalid request body or failed update"
+// @Failure 403 {string} string "forbidden"
+// @Router /admin/catalog/plans/update [put]
+func (h *CatalogHandler) UpdatePlanHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("UpdatePlanHandler called")
+	if !authorizeAdmin(w, r) {
+		return
+	}
+
+	id := r.URL.Query().Get("id")
+	if id == ""{
+		utils.WarningLogger.Println("Missing id parameter in request")
+		http.Error(w, "missing id paramter", http.StatusBadRequest)
+		return
+	}
+
+	var req JSONPlanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorLogger.Printf("Failed to decode request body: %v", err)
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	plan := &models.Plan{
+		ID: id,
+		ProviderID: req.ProviderID,
+		Name: req.Name,
+		Aliases: req.Aliases,
+		Price: req.Price,
+		BillingPeriod: req.BillingPeriod,
+	}
+
+	if err := h.catalogService.UpdatePlanService(plan); err != nil {
+		utils.ErrorLogger.Printf("Failed to update plan: %v", err)
+		http.Error(w, "failed to update plan: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(plan)
+}
+
+// DeletePlanHandler godoc
+// @Summary Delete a catalog plan
+// @Description Admin: delete a plan by ID, subscriptions on it are unlinked from the plan
+// @Tags catalog-admin
+// @Param X-Admin-Token header string true "Admin token"
+// @Param id query string true "Plan ID"
+// @Success 204 {string} string "No Content"
+// @Failure 400 {string} string "missing id"
+// @Failure 403 {string} string "forbidden"
+// @Router /admin/catalog/plans/delete [delete]
+func (h *CatalogHandler) DeletePlanHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("DeletePlanHandler called")
+	if !authorizeAdmin(w, r) {
+		return
+	}
+
+	id := r.URL.Query().Get("id")
+	if id == ""{
+		utils.WarningLogger.Println("Missing id parameter in request")
+		http.Error(w, "missing id paramter", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.catalogService.DeletePlanService(id); err != nil{
+		utils.ErrorLogger.Printf("Failed to delete plan id=%s: %v", id, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
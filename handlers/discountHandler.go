@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"online-subs-api/models"
+	"online-subs-api/utils"
+)
+
+type JSONDiscountRequest struct {
+	Code       string  `json:"code"`
+	Kind       string  `json:"kind"`
+	Percentage float64 `json:"percentage"`
+	Amount     int     `json:"amount"`
+	Duration   string  `json:"duration"`
+	Periods    int     `json:"periods"`
+	StartDate  string  `json:"start_date"`
+}
+
+// CreateDiscountHandler godoc
+// @Summary Add a discount to a subscription
+// @Description Keep the real price and record the discount on top of it: a percentage of the charge or a fixed amount, for the first charge from start_date (MM-YYYY, default the current month) on ("once"), for a number of charges ("repeating" with periods) or "forever". Discounts are taken off every charge they cover, never below zero.
+// @Tags discounts
+// @Accept json
+// @Produce json
+// @Param sub_id query string true "Subscription ID"
+// @Param discount body JSONDiscountRequest true "Discount"
+// @Success 201 {object} models.Discount
+// @Failure 400 {string} string "invalid request body or failed to create"
+// @Router /subs/discounts/create [post]
+func (h *SubsHandler) CreateDiscountHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("CreateDiscountHandler called")
+	subID := r.URL.Query().Get("sub_id")
+	if subID == ""{
+		utils.WarningLogger.Println("Missing sub_id parameter in request")
+		http.Error(w, "missing sub_id paramter", http.StatusBadRequest)
+		return
+	}
+
+	var req JSONDiscountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorLogger.Printf("Failed to decode request body: %v", err)
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	discount := &models.Discount{
+		SubID: subID,
+		Code: req.Code,
+		Kind: req.Kind,
+		Percentage: req.Percentage,
+		Amount: req.Amount,
+		Duration: req.Duration,
+		Periods: req.Periods,
+	}
+	if err := h.subsService.CreateDiscountService(discount, req.StartDate); err != nil {
+		utils.ErrorLogger.Printf("Failed to create discount: %v", err)
+		http.Error(w, "failed to create discount: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(discount)
+}
+
+// ListDiscountsHandler godoc
+// @Summary List discounts
+// @Description Get the discounts of a subscription, by start date
+// @Tags discounts
+// @Produce json
+// @Param sub_id query string true "Subscription ID"
+// @Success 200 {array} models.Discount
+// @Failure 400 {string} string "missing or invalid sub_id"
+// @Router /subs/discounts/listAll [get]
+func (h *SubsHandler) ListDiscountsHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("ListDiscountsHandler called")
+	subID := r.URL.Query().Get("sub_id")
+	if subID == ""{
+		utils.WarningLogger.Println("Missing sub_id parameter in request")
+		http.Error(w, "missing sub_id paramter", http.StatusBadRequest)
+		return
+	}
+
+	discounts, err := h.subsService.ListDiscountsService(subID)
+	if err != nil {
+		utils.ErrorLogger.Printf("Failed to list discounts: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(discounts)
+}
+
+// DeleteDiscountHandler godoc
+// @Summary Delete a discount
+// @Tags discounts
+// @Param sub_id query string true "Subscription ID"
+// @Param id query string true "Discount ID"
+// @Success 204 {string} string "No Content"
+// @Failure 404 {string} string "discount not found"
+// @Router /subs/discounts/delete [delete]
+func (h *SubsHandler) DeleteDiscountHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("DeleteDiscountHandler called")
+	q := r.URL.Query()
+	if q.Get("sub_id") == "" || q.Get("id") == ""{
+		utils.WarningLogger.Println("Missing sub_id/id parameter in request")
+		http.Error(w, "missing sub_id/id paramter", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.subsService.DeleteDiscountService(q.Get("sub_id"), q.Get("id")); err != nil {
+		utils.ErrorLogger.Printf("Failed to delete discount id=%s: %v", q.Get("id"), err)
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
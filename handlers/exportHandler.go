@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"online-subs-api/utils"
+	"strings"
+	"time"
+)
+
+// exportMediaTypes maps the media types accepted by the export to their format
+var exportMediaTypes = map[string]string{
+	"text/csv": utils.FormatCSV,
+	"application/x-ndjson": utils.FormatNDJSON,
+	"application/ndjson": utils.FormatNDJSON,
+	"application/jsonl": utils.FormatNDJSON,
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet": utils.FormatXLSX,
+}
+
+// exportFormat reads ?format=, or else the first media type of the Accept header that
+// can be served; CSV is the default
+func exportFormat(r *http.Request) (string, bool){
+	if format := strings.ToLower(r.URL.Query().Get("format")); format != "" {
+		_, ok := utils.TableContentTypes[format]
+		return format, ok
+	}
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return utils.FormatCSV, true
+	}
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil || params["q"] == "0" {
+			continue
+		}
+		if format, ok := exportMediaTypes[mediaType]; ok {
+			return format, true
+		}
+		if mediaType == "*/*" || mediaType == "text/*" {
+			return utils.FormatCSV, true
+		}
+	}
+	return "", false
+}
+
+// ExportSubsHandler godoc
+// @Summary Export subscriptions
+// @Description Stream the subscriptions matching the list filters as CSV, NDJSON or XLSX, picked by the format parameter or the Accept header (CSV by default). Rows are read from the database one at a time. The first row holds the column names, select them with columns (id, service_name, service_name_input, name_confidence, price, billing_period, user_id, start_date, end_date, trial_end_date, next_charge_date, category, tags, tenant_id, provider_id, plan_id, metadata, metadata.<key>).
+// @Tags subscriptions
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param format query string false "csv, ndjson or xlsx"
+// @Param columns query string false "Comma separated columns, e.g. service_name,price,metadata.email"
+// @Param user_id query string false "User ID (UUID format)"
+// @Param service_name query string false "Service name"
+// @Param provider_id query string false "Catalog provider ID"
+// @Param plan_id query string false "Catalog plan ID"
+// @Param category query string false "Category"
+// @Param tag query string false "Tag name"
+// @Param tenant_id query string false "Tenant ID"
+// @Param meta.key query string false "Metadata value filter, use meta.<key>=<value> for any key"
+// @Param meta_has query string false "Only subscriptions whose metadata has this key"
+// @Success 200 {file} file
+// @Failure 400 {string} string "invalid filter or column"
+// @Failure 406 {string} string "unsupported format"
+// @Router /subs/export [get]
+func (h *SubsHandler) ExportSubsHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("ExportSubsHandler called")
+
+	format, ok := exportFormat(r)
+	if !ok {
+		utils.WarningLogger.Println("Unsupported export format:", r.URL.Query().Get("format"), r.Header.Get("Accept"))
+		http.Error(w, "format must be csv, ndjson or xlsx", http.StatusNotAcceptable)
+		return
+	}
+
+	export, err := h.subsService.PrepareExportService(parseSubsFilter(r), r.URL.Query().Get("columns"))
+	if err != nil {
+		utils.ErrorLogger.Printf("Invalid export request: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writer, err := utils.NewTableWriter(format, w)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotAcceptable)
+		return
+	}
+	w.Header().Set("Content-Type", utils.TableContentTypes[format])
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="subscriptions-%s.%s"`, time.Now().UTC().Format("20060102"), format))
+	w.Header().Set("Vary", "Accept")
+
+	// the status is sent with the first row, a failure after that can only cut the file short
+	count, err := export.Write(writer)
+	if err != nil {
+		utils.ErrorLogger.Printf("Export aborted after %d rows: %v", count, err)
+		return
+	}
+	utils.InfoLogger.Printf("Exported %d subscriptions as %s", count, format)
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"online-subs-api/models"
+	"online-subs-api/services"
+	"online-subs-api/utils"
+)
+
+type FeedHandler struct{
+	feedService *services.FeedService
+}
+
+func NewFeedHandler(feedService *services.FeedService) *FeedHandler{
+	return &FeedHandler{feedService: feedService}
+}
+
+// feedScope reads whose feed is meant, either ?user_id= or ?tenant_id=
+func feedScope(r *http.Request) (string, string, error){
+	q := r.URL.Query()
+	userID, tenantID := q.Get("user_id"), q.Get("tenant_id")
+	switch {
+	case userID != "" && tenantID != "":
+		return "", "", errors.New("pass either user_id or tenant_id")
+	case userID != "":
+		return models.FeedScopeUser, userID, nil
+	case tenantID != "":
+		return models.FeedScopeTenant, tenantID, nil
+	}
+	return "", "", errors.New("missing user_id or tenant_id paramter")
+}
+
+// CreateFeedHandler godoc
+// @Summary Create a calendar feed
+// @Description Create the .ics feed of a user's or a tenant's subscriptions. The returned token is shown only once.
+// @Tags feeds
+// @Produce json
+// @Param user_id query string false "User ID (UUID format)"
+// @Param tenant_id query string false "Tenant ID"
+// @Success 201 {object} services.FeedToken
+// @Failure 400 {string} string "invalid scope or feed already exists"
+// @Router /feeds/create [post]
+func (h *FeedHandler) CreateFeedHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("CreateFeedHandler called")
+	scope, scopeID, err := feedScope(r)
+	if err != nil {
+		utils.WarningLogger.Println("Invalid feed scope:", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	token, err := h.feedService.CreateFeedService(scope, scopeID)
+	if err != nil {
+		utils.ErrorLogger.Printf("Failed to create feed: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(token)
+}
+
+// RotateFeedHandler godoc
+// @Summary Rotate a calendar feed token
+// @Description Issue a new token for the feed, the old feed URL stops working
+// @Tags feeds
+// @Produce json
+// @Param user_id query string false "User ID (UUID format)"
+// @Param tenant_id query string false "Tenant ID"
+// @Success 200 {object} services.FeedToken
+// @Failure 400 {string} string "invalid scope"
+// @Failure 404 {string} string "feed not found"
+// @Router /feeds/rotate [post]
+func (h *FeedHandler) RotateFeedHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("RotateFeedHandler called")
+	scope, scopeID, err := feedScope(r)
+	if err != nil {
+		utils.WarningLogger.Println("Invalid feed scope:", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	token, err := h.feedService.RotateFeedService(scope, scopeID)
+	if err != nil {
+		utils.ErrorLogger.Printf("Failed to rotate feed: %v", err)
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(token)
+}
+
+// DeleteFeedHandler godoc
+// @Summary Delete a calendar feed
+// @Description Revoke the feed of a user or tenant
+// @Tags feeds
+// @Param user_id query string false "User ID (UUID format)"
+// @Param tenant_id query string false "Tenant ID"
+// @Success 204 {string} string "No Content"
+// @Failure 400 {string} string "invalid scope"
+// @Router /feeds/delete [delete]
+func (h *FeedHandler) DeleteFeedHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("DeleteFeedHandler called")
+	scope, scopeID, err := feedScope(r)
+	if err != nil {
+		utils.WarningLogger.Println("Invalid feed scope:", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.feedService.DeleteFeedService(scope, scopeID); err != nil {
+		utils.ErrorLogger.Printf("Failed to delete feed: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CalendarFeedHandler godoc
+// @Summary iCalendar feed
+// @Description Renewals, trial ends and end dates of the feed's subscriptions, for calendar apps to subscribe to
+// @Tags feeds
+// @Produce text/calendar
+// @Param token query string true "Feed token"
+// @Success 200 {string} string "iCalendar feed"
+// @Failure 404 {string} string "feed not found"
+// @Router /feeds/calendar.ics [get]
+func (h *FeedHandler) CalendarFeedHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("CalendarFeedHandler called")
+	token := r.URL.Query().Get("token")
+	if token == ""{
+		http.Error(w, "feed not found", http.StatusNotFound)
+		return
+	}
+
+	calendar, err := h.feedService.FeedCalendarService(token)
+	if err != nil {
+		utils.WarningLogger.Printf("Failed to render feed: %v", err)
+		http.Error(w, "feed not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	calendar.WriteTo(w)
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"online-subs-api/services"
+	"online-subs-api/utils"
+	"strconv"
+	"unicode/utf8"
+)
+
+const maxImportUpload = 10 << 20
+
+// ImportSubsHandler godoc
+// @Summary Import subscriptions from CSV
+// @Description Import a CSV file of subscriptions. "mapping" maps fields (service_name, price, user_id, start_date, end_date, trial_end_date, billing_period, category, tags, tenant_id, provider_id, plan_id, metadata.<key>) to CSV column headers, unmapped fields use the column of the same name. Every row is validated first; with dry_run, or if any row is invalid, nothing is written and the report lists the errors per row. Otherwise all rows are created in one transaction.
+// @Tags subscriptions
+// @Accept mpfd
+// @Produce json
+// @Param file formData file true "CSV file with a header row"
+// @Param mapping formData string false "JSON object of field to column, e.g. {\"service_name\":\"Service\",\"price\":\"Cost\"}"
+// @Param delimiter formData string false "Column delimiter, defaults to ,"
+// @Param dry_run formData bool false "Validate only"
+// @Param user_id formData string false "User ID for rows without one"
+// @Param tenant_id formData string false "Tenant ID for rows without one"
+// @Success 200 {object} services.ImportReport "dry run report"
+// @Success 201 {object} services.ImportReport "imported"
+// @Failure 400 {string} string "invalid upload or mapping"
+// @Failure 422 {object} services.ImportReport "some rows are invalid, nothing was imported"
+// @Router /subs/import [post]
+func (h *SubsHandler) ImportSubsHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("ImportSubsHandler called")
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportUpload)
+	if err := r.ParseMultipartForm(maxImportUpload); err != nil {
+		utils.ErrorLogger.Printf("Failed to parse upload: %v", err)
+		http.Error(w, "invalid upload, expected multipart/form-data", http.StatusBadRequest)
+		return
+	}
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		utils.WarningLogger.Println("Missing file in import request")
+		http.Error(w, "missing file paramter", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	opts := services.ImportOptions{
+		DefaultUserID: r.FormValue("user_id"),
+		DefaultTenantID: r.FormValue("tenant_id"),
+	}
+	if mapping := r.FormValue("mapping"); mapping != "" {
+		if err := json.Unmarshal([]byte(mapping), &opts.Mapping); err != nil {
+			utils.ErrorLogger.Printf("Invalid import mapping: %v", err)
+			http.Error(w, "invalid mapping, expected a JSON object", http.StatusBadRequest)
+			return
+		}
+	}
+	if delimiter := r.FormValue("delimiter"); delimiter != "" {
+		if utf8.RuneCountInString(delimiter) != 1 {
+			http.Error(w, "delimiter must be a single character", http.StatusBadRequest)
+			return
+		}
+		opts.Delimiter, _ = utf8.DecodeRuneInString(delimiter)
+	}
+	if dryRun := r.FormValue("dry_run"); dryRun != "" {
+		if opts.DryRun, err = strconv.ParseBool(dryRun); err != nil {
+			http.Error(w, "invalid dry_run", http.StatusBadRequest)
+			return
+		}
+	}
+
+	report, err := h.subsService.ImportCSVService(file, opts)
+	if err != nil {
+		utils.ErrorLogger.Printf("Failed to import subscriptions: %v", err)
+		http.Error(w, "failed to import subscriptions: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	status := http.StatusOK
+	switch {
+	case report.Invalid > 0:
+		status = http.StatusUnprocessableEntity
+	case report.Imported > 0:
+		status = http.StatusCreated
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"online-subs-api/services"
+	"online-subs-api/utils"
+)
+
+type OutboxHandler struct{
+	relay *services.OutboxRelay
+}
+
+func NewOutboxHandler(relay *services.OutboxRelay) *OutboxHandler{
+	return &OutboxHandler{relay: relay}
+}
+
+// OutboxStatusHandler godoc
+// @Summary Outbox relay status
+// @Description Seq of the newest outbox event and the last seq each sink has published
+// @Tags outbox
+// @Produce json
+// @Param X-Admin-Token header string true "Admin token"
+// @Success 200 {object} services.OutboxStatus
+// @Failure 403 {string} string "forbidden"
+// @Router /admin/outbox/status [get]
+func (h *OutboxHandler) OutboxStatusHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("OutboxStatusHandler called")
+	if !authorizeAdmin(w, r) {
+		return
+	}
+
+	status, err := h.relay.StatusService()
+	if err != nil {
+		utils.ErrorLogger.Printf("Failed to load outbox status: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
@@ -0,0 +1,294 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"online-subs-api/models"
+	"online-subs-api/repo"
+	"online-subs-api/services"
+	"online-subs-api/utils"
+)
+
+type PaymentHandler struct{
+	paymentService *services.PaymentService
+	reconciliationService *services.ReconciliationService
+}
+
+func NewPaymentHandler(paymentService *services.PaymentService, reconciliationService *services.ReconciliationService) *PaymentHandler{
+	return &PaymentHandler{paymentService: paymentService, reconciliationService: reconciliationService}
+}
+
+type JSONPaymentRequest struct {
+	Date           string `json:"date"`
+	Amount         int    `json:"amount"`
+	Currency       string `json:"currency"`
+	Method         string `json:"method"`
+	Reference      string `json:"reference"`
+	Status         string `json:"status"`
+	RefundedAmount int    `json:"refunded_amount"`
+}
+
+func (req JSONPaymentRequest) payment(subID string) *models.Payment{
+	return &models.Payment{
+		SubID: subID,
+		Amount: req.Amount,
+		Currency: req.Currency,
+		Method: req.Method,
+		Reference: req.Reference,
+		Status: req.Status,
+		RefundedAmount: req.RefundedAmount,
+	}
+}
+
+// CreatePaymentHandler godoc
+// @Summary Record a payment
+// @Description Add an actual charge of a subscription to its payments ledger, date in YYYY-MM-DD format. Status is paid (default), refunded, partially_refunded (with refunded_amount) or disputed.
+// @Tags payments
+// @Accept json
+// @Produce json
+// @Param sub_id query string true "Subscription ID"
+// @Param payment body JSONPaymentRequest true "Payment"
+// @Success 201 {object} models.Payment
+// @Failure 400 {string} string "invalid request body or failed to create"
+// @Router /subs/payments/create [post]
+func (h *PaymentHandler) CreatePaymentHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("CreatePaymentHandler called")
+	subID := r.URL.Query().Get("sub_id")
+	if subID == ""{
+		utils.WarningLogger.Println("Missing sub_id parameter in request")
+		http.Error(w, "missing sub_id paramter", http.StatusBadRequest)
+		return
+	}
+
+	var req JSONPaymentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorLogger.Printf("Failed to decode request body: %v", err)
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	payment := req.payment(subID)
+	if err := h.paymentService.CreatePaymentService(payment, req.Date); err != nil {
+		utils.ErrorLogger.Printf("Failed to create payment: %v", err)
+		http.Error(w, "failed to create payment: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(payment)
+}
+
+// UpdatePaymentHandler godoc
+// @Summary Update a payment
+// @Description Change the details of a payment, e.g. to mark it refunded, partially_refunded (with refunded_amount) or disputed. Fields that are left out are kept.
+// @Tags payments
+// @Accept json
+// @Produce json
+// @Param sub_id query string true "Subscription ID"
+// @Param id query string true "Payment ID"
+// @Param payment body JSONPaymentRequest true "Payment"
+// @Success 200 {object} models.Payment
+// @Failure 400 {string} string "invalid request body or failed to update"
+// @Router /subs/payments/update [put]
+func (h *PaymentHandler) UpdatePaymentHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("UpdatePaymentHandler called")
+	q := r.URL.Query()
+	if q.Get("sub_id") == "" || q.Get("id") == ""{
+		utils.WarningLogger.Println("Missing sub_id/id parameter in request")
+		http.Error(w, "missing sub_id/id paramter", http.StatusBadRequest)
+		return
+	}
+
+	var req JSONPaymentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorLogger.Printf("Failed to decode request body: %v", err)
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	payment, err := h.paymentService.UpdatePaymentService(q.Get("sub_id"), q.Get("id"), req.payment(q.Get("sub_id")), req.Date)
+	if err != nil {
+		utils.ErrorLogger.Printf("Failed to update payment id=%s: %v", q.Get("id"), err)
+		http.Error(w, "failed to update payment: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(payment)
+}
+
+// ListPaymentsHandler godoc
+// @Summary List payments
+// @Description Get the payments ledger of a subscription, oldest first
+// @Tags payments
+// @Produce json
+// @Param sub_id query string true "Subscription ID"
+// @Success 200 {array} models.Payment
+// @Failure 400 {string} string "missing or invalid sub_id"
+// @Router /subs/payments/listAll [get]
+func (h *PaymentHandler) ListPaymentsHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("ListPaymentsHandler called")
+	subID := r.URL.Query().Get("sub_id")
+	if subID == ""{
+		utils.WarningLogger.Println("Missing sub_id parameter in request")
+		http.Error(w, "missing sub_id paramter", http.StatusBadRequest)
+		return
+	}
+
+	payments, err := h.paymentService.ListPaymentsService(subID)
+	if err != nil {
+		utils.ErrorLogger.Printf("Failed to list payments: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(payments)
+}
+
+// DeletePaymentHandler godoc
+// @Summary Delete a payment
+// @Tags payments
+// @Param sub_id query string true "Subscription ID"
+// @Param id query string true "Payment ID"
+// @Success 204 {string} string "No Content"
+// @Failure 404 {string} string "payment not found"
+// @Router /subs/payments/delete [delete]
+func (h *PaymentHandler) DeletePaymentHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("DeletePaymentHandler called")
+	q := r.URL.Query()
+	if q.Get("sub_id") == "" || q.Get("id") == ""{
+		utils.WarningLogger.Println("Missing sub_id/id parameter in request")
+		http.Error(w, "missing sub_id/id paramter", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.paymentService.DeletePaymentService(q.Get("sub_id"), q.Get("id")); err != nil {
+		utils.ErrorLogger.Printf("Failed to delete payment id=%s: %v", q.Get("id"), err)
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ImportPaymentsHandler godoc
+// @Summary Import payments
+// @Description Upload a CSV with date (YYYY-MM-DD), amount, reference and sub_id columns, optionally currency, method, status and refunded_amount. The sub_id column can be left out when sub_id is given as a form field. Nothing is stored unless every row is valid.
+// @Tags payments
+// @Accept mpfd
+// @Produce json
+// @Param file formData file true "CSV file"
+// @Param sub_id formData string false "Subscription of rows without a sub_id column"
+// @Success 201 {object} services.PaymentImportReport
+// @Failure 400 {string} string "invalid upload"
+// @Failure 422 {object} services.PaymentImportReport
+// @Router /subs/payments/import [post]
+func (h *PaymentHandler) ImportPaymentsHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("ImportPaymentsHandler called")
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportUpload*2)
+	if err := r.ParseMultipartForm(maxImportUpload); err != nil {
+		utils.ErrorLogger.Printf("Failed to parse upload: %v", err)
+		http.Error(w, "invalid upload, expected multipart/form-data", http.StatusBadRequest)
+		return
+	}
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		utils.WarningLogger.Println("Missing file in payment import request")
+		http.Error(w, "missing file paramter", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	report, err := h.paymentService.ImportPaymentsService(file, r.FormValue("sub_id"))
+	if err != nil {
+		utils.ErrorLogger.Printf("Failed to import payments: %v", err)
+		http.Error(w, "failed to import payments: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if report.Invalid > 0 {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	} else {
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(report)
+}
+
+// ListDiscrepanciesHandler godoc
+// @Summary Payment discrepancy report
+// @Description Discrepancies found by the reconciliation job between the expected charges and the recorded payments: missed, double, amount_mismatch, after_end and unexpected. Discrepancies that are no longer found on a later run are resolved.
+// @Tags reports
+// @Produce json
+// @Param user_id query string false "User ID (UUID format)"
+// @Param sub_id query string false "Subscription ID"
+// @Param tenant_id query string false "Tenant ID"
+// @Param kind query string false "missed, double, amount_mismatch, after_end or unexpected"
+// @Param status query string false "open (default) or resolved, all for both"
+// @Param start query string false "First month in MM-YYYY format"
+// @Param end query string false "Last month in MM-YYYY format"
+// @Success 200 {array} models.Discrepancy
+// @Failure 400 {string} string "Invalid input"
+// @Router /reports/discrepancies [get]
+func (h *PaymentHandler) ListDiscrepanciesHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("ListDiscrepanciesHandler called")
+	q := r.URL.Query()
+	filter := repo.DiscrepancyFilter{
+		UserID: q.Get("user_id"),
+		SubID: q.Get("sub_id"),
+		TenantID: q.Get("tenant_id"),
+		Kind: q.Get("kind"),
+		Status: q.Get("status"),
+	}
+	switch filter.Status {
+	case "":
+		filter.Status = models.DiscrepancyOpen
+	case "all":
+		filter.Status = ""
+	}
+
+	discrepancies, err := h.reconciliationService.ListDiscrepanciesService(filter, q.Get("start"), q.Get("end"))
+	if err != nil {
+		utils.ErrorLogger.Printf("Failed to list discrepancies: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(discrepancies)
+}
+
+// RunReconciliationHandler godoc
+// @Summary Run the reconciliation job
+// @Description Reconcile payments with the expected charges now instead of waiting for the daily run. Without start/end the trailing RECONCILE_MONTHS months up to the current month are checked.
+// @Tags payments
+// @Produce json
+// @Param X-Admin-Token header string true "Admin token"
+// @Param start query string false "First month in MM-YYYY format"
+// @Param end query string false "Last month in MM-YYYY format"
+// @Param user_id query string false "User ID (UUID format)"
+// @Param service_name query string false "Service name"
+// @Param tenant_id query string false "Tenant ID"
+// @Success 200 {object} services.ReconciliationRun
+// @Failure 400 {string} string "Invalid input"
+// @Failure 403 {string} string "forbidden"
+// @Router /admin/reconciliation/run [post]
+func (h *PaymentHandler) RunReconciliationHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("RunReconciliationHandler called")
+	if !authorizeAdmin(w, r) {
+		return
+	}
+	q := r.URL.Query()
+
+	run, err := h.reconciliationService.RunReconciliationService(q.Get("start"), q.Get("end"), parseSubsFilter(r))
+	if err != nil {
+		utils.ErrorLogger.Printf("Reconciliation failed: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(run)
+}
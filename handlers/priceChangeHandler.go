@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"online-subs-api/models"
+	"online-subs-api/services"
+	"online-subs-api/utils"
+)
+
+type JSONPriceChangeRequest struct {
+	EffectiveDate string `json:"effective_date"`
+	Price         int    `json:"price"`
+}
+
+// CreatePriceChangeHandler godoc
+// @Summary Schedule a price change
+// @Description Schedule a new price for a subscription, effective from the given month (MM-YYYY)
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param sub_id query string true "Subscription ID"
+// @Param change body JSONPriceChangeRequest true "Price change"
+// @Success 201 {object} models.PriceChange
+// @Failure 400 {string} string "invalid request body or failed to create"
+// @Router /subs/price-changes/create [post]
+func (h *SubsHandler) CreatePriceChangeHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("CreatePriceChangeHandler called")
+	subID := r.URL.Query().Get("sub_id")
+	if subID == ""{
+		utils.WarningLogger.Println("Missing sub_id parameter in request")
+		http.Error(w, "missing sub_id paramter", http.StatusBadRequest)
+		return
+	}
+
+	var req JSONPriceChangeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorLogger.Printf("Failed to decode request body: %v", err)
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	change := &models.PriceChange{SubID: subID, Price: req.Price}
+	if err := h.subsService.CreatePriceChangeService(change, req.EffectiveDate); err != nil {
+		utils.ErrorLogger.Printf("Failed to create price change: %v", err)
+		http.Error(w, "failed to create price change: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(change)
+}
+
+// ListPriceChangesHandler godoc
+// @Summary List price changes
+// @Description Get the scheduled price changes of a subscription, oldest first
+// @Tags subscriptions
+// @Produce json
+// @Param sub_id query string true "Subscription ID"
+// @Success 200 {array} models.PriceChange
+// @Failure 400 {string} string "missing or invalid sub_id"
+// @Router /subs/price-changes/listAll [get]
+func (h *SubsHandler) ListPriceChangesHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("ListPriceChangesHandler called")
+	subID := r.URL.Query().Get("sub_id")
+	if subID == ""{
+		utils.WarningLogger.Println("Missing sub_id parameter in request")
+		http.Error(w, "missing sub_id paramter", http.StatusBadRequest)
+		return
+	}
+
+	changes, err := h.subsService.ListPriceChangesService(subID)
+	if err != nil {
+		utils.ErrorLogger.Printf("Failed to list price changes: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(changes)
+}
+
+// DeletePriceChangeHandler godoc
+// @Summary Delete a price change
+// @Description Delete a scheduled price change by ID
+// @Tags subscriptions
+// @Param id query string true "Price change ID"
+// @Success 204 {string} string "No Content"
+// @Failure 400 {string} string "missing id"
+// @Router /subs/price-changes/delete [delete]
+func (h *SubsHandler) DeletePriceChangeHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("DeletePriceChangeHandler called")
+	id := r.URL.Query().Get("id")
+	if id == ""{
+		utils.WarningLogger.Println("Missing id parameter in request")
+		http.Error(w, "missing id paramter", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.subsService.DeletePriceChangeService(id); err != nil {
+		utils.ErrorLogger.Printf("Failed to delete price change id=%s: %v", id, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ChangePlanHandler godoc
+// @Summary Change plan mid-period
+// @Description Move a subscription to a new price or catalog plan from a day (YYYY-MM-DD). The old price segment is closed and a new one opened according to the proration policy: immediate (credit the rest of the period at the old price and charge it at the new one, by day, with the next charge, new price from the next charge), next_renewal (new price and catalog plan from the next charge) or none (new price for the whole current period). The default policy is PRORATION_POLICY (immediate).
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param sub_id query string true "Subscription ID"
+// @Param dry_run query bool false "Only compute the credits and charges"
+// @Param change body services.PlanChangeRequest true "Plan change"
+// @Success 201 {object} services.PlanChange
+// @Success 200 {object} services.PlanChange "dry run"
+// @Failure 400 {string} string "invalid request body or failed to change plan"
+// @Router /subs/plan-change [post]
+func (h *SubsHandler) ChangePlanHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("ChangePlanHandler called")
+	subID := r.URL.Query().Get("sub_id")
+	if subID == ""{
+		utils.WarningLogger.Println("Missing sub_id parameter in request")
+		http.Error(w, "missing sub_id paramter", http.StatusBadRequest)
+		return
+	}
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+
+	var req services.PlanChangeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorLogger.Printf("Failed to decode request body: %v", err)
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	change, err := h.subsService.ChangePlanService(subID, req, dryRun)
+	if err != nil {
+		utils.ErrorLogger.Printf("Failed to change plan: %v", err)
+		http.Error(w, "failed to change plan: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !dryRun {
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(change)
+}
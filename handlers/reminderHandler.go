@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"online-subs-api/services"
+	"online-subs-api/utils"
+)
+
+type ReminderHandler struct{
+	reminderService *services.ReminderService
+}
+
+func NewReminderHandler(reminderService *services.ReminderService) *ReminderHandler{
+	return &ReminderHandler{reminderService: reminderService}
+}
+
+// ListRemindersHandler godoc
+// @Summary List reminders
+// @Description Get the renewal, trial end and end reminders with their delivery status
+// @Tags reminders
+// @Produce json
+// @Param sub_id query string false "Subscription ID"
+// @Param status query string false "pending, sent or failed"
+// @Success 200 {array} models.Reminder
+// @Failure 400 {string} string "invalid filter"
+// @Router /reminders/listAll [get]
+func (h *ReminderHandler) ListRemindersHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("ListRemindersHandler called")
+	q := r.URL.Query()
+
+	reminders, err := h.reminderService.ListRemindersService(q.Get("sub_id"), q.Get("status"))
+	if err != nil {
+		utils.ErrorLogger.Printf("Failed to list reminders: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reminders)
+}
+
+// RunRemindersHandler godoc
+// @Summary Run the reminder scheduler
+// @Description Create and deliver the due reminders now instead of waiting for the next scheduler pass
+// @Tags reminders
+// @Produce json
+// @Param X-Admin-Token header string true "Admin token"
+// @Success 200 {object} services.ReminderRun
+// @Failure 403 {string} string "forbidden"
+// @Failure 500 {string} string "scheduler pass failed"
+// @Router /admin/reminders/run [post]
+func (h *ReminderHandler) RunRemindersHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("RunRemindersHandler called")
+	if !authorizeAdmin(w, r) {
+		return
+	}
+
+	run, err := h.reminderService.RunRemindersService()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(run)
+}
@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"online-subs-api/services"
+	"online-subs-api/utils"
+)
+
+type ReportHandler struct{
+	reportService *services.ReportService
+}
+
+func NewReportHandler(reportService *services.ReportService) *ReportHandler{
+	return &ReportHandler{reportService: reportService}
+}
+
+// SpendingReportHandler godoc
+// @Summary      Spending breakdown report
+// @Description  Time series of spend per month or year between start and end (inclusive), grouped by any combination of user, service and category.
+// @Description  Each subscription is counted in the months it is billed in, according to its start date and billing period.
+// @Tags         reports
+// @Produce      json
+// @Param        start         query     string  true   "Start month in MM-YYYY format"
+// @Param        end           query     string  true   "End month in MM-YYYY format"
+// @Param        interval      query     string  false  "month (default) or year"
+// @Param        group_by      query     string  false  "Comma separated list of user, service, category"
+// @Param        user_id       query     string  false  "User ID (UUID format), counts their share of shared subscriptions"
+// @Param        service_name  query     string  false  "Service name"
+// @Param        category      query     string  false  "Category"
+// @Param        tag           query     string  false  "Tag name"
+// @Param        tenant_id     query     string  false  "Tenant ID"
+// @Success      200  {object}  services.SpendingReport
+// @Failure      400  {string}  string  "Invalid input"
+// @Router       /reports/spending [get]
+func (h *ReportHandler) SpendingReportHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("SpendingReportHandler called")
+	q := r.URL.Query()
+	start := q.Get("start")
+	end := q.Get("end")
+
+	if start == "" || end == ""{
+		utils.WarningLogger.Println("Missing start/end parameter in request")
+		http.Error(w, "missing start/end paramter", http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.reportService.SpendingReportService(start, end, q.Get("interval"), q.Get("group_by"), parseSubsFilter(r))
+	if err != nil {
+		utils.ErrorLogger.Printf("Failed to build spending report: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// ForecastHandler godoc
+// @Summary      Spending forecast
+// @Description  Projects month-by-month charges of the active subscriptions per user and service, taking billing periods, trials and scheduled price changes into account.
+// @Description  Overrides add what-if scenarios (cancel, add, change_price), the baseline totals show the forecast without them. Months and the overall total break the amounts down into net, tax and gross.
+// @Tags         reports
+// @Accept       json
+// @Produce      json
+// @Param        forecast      body      services.ForecastRequest  false  "Start month (MM-YYYY, default next month), number of months (default 12) and what-if overrides"
+// @Param        user_id       query     string  false  "User ID (UUID format), counts their share of shared subscriptions"
+// @Param        service_name  query     string  false  "Service name"
+// @Param        category      query     string  false  "Category"
+// @Param        tag           query     string  false  "Tag name"
+// @Param        tenant_id     query     string  false  "Tenant ID"
+// @Success      200  {object}  services.Forecast
+// @Failure      400  {string}  string  "Invalid input"
+// @Router       /reports/forecast [post]
+func (h *ReportHandler) ForecastHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("ForecastHandler called")
+
+	var req services.ForecastRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.ErrorLogger.Printf("Failed to decode request body: %v", err)
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+	req.Filter = parseSubsFilter(r)
+
+	forecast, err := h.reportService.ForecastService(req)
+	if err != nil {
+		utils.ErrorLogger.Printf("Failed to build forecast: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(forecast)
+}
+
+// SettlementHandler godoc
+// @Summary      Settle shared subscriptions
+// @Description  Who owes whom for the shared subscriptions charged between start and end (inclusive). The owner of a subscription pays its charges and each member owes their share, balances are netted into as few transfers as possible.
+// @Tags         reports
+// @Produce      json
+// @Param        start         query     string  true   "Start month in MM-YYYY format"
+// @Param        end           query     string  true   "End month in MM-YYYY format"
+// @Param        user_id       query     string  false  "Only subscriptions the user owns or is a member of"
+// @Param        service_name  query     string  false  "Service name"
+// @Param        tenant_id     query     string  false  "Tenant ID"
+// @Success      200  {object}  services.Settlement
+// @Failure      400  {string}  string  "Invalid input"
+// @Router       /reports/settlement [get]
+func (h *ReportHandler) SettlementHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("SettlementHandler called")
+	q := r.URL.Query()
+	start := q.Get("start")
+	end := q.Get("end")
+
+	if start == "" || end == ""{
+		utils.WarningLogger.Println("Missing start/end parameter in request")
+		http.Error(w, "missing start/end paramter", http.StatusBadRequest)
+		return
+	}
+
+	settlement, err := h.reportService.SettlementService(start, end, parseSubsFilter(r))
+	if err != nil {
+		utils.ErrorLogger.Printf("Failed to settle shared subscriptions: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settlement)
+}
+
+// VATSummaryHandler godoc
+// @Summary      Yearly VAT summary
+// @Description  Net, tax and gross of the year's expected charges (after discounts) per jurisdiction and rate, for the year and per quarter, at the rate in effect in the month of each charge. Charges of subscriptions without tax settings are summed as untaxed.
+// @Tags         reports
+// @Produce      json
+// @Param        year          query     string  true   "Year in YYYY format"
+// @Param        user_id       query     string  false  "User ID (UUID format)"
+// @Param        service_name  query     string  false  "Service name"
+// @Param        category      query     string  false  "Category"
+// @Param        tag           query     string  false  "Tag name"
+// @Param        tenant_id     query     string  false  "Tenant ID"
+// @Success      200  {object}  services.VATSummary
+// @Failure      400  {string}  string  "Invalid input"
+// @Router       /reports/vat [get]
+func (h *ReportHandler) VATSummaryHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("VATSummaryHandler called")
+	year := r.URL.Query().Get("year")
+	if year == ""{
+		utils.WarningLogger.Println("Missing year parameter in request")
+		http.Error(w, "missing year paramter", http.StatusBadRequest)
+		return
+	}
+
+	summary, err := h.reportService.VATSummaryService(year, parseSubsFilter(r))
+	if err != nil {
+		utils.ErrorLogger.Printf("Failed to build VAT summary: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summary)
+}
+
+// VATExportHandler godoc
+// @Summary      Export the yearly VAT lines
+// @Description  One row per expected charge of a taxed subscription in the year, with its jurisdiction, rate, whether prices include tax and the net, tax and gross amounts, as CSV, NDJSON or XLSX (format parameter or Accept header, CSV by default).
+// @Tags         reports
+// @Produce      text/csv
+// @Produce      application/x-ndjson
+// @Produce      application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param        year          query     string  true   "Year in YYYY format"
+// @Param        format        query     string  false  "csv, ndjson or xlsx"
+// @Param        user_id       query     string  false  "User ID (UUID format)"
+// @Param        service_name  query     string  false  "Service name"
+// @Param        category      query     string  false  "Category"
+// @Param        tag           query     string  false  "Tag name"
+// @Param        tenant_id     query     string  false  "Tenant ID"
+// @Success      200  {file}    file
+// @Failure      400  {string}  string  "Invalid input"
+// @Failure      406  {string}  string  "unsupported format"
+// @Router       /reports/vat/export [get]
+func (h *ReportHandler) VATExportHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("VATExportHandler called")
+	year := r.URL.Query().Get("year")
+	if year == ""{
+		utils.WarningLogger.Println("Missing year parameter in request")
+		http.Error(w, "missing year paramter", http.StatusBadRequest)
+		return
+	}
+
+	format, ok := exportFormat(r)
+	if !ok {
+		utils.WarningLogger.Println("Unsupported export format:", r.URL.Query().Get("format"), r.Header.Get("Accept"))
+		http.Error(w, "format must be csv, ndjson or xlsx", http.StatusNotAcceptable)
+		return
+	}
+
+	summary, err := h.reportService.VATSummaryService(year, parseSubsFilter(r))
+	if err != nil {
+		utils.ErrorLogger.Printf("Failed to build VAT summary: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writer, err := utils.NewTableWriter(format, w)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotAcceptable)
+		return
+	}
+	w.Header().Set("Content-Type", utils.TableContentTypes[format])
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="vat-%d.%s"`, summary.Year, format))
+	w.Header().Set("Vary", "Accept")
+
+	if err := writer.WriteHeader(services.VATExportColumns); err != nil {
+		utils.ErrorLogger.Printf("VAT export aborted: %v", err)
+		return
+	}
+	for _, charge := range summary.Charges {
+		if err := writer.WriteRow(charge.Row()); err != nil {
+			utils.ErrorLogger.Printf("VAT export aborted: %v", err)
+			return
+		}
+	}
+	if err := writer.Close(); err != nil {
+		utils.ErrorLogger.Printf("VAT export aborted: %v", err)
+		return
+	}
+	utils.InfoLogger.Printf("Exported %d VAT lines for %d as %s", len(summary.Charges), summary.Year, format)
+}
@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"online-subs-api/models"
+	"online-subs-api/utils"
+)
+
+type JSONSeatChangeRequest struct {
+	EffectiveDate string `json:"effective_date"`
+	Seats         int    `json:"seats"`
+	Prorate       bool   `json:"prorate"`
+}
+
+type JSONSeatAssignRequest struct {
+	Assignee string  `json:"assignee"`
+	UserID   *string `json:"user_id"`
+	Date     string  `json:"date"`
+}
+
+// CreateSeatChangeHandler godoc
+// @Summary Change the seats of a subscription
+// @Description Set the number of seats from the given day (YYYY-MM-DD) on. The first seat change makes the subscription seat-based: its price is then the price of one seat. A prorated change inside a billing period is charged for the rest of the period with the next charge, otherwise it counts from the next charge.
+// @Tags seats
+// @Accept json
+// @Produce json
+// @Param sub_id query string true "Subscription ID"
+// @Param change body JSONSeatChangeRequest true "Seat change"
+// @Success 201 {object} models.SeatChange
+// @Failure 400 {string} string "invalid request body or failed to create"
+// @Router /subs/seats/create [post]
+func (h *SubsHandler) CreateSeatChangeHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("CreateSeatChangeHandler called")
+	subID := r.URL.Query().Get("sub_id")
+	if subID == ""{
+		utils.WarningLogger.Println("Missing sub_id parameter in request")
+		http.Error(w, "missing sub_id paramter", http.StatusBadRequest)
+		return
+	}
+
+	var req JSONSeatChangeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorLogger.Printf("Failed to decode request body: %v", err)
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	change := &models.SeatChange{SubID: subID, Seats: req.Seats, Prorate: req.Prorate}
+	if err := h.subsService.CreateSeatChangeService(change, req.EffectiveDate); err != nil {
+		utils.ErrorLogger.Printf("Failed to create seat change: %v", err)
+		http.Error(w, "failed to create seat change: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(change)
+}
+
+// ListSeatChangesHandler godoc
+// @Summary List seat changes
+// @Description Get the seat history of a subscription, oldest first
+// @Tags seats
+// @Produce json
+// @Param sub_id query string true "Subscription ID"
+// @Success 200 {array} models.SeatChange
+// @Failure 400 {string} string "missing or invalid sub_id"
+// @Router /subs/seats/listAll [get]
+func (h *SubsHandler) ListSeatChangesHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("ListSeatChangesHandler called")
+	subID := r.URL.Query().Get("sub_id")
+	if subID == ""{
+		utils.WarningLogger.Println("Missing sub_id parameter in request")
+		http.Error(w, "missing sub_id paramter", http.StatusBadRequest)
+		return
+	}
+
+	changes, err := h.subsService.ListSeatChangesService(subID)
+	if err != nil {
+		utils.ErrorLogger.Printf("Failed to list seat changes: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(changes)
+}
+
+// DeleteSeatChangeHandler godoc
+// @Summary Delete a seat change
+// @Tags seats
+// @Param sub_id query string true "Subscription ID"
+// @Param id query string true "Seat change ID"
+// @Success 204 {string} string "No Content"
+// @Failure 404 {string} string "seat change not found"
+// @Router /subs/seats/delete [delete]
+func (h *SubsHandler) DeleteSeatChangeHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("DeleteSeatChangeHandler called")
+	q := r.URL.Query()
+	if q.Get("sub_id") == "" || q.Get("id") == ""{
+		utils.WarningLogger.Println("Missing sub_id/id parameter in request")
+		http.Error(w, "missing sub_id/id paramter", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.subsService.DeleteSeatChangeService(q.Get("sub_id"), q.Get("id")); err != nil {
+		utils.ErrorLogger.Printf("Failed to delete seat change id=%s: %v", q.Get("id"), err)
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AssignSeatHandler godoc
+// @Summary Assign a seat
+// @Description Track who uses a seat of the subscription from the given day (YYYY-MM-DD, default today). The assignee is free text such as an email, user_id optionally links a user.
+// @Tags seats
+// @Accept json
+// @Produce json
+// @Param sub_id query string true "Subscription ID"
+// @Param assignment body JSONSeatAssignRequest true "Assignment"
+// @Success 201 {object} models.SeatAssignment
+// @Failure 400 {string} string "invalid request body or failed to assign"
+// @Router /subs/seats/assign [post]
+func (h *SubsHandler) AssignSeatHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("AssignSeatHandler called")
+	subID := r.URL.Query().Get("sub_id")
+	if subID == ""{
+		utils.WarningLogger.Println("Missing sub_id parameter in request")
+		http.Error(w, "missing sub_id paramter", http.StatusBadRequest)
+		return
+	}
+
+	var req JSONSeatAssignRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorLogger.Printf("Failed to decode request body: %v", err)
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	assignment := &models.SeatAssignment{SubID: subID, Assignee: req.Assignee, UserID: req.UserID}
+	if err := h.subsService.AssignSeatService(assignment, req.Date); err != nil {
+		utils.ErrorLogger.Printf("Failed to assign seat: %v", err)
+		http.Error(w, "failed to assign seat: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(assignment)
+}
+
+// UnassignSeatHandler godoc
+// @Summary Unassign a seat
+// @Description Free the seat from the given day (YYYY-MM-DD, default today) on
+// @Tags seats
+// @Produce json
+// @Param sub_id query string true "Subscription ID"
+// @Param id query string true "Seat assignment ID"
+// @Param date query string false "Day the seat is freed"
+// @Success 200 {object} models.SeatAssignment
+// @Failure 400 {string} string "failed to unassign"
+// @Router /subs/seats/unassign [post]
+func (h *SubsHandler) UnassignSeatHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("UnassignSeatHandler called")
+	q := r.URL.Query()
+	if q.Get("sub_id") == "" || q.Get("id") == ""{
+		utils.WarningLogger.Println("Missing sub_id/id parameter in request")
+		http.Error(w, "missing sub_id/id paramter", http.StatusBadRequest)
+		return
+	}
+
+	assignment, err := h.subsService.UnassignSeatService(q.Get("sub_id"), q.Get("id"), q.Get("date"))
+	if err != nil {
+		utils.ErrorLogger.Printf("Failed to unassign seat id=%s: %v", q.Get("id"), err)
+		http.Error(w, "failed to unassign seat: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(assignment)
+}
+
+// ListSeatAssignmentsHandler godoc
+// @Summary List seat assignments
+// @Tags seats
+// @Produce json
+// @Param sub_id query string true "Subscription ID"
+// @Success 200 {array} models.SeatAssignment
+// @Failure 400 {string} string "missing or invalid sub_id"
+// @Router /subs/seats/assignments [get]
+func (h *SubsHandler) ListSeatAssignmentsHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("ListSeatAssignmentsHandler called")
+	subID := r.URL.Query().Get("sub_id")
+	if subID == ""{
+		utils.WarningLogger.Println("Missing sub_id parameter in request")
+		http.Error(w, "missing sub_id paramter", http.StatusBadRequest)
+		return
+	}
+
+	assignments, err := h.subsService.ListSeatAssignmentsService(subID)
+	if err != nil {
+		utils.ErrorLogger.Printf("Failed to list seat assignments: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(assignments)
+}
+
+// SeatUtilizationHandler godoc
+// @Summary Seat utilization
+// @Description Seats, assigned users, unused seats and their monthly cost at the end of each month between start and end (MM-YYYY)
+// @Tags seats
+// @Produce json
+// @Param sub_id query string true "Subscription ID"
+// @Param start query string true "Start month in MM-YYYY format"
+// @Param end query string true "End month in MM-YYYY format"
+// @Success 200 {object} services.SeatUtilization
+// @Failure 400 {string} string "Invalid input"
+// @Router /subs/seats/utilization [get]
+func (h *SubsHandler) SeatUtilizationHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("SeatUtilizationHandler called")
+	q := r.URL.Query()
+	if q.Get("sub_id") == "" || q.Get("start") == "" || q.Get("end") == ""{
+		utils.WarningLogger.Println("Missing sub_id/start/end parameter in request")
+		http.Error(w, "missing sub_id/start/end paramter", http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.subsService.SeatUtilizationService(q.Get("sub_id"), q.Get("start"), q.Get("end"))
+	if err != nil {
+		utils.ErrorLogger.Printf("Failed to build seat utilization: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
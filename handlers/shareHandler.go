@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"online-subs-api/services"
+	"online-subs-api/utils"
+)
+
+// SetSplitHandler godoc
+// @Summary Share a subscription
+// @Description Set the members of a shared subscription and how its price is split: equal, percentage (share is 1-100) or fixed (share is an amount). The owner (user_id of the subscription) pays it and takes the rest; the owner can be listed to set their own share. Replaces the previous members.
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param sub_id query string true "Subscription ID"
+// @Param split body services.SplitRequest true "Split rule and members"
+// @Success 200 {object} services.SubSplitView
+// @Failure 400 {string} string "invalid request body or failed to share"
+// @Router /subs/members/set [put]
+func (h *SubsHandler) SetSplitHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("SetSplitHandler called")
+	subID := r.URL.Query().Get("sub_id")
+	if subID == ""{
+		utils.WarningLogger.Println("Missing sub_id parameter in request")
+		http.Error(w, "missing sub_id paramter", http.StatusBadRequest)
+		return
+	}
+
+	var req services.SplitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorLogger.Printf("Failed to decode request body: %v", err)
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	split, err := h.subsService.SetSplitService(subID, req)
+	if err != nil {
+		utils.ErrorLogger.Printf("Failed to share subscription: %v", err)
+		http.Error(w, "failed to share subscription: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(split)
+}
+
+// GetSplitHandler godoc
+// @Summary Get the members of a shared subscription
+// @Description Split rule, members and the share of each user at the current price
+// @Tags subscriptions
+// @Produce json
+// @Param sub_id query string true "Subscription ID"
+// @Success 200 {object} services.SubSplitView
+// @Failure 404 {string} string "subscription is not shared"
+// @Router /subs/members/get [get]
+func (h *SubsHandler) GetSplitHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("GetSplitHandler called")
+	subID := r.URL.Query().Get("sub_id")
+	if subID == ""{
+		utils.WarningLogger.Println("Missing sub_id parameter in request")
+		http.Error(w, "missing sub_id paramter", http.StatusBadRequest)
+		return
+	}
+
+	split, err := h.subsService.GetSplitService(subID)
+	if err != nil {
+		utils.ErrorLogger.Printf("Failed to get split: %v", err)
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(split)
+}
+
+// DeleteSplitHandler godoc
+// @Summary Stop sharing a subscription
+// @Description Remove all members, the owner pays the full price again
+// @Tags subscriptions
+// @Param sub_id query string true "Subscription ID"
+// @Success 204 {string} string "No Content"
+// @Failure 404 {string} string "subscription is not shared"
+// @Router /subs/members/delete [delete]
+func (h *SubsHandler) DeleteSplitHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("DeleteSplitHandler called")
+	subID := r.URL.Query().Get("sub_id")
+	if subID == ""{
+		utils.WarningLogger.Println("Missing sub_id parameter in request")
+		http.Error(w, "missing sub_id paramter", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.subsService.DeleteSplitService(subID); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"online-subs-api/services"
+	"online-subs-api/utils"
+	"strconv"
+)
+
+type StatementHandler struct{
+	statementService *services.StatementService
+}
+
+func NewStatementHandler(statementService *services.StatementService) *StatementHandler{
+	return &StatementHandler{statementService: statementService}
+}
+
+// ImportStatementHandler godoc
+// @Summary Find subscriptions in a bank statement
+// @Description Upload a bank statement (CSV, OFX/QFX or CAMT.053). Debits are grouped by merchant and recurring amounts at a weekly, monthly, quarterly or yearly cadence are stored as candidate subscriptions with a confidence score. Candidates the user already tracks carry matched_sub_id. The transactions themselves are not stored.
+// @Tags statements
+// @Accept mpfd
+// @Produce json
+// @Param file formData file true "Bank statement"
+// @Param user_id formData string true "User the statement belongs to"
+// @Param tenant_id formData string false "Tenant ID"
+// @Param format formData string false "csv, ofx, qfx or camt053, detected from the content when empty"
+// @Param min_confidence formData number false "Lowest confidence to propose, 0-1 (default 0.6)"
+// @Success 201 {object} services.StatementAnalysis
+// @Failure 400 {string} string "invalid upload or failed to parse"
+// @Router /statements/import [post]
+func (h *StatementHandler) ImportStatementHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("ImportStatementHandler called")
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportUpload*2)
+	if err := r.ParseMultipartForm(maxImportUpload); err != nil {
+		utils.ErrorLogger.Printf("Failed to parse upload: %v", err)
+		http.Error(w, "invalid upload, expected multipart/form-data", http.StatusBadRequest)
+		return
+	}
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		utils.WarningLogger.Println("Missing file in statement import request")
+		http.Error(w, "missing file paramter", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	minConfidence := 0.0
+	if value := r.FormValue("min_confidence"); value != "" {
+		if minConfidence, err = strconv.ParseFloat(value, 64); err != nil {
+			http.Error(w, "invalid min_confidence", http.StatusBadRequest)
+			return
+		}
+	}
+
+	analysis, err := h.statementService.AnalyzeStatementService(file, r.FormValue("format"), r.FormValue("user_id"), r.FormValue("tenant_id"), minConfidence)
+	if err != nil {
+		utils.ErrorLogger.Printf("Failed to import statement: %v", err)
+		http.Error(w, "failed to import statement: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(analysis)
+}
+
+// ListCandidatesHandler godoc
+// @Summary List subscription candidates
+// @Description Candidates found in the user's bank statements, highest confidence first
+// @Tags statements
+// @Produce json
+// @Param user_id query string true "User ID"
+// @Param import_id query string false "Only candidates of this statement import"
+// @Param status query string false "pending, accepted or dismissed"
+// @Success 200 {array} models.StatementCandidate
+// @Failure 400 {string} string "invalid filter"
+// @Router /statements/candidates [get]
+func (h *StatementHandler) ListCandidatesHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("ListCandidatesHandler called")
+	q := r.URL.Query()
+	if q.Get("user_id") == ""{
+		utils.WarningLogger.Println("Missing user_id parameter in request")
+		http.Error(w, "missing user_id paramter", http.StatusBadRequest)
+		return
+	}
+
+	candidates, err := h.statementService.ListCandidatesService(q.Get("user_id"), q.Get("import_id"), q.Get("status"))
+	if err != nil {
+		utils.ErrorLogger.Printf("Failed to list candidates: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(candidates)
+}
+
+// AcceptCandidatesHandler godoc
+// @Summary Accept subscription candidates
+// @Description Create subscriptions from the listed candidates in one transaction. Inactive series end with their last charge. Candidates that are not pending, already tracked (unless include_tracked) or fail validation are reported as skipped.
+// @Tags statements
+// @Accept json
+// @Produce json
+// @Param accept body services.StatementAccept true "Candidates to accept"
+// @Success 201 {object} services.StatementAcceptResult
+// @Failure 400 {string} string "invalid request body or failed to accept"
+// @Router /statements/candidates/accept [post]
+func (h *StatementHandler) AcceptCandidatesHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("AcceptCandidatesHandler called")
+
+	var req services.StatementAccept
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorLogger.Printf("Failed to decode request body: %v", err)
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.statementService.AcceptCandidatesService(req)
+	if err != nil {
+		utils.ErrorLogger.Printf("Failed to accept candidates: %v", err)
+		http.Error(w, "failed to accept candidates: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(result)
+}
+
+// DismissCandidateHandler godoc
+// @Summary Dismiss a subscription candidate
+// @Description Only pending candidates can be dismissed, accepted ones keep their subscription
+// @Tags statements
+// @Param id query string true "Candidate ID"
+// @Success 204 "No Content"
+// @Failure 404 {string} string "pending candidate not found"
+// @Router /statements/candidates/dismiss [post]
+func (h *StatementHandler) DismissCandidateHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("DismissCandidateHandler called")
+	id := r.URL.Query().Get("id")
+	if id == ""{
+		utils.WarningLogger.Println("Missing id parameter in request")
+		http.Error(w, "missing id paramter", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.statementService.DismissCandidateService(id); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"online-subs-api/services"
+	"online-subs-api/utils"
+	"strconv"
+	"time"
+)
+
+const streamHeartbeat = 15 * time.Second
+
+type StreamHandler struct{
+	streamService *services.StreamService
+}
+
+func NewStreamHandler(streamService *services.StreamService) *StreamHandler{
+	return &StreamHandler{streamService: streamService}
+}
+
+func writeSSE(w http.ResponseWriter, message services.OutboxMessage) error{
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", message.Seq, message.Type, data)
+	return err
+}
+
+// StreamSubsHandler godoc
+// @Summary Stream subscription changes
+// @Description Server-Sent Events stream of subscription.created, subscription.updated and subscription.deleted events, and of the budget.alert events of user and tenant budgets.
+// @Description The SSE id is the event seq; reconnect with the Last-Event-ID header (or last_event_id) to replay the missed events. A comment line is sent every 15 seconds as heartbeat.
+// @Tags subscriptions
+// @Produce text/event-stream
+// @Param user_id query string false "User ID (UUID format)"
+// @Param service_name query string false "Service name"
+// @Param tenant_id query string false "Tenant ID"
+// @Param last_event_id query int false "Resume after this event id, for clients that can't send Last-Event-ID"
+// @Param Last-Event-ID header int false "Resume after this event id"
+// @Success 200 {string} string "event stream"
+// @Failure 400 {string} string "invalid filter"
+// @Router /subs/stream [get]
+func (h *StreamHandler) StreamSubsHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("StreamSubsHandler called")
+	q := r.URL.Query()
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = q.Get("last_event_id")
+	}
+	var lastSeq int64
+	if lastEventID != "" {
+		var err error
+		if lastSeq, err = strconv.ParseInt(lastEventID, 10, 64); err != nil {
+			http.Error(w, "invalid Last-Event-ID", http.StatusBadRequest)
+			return
+		}
+	}
+
+	filter := services.StreamFilter{UserID: q.Get("user_id"), ServiceName: q.Get("service_name"), TenantID: q.Get("tenant_id")}
+	sub, backlog, err := h.streamService.Subscribe(filter, lastSeq)
+	if err != nil {
+		utils.ErrorLogger.Printf("Failed to open stream: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer h.streamService.Unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	fmt.Fprint(w, "retry: 3000\n\n")
+
+	for _, message := range backlog {
+		if err := writeSSE(w, message); err != nil {
+			return
+		}
+		lastSeq = message.Seq
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case message, ok := <-sub.Events:
+			if !ok {
+				// dropped for falling behind, the client reconnects with its last id
+				return
+			}
+			if message.Seq <= lastSeq {
+				continue
+			}
+			if err := writeSSE(w, message); err != nil {
+				return
+			}
+			lastSeq = message.Seq
+			flusher.Flush()
+		}
+	}
+}
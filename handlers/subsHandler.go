@@ -4,16 +4,31 @@ import (
 	"encoding/json"
 	"net/http"
 	"online-subs-api/models"
+	"online-subs-api/repo"
 	"online-subs-api/services"
 	"online-subs-api/utils"
+	"strconv"
+	"strings"
 )
 
 type JSONSubRequest struct {
-	ServiceName string `json:"service_name"`
-	Price       int    `json:"price"`
-	UserID      string `json:"user_id"`
-	StartDate   string `json:"start_date"`
-	EndDate     string `json:"end_date"`
+	ServiceName   string                 `json:"service_name"`
+	Price         int                    `json:"price"`
+	UserID        string                 `json:"user_id"`
+	StartDate     string                 `json:"start_date"`
+	EndDate       string                 `json:"end_date"`
+	ProviderID    string                 `json:"provider_id"`
+	PlanID        string                 `json:"plan_id"`
+	BillingPeriod string                 `json:"billing_period"`
+	Category      string                 `json:"category"`
+	Tags          []string               `json:"tags"`
+	TenantID      string                 `json:"tenant_id"`
+	Metadata      map[string]interface{} `json:"metadata"`
+	TrialEndDate  string                 `json:"trial_end_date"`
+}
+
+type JSONSubTagsRequest struct {
+	Tags []string `json:"tags"`
 }
 
 type SubsHandler struct{
@@ -24,6 +39,50 @@ func NewSubHandler(subsService *services.SubsService) *SubsHandler{
 	return &SubsHandler{subsService: subsService}
 }
 
+// optionalID turns an empty id from the request into nil
+func optionalID(id string) *string{
+	if id == "" {
+		return nil
+	}
+	return &id
+}
+
+// tagsFromNames keeps nil as "tags not given" so updates leave them untouched
+func tagsFromNames(names []string) []models.Tag{
+	if names == nil {
+		return nil
+	}
+	tags := []models.Tag{}
+	for _, name := range names {
+		tags = append(tags, models.Tag{Name: name})
+	}
+	return tags
+}
+
+// parseSubsFilter reads the list filters, metadata is matched with meta.<key>=<value>
+// and meta_has=<key> (repeatable)
+func parseSubsFilter(r *http.Request) repo.SubsFilter{
+	q := r.URL.Query()
+	metadata := map[string]string{}
+	for key, values := range q {
+		if name, ok := strings.CutPrefix(key, "meta."); ok && name != "" && len(values) > 0 {
+			metadata[name] = values[0]
+		}
+	}
+
+	return repo.SubsFilter{
+		UserID: q.Get("user_id"),
+		ServiceName: q.Get("service_name"),
+		ProviderID: q.Get("provider_id"),
+		PlanID: q.Get("plan_id"),
+		Category: q.Get("category"),
+		Tag: q.Get("tag"),
+		TenantID: q.Get("tenant_id"),
+		Metadata: metadata,
+		MetadataKeys: q["meta_has"],
+	}
+}
+
 // CreateSubHandler godoc
 // @Summary Create a new subscription
 // @Description Create a subscription for a user
@@ -48,11 +107,18 @@ func (h *SubsHandler) CreateSubHandler(w http.ResponseWriter, r *http.Request){
 		ServiceName: req.ServiceName,
 		Price: req.Price,
 		UserID: req.UserID,
+		ProviderID: optionalID(req.ProviderID),
+		PlanID: optionalID(req.PlanID),
+		BillingPeriod: req.BillingPeriod,
+		Category: req.Category,
+		Tags: tagsFromNames(req.Tags),
+		TenantID: req.TenantID,
+		Metadata: req.Metadata,
 	}
 
-	if err := h.subsService.CreateService(sub, req.StartDate, req.EndDate); err != nil {
+	if err := h.subsService.CreateService(sub, req.StartDate, req.EndDate, req.TrialEndDate); err != nil {
 		utils.ErrorLogger.Printf("Failed to create subscription: %v\n", err)
-		http.Error(w, "failed to create subscription", http.StatusBadRequest)
+		http.Error(w, "failed to create subscription: "+err.Error(), http.StatusBadRequest)
 		return
 	}
 
@@ -97,10 +163,19 @@ func (h *SubsHandler) GetSubHandlerByID(w http.ResponseWriter, r *http.Request){
 
 // ListAllSubsHandler godoc
 // @Summary List all subscriptions
-// @Description Get all subscriptions for a user or service
+// @Description Get all subscriptions, optionally filtered by user, service, catalog provider, plan, category, tag, tenant or metadata
 // @Tags subscriptions
 // @Accept json
 // @Produce json
+// @Param user_id query string false "User ID (UUID format)"
+// @Param service_name query string false "Service name"
+// @Param provider_id query string false "Catalog provider ID"
+// @Param plan_id query string false "Catalog plan ID"
+// @Param category query string false "Category"
+// @Param tag query string false "Tag name"
+// @Param tenant_id query string false "Tenant ID"
+// @Param meta.key query string false "Metadata value filter, use meta.<key>=<value> for any key"
+// @Param meta_has query string false "Only subscriptions whose metadata has this key"
 // @Success 200 {array} models.Sub
 // @Failure 400 {string} string "invalid request body"
 // @Failure 500 {string} string "failed to list subscriptions"
@@ -108,7 +183,7 @@ func (h *SubsHandler) GetSubHandlerByID(w http.ResponseWriter, r *http.Request){
 func (h *SubsHandler) ListAllSubsHandler(w http.ResponseWriter, r *http.Request){
 	utils.InfoLogger.Println("ListAllSubsHandler Called")
 
-	subs, err := h.subsService.ListAllSubsService()
+	subs, err := h.subsService.ListAllSubsService(parseSubsFilter(r))
 	if err != nil{
 		utils.ErrorLogger.Printf("Failed to list subscriptions: %v", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -153,12 +228,19 @@ func (h *SubsHandler) UpdateSubHandler(w http.ResponseWriter, r *http.Request){
 		ServiceName: req.ServiceName,
 		Price: req.Price,
 		UserID: req.UserID,
+		ProviderID: optionalID(req.ProviderID),
+		PlanID: optionalID(req.PlanID),
+		BillingPeriod: req.BillingPeriod,
+		Category: req.Category,
+		Tags: tagsFromNames(req.Tags),
+		TenantID: req.TenantID,
+		Metadata: req.Metadata,
 	}
 	sub.ID = id
 
-	if err := h.subsService.UpdateSubService(sub, req.StartDate, req.EndDate); err != nil{
+	if err := h.subsService.UpdateSubService(sub, req.StartDate, req.EndDate, req.TrialEndDate); err != nil{
 		utils.ErrorLogger.Printf("Failed to update sub: %v", err)
-		http.Error(w, "failed to update subscription", http.StatusBadRequest)
+		http.Error(w, "failed to update subscription: "+err.Error(), http.StatusBadRequest)
 		return
 	}
 
@@ -200,7 +282,8 @@ func (h* SubsHandler) DeleteSubHandler(w http.ResponseWriter, r *http.Request){
 
 // GetTotalCostHandler godoc
 // @Summary      Get total subscription cost
-// @Description  Returns the total subscription cost in a given date range, optionally filtered by user_id and service_name
+// @Description  Returns the total subscription cost in a given date range, optionally filtered by user_id, service_name, catalog provider, plan, category or tag and grouped by category or tag
+// @Description  basis=expected (default) sums the list prices after discounts, with the gross_cost before them and a tax_breakdown of the total into net, tax and gross, basis=actual sums the recorded payments after refunds, with paid, refunded and disputed amounts per currency
 // @Tags         subscriptions
 // @Accept       json
 // @Produce      json
@@ -208,14 +291,19 @@ func (h* SubsHandler) DeleteSubHandler(w http.ResponseWriter, r *http.Request){
 // @Param        end          query     string  true   "End date in YYYY-MM-DD format (write - 01 for DD as it is set like that in GORM by default) - like YYYY-MM-01" 
 // @Param        user_id      query     string  false  "User ID (UUID format)"
 // @Param        service_name query     string  false  "Service name"
+// @Param        provider_id  query     string  false  "Catalog provider ID"
+// @Param        plan_id      query     string  false  "Catalog plan ID"
+// @Param        category     query     string  false  "Category"
+// @Param        tag          query     string  false  "Tag name"
+// @Param        tenant_id    query     string  false  "Tenant ID"
+// @Param        group_by     query     string  false  "Break the total down by category or tag"
+// @Param        basis        query     string  false  "expected (default) or actual"
 // @Success      200  {object}  map[string]interface{} "Total cost response"
 // @Failure      400  {string}  string  "Invalid input"
 // @Router       /subs/total-cost [get]
 func (h* SubsHandler) GetTotalCostHandler(w http.ResponseWriter, r *http.Request){
 	start := r.URL.Query().Get("start")
 	end := r.URL.Query().Get("end")
-	userID := r.URL.Query().Get("user_id")
-	serviceName := r.URL.Query().Get("service_name")
 
 	if start == "" || end == ""{
 		utils.WarningLogger.Println("Missing start/end parameter in request")
@@ -223,15 +311,61 @@ func (h* SubsHandler) GetTotalCostHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	totalCost, err := h.subsService.GetTotalCostService(start, end, userID, serviceName)
-	if err != nil {
-		utils.ErrorLogger.Printf("Failed to get the Total Cost: %v", err)
-		http.Error(w, err.Error(), http.StatusNotFound)
+	basis := r.URL.Query().Get("basis")
+	if basis == "" {
+		basis = services.TotalBasisExpected
+	}
+	resp := map[string]interface{}{
+		"basis": basis,
+	}
+
+	switch basis {
+	case services.TotalBasisExpected:
+		totalCost, err := h.subsService.GetTotalCostService(start, end, parseSubsFilter(r))
+		if err != nil {
+			utils.ErrorLogger.Printf("Failed to get the Total Cost: %v", err)
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		resp["total_cost"] = totalCost.Net
+		resp["gross_cost"] = totalCost.Gross
+		resp["discount"] = totalCost.Gross - totalCost.Net
+		resp["tax_breakdown"] = totalCost.TaxBreakdown()
+	case services.TotalBasisActual:
+		totals, err := h.subsService.GetTotalPaidService(start, end, parseSubsFilter(r))
+		if err != nil {
+			utils.ErrorLogger.Printf("Failed to get the Total Paid: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		// amounts are added up across currencies, the per currency totals are listed as well
+		var paid, refunded, disputed, net int
+		for _, total := range totals {
+			paid += total.Paid
+			refunded += total.Refunded
+			disputed += total.Disputed
+			net += total.Net
+		}
+		resp["total_cost"] = net
+		resp["paid"] = paid
+		resp["refunded"] = refunded
+		resp["disputed"] = disputed
+		resp["currencies"] = totals
+	default:
+		utils.WarningLogger.Println("Invalid basis:", basis)
+		http.Error(w, "basis must be expected or actual", http.StatusBadRequest)
 		return
 	}
 
-	resp := map[string]interface{}{
-		"total_cost": totalCost,
+	if groupBy := r.URL.Query().Get("group_by"); groupBy != "" {
+		groups, err := h.subsService.GetTotalCostBreakdownService(start, end, groupBy, basis, parseSubsFilter(r))
+		if err != nil {
+			utils.ErrorLogger.Printf("Failed to get the Total Cost breakdown: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		resp["group_by"] = groupBy
+		resp["groups"] = groups
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -239,6 +373,128 @@ func (h* SubsHandler) GetTotalCostHandler(w http.ResponseWriter, r *http.Request
 }
 
 
+// SetSubTagsHandler godoc
+// @Summary      Set subscription tags
+// @Description  Replace the tags of a subscription, unknown tag names are created
+// @Tags         subscriptions
+// @Accept       json
+// @Produce      json
+// @Param        id    query     string              true  "Subscription ID"
+// @Param        tags  body      JSONSubTagsRequest  true  "Tag names"
+// @Success      200  {object}  models.Sub
+// @Failure      400  {string}  string  "invalid request body or failed update"
+// @Router       /subs/tags/set [put]
+func (h* SubsHandler) SetSubTagsHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("SetSubTagsHandler called")
+	id := r.URL.Query().Get("id")
+	if id == ""{
+		utils.WarningLogger.Println("Missing id parameter in request")
+		http.Error(w, "missing id paramter", http.StatusBadRequest)
+		return
+	}
+
+	var req JSONSubTagsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorLogger.Printf("Failed to decode request body: %v", err)
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	sub, err := h.subsService.SetSubTagsService(id, req.Tags)
+	if err != nil {
+		utils.ErrorLogger.Printf("Failed to set tags for sub id=%s: %v", id, err)
+		http.Error(w, "failed to set tags: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sub)
+}
+
+// SetSubCategoryHandler godoc
+// @Summary      Set subscription category
+// @Description  Set the category of a subscription, an empty category clears it
+// @Tags         subscriptions
+// @Produce      json
+// @Param        id        query     string  true   "Subscription ID"
+// @Param        category  query     string  false  "Category name"
+// @Success      200  {object}  models.Sub
+// @Failure      400  {string}  string  "missing id or unknown category"
+// @Router       /subs/category/set [put]
+func (h* SubsHandler) SetSubCategoryHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("SetSubCategoryHandler called")
+	id := r.URL.Query().Get("id")
+	if id == ""{
+		utils.WarningLogger.Println("Missing id parameter in request")
+		http.Error(w, "missing id paramter", http.StatusBadRequest)
+		return
+	}
+
+	sub, err := h.subsService.SetSubCategoryService(id, r.URL.Query().Get("category"))
+	if err != nil {
+		utils.ErrorLogger.Printf("Failed to set category for sub id=%s: %v", id, err)
+		http.Error(w, "failed to set category: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sub)
+}
+
+// SuggestServiceNamesHandler godoc
+// @Summary      Suggest service names
+// @Description  Autocomplete for service names: matches the query against catalog names and aliases (English/Russian, transliterated, typo tolerant)
+// @Tags         subscriptions
+// @Produce      json
+// @Param        q      query     string  true   "Partial service name"
+// @Param        limit  query     int     false  "Max suggestions (default 10)"
+// @Success      200  {array}   services.Resolution
+// @Failure      400  {string}  string  "missing q"
+// @Router       /subs/suggest [get]
+func (h* SubsHandler) SuggestServiceNamesHandler(w http.ResponseWriter, r *http.Request){
+	q := r.URL.Query().Get("q")
+	if q == ""{
+		utils.WarningLogger.Println("Missing q parameter in request")
+		http.Error(w, "missing q paramter", http.StatusBadRequest)
+		return
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	suggestions, err := h.subsService.SuggestService(q, limit)
+	if err != nil {
+		utils.ErrorLogger.Printf("Failed to suggest service names: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(suggestions)
+}
+
+// ResolveServiceNameHandler godoc
+// @Summary      Resolve a service name
+// @Description  Returns the canonical catalog name for a free-text service name together with a confidence score, and the closest catalog names as suggestions when it is not resolved
+// @Tags         subscriptions
+// @Produce      json
+// @Param        name  query     string  true  "Service name as typed by the user"
+// @Success      200  {object}  services.Resolution
+// @Failure      400  {string}  string  "missing name"
+// @Router       /subs/resolve [get]
+func (h* SubsHandler) ResolveServiceNameHandler(w http.ResponseWriter, r *http.Request){
+	name := r.URL.Query().Get("name")
+
+	res, err := h.subsService.ResolveNameService(name)
+	if err != nil {
+		utils.ErrorLogger.Printf("Failed to resolve service name: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
+
+
 // POST /subscriptions
 // GET /subscriptions/{id}
 // GET /subscriptions?user_id=...&service_name=...
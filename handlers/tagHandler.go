@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"online-subs-api/models"
+	"online-subs-api/services"
+	"online-subs-api/utils"
+)
+
+type JSONTagRequest struct {
+	Name string `json:"name"`
+}
+
+type JSONCategoryRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type TagHandler struct{
+	tagService *services.TagService
+}
+
+func NewTagHandler(tagService *services.TagService) *TagHandler{
+	return &TagHandler{tagService: tagService}
+}
+
+// CreateTagHandler godoc
+// @Summary Create a tag
+// @Description Create a user-defined tag, names are lowercased
+// @Tags tags
+// @Accept json
+// @Produce json
+// @Param tag body JSONTagRequest true "Tag"
+// @Success 201 {object} models.Tag
+// @Failure 400 {string} string "invalid request body or failed to create"
+// @Router /tags/create [post]
+func (h *TagHandler) CreateTagHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("CreateTagHandler called")
+
+	var req JSONTagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorLogger.Printf("Failed to decode request body: %v\n", err)
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	tag := &models.Tag{Name: req.Name}
+	if err := h.tagService.CreateTagService(tag); err != nil {
+		utils.ErrorLogger.Printf("Failed to create tag: %v\n", err)
+		http.Error(w, "failed to create tag", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(tag)
+}
+
+// ListTagsHandler godoc
+// @Summary List tags
+// @Description Get all tags
+// @Tags tags
+// @Produce json
+// @Success 200 {array} models.Tag
+// @Failure 500 {string} string "failed to list tags"
+// @Router /tags/listAll [get]
+func (h *TagHandler) ListTagsHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("ListTagsHandler called")
+
+	tags, err := h.tagService.ListTagsService()
+	if err != nil{
+		utils.ErrorLogger.Printf("Failed to list tags: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tags)
+}
+
+// DeleteTagHandler godoc
+// @Summary Delete a tag
+// @Description Delete a tag and remove it from all subscriptions
+// @Tags tags
+// @Param id query string true "Tag ID"
+// @Success 204 {string} string "No Content"
+// @Failure 400 {string} string "missing id"
+// @Router /tags/delete [delete]
+func (h *TagHandler) DeleteTagHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("DeleteTagHandler called")
+	id := r.URL.Query().Get("id")
+	if id == ""{
+		utils.WarningLogger.Println("Missing id parameter in request")
+		http.Error(w, "missing id paramter", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.tagService.DeleteTagService(id); err != nil{
+		utils.ErrorLogger.Printf("Failed to delete tag id=%s: %v", id, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CreateCategoryHandler godoc
+// @Summary Create a category
+// @Description Create a spending category, names are lowercased
+// @Tags categories
+// @Accept json
+// @Produce json
+// @Param category body JSONCategoryRequest true "Category"
+// @Success 201 {object} models.Category
+// @Failure 400 {string} string "invalid request body or failed to create"
+// @Router /categories/create [post]
+func (h *TagHandler) CreateCategoryHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("CreateCategoryHandler called")
+
+	var req JSONCategoryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorLogger.Printf("Failed to decode request body: %v\n", err)
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	category := &models.Category{Name: req.Name, Description: req.Description}
+	if err := h.tagService.CreateCategoryService(category); err != nil {
+		utils.ErrorLogger.Printf("Failed to create category: %v\n", err)
+		http.Error(w, "failed to create category", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(category)
+}
+
+// ListCategoriesHandler godoc
+// @Summary List categories
+// @Description Get all categories
+// @Tags categories
+// @Produce json
+// @Success 200 {array} models.Category
+// @Failure 500 {string} string "failed to list categories"
+// @Router /categories/listAll [get]
+func (h *TagHandler) ListCategoriesHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("ListCategoriesHandler called")
+
+	categories, err := h.tagService.ListCategoriesService()
+	if err != nil{
+		utils.ErrorLogger.Printf("Failed to list categories: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(categories)
+}
+
+// DeleteCategoryHandler godoc
+// @Summary Delete a category
+// @Description Delete a category, subscriptions using it are left without a category
+// @Tags categories
+// @Param name query string true "Category name"
+// @Success 204 {string} string "No Content"
+// @Failure 400 {string} string "missing name"
+// @Failure 404 {string} string "category not found"
+// @Router /categories/delete [delete]
+func (h *TagHandler) DeleteCategoryHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("DeleteCategoryHandler called")
+	name := r.URL.Query().Get("name")
+	if name == ""{
+		utils.WarningLogger.Println("Missing name parameter in request")
+		http.Error(w, "missing name paramter", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.tagService.DeleteCategoryService(name); err != nil{
+		utils.ErrorLogger.Printf("Failed to delete category %s: %v", name, err)
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
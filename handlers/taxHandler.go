@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"online-subs-api/models"
+	"online-subs-api/services"
+	"online-subs-api/utils"
+)
+
+type JSONTaxRateRequest struct {
+	Jurisdiction string  `json:"jurisdiction"`
+	Name         string  `json:"name"`
+	Rate         float64 `json:"rate"`
+	ValidFrom    string  `json:"valid_from"`
+}
+
+// CreateTaxRateHandler godoc
+// @Summary Add a tax rate
+// @Description Set the tax rate (in percent) of a country or jurisdiction such as DE or US-CA from valid_from (MM-YYYY) on, a later rate of the same jurisdiction replaces it. Requires the X-Admin-Token header.
+// @Tags taxes
+// @Accept json
+// @Produce json
+// @Param X-Admin-Token header string true "Admin token"
+// @Param rate body JSONTaxRateRequest true "Tax rate"
+// @Success 201 {object} models.TaxRate
+// @Failure 400 {string} string "invalid request body or failed to create"
+// @Failure 403 {string} string "forbidden"
+// @Router /taxes/rates/create [post]
+func (h *SubsHandler) CreateTaxRateHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("CreateTaxRateHandler called")
+	if !authorizeAdmin(w, r) {
+		return
+	}
+
+	var req JSONTaxRateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorLogger.Printf("Failed to decode request body: %v", err)
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	rate := &models.TaxRate{Jurisdiction: req.Jurisdiction, Name: req.Name, Rate: req.Rate}
+	if err := h.subsService.CreateTaxRateService(rate, req.ValidFrom); err != nil {
+		utils.ErrorLogger.Printf("Failed to create tax rate: %v", err)
+		http.Error(w, "failed to create tax rate: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(rate)
+}
+
+// ListTaxRatesHandler godoc
+// @Summary List tax rates
+// @Description Get the tax rates of all jurisdictions, oldest first per jurisdiction
+// @Tags taxes
+// @Produce json
+// @Success 200 {array} models.TaxRate
+// @Failure 500 {string} string "failed to list tax rates"
+// @Router /taxes/rates/listAll [get]
+func (h *SubsHandler) ListTaxRatesHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("ListTaxRatesHandler called")
+
+	rates, err := h.subsService.ListTaxRatesService()
+	if err != nil {
+		utils.ErrorLogger.Printf("Failed to list tax rates: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rates)
+}
+
+// DeleteTaxRateHandler godoc
+// @Summary Delete a tax rate
+// @Description Requires the X-Admin-Token header
+// @Tags taxes
+// @Param X-Admin-Token header string true "Admin token"
+// @Param id query string true "Tax rate ID"
+// @Success 204 {string} string "No Content"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "tax rate not found"
+// @Router /taxes/rates/delete [delete]
+func (h *SubsHandler) DeleteTaxRateHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("DeleteTaxRateHandler called")
+	if !authorizeAdmin(w, r) {
+		return
+	}
+	id := r.URL.Query().Get("id")
+	if id == ""{
+		utils.WarningLogger.Println("Missing id parameter in request")
+		http.Error(w, "missing id paramter", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.subsService.DeleteTaxRateService(id); err != nil {
+		utils.ErrorLogger.Printf("Failed to delete tax rate id=%s: %v", id, err)
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SetSubTaxHandler godoc
+// @Summary Set the tax of a subscription
+// @Description Tax the subscription at the rate of its jurisdiction, or at its own rate (in percent) when given. With inclusive (the default) the price already contains the tax, with inclusive false the tax is added on top of it.
+// @Tags taxes
+// @Accept json
+// @Produce json
+// @Param sub_id query string true "Subscription ID"
+// @Param tax body services.SubTaxRequest true "Tax settings"
+// @Success 200 {object} models.SubTax
+// @Failure 400 {string} string "invalid request body or tax settings"
+// @Router /subs/tax/set [put]
+func (h *SubsHandler) SetSubTaxHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("SetSubTaxHandler called")
+	subID := r.URL.Query().Get("sub_id")
+	if subID == ""{
+		utils.WarningLogger.Println("Missing sub_id parameter in request")
+		http.Error(w, "missing sub_id paramter", http.StatusBadRequest)
+		return
+	}
+
+	var req services.SubTaxRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorLogger.Printf("Failed to decode request body: %v", err)
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	tax, err := h.subsService.SetSubTaxService(subID, req)
+	if err != nil {
+		utils.ErrorLogger.Printf("Failed to set tax: %v", err)
+		http.Error(w, "failed to set tax: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tax)
+}
+
+// GetSubTaxHandler godoc
+// @Summary Get the tax of a subscription
+// @Tags taxes
+// @Produce json
+// @Param sub_id query string true "Subscription ID"
+// @Success 200 {object} models.SubTax
+// @Failure 404 {string} string "subscription has no tax settings"
+// @Router /subs/tax/get [get]
+func (h *SubsHandler) GetSubTaxHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("GetSubTaxHandler called")
+	subID := r.URL.Query().Get("sub_id")
+	if subID == ""{
+		utils.WarningLogger.Println("Missing sub_id parameter in request")
+		http.Error(w, "missing sub_id paramter", http.StatusBadRequest)
+		return
+	}
+
+	tax, err := h.subsService.GetSubTaxService(subID)
+	if err != nil {
+		utils.ErrorLogger.Printf("Failed to get tax: %v", err)
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tax)
+}
+
+// DeleteSubTaxHandler godoc
+// @Summary Remove the tax of a subscription
+// @Description The subscription is no longer taxed
+// @Tags taxes
+// @Param sub_id query string true "Subscription ID"
+// @Success 204 {string} string "No Content"
+// @Failure 404 {string} string "tax settings not found"
+// @Router /subs/tax/delete [delete]
+func (h *SubsHandler) DeleteSubTaxHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("DeleteSubTaxHandler called")
+	subID := r.URL.Query().Get("sub_id")
+	if subID == ""{
+		utils.WarningLogger.Println("Missing sub_id parameter in request")
+		http.Error(w, "missing sub_id paramter", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.subsService.DeleteSubTaxService(subID); err != nil {
+		utils.ErrorLogger.Printf("Failed to delete tax of sub %s: %v", subID, err)
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"online-subs-api/models"
+	"online-subs-api/services"
+	"online-subs-api/utils"
+)
+
+type TenantHandler struct{
+	tenantService *services.TenantService
+}
+
+func NewTenantHandler(tenantService *services.TenantService) *TenantHandler{
+	return &TenantHandler{tenantService: tenantService}
+}
+
+// SetMetadataSchemaHandler godoc
+// @Summary Set a tenant's metadata schema
+// @Description Create or replace the JSON Schema that the metadata of the tenant's subscriptions must satisfy
+// @Tags tenants
+// @Accept json
+// @Produce json
+// @Param tenant_id query string true "Tenant ID"
+// @Param schema body object true "JSON Schema"
+// @Success 200 {object} models.TenantSchema
+// @Failure 400 {string} string "invalid schema"
+// @Router /tenants/metadata-schema/set [put]
+func (h *TenantHandler) SetMetadataSchemaHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("SetMetadataSchemaHandler called")
+	tenantID := r.URL.Query().Get("tenant_id")
+	if tenantID == ""{
+		utils.WarningLogger.Println("Missing tenant_id parameter in request")
+		http.Error(w, "missing tenant_id paramter", http.StatusBadRequest)
+		return
+	}
+
+	var schema models.JSONMap
+	if err := json.NewDecoder(r.Body).Decode(&schema); err != nil {
+		utils.ErrorLogger.Printf("Failed to decode request body: %v", err)
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	tenantSchema, err := h.tenantService.SetMetadataSchemaService(tenantID, schema)
+	if err != nil {
+		utils.ErrorLogger.Printf("Failed to set metadata schema for tenant %s: %v", tenantID, err)
+		http.Error(w, "failed to set schema: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tenantSchema)
+}
+
+// GetMetadataSchemaHandler godoc
+// @Summary Get a tenant's metadata schema
+// @Description Retrieve the JSON Schema used to validate the tenant's subscription metadata
+// @Tags tenants
+// @Produce json
+// @Param tenant_id query string true "Tenant ID"
+// @Success 200 {object} models.TenantSchema
+// @Failure 400 {string} string "missing tenant_id"
+// @Failure 404 {string} string "schema not found"
+// @Router /tenants/metadata-schema/get [get]
+func (h *TenantHandler) GetMetadataSchemaHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("GetMetadataSchemaHandler called")
+	tenantID := r.URL.Query().Get("tenant_id")
+	if tenantID == ""{
+		utils.WarningLogger.Println("Missing tenant_id parameter in request")
+		http.Error(w, "missing tenant_id paramter", http.StatusBadRequest)
+		return
+	}
+
+	tenantSchema, err := h.tenantService.GetMetadataSchemaService(tenantID)
+	if err != nil {
+		utils.ErrorLogger.Printf("Metadata schema not found for tenant %s: %v", tenantID, err)
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tenantSchema)
+}
+
+// DeleteMetadataSchemaHandler godoc
+// @Summary Delete a tenant's metadata schema
+// @Description Remove the schema, the tenant's metadata is no longer validated
+// @Tags tenants
+// @Param tenant_id query string true "Tenant ID"
+// @Success 204 {string} string "No Content"
+// @Failure 400 {string} string "missing tenant_id"
+// @Router /tenants/metadata-schema/delete [delete]
+func (h *TenantHandler) DeleteMetadataSchemaHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("DeleteMetadataSchemaHandler called")
+	tenantID := r.URL.Query().Get("tenant_id")
+	if tenantID == ""{
+		utils.WarningLogger.Println("Missing tenant_id parameter in request")
+		http.Error(w, "missing tenant_id paramter", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.tenantService.DeleteMetadataSchemaService(tenantID); err != nil {
+		utils.ErrorLogger.Printf("Failed to delete metadata schema for tenant %s: %v", tenantID, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
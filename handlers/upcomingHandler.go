@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"online-subs-api/utils"
+	"strconv"
+	"strings"
+)
+
+// wantsCalendar is true for ?format=ics or an Accept header asking for text/calendar
+func wantsCalendar(r *http.Request) bool{
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format == "ics"
+	}
+	return strings.Contains(r.Header.Get("Accept"), "text/calendar")
+}
+
+// UpcomingChargesHandler godoc
+// @Summary List upcoming charges
+// @Description Charges due within a window, sorted by date and summed per day, with their tax breakdown. Returns an iCalendar file of the gross amounts charged with format=ics or Accept: text/calendar.
+// @Tags subscriptions
+// @Produce json
+// @Produce text/calendar
+// @Param from query string false "First day of the window in YYYY-MM-DD format, defaults to today"
+// @Param days query int false "Window length in days (default 14, max 366)"
+// @Param format query string false "json (default) or ics"
+// @Param user_id query string false "User ID (UUID format), lists their share of shared subscriptions"
+// @Param service_name query string false "Service name"
+// @Param category query string false "Category"
+// @Param tag query string false "Tag name"
+// @Param tenant_id query string false "Tenant ID"
+// @Success 200 {object} services.UpcomingCharges
+// @Failure 400 {string} string "Invalid input"
+// @Router /subs/upcoming [get]
+func (h *SubsHandler) UpcomingChargesHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("UpcomingChargesHandler called")
+	q := r.URL.Query()
+
+	days := 0
+	if daysStr := q.Get("days"); daysStr != "" {
+		var err error
+		if days, err = strconv.Atoi(daysStr); err != nil {
+			utils.WarningLogger.Println("Invalid days parameter:", daysStr)
+			http.Error(w, "days must be a number", http.StatusBadRequest)
+			return
+		}
+	}
+
+	upcoming, err := h.subsService.UpcomingChargesService(q.Get("from"), days, parseSubsFilter(r))
+	if err != nil {
+		utils.ErrorLogger.Printf("Failed to list upcoming charges: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if wantsCalendar(r) {
+		w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="upcoming-charges.ics"`)
+		upcoming.Calendar().WriteTo(w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(upcoming)
+}
@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"online-subs-api/services"
+	"online-subs-api/utils"
+)
+
+type JSONUsageRequest struct {
+	Records []services.UsageInput `json:"records"`
+}
+
+// SetPricingHandler godoc
+// @Summary Set the pricing model of a subscription
+// @Description Make the subscription usage-based: every billing period costs base_fee plus the usage recorded in it, priced per_unit, tiered (each tier prices its own units, e.g. the first 1000 free) or volume (the tier the total falls into prices all units). Unit prices are per unit_size units. Recorded usage is repriced; model "flat" goes back to the subscription price.
+// @Tags usage
+// @Accept json
+// @Produce json
+// @Param sub_id query string true "Subscription ID"
+// @Param pricing body services.PricingRequest true "Pricing model"
+// @Success 200 {object} models.SubPricing
+// @Failure 400 {string} string "invalid request body or pricing"
+// @Router /subs/pricing/set [put]
+func (h *SubsHandler) SetPricingHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("SetPricingHandler called")
+	subID := r.URL.Query().Get("sub_id")
+	if subID == ""{
+		utils.WarningLogger.Println("Missing sub_id parameter in request")
+		http.Error(w, "missing sub_id paramter", http.StatusBadRequest)
+		return
+	}
+
+	var req services.PricingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorLogger.Printf("Failed to decode request body: %v", err)
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	pricing, err := h.subsService.SetPricingService(subID, req)
+	if err != nil {
+		utils.ErrorLogger.Printf("Failed to set pricing: %v", err)
+		http.Error(w, "failed to set pricing: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pricing)
+}
+
+// GetPricingHandler godoc
+// @Summary Get the pricing model of a subscription
+// @Tags usage
+// @Produce json
+// @Param sub_id query string true "Subscription ID"
+// @Success 200 {object} models.SubPricing
+// @Failure 400 {string} string "missing or invalid sub_id"
+// @Router /subs/pricing/get [get]
+func (h *SubsHandler) GetPricingHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("GetPricingHandler called")
+	subID := r.URL.Query().Get("sub_id")
+	if subID == ""{
+		utils.WarningLogger.Println("Missing sub_id parameter in request")
+		http.Error(w, "missing sub_id paramter", http.StatusBadRequest)
+		return
+	}
+
+	pricing, err := h.subsService.GetPricingService(subID)
+	if err != nil {
+		utils.ErrorLogger.Printf("Failed to get pricing: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pricing)
+}
+
+// DeletePricingHandler godoc
+// @Summary Remove the pricing model of a subscription
+// @Description The subscription costs its price again, recorded usage is kept
+// @Tags usage
+// @Param sub_id query string true "Subscription ID"
+// @Success 204 {string} string "No Content"
+// @Failure 404 {string} string "pricing not found"
+// @Router /subs/pricing/delete [delete]
+func (h *SubsHandler) DeletePricingHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("DeletePricingHandler called")
+	subID := r.URL.Query().Get("sub_id")
+	if subID == ""{
+		utils.WarningLogger.Println("Missing sub_id parameter in request")
+		http.Error(w, "missing sub_id paramter", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.subsService.DeletePricingService(subID); err != nil {
+		utils.ErrorLogger.Printf("Failed to delete pricing of sub %s: %v", subID, err)
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RecordUsageHandler godoc
+// @Summary Record usage
+// @Description Ingest up to 1000 usage records of a usage-based subscription. Timestamps are RFC 3339 or YYYY-MM-DD; a record whose idempotency_key was already recorded for the subscription is skipped, so batches can be retried safely.
+// @Tags usage
+// @Accept json
+// @Produce json
+// @Param sub_id query string true "Subscription ID"
+// @Param usage body JSONUsageRequest true "Usage records"
+// @Success 201 {object} services.UsageIngestResult
+// @Failure 400 {string} string "invalid request body or records"
+// @Router /subs/usage/record [post]
+func (h *SubsHandler) RecordUsageHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("RecordUsageHandler called")
+	subID := r.URL.Query().Get("sub_id")
+	if subID == ""{
+		utils.WarningLogger.Println("Missing sub_id parameter in request")
+		http.Error(w, "missing sub_id paramter", http.StatusBadRequest)
+		return
+	}
+
+	var req JSONUsageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorLogger.Printf("Failed to decode request body: %v", err)
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.subsService.RecordUsageService(subID, req.Records)
+	if err != nil {
+		utils.ErrorLogger.Printf("Failed to record usage: %v", err)
+		http.Error(w, "failed to record usage: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(result)
+}
+
+// ListUsageHandler godoc
+// @Summary List usage records
+// @Description Usage records of a subscription between two days (YYYY-MM-DD, inclusive), the current month by default
+// @Tags usage
+// @Produce json
+// @Param sub_id query string true "Subscription ID"
+// @Param from query string false "First day in YYYY-MM-DD format"
+// @Param to query string false "Last day in YYYY-MM-DD format"
+// @Success 200 {array} models.UsageRecord
+// @Failure 400 {string} string "Invalid input"
+// @Router /subs/usage/listAll [get]
+func (h *SubsHandler) ListUsageHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("ListUsageHandler called")
+	q := r.URL.Query()
+	if q.Get("sub_id") == ""{
+		utils.WarningLogger.Println("Missing sub_id parameter in request")
+		http.Error(w, "missing sub_id paramter", http.StatusBadRequest)
+		return
+	}
+
+	records, err := h.subsService.ListUsageService(q.Get("sub_id"), q.Get("from"), q.Get("to"))
+	if err != nil {
+		utils.ErrorLogger.Printf("Failed to list usage: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(records)
+}
+
+// UsageSummaryHandler godoc
+// @Summary Usage charges per billing period
+// @Description Base fee, units and usage priced per tier for each billing period starting between start and end (MM-YYYY, the last 12 months by default)
+// @Tags usage
+// @Produce json
+// @Param sub_id query string true "Subscription ID"
+// @Param start query string false "Start month in MM-YYYY format"
+// @Param end query string false "End month in MM-YYYY format"
+// @Success 200 {object} services.UsageSummary
+// @Failure 400 {string} string "Invalid input"
+// @Router /subs/usage/summary [get]
+func (h *SubsHandler) UsageSummaryHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("UsageSummaryHandler called")
+	q := r.URL.Query()
+	if q.Get("sub_id") == ""{
+		utils.WarningLogger.Println("Missing sub_id parameter in request")
+		http.Error(w, "missing sub_id paramter", http.StatusBadRequest)
+		return
+	}
+
+	summary, err := h.subsService.UsageSummaryService(q.Get("sub_id"), q.Get("start"), q.Get("end"))
+	if err != nil {
+		utils.ErrorLogger.Printf("Failed to build usage summary: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summary)
+}
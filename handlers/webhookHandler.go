@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"online-subs-api/models"
+	"online-subs-api/services"
+	"online-subs-api/utils"
+)
+
+type JSONWebhookRequest struct {
+	TenantID   string   `json:"tenant_id"`
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+	Active     *bool    `json:"active"`
+}
+
+type WebhookHandler struct{
+	webhookService *services.WebhookService
+}
+
+func NewWebhookHandler(webhookService *services.WebhookService) *WebhookHandler{
+	return &WebhookHandler{webhookService: webhookService}
+}
+
+// CreateWebhookHandler godoc
+// @Summary Register a webhook endpoint
+// @Description Register an endpoint for the subscription events of a tenant. event_types filters the events (subscription.created, subscription.updated, subscription.deleted, subscription.renewed, subscription.ended, budget.alert), empty means all.
+// @Description The signing secret is returned only once.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param endpoint body JSONWebhookRequest true "Webhook endpoint"
+// @Success 201 {object} services.WebhookEndpointSecret
+// @Failure 400 {string} string "invalid request body or failed to create"
+// @Router /webhooks/create [post]
+func (h *WebhookHandler) CreateWebhookHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("CreateWebhookHandler called")
+
+	var req JSONWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorLogger.Printf("Failed to decode request body: %v", err)
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	endpoint := &models.WebhookEndpoint{TenantID: req.TenantID, URL: req.URL, EventTypes: req.EventTypes}
+	created, err := h.webhookService.CreateEndpointService(endpoint)
+	if err != nil {
+		utils.ErrorLogger.Printf("Failed to create webhook endpoint: %v", err)
+		http.Error(w, "failed to create webhook endpoint: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// ListWebhooksHandler godoc
+// @Summary List webhook endpoints
+// @Description Get the registered webhook endpoints, optionally of one tenant
+// @Tags webhooks
+// @Produce json
+// @Param tenant_id query string false "Tenant ID"
+// @Success 200 {array} models.WebhookEndpoint
+// @Failure 400 {string} string "invalid tenant_id"
+// @Router /webhooks/listAll [get]
+func (h *WebhookHandler) ListWebhooksHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("ListWebhooksHandler called")
+
+	endpoints, err := h.webhookService.ListEndpointsService(r.URL.Query().Get("tenant_id"))
+	if err != nil {
+		utils.ErrorLogger.Printf("Failed to list webhook endpoints: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(endpoints)
+}
+
+// UpdateWebhookHandler godoc
+// @Summary Update a webhook endpoint
+// @Description Change the url, event types or active flag of an endpoint
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param id query string true "Webhook endpoint ID"
+// @Param endpoint body JSONWebhookRequest true "Webhook endpoint"
+// @Success 200 {object} models.WebhookEndpoint
+// @Failure 400 {string} string "invalid request body or failed update"
+// @Router /webhooks/update [put]
+func (h *WebhookHandler) UpdateWebhookHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("UpdateWebhookHandler called")
+	id := r.URL.Query().Get("id")
+	if id == ""{
+		utils.WarningLogger.Println("Missing id parameter in request")
+		http.Error(w, "missing id paramter", http.StatusBadRequest)
+		return
+	}
+
+	var req JSONWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorLogger.Printf("Failed to decode request body: %v", err)
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	update := &models.WebhookEndpoint{URL: req.URL, EventTypes: req.EventTypes, Active: req.Active == nil || *req.Active}
+	endpoint, err := h.webhookService.UpdateEndpointService(id, update)
+	if err != nil {
+		utils.ErrorLogger.Printf("Failed to update webhook endpoint id=%s: %v", id, err)
+		http.Error(w, "failed to update webhook endpoint: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(endpoint)
+}
+
+// DeleteWebhookHandler godoc
+// @Summary Delete a webhook endpoint
+// @Description Delete an endpoint and its delivery log
+// @Tags webhooks
+// @Param id query string true "Webhook endpoint ID"
+// @Success 204 {string} string "No Content"
+// @Failure 400 {string} string "missing id"
+// @Router /webhooks/delete [delete]
+func (h *WebhookHandler) DeleteWebhookHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("DeleteWebhookHandler called")
+	id := r.URL.Query().Get("id")
+	if id == ""{
+		utils.WarningLogger.Println("Missing id parameter in request")
+		http.Error(w, "missing id paramter", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.webhookService.DeleteEndpointService(id); err != nil {
+		utils.ErrorLogger.Printf("Failed to delete webhook endpoint id=%s: %v", id, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListDeliveriesHandler godoc
+// @Summary Webhook delivery log
+// @Description Get the latest 200 deliveries with their status, attempts and last error
+// @Tags webhooks
+// @Produce json
+// @Param endpoint_id query string false "Webhook endpoint ID"
+// @Param status query string false "pending, delivered or dead"
+// @Success 200 {array} models.WebhookDelivery
+// @Failure 400 {string} string "invalid filter"
+// @Router /webhooks/deliveries [get]
+func (h *WebhookHandler) ListDeliveriesHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("ListDeliveriesHandler called")
+	q := r.URL.Query()
+
+	deliveries, err := h.webhookService.ListDeliveriesService(q.Get("endpoint_id"), q.Get("status"))
+	if err != nil {
+		utils.ErrorLogger.Printf("Failed to list webhook deliveries: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(deliveries)
+}
+
+// RedeliverHandler godoc
+// @Summary Redeliver a webhook
+// @Description Queue a delivery again with a fresh set of attempts, e.g. a dead one
+// @Tags webhooks
+// @Produce json
+// @Param id query string true "Delivery ID"
+// @Success 202 {object} models.WebhookDelivery
+// @Failure 404 {string} string "delivery not found"
+// @Router /webhooks/deliveries/redeliver [post]
+func (h *WebhookHandler) RedeliverHandler(w http.ResponseWriter, r *http.Request){
+	utils.InfoLogger.Println("RedeliverHandler called")
+	id := r.URL.Query().Get("id")
+	if id == ""{
+		utils.WarningLogger.Println("Missing id parameter in request")
+		http.Error(w, "missing id paramter", http.StatusBadRequest)
+		return
+	}
+
+	delivery, err := h.webhookService.RedeliverService(id)
+	if err != nil {
+		utils.ErrorLogger.Printf("Failed to redeliver id=%s: %v", id, err)
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(delivery)
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"log"
 	"net/http"
+	"os"
 	"online-subs-api/handlers"
 	"online-subs-api/models"
 	"online-subs-api/repo"
@@ -21,18 +22,83 @@ import (
 func main(){
 	utils.InitLogger()
 	db := repo.Connect()
-	db.AutoMigrate(&models.Sub{})
+	db.AutoMigrate(&models.Sub{}, &models.Provider{}, &models.Plan{}, &models.Tag{}, &models.Category{}, &models.TenantSchema{}, &models.PriceChange{}, &models.CalendarFeed{}, &models.Budget{}, &models.BudgetAlert{}, &models.Reminder{}, &models.WebhookEndpoint{}, &models.WebhookDelivery{}, &models.OutboxEvent{}, &models.OutboxCursor{}, &models.ImportPreview{}, &models.StatementImport{}, &models.StatementCandidate{}, &models.Payment{}, &models.Discrepancy{}, &models.SubSplit{}, &models.SubMember{}, &models.SeatChange{}, &models.SeatAssignment{}, &models.SubPricing{}, &models.UsageRecord{}, &models.UsageCharge{}, &models.Discount{}, &models.TaxRate{}, &models.SubTax{})
 
-	repo := repo.NewSubsRepo(db)
-	service := services.NewSubsService(repo)
+	subsRepo := repo.NewSubsRepo(db)
+	catalogRepo := repo.NewCatalogRepo(db)
+	tagRepo := repo.NewTagRepo(db)
+	tenantRepo := repo.NewTenantRepo(db)
+	if err := catalogRepo.SeedCatalogRepo(); err != nil {
+		log.Fatal("Failed to seed service catalog:", err)
+	}
+	if err := subsRepo.BackfillCategoryRepo(); err != nil {
+		log.Fatal("Failed to backfill subscription categories:", err)
+	}
+
+	resolver := services.NewNameResolver(catalogRepo)
+	service := services.NewSubsService(subsRepo, catalogRepo, tagRepo, tenantRepo, resolver)
 	handler := handlers.NewSubHandler(service)
 
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		runImportCommand(service, os.Args[2:])
+		return
+	}
+
+	budgetService := services.NewBudgetService(repo.NewBudgetRepo(db), subsRepo)
+	service.AddListener(budgetService.OnSubEvent)
+	budgetService.StartDailyEvaluation()
+	budgetHandler := handlers.NewBudgetHandler(budgetService)
+
+	webhookService := services.NewWebhookService(repo.NewWebhookRepo(db), subsRepo)
+	webhookService.StartWorker()
+	webhookHandler := handlers.NewWebhookHandler(webhookService)
+
+	// subscription events are written to the outbox by SubsRepo and relayed to the sinks
+	sinks, err := services.OutboxSinksFromEnv(webhookService)
+	if err != nil {
+		log.Fatal("Invalid outbox sinks:", err)
+	}
+	outboxRepo := repo.NewOutboxRepo(db)
+	relay := services.NewOutboxRelay(outboxRepo, sinks)
+	service.AddListener(relay.OnSubEvent)
+	relay.Start()
+	outboxHandler := handlers.NewOutboxHandler(relay)
+
+	streamService := services.NewStreamService(outboxRepo)
+	service.AddListener(streamService.OnSubEvent)
+	if err := streamService.Start(); err != nil {
+		log.Fatal("Failed to start the subscription stream:", err)
+	}
+	streamHandler := handlers.NewStreamHandler(streamService)
+
+	notifiers, err := services.NotifiersFromEnv()
+	if err != nil {
+		log.Fatal("Invalid reminder notifiers:", err)
+	}
+	reminderService := services.NewReminderService(repo.NewReminderRepo(db), subsRepo, notifiers, services.ReminderConfigFromEnv())
+	reminderService.StartScheduler()
+	reminderHandler := handlers.NewReminderHandler(reminderService)
+
+	paymentRepo := repo.NewPaymentRepo(db)
+	reconciliationService := services.NewReconciliationService(paymentRepo, subsRepo, services.ReconciliationConfigFromEnv())
+	reconciliationService.StartDailyReconciliation()
+	service.StartPlanChangeScheduler()
+	paymentHandler := handlers.NewPaymentHandler(services.NewPaymentService(paymentRepo, subsRepo), reconciliationService)
+
+	catalogHandler := handlers.NewCatalogHandler(services.NewCatalogService(catalogRepo, resolver))
+	tagHandler := handlers.NewTagHandler(services.NewTagService(tagRepo))
+	tenantHandler := handlers.NewTenantHandler(services.NewTenantService(tenantRepo))
+	reportHandler := handlers.NewReportHandler(services.NewReportService(repo.NewReportRepo(db), subsRepo))
+	feedHandler := handlers.NewFeedHandler(services.NewFeedService(repo.NewFeedRepo(db), subsRepo))
+	appImportHandler := handlers.NewAppImportHandler(services.NewAppImportService(repo.NewImportRepo(db), service))
+	statementHandler := handlers.NewStatementHandler(services.NewStatementService(repo.NewStatementRepo(db), service))
+
 	mux := http.NewServeMux()
-	router.Routes(mux, handler)
+	router.Routes(mux, handler, catalogHandler, tagHandler, tenantHandler, reportHandler, feedHandler, budgetHandler, reminderHandler, webhookHandler, outboxHandler, streamHandler, appImportHandler, statementHandler, paymentHandler)
 	mux.Handle("/swagger/", httpSwagger.WrapHandler)
 
 	log.Println("Server running at :8080")
-	err := http.ListenAndServe(":8080", mux)
+	err = http.ListenAndServe(":8080", mux)
 	if err != nil {
 		log.Fatal(err)
 	}
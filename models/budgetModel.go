@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+const (
+	BudgetScopeUser     = "user"
+	BudgetScopeCategory = "category"
+	BudgetScopeTenant   = "tenant"
+)
+
+// Budget is a monthly spending limit for a user, a category or a tenant
+type Budget struct{
+	ID				string			`json:"id"  gorm:"type:uuid;  primaryKey"`
+	Scope			string			`json:"scope"  gorm:"not null;  uniqueIndex:idx_budget_scope"`
+	ScopeID			string			`json:"scope_id"  gorm:"not null;  uniqueIndex:idx_budget_scope"`
+	Amount			int				`json:"amount"  gorm:"not null"`
+	CreatedAt		time.Time		`json:"created_at"`
+	UpdatedAt		time.Time		`json:"updated_at"`
+}
+
+// BudgetAlert records a crossed threshold, there is at most one per budget, month and threshold
+type BudgetAlert struct{
+	ID				string			`json:"id"  gorm:"type:uuid;  primaryKey"`
+	BudgetID		string			`json:"budget_id"  gorm:"type:uuid;  not null;  uniqueIndex:idx_budget_alert"`
+	Period			string			`json:"period"  gorm:"not null;  uniqueIndex:idx_budget_alert"`
+	Threshold		int				`json:"threshold"  gorm:"not null;  uniqueIndex:idx_budget_alert"`
+	Scope			string			`json:"scope"`
+	ScopeID			string			`json:"scope_id"`
+	Spend			int				`json:"spend"`
+	Amount			int				`json:"amount"`
+	CreatedAt		time.Time		`json:"created_at"`
+}
@@ -0,0 +1,34 @@
+package models
+
+const (
+	BillingMonthly   = "monthly"
+	BillingQuarterly = "quarterly"
+	BillingYearly    = "yearly"
+)
+
+// Provider is a canonical service in the catalog (e.g. Netflix)
+type Provider struct{
+	ID				string			`json:"id"  gorm:"type:uuid;  primaryKey"`
+	Name			string			`json:"name"  gorm:"not null;  uniqueIndex"`
+	Aliases			StringList		`json:"aliases"  gorm:"type:jsonb"`
+	Category		string			`json:"category"`
+	Plans			[]Plan			`json:"plans,omitempty"  gorm:"foreignKey:ProviderID;  constraint:OnDelete:CASCADE"`
+}
+
+// Plan is a priced tier of a provider (e.g. Netflix Premium)
+type Plan struct{
+	ID				string			`json:"id"  gorm:"type:uuid;  primaryKey"`
+	ProviderID		string			`json:"provider_id"  gorm:"type:uuid;  not null;  uniqueIndex:idx_plan_provider_name"`
+	Name			string			`json:"name"  gorm:"not null;  uniqueIndex:idx_plan_provider_name"`
+	Aliases			StringList		`json:"aliases"  gorm:"type:jsonb"`
+	Price			int				`json:"price"  gorm:"not null"`
+	BillingPeriod	string			`json:"billing_period"  gorm:"not null;  default:monthly"`
+}
+
+func (Provider) TableName() string{
+	return "catalog_providers"
+}
+
+func (Plan) TableName() string{
+	return "catalog_plans"
+}
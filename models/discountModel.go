@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+const (
+	DiscountPercentage	= "percentage"
+	DiscountFixed		= "fixed"
+
+	DiscountOnce		= "once"
+	DiscountRepeating	= "repeating"
+	DiscountForever		= "forever"
+)
+
+// Discount lowers the charges of a subscription from the billing period starting in
+// StartDate's month on: by Percentage of the gross charge or by a fixed Amount, for one
+// charge, for Periods charges or forever. Code is the coupon or promotion it came from.
+type Discount struct{
+	ID				string			`json:"id"  gorm:"type:uuid;  primaryKey"`
+	SubID			string			`json:"sub_id"  gorm:"type:uuid;  not null;  index"`
+	Code			string			`json:"code,omitempty"`
+	Kind			string			`json:"kind"  gorm:"not null"`
+	Percentage		float64			`json:"percentage,omitempty"`
+	Amount			int				`json:"amount,omitempty"`
+	Duration		string			`json:"duration"  gorm:"not null"`
+	Periods			int				`json:"periods,omitempty"`
+	StartDate		time.Time		`json:"start_date"  gorm:"not null"`
+	CreatedAt		time.Time		`json:"created_at"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+const (
+	FeedScopeUser   = "user"
+	FeedScopeTenant = "tenant"
+)
+
+// CalendarFeed gives read access to the .ics feed of a user's or a tenant's subscriptions.
+// Only the SHA-256 of the token is stored, the token itself is shown once on create/rotate.
+type CalendarFeed struct{
+	ID				string			`json:"id"  gorm:"type:uuid;  primaryKey"`
+	Scope			string			`json:"scope"  gorm:"not null;  uniqueIndex:idx_feed_scope"`
+	ScopeID			string			`json:"scope_id"  gorm:"not null;  uniqueIndex:idx_feed_scope"`
+	TokenHash		string			`json:"-"  gorm:"not null;  uniqueIndex"`
+	CreatedAt		time.Time		`json:"created_at"`
+	RotatedAt		time.Time		`json:"rotated_at"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// ImportPreview holds the parsed rows of an app import between preview and commit.
+// Rows is the JSON of the previewed rows, a preview can be committed once before it expires.
+type ImportPreview struct{
+	ID				string			`json:"id"  gorm:"type:uuid;  primaryKey"`
+	App				string			`json:"app"  gorm:"not null"`
+	UserID			string			`json:"user_id"  gorm:"type:uuid;  not null"`
+	TenantID		string			`json:"tenant_id,omitempty"`
+	Rows			string			`json:"-"  gorm:"type:text;  not null"`
+	CreatedAt		time.Time		`json:"created_at"`
+	ExpiresAt		time.Time		`json:"expires_at"  gorm:"index"`
+	CommittedAt		*time.Time		`json:"committed_at,omitempty"`
+}
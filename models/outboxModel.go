@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+const (
+	AggregateSubscription = "subscription"
+	AggregateBudget = "budget"
+
+	EventSubCreated = "subscription.created"
+	EventSubUpdated = "subscription.updated"
+	EventSubDeleted = "subscription.deleted"
+	EventBudgetAlert = "budget.alert"
+)
+
+// OutboxEvent is a domain event written in the same transaction as the change it
+// describes. Seq orders the events, ID is the event id consumers deduplicate on.
+type OutboxEvent struct{
+	Seq				int64			`json:"seq"  gorm:"primaryKey;  autoIncrement"`
+	ID				string			`json:"id"  gorm:"type:uuid;  not null;  uniqueIndex"`
+	AggregateType	string			`json:"aggregate_type"  gorm:"not null"`
+	AggregateID		string			`json:"aggregate_id"  gorm:"not null;  index"`
+	Type			string			`json:"type"  gorm:"not null"`
+	TenantID		string			`json:"tenant_id"`
+	Payload			string			`json:"payload"  gorm:"type:text;  not null"`
+	CreatedAt		time.Time		`json:"created_at"`
+}
+
+// OutboxCursor is the last event a relay sink has published
+type OutboxCursor struct{
+	Sink			string			`json:"sink"  gorm:"primaryKey"`
+	Seq				int64			`json:"seq"  gorm:"not null"`
+	UpdatedAt		time.Time		`json:"updated_at"`
+}
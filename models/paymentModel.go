@@ -0,0 +1,70 @@
+package models
+
+import "time"
+
+const (
+	PaymentSourceManual = "manual"
+	PaymentSourceImport = "import"
+
+	PaymentPaid              = "paid"
+	PaymentRefunded          = "refunded"
+	PaymentPartiallyRefunded = "partially_refunded"
+	PaymentDisputed          = "disputed"
+
+	DiscrepancyMissed         = "missed"
+	DiscrepancyDouble         = "double"
+	DiscrepancyAmountMismatch = "amount_mismatch"
+	DiscrepancyAfterEnd       = "after_end"
+	DiscrepancyUnexpected     = "unexpected"
+
+	DiscrepancyOpen     = "open"
+	DiscrepancyResolved = "resolved"
+)
+
+// Payment is an actual charge of a subscription, as entered or imported
+type Payment struct{
+	ID				string			`json:"id"  gorm:"type:uuid;  primaryKey"`
+	SubID			string			`json:"sub_id"  gorm:"type:uuid;  not null;  index"`
+	Date			time.Time		`json:"date"  gorm:"not null;  index"`
+	Amount			int				`json:"amount"  gorm:"not null"`
+	Currency		string			`json:"currency,omitempty"`
+	Method			string			`json:"method,omitempty"`
+	Reference		string			`json:"reference,omitempty"`
+	Status			string			`json:"status"  gorm:"not null;  default:paid;  index"`
+	RefundedAmount	int				`json:"refunded_amount,omitempty"`
+	Source			string			`json:"source"  gorm:"not null;  default:manual"`
+	CreatedAt		time.Time		`json:"created_at"`
+	UpdatedAt		time.Time		`json:"updated_at"`
+}
+
+// NetAmount is what was kept by the provider: nothing of a refunded payment and the amount
+// minus the refund of a partially refunded one. Disputed payments still count in full.
+func (p *Payment) NetAmount() int{
+	switch p.Status {
+	case PaymentRefunded:
+		return 0
+	case PaymentPartiallyRefunded:
+		return p.Amount - p.RefundedAmount
+	}
+	return p.Amount
+}
+
+// Discrepancy is a mismatch between the expected charges of a sub and its payments in a
+// month. Key identifies it across reconciliation runs, a discrepancy that is no longer
+// found is resolved.
+type Discrepancy struct{
+	ID				string			`json:"id"  gorm:"type:uuid;  primaryKey"`
+	Key				string			`json:"-"  gorm:"not null;  uniqueIndex"`
+	SubID			string			`json:"sub_id"  gorm:"type:uuid;  not null;  index"`
+	UserID			string			`json:"user_id"  gorm:"type:uuid;  index"`
+	TenantID		string			`json:"tenant_id,omitempty"`
+	ServiceName		string			`json:"service_name"`
+	Kind			string			`json:"kind"  gorm:"not null;  index"`
+	Month			time.Time		`json:"month"  gorm:"not null;  index"`
+	PaymentIDs		StringList		`json:"payment_ids"  gorm:"type:jsonb"`
+	Expected		int				`json:"expected"`
+	Actual			int				`json:"actual"`
+	Status			string			`json:"status"  gorm:"not null;  index"`
+	DetectedAt		time.Time		`json:"detected_at"`
+	ResolvedAt		*time.Time		`json:"resolved_at,omitempty"`
+}
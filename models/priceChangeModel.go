@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+const (
+	ProrationImmediate   = "immediate"
+	ProrationNextRenewal = "next_renewal"
+	ProrationNone        = "none"
+)
+
+// PriceChange schedules a new price for a subscription from EffectiveDate on. A plan change
+// (ChangedAt set) made inside a billing period opens the new segment at the next charge;
+// with immediate proration Charge - Credit is added to that next charge (AdjustmentMonth).
+// The sub moves to PlanID on PlanFrom, PlanApplied is set once it has.
+type PriceChange struct{
+	ID				string			`json:"id"  gorm:"type:uuid;  primaryKey"`
+	SubID			string			`json:"sub_id"  gorm:"type:uuid;  not null;  index"`
+	EffectiveDate	time.Time		`json:"effective_date"  gorm:"not null"`
+	Price			int				`json:"price"  gorm:"not null"`
+	ChangedAt		*time.Time		`json:"changed_at,omitempty"`
+	PreviousPrice	int				`json:"previous_price,omitempty"`
+	PlanID			*string			`json:"plan_id,omitempty"  gorm:"type:uuid"`
+	Policy			string			`json:"proration_policy,omitempty"`
+	Credit			int				`json:"credit,omitempty"`
+	Charge			int				`json:"charge,omitempty"`
+	AdjustmentMonth	*time.Time		`json:"adjustment_month,omitempty"`
+	PlanFrom		*time.Time		`json:"plan_from,omitempty"`
+	PlanApplied		bool			`json:"plan_applied,omitempty"  gorm:"not null;  default:false"`
+	CreatedAt		time.Time		`json:"created_at"`
+}
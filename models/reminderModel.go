@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+const (
+	ReminderRenewal  = "renewal"
+	ReminderTrialEnd = "trial_end"
+	ReminderEnd      = "end"
+
+	ReminderPending = "pending"
+	ReminderSent    = "sent"
+	ReminderFailed  = "failed"
+)
+
+// Reminder is one notice about an upcoming renewal, trial end or end of a sub, sent
+// through one notifier channel. The unique index keeps every reminder to a single record.
+type Reminder struct{
+	ID				string			`json:"id"  gorm:"type:uuid;  primaryKey"`
+	SubID			string			`json:"sub_id"  gorm:"type:uuid;  not null;  uniqueIndex:idx_reminder"`
+	Kind			string			`json:"kind"  gorm:"not null;  uniqueIndex:idx_reminder"`
+	EventDate		time.Time		`json:"event_date"  gorm:"not null;  uniqueIndex:idx_reminder"`
+	Channel			string			`json:"channel"  gorm:"not null;  uniqueIndex:idx_reminder"`
+	UserID			string			`json:"user_id"  gorm:"type:uuid"`
+	TenantID		string			`json:"tenant_id,omitempty"`
+	ServiceName		string			`json:"service_name"`
+	Price			int				`json:"price"`
+	Status			string			`json:"status"  gorm:"not null;  index"`
+	Attempts		int				`json:"attempts"`
+	LastError		string			`json:"last_error,omitempty"`
+	NextAttemptAt	time.Time		`json:"next_attempt_at"  gorm:"index"`
+	SentAt			*time.Time		`json:"sent_at,omitempty"`
+	CreatedAt		time.Time		`json:"created_at"`
+	UpdatedAt		time.Time		`json:"updated_at"`
+}
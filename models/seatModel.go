@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// SeatChange sets the number of seats of a seat-based subscription from EffectiveDate on,
+// the sub's price is then the price of one seat. A prorated change that falls inside a
+// billing period is charged for the rest of that period with the next charge, otherwise
+// it counts from the next charge.
+type SeatChange struct{
+	ID				string			`json:"id"  gorm:"type:uuid;  primaryKey"`
+	SubID			string			`json:"sub_id"  gorm:"type:uuid;  not null;  index"`
+	EffectiveDate	time.Time		`json:"effective_date"  gorm:"not null"`
+	Seats			int				`json:"seats"  gorm:"not null"`
+	Prorate			bool			`json:"prorate"`
+	CreatedAt		time.Time		`json:"created_at"`
+}
+
+// SeatAssignment is a seat used by someone from AssignedAt until UnassignedAt
+type SeatAssignment struct{
+	ID				string			`json:"id"  gorm:"type:uuid;  primaryKey"`
+	SubID			string			`json:"sub_id"  gorm:"type:uuid;  not null;  index"`
+	Assignee		string			`json:"assignee"  gorm:"not null"`
+	UserID			*string			`json:"user_id,omitempty"  gorm:"type:uuid"`
+	AssignedAt		time.Time		`json:"assigned_at"  gorm:"not null"`
+	UnassignedAt	*time.Time		`json:"unassigned_at,omitempty"`
+	CreatedAt		time.Time		`json:"created_at"`
+}
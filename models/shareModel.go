@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+const (
+	SplitEqual      = "equal"
+	SplitPercentage = "percentage"
+	SplitFixed      = "fixed"
+)
+
+// SubSplit shares a subscription paid by its owner (Sub.UserID) with other users. The
+// owner always takes part and pays what is left after the members' shares.
+type SubSplit struct{
+	SubID			string			`json:"sub_id"  gorm:"type:uuid;  primaryKey"`
+	Rule			string			`json:"split_rule"  gorm:"not null"`
+	Members			[]SubMember		`json:"members"  gorm:"foreignKey:SubID;  references:SubID"`
+	UpdatedAt		time.Time		`json:"updated_at"`
+}
+
+// SubMember is a user sharing a subscription. Share is a percentage (1-100) for the
+// percentage rule, an amount for the fixed rule and unused for the equal rule.
+type SubMember struct{
+	ID				string			`json:"id"  gorm:"type:uuid;  primaryKey"`
+	SubID			string			`json:"sub_id"  gorm:"type:uuid;  not null;  uniqueIndex:idx_sub_member"`
+	UserID			string			`json:"user_id"  gorm:"type:uuid;  not null;  uniqueIndex:idx_sub_member;  index"`
+	Share			int				`json:"share,omitempty"`
+	Position		int				`json:"-"`
+}
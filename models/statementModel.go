@@ -0,0 +1,99 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	CadenceWeekly    = "weekly"
+	CadenceMonthly   = "monthly"
+	CadenceQuarterly = "quarterly"
+	CadenceYearly    = "yearly"
+
+	CandidatePending   = "pending"
+	CandidateAccepted  = "accepted"
+	CandidateDismissed = "dismissed"
+)
+
+// StatementCharge is one bank transaction behind a candidate, amounts are positive debits
+type StatementCharge struct{
+	Date			time.Time		`json:"date"`
+	Amount			float64			`json:"amount"`
+	Description		string			`json:"description"`
+}
+
+// ChargeList is a list of charges stored as a JSONB array
+type ChargeList []StatementCharge
+
+func (l ChargeList) Value() (driver.Value, error){
+	if l == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal([]StatementCharge(l))
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (l *ChargeList) Scan(value interface{}) error{
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into ChargeList", value)
+	}
+	return json.Unmarshal(data, (*[]StatementCharge)(l))
+}
+
+// StatementImport is an analysed bank statement, the transactions themselves are not kept
+type StatementImport struct{
+	ID				string			`json:"id"  gorm:"type:uuid;  primaryKey"`
+	UserID			string			`json:"user_id"  gorm:"type:uuid;  not null;  index"`
+	TenantID		string			`json:"tenant_id,omitempty"`
+	Format			string			`json:"format"`
+	Transactions	int				`json:"transactions"`
+	FromDate		time.Time		`json:"from_date"`
+	ToDate			time.Time		`json:"to_date"`
+	CreatedAt		time.Time		`json:"created_at"`
+}
+
+// StatementCandidate is a recurring charge found in a statement. Price and BillingPeriod
+// are what the subscription gets on accept, weekly charges become a monthly price.
+// MatchedSubID is set when the user already tracks the subscription.
+type StatementCandidate struct{
+	ID				string			`json:"id"  gorm:"type:uuid;  primaryKey"`
+	ImportID		string			`json:"import_id"  gorm:"type:uuid;  not null;  index"`
+	UserID			string			`json:"user_id"  gorm:"type:uuid;  not null;  index"`
+	TenantID		string			`json:"tenant_id,omitempty"`
+	Merchant		string			`json:"merchant"`
+	ServiceName		string			`json:"service_name"`
+	ProviderID		*string			`json:"provider_id,omitempty"  gorm:"type:uuid"`
+	Category		string			`json:"category,omitempty"`
+	Currency		string			`json:"currency,omitempty"`
+	Amount			float64			`json:"amount"`
+	Cadence			string			`json:"cadence"`
+	Price			int				`json:"price"`
+	BillingPeriod	string			`json:"billing_period"`
+	Occurrences		int				`json:"occurrences"`
+	FirstDate		time.Time		`json:"first_date"`
+	LastDate		time.Time		`json:"last_date"`
+	NextExpected	time.Time		`json:"next_expected"`
+	Active			bool			`json:"active"`
+	Confidence		float64			`json:"confidence"`
+	MatchedSubID	*string			`json:"matched_sub_id,omitempty"  gorm:"type:uuid"`
+	Status			string			`json:"status"  gorm:"not null;  index"`
+	SubID			*string			`json:"sub_id,omitempty"  gorm:"type:uuid"`
+	Charges			ChargeList		`json:"charges"  gorm:"type:jsonb"`
+	CreatedAt		time.Time		`json:"created_at"`
+	UpdatedAt		time.Time		`json:"updated_at"`
+}
@@ -9,4 +9,20 @@ type Sub struct{
 	UserID			string			`json:"user_id"  gorm:"type:uuid;  not null"`
 	StartDate 		time.Time		`json:"start_date"  gorm:"not null"`
 	EndDate			time.Time		`json:"end_date"  gorm:"not null"`
+	ProviderID		*string			`json:"provider_id,omitempty"  gorm:"type:uuid;  index"`
+	PlanID			*string			`json:"plan_id,omitempty"  gorm:"type:uuid;  index"`
+	BillingPeriod	string			`json:"billing_period"  gorm:"not null;  default:monthly"`
+	ServiceNameInput	string		`json:"service_name_input,omitempty"`
+	NameConfidence	float64			`json:"name_confidence"`
+	Category		string			`json:"category"  gorm:"index"`
+	Tags			[]Tag			`json:"tags,omitempty"  gorm:"many2many:sub_tags"`
+	TenantID		string			`json:"tenant_id,omitempty"  gorm:"index"`
+	Metadata		JSONMap			`json:"metadata,omitempty"  gorm:"type:jsonb"`
+	TrialEndDate	*time.Time		`json:"trial_end_date,omitempty"`
+	SeatChanges		[]SeatChange	`json:"seat_changes,omitempty"  gorm:"foreignKey:SubID"`
+	Pricing			*SubPricing		`json:"pricing,omitempty"  gorm:"foreignKey:SubID"`
+	UsageCharges	[]UsageCharge	`json:"-"  gorm:"foreignKey:SubID"`
+	Discounts		[]Discount		`json:"discounts,omitempty"  gorm:"foreignKey:SubID"`
+	Tax				*SubTax			`json:"tax,omitempty"  gorm:"foreignKey:SubID"`
+	NextChargeDate	*time.Time		`json:"next_charge_date,omitempty"  gorm:"-"`
 }
\ No newline at end of file
@@ -0,0 +1,13 @@
+package models
+
+// Tag is a user-defined label, a subscription can have many of them
+type Tag struct{
+	ID				string			`json:"id"  gorm:"type:uuid;  primaryKey"`
+	Name			string			`json:"name"  gorm:"not null;  uniqueIndex"`
+}
+
+// Category is the single spending group of a subscription (e.g. "dev tools")
+type Category struct{
+	Name			string			`json:"name"  gorm:"primaryKey"`
+	Description		string			`json:"description"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// TaxRate is the tax rate (in percent) of a country or jurisdiction, e.g. "DE" or "US-CA",
+// from ValidFrom on. A later rate of the same jurisdiction replaces it.
+type TaxRate struct{
+	ID				string			`json:"id"  gorm:"type:uuid;  primaryKey"`
+	Jurisdiction	string			`json:"jurisdiction"  gorm:"not null;  uniqueIndex:idx_tax_rate_from"`
+	Name			string			`json:"name,omitempty"`
+	Rate			float64			`json:"rate"  gorm:"not null"`
+	ValidFrom		time.Time		`json:"valid_from"  gorm:"not null;  uniqueIndex:idx_tax_rate_from"`
+	CreatedAt		time.Time		`json:"created_at"`
+}
+
+// SubTax is how a subscription is taxed: at the rate of its jurisdiction, or at Rate when it
+// is set, with prices that include the tax (Inclusive) or have it added on top.
+// Subscriptions without one are not taxed.
+type SubTax struct{
+	SubID			string			`json:"sub_id"  gorm:"type:uuid;  primaryKey"`
+	Jurisdiction	string			`json:"jurisdiction,omitempty"`
+	Rate			*float64		`json:"rate,omitempty"`
+	Inclusive		bool			`json:"inclusive"`
+	UpdatedAt		time.Time		`json:"updated_at"`
+}
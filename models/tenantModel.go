@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+// TenantSchema is an optional JSON Schema the metadata of a tenant's subscriptions must satisfy
+type TenantSchema struct{
+	TenantID		string			`json:"tenant_id"  gorm:"primaryKey"`
+	Schema			JSONMap			`json:"schema"  gorm:"type:jsonb;  not null"`
+	UpdatedAt		time.Time		`json:"updated_at"`
+}
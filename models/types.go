@@ -0,0 +1,67 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// StringList is a list of strings stored as a JSONB array
+type StringList []string
+
+func (l StringList) Value() (driver.Value, error){
+	if l == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal([]string(l))
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (l *StringList) Scan(value interface{}) error{
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into StringList", value)
+	}
+	return json.Unmarshal(data, (*[]string)(l))
+}
+
+// JSONMap is a free-form JSON object stored as JSONB
+type JSONMap map[string]interface{}
+
+func (m JSONMap) Value() (driver.Value, error){
+	if m == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(map[string]interface{}(m))
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (m *JSONMap) Scan(value interface{}) error{
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*m = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into JSONMap", value)
+	}
+	return json.Unmarshal(data, (*map[string]interface{})(m))
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+const (
+	PricingFlat		= "flat"
+	PricingPerUnit	= "per_unit"
+	PricingTiered	= "tiered"
+	PricingVolume	= "volume"
+)
+
+// PricingTier prices the units up to UpTo (inclusive), the last tier has no UpTo. Unit
+// prices are per UnitSize units of the pricing, FlatFee is added once the tier is reached.
+type PricingTier struct{
+	UpTo			*int64		`json:"up_to,omitempty"`
+	UnitPrice		int			`json:"unit_price"`
+	FlatFee			int			`json:"flat_fee,omitempty"`
+}
+
+// TierList is a list of pricing tiers stored as a JSONB array
+type TierList []PricingTier
+
+func (l TierList) Value() (driver.Value, error){
+	if l == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal([]PricingTier(l))
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (l *TierList) Scan(value interface{}) error{
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return errors.New("unsupported type for TierList")
+	}
+	return json.Unmarshal(data, (*[]PricingTier)(l))
+}
+
+// SubPricing is the pricing model of a usage-based subscription. Subs without one are flat:
+// they cost their price every billing period. Otherwise a period costs BaseFee plus the
+// usage recorded in it, priced per unit, in graduated tiers ("tiered") or with the tier
+// the total falls into applied to all units ("volume").
+type SubPricing struct{
+	SubID			string			`json:"sub_id"  gorm:"type:uuid;  primaryKey"`
+	Model			string			`json:"model"  gorm:"not null"`
+	Unit			string			`json:"unit,omitempty"`
+	UnitSize		int				`json:"unit_size"  gorm:"not null;  default:1"`
+	UnitPrice		int				`json:"unit_price,omitempty"`
+	BaseFee			int				`json:"base_fee"`
+	Tiers			TierList		`json:"tiers,omitempty"  gorm:"type:jsonb"`
+	UpdatedAt		time.Time		`json:"updated_at"`
+}
+
+// UsageRecord is a quantity used at a point in time, records with the same non-empty
+// IdempotencyKey are only stored once per sub
+type UsageRecord struct{
+	ID				string			`json:"id"  gorm:"type:uuid;  primaryKey"`
+	SubID			string			`json:"sub_id"  gorm:"type:uuid;  not null;  index;  uniqueIndex:idx_usage_key,where:idempotency_key <> ''"`
+	Timestamp		time.Time		`json:"timestamp"  gorm:"not null;  index"`
+	Quantity		int64			`json:"quantity"  gorm:"not null"`
+	IdempotencyKey	string			`json:"idempotency_key,omitempty"  gorm:"uniqueIndex:idx_usage_key,where:idempotency_key <> ''"`
+	CreatedAt		time.Time		`json:"created_at"`
+}
+
+// UsageCharge is the priced usage of one billing period, starting in PeriodStart. It is
+// recomputed whenever usage is recorded or the pricing changes so reports can sum it.
+type UsageCharge struct{
+	SubID			string			`json:"sub_id"  gorm:"type:uuid;  primaryKey"`
+	PeriodStart		time.Time		`json:"period_start"  gorm:"primaryKey"`
+	Units			int64			`json:"units"`
+	Amount			int				`json:"amount"`
+}
@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryDead      = "dead"
+)
+
+// WebhookEndpoint receives the subscription events of one tenant, EventTypes limits
+// which ones (all of them when empty)
+type WebhookEndpoint struct{
+	ID				string			`json:"id"  gorm:"type:uuid;  primaryKey"`
+	TenantID		string			`json:"tenant_id"  gorm:"index"`
+	URL				string			`json:"url"  gorm:"not null"`
+	Secret			string			`json:"-"  gorm:"not null"`
+	EventTypes		StringList		`json:"event_types"  gorm:"type:jsonb"`
+	Active			bool			`json:"active"  gorm:"not null;  default:true"`
+	CreatedAt		time.Time		`json:"created_at"`
+	UpdatedAt		time.Time		`json:"updated_at"`
+}
+
+// WebhookDelivery is one event sent to one endpoint, it doubles as the delivery log.
+// Deliveries that run out of attempts are kept as "dead" until redelivered.
+type WebhookDelivery struct{
+	ID				string			`json:"id"  gorm:"type:uuid;  primaryKey"`
+	EndpointID		string			`json:"endpoint_id"  gorm:"type:uuid;  not null;  uniqueIndex:idx_delivery_event"`
+	EventID			string			`json:"event_id"  gorm:"not null;  uniqueIndex:idx_delivery_event"`
+	EventType		string			`json:"event_type"  gorm:"not null"`
+	Payload			string			`json:"payload"  gorm:"type:text;  not null"`
+	Status			string			`json:"status"  gorm:"not null;  index"`
+	Attempts		int				`json:"attempts"`
+	NextAttemptAt	time.Time		`json:"next_attempt_at"  gorm:"index"`
+	LastStatusCode	int				`json:"last_status_code,omitempty"`
+	LastError		string			`json:"last_error,omitempty"`
+	DeliveredAt		*time.Time		`json:"delivered_at,omitempty"`
+	CreatedAt		time.Time		`json:"created_at"`
+	UpdatedAt		time.Time		`json:"updated_at"`
+}
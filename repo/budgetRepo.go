@@ -0,0 +1,125 @@
+package repo
+
+import (
+	"online-subs-api/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type BudgetRepo struct{
+	db *gorm.DB
+}
+
+func NewBudgetRepo(db *gorm.DB) *BudgetRepo{
+	return &BudgetRepo{
+		db: db,
+	}
+}
+
+func (r *BudgetRepo) CreateBudgetRepo(budget *models.Budget) error{
+	return r.db.Create(budget).Error
+}
+
+func (r *BudgetRepo) GetBudgetRepo(id string) (*models.Budget, error){
+	var budget models.Budget
+	if err := r.db.First(&budget, "id = ?", id).Error; err != nil{
+		return nil, err
+	}
+	return &budget, nil
+}
+
+func (r *BudgetRepo) ListBudgetsRepo(scope string) ([]models.Budget, error){
+	var budgets []models.Budget
+	query := r.db.Order("scope, scope_id")
+	if scope != "" {
+		query = query.Where("scope = ?", scope)
+	}
+	if err := query.Find(&budgets).Error; err != nil{
+		return nil, err
+	}
+	return budgets, nil
+}
+
+// ListBudgetsForSubRepo returns the budgets the sub counts towards, the ones of its owner
+// and members included
+func (r *BudgetRepo) ListBudgetsForSubRepo(sub *models.Sub) ([]models.Budget, error){
+	var budgets []models.Budget
+	err := r.db.Where("(scope = ? AND (scope_id = ? OR scope_id IN (SELECT user_id::text FROM sub_members WHERE sub_id = ?))) OR (scope = ? AND scope_id = ?) OR (scope = ? AND scope_id = ?)",
+		models.BudgetScopeUser, sub.UserID, sub.ID,
+		models.BudgetScopeCategory, sub.Category,
+		models.BudgetScopeTenant, sub.TenantID,
+	).Find(&budgets).Error
+	if err != nil{
+		return nil, err
+	}
+	return budgets, nil
+}
+
+func (r *BudgetRepo) UpdateBudgetRepo(budget *models.Budget) error{
+	return r.db.Model(budget).Update("amount", budget.Amount).Error
+}
+
+func (r *BudgetRepo) DeleteBudgetRepo(id string) error{
+	return r.db.Transaction(func(tx *gorm.DB) error{
+		if err := tx.Delete(&models.BudgetAlert{}, "budget_id = ?", id).Error; err != nil{
+			return err
+		}
+		return tx.Delete(&models.Budget{}, "id = ?", id).Error
+	})
+}
+
+// budgetAlertPayload is the alert with the user or tenant of its budget, so webhooks and
+// streams filtered by user_id or tenant_id get the alerts of that scope
+type budgetAlertPayload struct{
+	models.BudgetAlert
+	UserID			string		`json:"user_id,omitempty"`
+	TenantID		string		`json:"tenant_id,omitempty"`
+}
+
+// CreateAlertRepo stores the alert unless the same budget, period and threshold already
+// has one, created reports whether this call inserted it. A new alert is written to the
+// outbox as budget.alert in the same transaction.
+func (r *BudgetRepo) CreateAlertRepo(alert *models.BudgetAlert) (bool, error){
+	created := false
+	err := r.db.Transaction(func(tx *gorm.DB) error{
+		result := tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "budget_id"}, {Name: "period"}, {Name: "threshold"}},
+			DoNothing: true,
+		}).Create(alert)
+		if result.Error != nil{
+			return result.Error
+		}
+		if created = result.RowsAffected == 1; !created {
+			return nil
+		}
+
+		payload := budgetAlertPayload{BudgetAlert: *alert}
+		switch alert.Scope {
+		case models.BudgetScopeUser:
+			payload.UserID = alert.ScopeID
+		case models.BudgetScopeTenant:
+			payload.TenantID = alert.ScopeID
+		}
+		return writeEvent(tx, models.AggregateBudget, alert.BudgetID, models.EventBudgetAlert, payload.TenantID, payload)
+	})
+	if err != nil{
+		return false, err
+	}
+	return created, nil
+}
+
+func (r *BudgetRepo) ListAlertsRepo(budgetID, period string) ([]models.BudgetAlert, error){
+	var alerts []models.BudgetAlert
+	query := r.db.Order("created_at DESC")
+	if budgetID != "" {
+		query = query.Where("budget_id = ?", budgetID)
+	}
+	if period != "" {
+		query = query.Where("period = ?", period)
+	}
+	if err := query.Find(&alerts).Error; err != nil{
+		return nil, err
+	}
+	return alerts, nil
+}
@@ -0,0 +1,196 @@
+package repo
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"online-subs-api/models"
+	"online-subs-api/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+//go:embed seed/catalog.json
+var catalogSeed []byte
+
+type CatalogRepo struct{
+	db *gorm.DB
+}
+
+func NewCatalogRepo(db *gorm.DB) *CatalogRepo{
+	return &CatalogRepo{
+		db: db,
+	}
+}
+
+// SeedCatalogRepo inserts the embedded providers and plans that are not in the db yet,
+// matching existing rows by name so admin edits are never overwritten
+func (r *CatalogRepo) SeedCatalogRepo() error{
+	var providers []models.Provider
+	if err := json.Unmarshal(catalogSeed, &providers); err != nil{
+		return err
+	}
+
+	return r.db.Transaction(func(tx *gorm.DB) error{
+		for _, p := range providers {
+			if err := ensureCategory(tx, p.Category); err != nil{
+				return err
+			}
+
+			var existing models.Provider
+			err := tx.First(&existing, "name = ?", p.Name).Error
+			if errors.Is(err, gorm.ErrRecordNotFound){
+				existing = models.Provider{Name: p.Name, Aliases: p.Aliases, Category: p.Category}
+				if existing.ID, err = utils.NewUUID(); err != nil{
+					return err
+				}
+				if err := tx.Create(&existing).Error; err != nil{
+					return err
+				}
+			} else if err != nil{
+				return err
+			}
+
+			for _, plan := range p.Plans {
+				var count int64
+				if err := tx.Model(&models.Plan{}).Where("provider_id = ? AND name = ?", existing.ID, plan.Name).Count(&count).Error; err != nil{
+					return err
+				}
+				if count > 0 {
+					continue
+				}
+				plan.ProviderID = existing.ID
+				if plan.ID, err = utils.NewUUID(); err != nil{
+					return err
+				}
+				if err := tx.Create(&plan).Error; err != nil{
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// ensureCategory keeps catalog categories available for subscriptions
+func ensureCategory(tx *gorm.DB, name string) error{
+	if name == "" {
+		return nil
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.Category{Name: name}).Error
+}
+
+func (r *CatalogRepo) CreateProviderRepo(provider *models.Provider) error{
+	return r.db.Transaction(func(tx *gorm.DB) error{
+		if err := ensureCategory(tx, provider.Category); err != nil{
+			return err
+		}
+		return tx.Omit("Plans").Create(provider).Error
+	})
+}
+
+func (r *CatalogRepo) GetProviderRepoById(id string) (*models.Provider, error){
+	var provider models.Provider
+	if err := r.db.Preload("Plans").First(&provider, "id=?", id).Error; err != nil{
+		return nil, err
+	}
+	return &provider, nil
+}
+
+func (r *CatalogRepo) ListProvidersRepo(category string) ([]models.Provider, error){
+	var providers []models.Provider
+	query := r.db.Preload("Plans").Order("name")
+
+	if category != "" {
+		query = query.Where("category = ?", category)
+	}
+
+	if err := query.Find(&providers).Error; err != nil{
+		return nil, err
+	}
+	return providers, nil
+}
+
+// mustExist returns gorm.ErrRecordNotFound unless the row exists, Save would insert it
+func mustExist(tx *gorm.DB, model interface{}, id string) error{
+	var count int64
+	if err := tx.Model(model).Where("id = ?", id).Count(&count).Error; err != nil{
+		return err
+	}
+	if count == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *CatalogRepo) UpdateProviderRepo(provider *models.Provider) error{
+	return r.db.Transaction(func(tx *gorm.DB) error{
+		if err := mustExist(tx, &models.Provider{}, provider.ID); err != nil{
+			return err
+		}
+		if err := ensureCategory(tx, provider.Category); err != nil{
+			return err
+		}
+		return tx.Omit("Plans").Save(provider).Error
+	})
+}
+
+// unlinkPlans clears the plans from the subscriptions and plan changes referencing them
+func unlinkPlans(tx *gorm.DB, planIDs interface{}) error{
+	if err := tx.Model(&models.Sub{}).Where("plan_id IN (?)", planIDs).Update("plan_id", nil).Error; err != nil{
+		return err
+	}
+	return tx.Model(&models.PriceChange{}).Where("plan_id IN (?)", planIDs).Update("plan_id", nil).Error
+}
+
+// DeleteProviderRepo deletes the provider with its plans, subscriptions and statement
+// candidates keep their service name but no longer reference the catalog
+func (r *CatalogRepo) DeleteProviderRepo(id string) error{
+	return r.db.Transaction(func(tx *gorm.DB) error{
+		plans := tx.Model(&models.Plan{}).Select("id").Where("provider_id = ?", id)
+		if err := unlinkPlans(tx, plans); err != nil{
+			return err
+		}
+		if err := tx.Model(&models.Sub{}).Where("provider_id = ?", id).Update("provider_id", nil).Error; err != nil{
+			return err
+		}
+		if err := tx.Model(&models.StatementCandidate{}).Where("provider_id = ?", id).Update("provider_id", nil).Error; err != nil{
+			return err
+		}
+		if err := tx.Delete(&models.Plan{}, "provider_id=?", id).Error; err != nil{
+			return err
+		}
+		return tx.Delete(&models.Provider{}, "id=?", id).Error
+	})
+}
+
+func (r *CatalogRepo) CreatePlanRepo(plan *models.Plan) error{
+	return r.db.Create(plan).Error
+}
+
+func (r *CatalogRepo) GetPlanRepoById(id string) (*models.Plan, error){
+	var plan models.Plan
+	if err := r.db.First(&plan, "id=?", id).Error; err != nil{
+		return nil, err
+	}
+	return &plan, nil
+}
+
+func (r *CatalogRepo) UpdatePlanRepo(plan *models.Plan) error{
+	return r.db.Transaction(func(tx *gorm.DB) error{
+		if err := mustExist(tx, &models.Plan{}, plan.ID); err != nil{
+			return err
+		}
+		return tx.Save(plan).Error
+	})
+}
+
+func (r *CatalogRepo) DeletePlanRepo(id string) error{
+	return r.db.Transaction(func(tx *gorm.DB) error{
+		if err := unlinkPlans(tx, []string{id}); err != nil{
+			return err
+		}
+		return tx.Delete(&models.Plan{}, "id=?", id).Error
+	})
+}
@@ -0,0 +1,37 @@
+package repo
+
+import (
+	"online-subs-api/models"
+
+	"gorm.io/gorm"
+)
+
+func (r *SubsRepo) CreateDiscountRepo(discount *models.Discount) error{
+	return r.db.Transaction(func(tx *gorm.DB) error{
+		if err := tx.Create(discount).Error; err != nil{
+			return err
+		}
+		return writeStoredSubEvent(tx, models.EventSubUpdated, discount.SubID)
+	})
+}
+
+func (r *SubsRepo) ListDiscountsRepo(subID string) ([]models.Discount, error){
+	var discounts []models.Discount
+	if err := r.db.Where("sub_id = ?", subID).Order("start_date, created_at").Find(&discounts).Error; err != nil{
+		return nil, err
+	}
+	return discounts, nil
+}
+
+func (r *SubsRepo) DeleteDiscountRepo(subID, id string) error{
+	return r.db.Transaction(func(tx *gorm.DB) error{
+		result := tx.Delete(&models.Discount{}, "id = ? AND sub_id = ?", id, subID)
+		if result.Error != nil{
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return writeStoredSubEvent(tx, models.EventSubUpdated, subID)
+	})
+}
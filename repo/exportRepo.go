@@ -0,0 +1,43 @@
+package repo
+
+import "online-subs-api/models"
+
+// exportColumnsSQL selects the sub columns scanned by EachSubRepo, text columns added by
+// later migrations may still be NULL on old rows. Tags are aggregated per row so the
+// export needs no second query.
+const exportColumnsSQL = `subs.id, subs.service_name, subs.price, subs.user_id, subs.start_date, subs.end_date,
+	subs.provider_id, subs.plan_id, COALESCE(subs.billing_period, 'monthly'), COALESCE(subs.service_name_input, ''),
+	COALESCE(subs.name_confidence, 0), COALESCE(subs.category, ''), COALESCE(subs.tenant_id, ''), subs.metadata,
+	subs.trial_end_date,
+	COALESCE((SELECT json_agg(tags.name ORDER BY tags.name) FROM sub_tags JOIN tags ON tags.id = sub_tags.tag_id WHERE sub_tags.sub_id = subs.id), '[]')`
+
+// EachSubRepo streams the subs matching the filter in id order, reading them one row at
+// a time from the result set. fn gets a reused Sub and may stop the export by returning
+// an error.
+func (r *SubsRepo) EachSubRepo(filter SubsFilter, fn func(sub *models.Sub) error) error{
+	rows, err := filter.apply(r.db.Table("subs")).Select(exportColumnsSQL).Order("subs.id").Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var sub models.Sub
+	var tagNames models.StringList
+	for rows.Next() {
+		sub = models.Sub{}
+		err := rows.Scan(&sub.ID, &sub.ServiceName, &sub.Price, &sub.UserID, &sub.StartDate, &sub.EndDate,
+			&sub.ProviderID, &sub.PlanID, &sub.BillingPeriod, &sub.ServiceNameInput,
+			&sub.NameConfidence, &sub.Category, &sub.TenantID, &sub.Metadata,
+			&sub.TrialEndDate, &tagNames)
+		if err != nil {
+			return err
+		}
+		for _, name := range tagNames {
+			sub.Tags = append(sub.Tags, models.Tag{Name: name})
+		}
+		if err := fn(&sub); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,49 @@
+package repo
+
+import (
+	"online-subs-api/models"
+
+	"gorm.io/gorm"
+)
+
+type FeedRepo struct{
+	db *gorm.DB
+}
+
+func NewFeedRepo(db *gorm.DB) *FeedRepo{
+	return &FeedRepo{
+		db: db,
+	}
+}
+
+func (r *FeedRepo) CreateFeedRepo(feed *models.CalendarFeed) error{
+	return r.db.Create(feed).Error
+}
+
+func (r *FeedRepo) GetFeedRepo(scope, scopeID string) (*models.CalendarFeed, error){
+	var feed models.CalendarFeed
+	if err := r.db.First(&feed, "scope = ? AND scope_id = ?", scope, scopeID).Error; err != nil{
+		return nil, err
+	}
+	return &feed, nil
+}
+
+func (r *FeedRepo) GetFeedByTokenRepo(tokenHash string) (*models.CalendarFeed, error){
+	var feed models.CalendarFeed
+	if err := r.db.First(&feed, "token_hash = ?", tokenHash).Error; err != nil{
+		return nil, err
+	}
+	return &feed, nil
+}
+
+// RotateFeedRepo replaces the token, the old one stops working immediately
+func (r *FeedRepo) RotateFeedRepo(feed *models.CalendarFeed) error{
+	return r.db.Model(feed).Updates(map[string]interface{}{
+		"token_hash": feed.TokenHash,
+		"rotated_at": feed.RotatedAt,
+	}).Error
+}
+
+func (r *FeedRepo) DeleteFeedRepo(scope, scopeID string) error{
+	return r.db.Delete(&models.CalendarFeed{}, "scope = ? AND scope_id = ?", scope, scopeID).Error
+}
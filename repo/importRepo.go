@@ -0,0 +1,48 @@
+package repo
+
+import (
+	"online-subs-api/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ImportRepo struct{
+	db *gorm.DB
+}
+
+func NewImportRepo(db *gorm.DB) *ImportRepo{
+	return &ImportRepo{
+		db: db,
+	}
+}
+
+func (r *ImportRepo) CreatePreviewRepo(preview *models.ImportPreview) error{
+	return r.db.Create(preview).Error
+}
+
+func (r *ImportRepo) GetPreviewRepo(id string) (*models.ImportPreview, error){
+	var preview models.ImportPreview
+	if err := r.db.First(&preview, "id = ?", id).Error; err != nil{
+		return nil, err
+	}
+	return &preview, nil
+}
+
+// ClaimPreviewRepo marks the preview as committed, claimed is false when it was already
+// committed or has expired so concurrent commits import the rows only once
+func (r *ImportRepo) ClaimPreviewRepo(id string, now time.Time) (bool, error){
+	result := r.db.Model(&models.ImportPreview{}).
+		Where("id = ? AND committed_at IS NULL AND expires_at > ?", id, now).
+		Update("committed_at", now)
+	return result.RowsAffected == 1, result.Error
+}
+
+// ReleasePreviewRepo undoes a claim after a failed commit
+func (r *ImportRepo) ReleasePreviewRepo(id string) error{
+	return r.db.Model(&models.ImportPreview{}).Where("id = ?", id).Update("committed_at", nil).Error
+}
+
+func (r *ImportRepo) DeleteExpiredPreviewsRepo(now time.Time) error{
+	return r.db.Where("expires_at <= ?", now).Delete(&models.ImportPreview{}).Error
+}
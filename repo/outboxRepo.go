@@ -0,0 +1,107 @@
+package repo
+
+import (
+	"encoding/json"
+	"online-subs-api/models"
+	"online-subs-api/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// outboxLockKey serializes the transactions writing to the outbox, so events become
+// visible in seq order and the relay never skips a seq that commits late
+const outboxLockKey = 7310561
+
+// writeEvent adds an event to the outbox inside the transaction of the change it describes
+func writeEvent(tx *gorm.DB, aggregateType, aggregateID, eventType, tenantID string, data interface{}) error{
+	if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", outboxLockKey).Error; err != nil{
+		return err
+	}
+	payload, err := json.Marshal(data)
+	if err != nil{
+		return err
+	}
+	id, err := utils.NewUUID()
+	if err != nil{
+		return err
+	}
+	return tx.Create(&models.OutboxEvent{
+		ID: id,
+		AggregateType: aggregateType,
+		AggregateID: aggregateID,
+		Type: eventType,
+		TenantID: tenantID,
+		Payload: string(payload),
+	}).Error
+}
+
+// writeSubEvent adds the event of a sub mutation to the outbox inside the mutation's
+// transaction, the payload is the sub as stored
+func writeSubEvent(tx *gorm.DB, eventType string, sub *models.Sub) error{
+	return writeEvent(tx, models.AggregateSubscription, sub.ID, eventType, sub.TenantID, sub)
+}
+
+// writeStoredSubEvent reloads the sub inside the transaction so the event carries the
+// stored state, tags included
+func writeStoredSubEvent(tx *gorm.DB, eventType, id string) error{
+	var sub models.Sub
+	if err := tx.Preload("Tags").First(&sub, "id = ?", id).Error; err != nil{
+		return err
+	}
+	return writeSubEvent(tx, eventType, &sub)
+}
+
+type OutboxRepo struct{
+	db *gorm.DB
+}
+
+func NewOutboxRepo(db *gorm.DB) *OutboxRepo{
+	return &OutboxRepo{
+		db: db,
+	}
+}
+
+// ListOutboxAfterRepo returns the events after the given seq in order
+func (r *OutboxRepo) ListOutboxAfterRepo(seq int64, limit int) ([]models.OutboxEvent, error){
+	var events []models.OutboxEvent
+	if err := r.db.Where("seq > ?", seq).Order("seq").Limit(limit).Find(&events).Error; err != nil{
+		return nil, err
+	}
+	return events, nil
+}
+
+func (r *OutboxRepo) GetCursorRepo(sink string) (int64, error){
+	var cursors []models.OutboxCursor
+	if err := r.db.Where("sink = ?", sink).Limit(1).Find(&cursors).Error; err != nil{
+		return 0, err
+	}
+	if len(cursors) == 0 {
+		return 0, nil
+	}
+	return cursors[0].Seq, nil
+}
+
+func (r *OutboxRepo) SetCursorRepo(sink string, seq int64) error{
+	return r.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "sink"}},
+		DoUpdates: clause.AssignmentColumns([]string{"seq", "updated_at"}),
+	}).Create(&models.OutboxCursor{Sink: sink, Seq: seq}).Error
+}
+
+func (r *OutboxRepo) ListCursorsRepo() ([]models.OutboxCursor, error){
+	var cursors []models.OutboxCursor
+	if err := r.db.Order("sink").Find(&cursors).Error; err != nil{
+		return nil, err
+	}
+	return cursors, nil
+}
+
+// LastSeqRepo returns the seq of the newest event, 0 for an empty outbox
+func (r *OutboxRepo) LastSeqRepo() (int64, error){
+	var seq int64
+	if err := r.db.Model(&models.OutboxEvent{}).Select("COALESCE(MAX(seq), 0)").Scan(&seq).Error; err != nil{
+		return 0, err
+	}
+	return seq, nil
+}
@@ -0,0 +1,146 @@
+package repo
+
+import (
+	"online-subs-api/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PaymentRepo struct{
+	db *gorm.DB
+}
+
+func NewPaymentRepo(db *gorm.DB) *PaymentRepo{
+	return &PaymentRepo{
+		db: db,
+	}
+}
+
+func (r *PaymentRepo) CreatePaymentRepo(payment *models.Payment) error{
+	return r.db.Create(payment).Error
+}
+
+// CreatePaymentsRepo inserts all payments in a single statement, none is stored if one fails
+func (r *PaymentRepo) CreatePaymentsRepo(payments []models.Payment) error{
+	if len(payments) == 0 {
+		return nil
+	}
+	return r.db.Create(&payments).Error
+}
+
+func (r *PaymentRepo) GetPaymentRepo(subID, id string) (*models.Payment, error){
+	var payment models.Payment
+	if err := r.db.First(&payment, "id = ? AND sub_id = ?", id, subID).Error; err != nil{
+		return nil, err
+	}
+	return &payment, nil
+}
+
+func (r *PaymentRepo) ListPaymentsRepo(subID string) ([]models.Payment, error){
+	var payments []models.Payment
+	if err := r.db.Where("sub_id = ?", subID).Order("date, created_at").Find(&payments).Error; err != nil{
+		return nil, err
+	}
+	return payments, nil
+}
+
+// ListPaymentsBySubRepo returns the payments between from and to (exclusive) per sub id
+func (r *PaymentRepo) ListPaymentsBySubRepo(ids []string, from, to time.Time) (map[string][]models.Payment, error){
+	bySub := map[string][]models.Payment{}
+	if len(ids) == 0 {
+		return bySub, nil
+	}
+	var payments []models.Payment
+	err := r.db.Where("sub_id IN ? AND date >= ? AND date < ?", ids, from, to).Order("date, created_at").Find(&payments).Error
+	if err != nil{
+		return nil, err
+	}
+	for _, payment := range payments {
+		bySub[payment.SubID] = append(bySub[payment.SubID], payment)
+	}
+	return bySub, nil
+}
+
+func (r *PaymentRepo) UpdatePaymentRepo(payment *models.Payment) error{
+	return r.db.Save(payment).Error
+}
+
+func (r *PaymentRepo) DeletePaymentRepo(subID, id string) error{
+	result := r.db.Delete(&models.Payment{}, "id = ? AND sub_id = ?", id, subID)
+	if result.Error != nil{
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// SaveDiscrepanciesRepo stores the discrepancies found for the subs in [from, to). Known
+// ones are updated and reopened, open ones of those subs and months that were not found
+// again are resolved.
+func (r *PaymentRepo) SaveDiscrepanciesRepo(found []models.Discrepancy, subIDs []string, from, to, now time.Time) error{
+	return r.db.Transaction(func(tx *gorm.DB) error{
+		keys := []string{}
+		for i := range found {
+			keys = append(keys, found[i].Key)
+			result := tx.Clauses(clause.OnConflict{
+				Columns: []clause.Column{{Name: "key"}},
+				DoUpdates: clause.Assignments(map[string]interface{}{
+					"payment_ids": found[i].PaymentIDs,
+					"expected": found[i].Expected,
+					"actual": found[i].Actual,
+					"service_name": found[i].ServiceName,
+					"status": models.DiscrepancyOpen,
+					"resolved_at": nil,
+				}),
+			}).Create(&found[i])
+			if result.Error != nil{
+				return result.Error
+			}
+		}
+
+		if len(subIDs) == 0 {
+			return nil
+		}
+		query := tx.Model(&models.Discrepancy{}).
+			Where("status = ? AND sub_id IN ? AND month >= ? AND month < ?", models.DiscrepancyOpen, subIDs, from, to)
+		if len(keys) > 0 {
+			query = query.Where("key NOT IN ?", keys)
+		}
+		return query.Updates(map[string]interface{}{"status": models.DiscrepancyResolved, "resolved_at": now}).Error
+	})
+}
+
+// DiscrepancyFilter narrows the discrepancy report, empty fields match everything
+type DiscrepancyFilter struct{
+	UserID		string
+	SubID		string
+	TenantID	string
+	Kind		string
+	Status		string
+	From		time.Time
+	To			time.Time
+}
+
+func (r *PaymentRepo) ListDiscrepanciesRepo(filter DiscrepancyFilter) ([]models.Discrepancy, error){
+	query := r.db.Model(&models.Discrepancy{})
+	for column, value := range map[string]string{"user_id": filter.UserID, "sub_id": filter.SubID, "tenant_id": filter.TenantID, "kind": filter.Kind, "status": filter.Status} {
+		if value != "" {
+			query = query.Where(column+" = ?", value)
+		}
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("month >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("month <= ?", filter.To)
+	}
+	var discrepancies []models.Discrepancy
+	if err := query.Order("month DESC, service_name, kind").Find(&discrepancies).Error; err != nil{
+		return nil, err
+	}
+	return discrepancies, nil
+}
@@ -0,0 +1,126 @@
+package repo
+
+import (
+	"online-subs-api/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func (r *SubsRepo) CreatePriceChangeRepo(change *models.PriceChange) error{
+	return r.db.Transaction(func(tx *gorm.DB) error{
+		if err := tx.Create(change).Error; err != nil{
+			return err
+		}
+		return writeStoredSubEvent(tx, models.EventSubUpdated, change.SubID)
+	})
+}
+
+func (r *SubsRepo) ListPriceChangesRepo(subID string) ([]models.PriceChange, error){
+	var changes []models.PriceChange
+	if err := r.db.Where("sub_id = ?", subID).Order("effective_date, created_at").Find(&changes).Error; err != nil{
+		return nil, err
+	}
+	return changes, nil
+}
+
+// ListPriceChangesBySubRepo loads the price changes of many subs keyed by sub id
+func (r *SubsRepo) ListPriceChangesBySubRepo(subIDs []string) (map[string][]models.PriceChange, error){
+	changes := map[string][]models.PriceChange{}
+	if len(subIDs) == 0 {
+		return changes, nil
+	}
+
+	var rows []models.PriceChange
+	if err := r.db.Where("sub_id IN ?", subIDs).Order("effective_date, created_at").Find(&rows).Error; err != nil{
+		return nil, err
+	}
+	for _, row := range rows {
+		changes[row.SubID] = append(changes[row.SubID], row)
+	}
+	return changes, nil
+}
+
+// DeletePriceChangeRepo returns the deleted change, nil when there was none with the id
+func (r *SubsRepo) DeletePriceChangeRepo(id string) (*models.PriceChange, error){
+	var changes []models.PriceChange
+	err := r.db.Transaction(func(tx *gorm.DB) error{
+		if err := tx.Where("id=?", id).Limit(1).Find(&changes).Error; err != nil{
+			return err
+		}
+		if len(changes) == 0 {
+			return nil
+		}
+		if err := tx.Delete(&models.PriceChange{}, "id=?", id).Error; err != nil{
+			return err
+		}
+		return writeStoredSubEvent(tx, models.EventSubUpdated, changes[0].SubID)
+	})
+	if err != nil || len(changes) == 0 {
+		return nil, err
+	}
+	return &changes[0], nil
+}
+
+// ListActiveSubsRepo returns the subs that are not over before the given month, filter.UserID
+// matches the owner as well as the members of a shared sub
+func (r *SubsRepo) ListActiveSubsRepo(from time.Time, filter SubsFilter) ([]models.Sub, error){
+	var subs []models.Sub
+	query := filter.applyWithMembers(preloadBilling(r.db)).
+		Where("("+subOpenEndedSQL+" OR subs.end_date >= ?)", from)
+
+	if err := query.Find(&subs).Error; err != nil{
+		return nil, err
+	}
+	return subs, nil
+}
+
+// CreatePlanChangeRepo stores the new price segment of a plan change and moves the sub to
+// the new catalog plan when the change applies it right away (PlanApplied)
+func (r *SubsRepo) CreatePlanChangeRepo(change *models.PriceChange) error{
+	return r.db.Transaction(func(tx *gorm.DB) error{
+		if err := tx.Create(change).Error; err != nil{
+			return err
+		}
+		if change.PlanID == nil || !change.PlanApplied {
+			return nil
+		}
+		if err := tx.Model(&models.Sub{}).Where("id = ?", change.SubID).Update("plan_id", change.PlanID).Error; err != nil{
+			return err
+		}
+		return writeStoredSubEvent(tx, models.EventSubUpdated, change.SubID)
+	})
+}
+
+// ApplyDuePlanChangesRepo moves the subs to the catalog plans of the plan changes due by now
+// that have not been applied yet, in the order they take effect, and returns the moved subs
+func (r *SubsRepo) ApplyDuePlanChangesRepo(now time.Time) ([]models.Sub, error){
+	var changes []models.PriceChange
+	err := r.db.Where("plan_id IS NOT NULL AND NOT plan_applied AND plan_from <= ?", now).
+		Order("plan_from, created_at").Find(&changes).Error
+	if err != nil{
+		return nil, err
+	}
+
+	subs := []models.Sub{}
+	for _, change := range changes {
+		var sub models.Sub
+		err := r.db.Transaction(func(tx *gorm.DB) error{
+			if err := tx.Model(&models.PriceChange{}).Where("id = ?", change.ID).Update("plan_applied", true).Error; err != nil{
+				return err
+			}
+			if err := tx.Model(&models.Sub{}).Where("id = ?", change.SubID).Update("plan_id", change.PlanID).Error; err != nil{
+				return err
+			}
+			if err := tx.Preload("Tags").First(&sub, "id = ?", change.SubID).Error; err != nil{
+				return err
+			}
+			return writeSubEvent(tx, models.EventSubUpdated, &sub)
+		})
+		if err != nil{
+			return subs, err
+		}
+		subs = append(subs, sub)
+	}
+	return subs, nil
+}
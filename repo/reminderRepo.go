@@ -0,0 +1,64 @@
+package repo
+
+import (
+	"online-subs-api/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ReminderRepo struct{
+	db *gorm.DB
+}
+
+func NewReminderRepo(db *gorm.DB) *ReminderRepo{
+	return &ReminderRepo{
+		db: db,
+	}
+}
+
+// CreateReminderRepo inserts the reminder unless it already exists for the same sub,
+// kind, event date and channel, created reports whether this call inserted it
+func (r *ReminderRepo) CreateReminderRepo(reminder *models.Reminder) (bool, error){
+	result := r.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "sub_id"}, {Name: "kind"}, {Name: "event_date"}, {Name: "channel"}},
+		DoNothing: true,
+	}).Create(reminder)
+	if result.Error != nil{
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// ListDueRemindersRepo returns pending reminders whose next attempt is due
+func (r *ReminderRepo) ListDueRemindersRepo(now time.Time, limit int) ([]models.Reminder, error){
+	var reminders []models.Reminder
+	err := r.db.Where("status = ? AND next_attempt_at <= ?", models.ReminderPending, now).
+		Order("next_attempt_at").
+		Limit(limit).
+		Find(&reminders).Error
+	if err != nil{
+		return nil, err
+	}
+	return reminders, nil
+}
+
+func (r *ReminderRepo) UpdateReminderRepo(reminder *models.Reminder) error{
+	return r.db.Save(reminder).Error
+}
+
+func (r *ReminderRepo) ListRemindersRepo(subID, status string) ([]models.Reminder, error){
+	var reminders []models.Reminder
+	query := r.db.Order("event_date DESC, channel")
+	if subID != "" {
+		query = query.Where("sub_id = ?", subID)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Find(&reminders).Error; err != nil{
+		return nil, err
+	}
+	return reminders, nil
+}
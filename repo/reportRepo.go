@@ -0,0 +1,247 @@
+package repo
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SQL fragments shared by the billing queries, they mirror the billing engine in
+// services/billing.go. Dates are compared as UTC timestamps so month boundaries don't
+// depend on the session time zone, and the zero end_date written by GORM for
+// open-ended subs is treated as "no end". Billing starts at the end of the trial.
+const (
+	subAnchorMonthSQL  = "date_trunc('month', GREATEST(subs.trial_end_date, subs.start_date) AT TIME ZONE 'UTC')"
+	subOpenEndedSQL    = "(subs.end_date IS NULL OR subs.end_date < '0002-01-01')"
+	subPeriodMonthsSQL = "(CASE subs.billing_period WHEN 'yearly' THEN 12 WHEN 'quarterly' THEN 3 ELSE 1 END)"
+	// price in effect in the month %[1]s, after scheduled price changes and plan changes
+	subPriceSQL = `COALESCE((
+		SELECT pc.price FROM price_changes pc
+		WHERE pc.sub_id = subs.id AND date_trunc('month', pc.effective_date AT TIME ZONE 'UTC') <= %[1]s
+		ORDER BY pc.effective_date DESC, pc.created_at DESC LIMIT 1
+	), subs.price)`
+	// seats in effect on the day %[1]s of a seat-based sub (the price is per seat), the
+	// first seat change before it starts and 1 for other subs
+	subSeatsSQL = `COALESCE((
+		SELECT sc.seats FROM seat_changes sc
+		WHERE sc.sub_id = subs.id AND sc.effective_date AT TIME ZONE 'UTC' <= %[1]s
+		ORDER BY sc.effective_date DESC LIMIT 1
+	), (
+		SELECT sc.seats FROM seat_changes sc WHERE sc.sub_id = subs.id ORDER BY sc.effective_date LIMIT 1
+	), 1)`
+	// prorated plan change charges minus credits booked in the months %[1]s to %[2]s, like
+	// bookedMonth a sub that has ended before the next charge books them a period earlier
+	subAdjustmentSQL = `COALESCE((
+		SELECT SUM(pc.charge - pc.credit) FROM price_changes pc
+		CROSS JOIN LATERAL (SELECT date_trunc('month', pc.adjustment_month AT TIME ZONE 'UTC') AS month) a
+		WHERE pc.sub_id = subs.id AND pc.adjustment_month IS NOT NULL
+		AND (CASE WHEN ` + subOpenEndedSQL + ` OR a.month <= date_trunc('month', subs.end_date AT TIME ZONE 'UTC') THEN a.month
+			ELSE a.month - make_interval(months => ` + subPeriodMonthsSQL + `) END) BETWEEN %[1]s AND %[2]s
+	), 0)`
+	// base fee of a usage-based sub, NULL for flat subs
+	subBaseFeeSQL = "(SELECT sp.base_fee FROM sub_pricings sp WHERE sp.sub_id = subs.id AND sp.model <> 'flat')"
+	// tax rate in percent of a sub in the month %[1]s: its own rate, else the rate of its
+	// jurisdiction in effect then, 0 for untaxed subs
+	subTaxRateSQL = `COALESCE((
+		SELECT st.rate FROM sub_taxes st WHERE st.sub_id = subs.id
+	), (
+		SELECT tr.rate FROM sub_taxes st JOIN tax_rates tr ON tr.jurisdiction = st.jurisdiction
+		WHERE st.sub_id = subs.id AND date_trunc('month', tr.valid_from AT TIME ZONE 'UTC') <= %[1]s
+		ORDER BY tr.valid_from DESC LIMIT 1
+	), 0)`
+	subTaxInclusiveSQL = "COALESCE((SELECT st.inclusive FROM sub_taxes st WHERE st.sub_id = subs.id), true)"
+	// priced usage of the billing periods starting in the months %[1]s to %[2]s
+	subUsageSQL = `COALESCE((
+		SELECT SUM(uc.amount) FROM usage_charges uc
+		WHERE uc.sub_id = subs.id
+		AND date_trunc('month', uc.period_start AT TIME ZONE 'UTC') BETWEEN %[1]s AND %[2]s
+	), 0)`
+)
+
+// subSeatProrationSQL sums the prorated seat changes booked in the months fromSQL to toSQL,
+// like seatProrations in the billing engine: a change inside a billing period is charged
+// for the rest of it at the period's unit price with the next charge, or with the period's
+// own charge when the sub ends before. Usage-based subs are not prorated.
+func subSeatProrationSQL(fromSQL, toSQL string) string{
+	index := func(t string) string{ return fmt.Sprintf("(EXTRACT(YEAR FROM %[1]s) * 12 + EXTRACT(MONTH FROM %[1]s))", t) }
+	periodStart := fmt.Sprintf("%[1]s + make_interval(months => (FLOOR((%[2]s - %[3]s) / %[4]s) * %[4]s)::int)",
+		subAnchorMonthSQL, index("d.day"), index(subAnchorMonthSQL), subPeriodMonthsSQL)
+	before := `COALESCE((
+		SELECT b.seats FROM seat_changes b WHERE b.sub_id = sc.sub_id AND b.effective_date < sc.effective_date
+		ORDER BY b.effective_date DESC LIMIT 1
+	), sc.seats)`
+	return fmt.Sprintf(`(CASE WHEN %[1]s IS NULL THEN COALESCE((
+		SELECT SUM(ROUND(((sc.seats - %[2]s) * %[3]s)::numeric
+			* EXTRACT(EPOCH FROM p.period_end - d.day) / EXTRACT(EPOCH FROM p.period_end - p.period_start))::bigint)
+		FROM seat_changes sc
+		CROSS JOIN LATERAL (SELECT sc.effective_date AT TIME ZONE 'UTC' AS day) d
+		CROSS JOIN LATERAL (SELECT %[4]s AS period_start) s
+		CROSS JOIN LATERAL (SELECT s.period_start, s.period_start + make_interval(months => %[5]s) AS period_end) p
+		WHERE sc.sub_id = subs.id AND sc.prorate AND d.day >= %[6]s AND d.day > p.period_start
+		AND (CASE WHEN %[7]s OR p.period_end <= date_trunc('month', subs.end_date AT TIME ZONE 'UTC')
+			THEN p.period_end ELSE p.period_start END) BETWEEN %[8]s AND %[9]s
+	), 0)::bigint ELSE 0 END)`, subBaseFeeSQL, before, fmt.Sprintf(subPriceSQL, "p.period_start"), periodStart,
+		subPeriodMonthsSQL, subAnchorMonthSQL, subOpenEndedSQL, fromSQL, toSQL)
+}
+
+// subDiscountSQL is the discount on the gross charge grossSQL of a sub in the month monthSQL,
+// never more than the charge. Like discountAt in the billing engine a discount counts the
+// charges from its start month, and a month falls under the last charge at or before it.
+func subDiscountSQL(monthSQL, grossSQL string) string{
+	index := func(t string) string{ return fmt.Sprintf("(EXTRACT(YEAR FROM %[1]s) * 12 + EXTRACT(MONTH FROM %[1]s))", t) }
+	start := "date_trunc('month', d.start_date AT TIME ZONE 'UTC')"
+	ordinal := fmt.Sprintf("(FLOOR((%[1]s - %[2]s)::numeric / %[4]s) - GREATEST(CEIL((%[3]s - %[2]s)::numeric / %[4]s), 0) + 1)",
+		index(monthSQL), index(subAnchorMonthSQL), index(start), subPeriodMonthsSQL)
+	return fmt.Sprintf(`GREATEST(LEAST(%[1]s, COALESCE((
+		SELECT SUM(CASE d.kind WHEN 'percentage' THEN ROUND((%[1]s * d.percentage / 100)::numeric) ELSE d.amount END)
+		FROM discounts d
+		WHERE d.sub_id = subs.id AND %[2]s <= %[3]s
+		AND (d.duration = 'forever' OR %[4]s BETWEEN 1 AND d.periods)
+	), 0)), 0)::bigint`, grossSQL, start, monthSQL, ordinal)
+}
+
+// taxSQL is the tax in the amount column at the rate column, like taxSplit in the billing
+// engine: taken out of tax-inclusive amounts and added on top of the others
+func taxSQL(amount, rate, inclusive string) string{
+	return fmt.Sprintf(`(CASE WHEN %[3]s THEN %[1]s - ROUND((%[1]s * 100 / (100 + %[2]s))::numeric)
+		ELSE ROUND((%[1]s * %[2]s / 100)::numeric) END)::bigint`, amount, rate, inclusive)
+}
+
+// taxNetSQL is the amount column without tax
+func taxNetSQL(amount, rate, inclusive string) string{
+	return fmt.Sprintf("(CASE WHEN %s THEN %s - %s ELSE %s END)", inclusive, amount, taxSQL(amount, rate, inclusive), amount)
+}
+
+// subUsagePeriodSQL is the first month of the billing period the month monthSQL belongs to,
+// like usagePeriodStart in the billing engine: the anchor month before the first charge
+func subUsagePeriodSQL(monthSQL string) string{
+	index := func(t string) string{ return fmt.Sprintf("(EXTRACT(YEAR FROM %[1]s) * 12 + EXTRACT(MONTH FROM %[1]s))", t) }
+	return fmt.Sprintf("(CASE WHEN %[2]s < %[1]s THEN %[1]s ELSE %[1]s + make_interval(months => (FLOOR((%[3]s - %[4]s) / %[5]s) * %[5]s)::int) END)",
+		subAnchorMonthSQL, monthSQL, index(monthSQL), index(subAnchorMonthSQL), subPeriodMonthsSQL)
+}
+
+// subRecurringSQL is the recurring part of a charge in the month monthSQL: the base fee of
+// usage-based subs, the price times the seats otherwise
+func subRecurringSQL(monthSQL string) string{
+	return fmt.Sprintf("COALESCE(%s, %s * %s)", subBaseFeeSQL, fmt.Sprintf(subPriceSQL, monthSQL), fmt.Sprintf(subSeatsSQL, monthSQL))
+}
+
+// subChargeSQL is the charge of a sub in the month monthSQL before discounts, like
+// grossPriceAt in the billing engine
+func subChargeSQL(monthSQL string) string{
+	return fmt.Sprintf("GREATEST(%s + %s + %s + %s, 0)", subRecurringSQL(monthSQL), fmt.Sprintf(subAdjustmentSQL, monthSQL, monthSQL),
+		subSeatProrationSQL(monthSQL, monthSQL), fmt.Sprintf(subUsageSQL, monthSQL, monthSQL))
+}
+
+// ReportDimensions maps the group_by names accepted by the report to subs columns
+var ReportDimensions = map[string]string{
+	"user":     "user_id",
+	"service":  "service_name",
+	"category": "category",
+}
+
+// ReportRow is one cell of the spending report. Period is nil on rows totalled over the
+// whole range and the dimension fields are nil on subtotal rows. Amount is net of discounts,
+// Tax is the tax in it and TaxNet the amount without tax.
+type ReportRow struct{
+	Period			*time.Time
+	UserID			*string
+	ServiceName		*string
+	Category		*string
+	Amount			int
+	Gross			int
+	Tax				int
+	TaxNet			int
+	PeriodGrouped	int
+	DimsGrouped		int
+}
+
+type ReportRepo struct{
+	db *gorm.DB
+}
+
+func NewReportRepo(db *gorm.DB) *ReportRepo{
+	return &ReportRepo{
+		db: db,
+	}
+}
+
+// billedMonthsQuery returns one row per sub and month in [start, end] in which the sub is
+// charged, filter.UserID matches the owner as well as the members of a shared sub
+func (r *ReportRepo) billedMonthsQuery(start, end time.Time, filter SubsFilter) *gorm.DB{
+	return filter.applyWithMembers(r.db.Table("subs")).
+		Joins(fmt.Sprintf(`JOIN generate_series(?::timestamp, ?::timestamp, interval '1 month') AS months(month)
+			ON months.month >= %s
+			AND (%s OR months.month <= subs.end_date AT TIME ZONE 'UTC')`, subAnchorMonthSQL, subOpenEndedSQL),
+			start.Format("2006-01-02"), end.Format("2006-01-02")).
+		Where(fmt.Sprintf(`MOD(
+			(EXTRACT(YEAR FROM months.month) * 12 + EXTRACT(MONTH FROM months.month))
+			- (EXTRACT(YEAR FROM %[1]s) * 12 + EXTRACT(MONTH FROM %[1]s)),
+			%[2]s) = 0`, subAnchorMonthSQL, subPeriodMonthsSQL))
+}
+
+// GetSpendingReportRepo sums the charges per period ("month" or "year") and the given
+// dimensions, with subtotals per period, totals per group over the range and a grand total.
+// Charges of shared subs count for every user with their share, filter.UserID keeps the
+// shares of that user.
+func (r *ReportRepo) GetSpendingReportRepo(start, end time.Time, interval string, dims []string, filter SubsFilter) ([]ReportRow, error){
+	if interval != "month" && interval != "year" {
+		return nil, fmt.Errorf("unsupported interval %q", interval)
+	}
+
+	columns := []string{}
+	for _, dim := range dims {
+		column, ok := ReportDimensions[dim]
+		if !ok {
+			return nil, fmt.Errorf("unsupported group_by %q", dim)
+		}
+		columns = append(columns, column)
+	}
+
+	gross := subChargeSQL("months.month")
+	billed := r.billedMonthsQuery(start, end, filter).
+		Select(fmt.Sprintf("subs.id AS sub_id, subs.user_id AS owner_id, subs.service_name, subs.category, date_trunc('%s', months.month) AS period, %s AS gross, %s - %s AS amount, %s AS tax_rate, %s AS tax_inclusive",
+			interval, gross, gross, subDiscountSQL("months.month", gross), fmt.Sprintf(subTaxRateSQL, "months.month"), subTaxInclusiveSQL))
+	shares := r.db.Table("(?) AS billed", billed).
+		Joins("CROSS JOIN LATERAL " + subSharesSQL("billed") + " AS shares").
+		Select("shares.user_id, billed.service_name, billed.category, billed.period, shares.gross, shares.amount, billed.tax_rate, billed.tax_inclusive")
+	if filter.UserID != "" {
+		shares = shares.Where("shares.user_id = ?", filter.UserID)
+	}
+	charges := r.db.Table("(?) AS shares", shares).
+		Select("shares.*, " + taxSQL("amount", "tax_rate", "tax_inclusive") + " AS tax, " + taxNetSQL("amount", "tax_rate", "tax_inclusive") + " AS tax_net")
+
+	selects := []string{"period", "SUM(amount) AS amount", "SUM(gross) AS gross", "SUM(tax) AS tax", "SUM(tax_net) AS tax_net", "GROUPING(period) AS period_grouped"}
+	for _, column := range []string{"user_id", "service_name", "category"} {
+		if slices.Contains(columns, column) {
+			selects = append(selects, column)
+		} else {
+			selects = append(selects, "NULL AS "+column)
+		}
+	}
+
+	sets := "(period), ()"
+	order := "period NULLS LAST"
+	if len(columns) > 0 {
+		dimList := strings.Join(columns, ", ")
+		sets = fmt.Sprintf("(period, %[1]s), (period), (%[1]s), ()", dimList)
+		selects = append(selects, fmt.Sprintf("GROUPING(%s) AS dims_grouped", dimList))
+		order += ", " + strings.Join(columns, " NULLS LAST, ") + " NULLS LAST"
+	} else {
+		selects = append(selects, "0 AS dims_grouped")
+	}
+
+	rows := []ReportRow{}
+	err := r.db.Table("(?) AS charges", charges).
+		Select(strings.Join(selects, ", ")).
+		Group("GROUPING SETS (" + sets + ")").
+		Order(order).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
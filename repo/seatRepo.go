@@ -0,0 +1,61 @@
+package repo
+
+import (
+	"online-subs-api/models"
+
+	"gorm.io/gorm"
+)
+
+func (r *SubsRepo) CreateSeatChangeRepo(change *models.SeatChange) error{
+	return r.db.Transaction(func(tx *gorm.DB) error{
+		if err := tx.Create(change).Error; err != nil{
+			return err
+		}
+		return writeStoredSubEvent(tx, models.EventSubUpdated, change.SubID)
+	})
+}
+
+func (r *SubsRepo) ListSeatChangesRepo(subID string) ([]models.SeatChange, error){
+	var changes []models.SeatChange
+	if err := r.db.Where("sub_id = ?", subID).Order("effective_date").Find(&changes).Error; err != nil{
+		return nil, err
+	}
+	return changes, nil
+}
+
+func (r *SubsRepo) DeleteSeatChangeRepo(subID, id string) error{
+	return r.db.Transaction(func(tx *gorm.DB) error{
+		result := tx.Delete(&models.SeatChange{}, "id = ? AND sub_id = ?", id, subID)
+		if result.Error != nil{
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return writeStoredSubEvent(tx, models.EventSubUpdated, subID)
+	})
+}
+
+func (r *SubsRepo) CreateSeatAssignmentRepo(assignment *models.SeatAssignment) error{
+	return r.db.Create(assignment).Error
+}
+
+func (r *SubsRepo) GetSeatAssignmentRepo(subID, id string) (*models.SeatAssignment, error){
+	var assignment models.SeatAssignment
+	if err := r.db.First(&assignment, "id = ? AND sub_id = ?", id, subID).Error; err != nil{
+		return nil, err
+	}
+	return &assignment, nil
+}
+
+func (r *SubsRepo) ListSeatAssignmentsRepo(subID string) ([]models.SeatAssignment, error){
+	var assignments []models.SeatAssignment
+	if err := r.db.Where("sub_id = ?", subID).Order("assigned_at, assignee").Find(&assignments).Error; err != nil{
+		return nil, err
+	}
+	return assignments, nil
+}
+
+func (r *SubsRepo) UpdateSeatAssignmentRepo(assignment *models.SeatAssignment) error{
+	return r.db.Save(assignment).Error
+}
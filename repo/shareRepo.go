@@ -0,0 +1,130 @@
+package repo
+
+import (
+	"fmt"
+	"online-subs-api/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SetSplitRepo replaces the split rule and members of a sub
+func (r *SubsRepo) SetSplitRepo(split *models.SubSplit) error{
+	return r.db.Transaction(func(tx *gorm.DB) error{
+		if err := tx.Delete(&models.SubMember{}, "sub_id = ?", split.SubID).Error; err != nil{
+			return err
+		}
+		if err := tx.Omit("Members").Save(split).Error; err != nil{
+			return err
+		}
+		if len(split.Members) > 0 {
+			if err := tx.Create(&split.Members).Error; err != nil{
+				return err
+			}
+		}
+		return writeStoredSubEvent(tx, models.EventSubUpdated, split.SubID)
+	})
+}
+
+func (r *SubsRepo) GetSplitRepo(subID string) (*models.SubSplit, error){
+	var split models.SubSplit
+	err := r.db.Preload("Members", func(db *gorm.DB) *gorm.DB{ return db.Order("position") }).
+		First(&split, "sub_id = ?", subID).Error
+	if err != nil{
+		return nil, err
+	}
+	return &split, nil
+}
+
+func (r *SubsRepo) DeleteSplitRepo(subID string) error{
+	return r.db.Transaction(func(tx *gorm.DB) error{
+		if err := tx.Delete(&models.SubMember{}, "sub_id = ?", subID).Error; err != nil{
+			return err
+		}
+		result := tx.Delete(&models.SubSplit{}, "sub_id = ?", subID)
+		if result.Error != nil{
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return writeStoredSubEvent(tx, models.EventSubUpdated, subID)
+	})
+}
+
+// ListSplitsBySubRepo loads the splits of many subs keyed by sub id, unshared subs are missing
+func (r *SubsRepo) ListSplitsBySubRepo(subIDs []string) (map[string]*models.SubSplit, error){
+	splits := map[string]*models.SubSplit{}
+	if len(subIDs) == 0 {
+		return splits, nil
+	}
+
+	var rows []models.SubSplit
+	err := r.db.Preload("Members", func(db *gorm.DB) *gorm.DB{ return db.Order("position") }).
+		Where("sub_id IN ?", subIDs).Find(&rows).Error
+	if err != nil{
+		return nil, err
+	}
+	for i := range rows {
+		splits[rows[i].SubID] = &rows[i]
+	}
+	return splits, nil
+}
+
+// applyWithMembers applies the filter like apply, except that UserID matches the owner as
+// well as the members of a shared sub
+func (f SubsFilter) applyWithMembers(query *gorm.DB) *gorm.DB{
+	userID := f.UserID
+	f.UserID = ""
+	query = f.apply(query)
+	if userID != "" {
+		query = query.Where("(subs.user_id = ? OR subs.id IN (SELECT sub_id FROM sub_members WHERE user_id = ?))", userID, userID)
+	}
+	return query
+}
+
+// subMembersSQL lists the members of every split who pay a share, with the split rule, the
+// number of them and the fixed shares listed before each one. An owner listed in an equal
+// split is left out, splitShares counts them with the rest.
+const subMembersSQL = `(SELECT m.sub_id, m.user_id, m.share, sp.rule,
+		COUNT(*) OVER (PARTITION BY m.sub_id) AS members,
+		COALESCE(SUM(m.share) OVER (PARTITION BY m.sub_id ORDER BY m.position ROWS BETWEEN UNBOUNDED PRECEDING AND 1 PRECEDING), 0) AS shares_before
+	FROM sub_members m
+	JOIN sub_splits sp ON sp.sub_id = m.sub_id
+	JOIN subs o ON o.id = m.sub_id
+	WHERE NOT (sp.rule = 'equal' AND m.user_id = o.user_id))`
+
+// memberShareSQL is the share of the member m in the amount column, like splitShares
+func memberShareSQL(amount string) string{
+	return fmt.Sprintf(`(CASE m.rule WHEN 'equal' THEN %[1]s / (m.members + 1)
+		WHEN 'percentage' THEN %[1]s * m.share / 100
+		ELSE GREATEST(LEAST(m.share, %[1]s - m.shares_before), 0) END)::bigint`, amount)
+}
+
+// subSharesSQL splits the gross and amount columns of the billed row of a sub (sub_id,
+// owner_id) into one row per user: the members' shares and the owner paying the rest
+func subSharesSQL(billed string) string{
+	members := fmt.Sprintf(`SELECT m.user_id, %[1]s AS gross, %[2]s AS amount FROM %[3]s AS m
+		WHERE m.sub_id = %[4]s.sub_id AND m.user_id <> %[4]s.owner_id`,
+		memberShareSQL(billed+".gross"), memberShareSQL(billed+".amount"), subMembersSQL, billed)
+	return fmt.Sprintf(`(SELECT %[1]s.owner_id AS user_id, (%[1]s.gross - COALESCE(SUM(s.gross), 0))::bigint AS gross,
+		(%[1]s.amount - COALESCE(SUM(s.amount), 0))::bigint AS amount FROM (%[2]s) AS s
+	UNION ALL %[2]s)`, billed, members)
+}
+
+// ListUserSubsRepo returns the subs active in [startDate, endDate] the way GetTotalCostRepo
+// selects them, except that filter.UserID matches the owner as well as the members of a
+// shared sub. sharedOnly leaves out subs without a split.
+func (r *SubsRepo) ListUserSubsRepo(startDate, endDate time.Time, filter SubsFilter, sharedOnly bool) ([]models.Sub, error){
+	var subs []models.Sub
+	query := filter.applyWithMembers(preloadBilling(r.db.Preload("Tags"))).
+		Where("subs.start_date <= ? AND ("+subOpenEndedSQL+" OR subs.end_date >= ?)", endDate, startDate)
+	if sharedOnly {
+		query = query.Where("subs.id IN (SELECT sub_id FROM sub_splits)")
+	}
+
+	if err := query.Find(&subs).Error; err != nil{
+		return nil, err
+	}
+	return subs, nil
+}
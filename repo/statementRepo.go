@@ -0,0 +1,93 @@
+package repo
+
+import (
+	"errors"
+	"online-subs-api/models"
+
+	"gorm.io/gorm"
+)
+
+type StatementRepo struct{
+	db *gorm.DB
+}
+
+func NewStatementRepo(db *gorm.DB) *StatementRepo{
+	return &StatementRepo{
+		db: db,
+	}
+}
+
+// CreateImportRepo stores the statement import together with its candidates
+func (r *StatementRepo) CreateImportRepo(statement *models.StatementImport, candidates []models.StatementCandidate) error{
+	return r.db.Transaction(func(tx *gorm.DB) error{
+		if err := tx.Create(statement).Error; err != nil{
+			return err
+		}
+		if len(candidates) == 0 {
+			return nil
+		}
+		return tx.Create(&candidates).Error
+	})
+}
+
+// ListCandidatesRepo lists candidates by confidence, importID and status are optional
+func (r *StatementRepo) ListCandidatesRepo(userID, importID, status string) ([]models.StatementCandidate, error){
+	var candidates []models.StatementCandidate
+	query := r.db.Where("user_id = ?", userID)
+	if importID != "" {
+		query = query.Where("import_id = ?", importID)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Order("confidence DESC, merchant").Find(&candidates).Error; err != nil{
+		return nil, err
+	}
+	return candidates, nil
+}
+
+func (r *StatementRepo) GetCandidatesRepo(ids []string) ([]models.StatementCandidate, error){
+	var candidates []models.StatementCandidate
+	if err := r.db.Where("id IN ?", ids).Find(&candidates).Error; err != nil{
+		return nil, err
+	}
+	return candidates, nil
+}
+
+// AcceptCandidatesRepo creates the subs and marks their candidates accepted in one
+// transaction; subs[i] belongs to candidates[i]. It fails if a candidate is no longer pending.
+func (r *StatementRepo) AcceptCandidatesRepo(candidates []models.StatementCandidate, subs []models.Sub) error{
+	return r.db.Transaction(func(tx *gorm.DB) error{
+		for i := range subs {
+			result := tx.Model(&models.StatementCandidate{}).
+				Where("id = ? AND status = ?", candidates[i].ID, models.CandidatePending).
+				Updates(map[string]interface{}{"status": models.CandidateAccepted, "sub_id": subs[i].ID})
+			if result.Error != nil{
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return errors.New("candidate " + candidates[i].ID + " is no longer pending")
+			}
+			if err := tx.Create(&subs[i]).Error; err != nil{
+				return err
+			}
+			if err := writeStoredSubEvent(tx, models.EventSubCreated, subs[i].ID); err != nil{
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// SetCandidateStatusRepo moves a pending candidate to the status, accepted and dismissed
+// candidates are not found
+func (r *StatementRepo) SetCandidateStatusRepo(id, status string) error{
+	result := r.db.Model(&models.StatementCandidate{}).Where("id = ? AND status = ?", id, models.CandidatePending).Update("status", status)
+	if result.Error != nil{
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
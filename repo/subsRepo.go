@@ -1,6 +1,7 @@
 package repo
 
 import (
+	"fmt"
 	"online-subs-api/models"
 	"time"
 
@@ -11,27 +12,160 @@ type SubsRepo struct{
 	db *gorm.DB
 }
 
+// SubsFilter holds the optional filters shared by listing and total cost queries
+type SubsFilter struct{
+	UserID		string
+	ServiceName	string
+	ProviderID	string
+	PlanID		string
+	Category	string
+	Tag			string
+	TenantID	string
+	// Metadata matches metadata values by key, MetadataKeys only requires the keys to be present
+	Metadata		map[string]string
+	MetadataKeys	[]string
+}
+
+// CostTotal is the total cost before (Gross) and after (Net) discounts, Tax is the tax in
+// Net and TaxNet is Net without tax
+type CostTotal struct{
+	Gross		int		`json:"gross"`
+	Net			int		`json:"net"`
+	Tax			int		`json:"tax"`
+	TaxNet		int		`json:"tax_net"`
+}
+
+// TaxBreakdown splits an amount into its part without tax, the tax and the total with tax
+type TaxBreakdown struct{
+	Net			int		`json:"net"`
+	Tax			int		`json:"tax"`
+	Gross		int		`json:"gross"`
+}
+
+func (t CostTotal) TaxBreakdown() TaxBreakdown{
+	return TaxBreakdown{Net: t.TaxNet, Tax: t.Tax, Gross: t.TaxNet + t.Tax}
+}
+
+// CostGroup is one row of a total cost breakdown
+type CostGroup struct{
+	Key			string	`json:"key"`
+	TotalCost	int		`json:"total_cost"`
+}
+
+// PaidTotal sums the recorded payments in one currency, Net is what was kept after refunds
+type PaidTotal struct{
+	Currency	string	`json:"currency"`
+	Paid		int		`json:"paid"`
+	Refunded	int		`json:"refunded"`
+	Disputed	int		`json:"disputed"`
+	Net			int		`json:"net"`
+}
+
+// subCostSQL is what a sub counts for in the total cost of the months [startDate, endDate]:
+// one billing period at the end of the range, its price segment and seats (or base fee and
+// the priced usage of that period), plus the plan change adjustments and seat prorations
+// in the range, before discounts
+func subCostSQL(startDate, endDate time.Time) string{
+	start := "'" + startDate.Format("2006-01-02") + "'::timestamp"
+	end := "'" + endDate.Format("2006-01-02") + "'::timestamp"
+	period := subUsagePeriodSQL(end)
+	return fmt.Sprintf("(%s + %s + %s + %s)", subRecurringSQL(end), fmt.Sprintf(subAdjustmentSQL, start, end),
+		subSeatProrationSQL(start, end), fmt.Sprintf(subUsageSQL, period, period))
+}
+
+// subNetCostSQL is subCostSQL minus the discounts in effect at the end of the range
+func subNetCostSQL(startDate, endDate time.Time) string{
+	gross := subCostSQL(startDate, endDate)
+	end := "'" + endDate.Format("2006-01-02") + "'::timestamp"
+	return fmt.Sprintf("(%s - %s)", gross, subDiscountSQL(end, gross))
+}
+
+// paymentNetSQL mirrors models.Payment.NetAmount
+const paymentNetSQL = "(CASE payments.status WHEN 'refunded' THEN 0 WHEN 'partially_refunded' THEN payments.amount - payments.refunded_amount ELSE payments.amount END)"
+
 func NewSubsRepo(db *gorm.DB) *SubsRepo{
 	return &SubsRepo{
 		db: db,
 	}
 }
 
+func (f SubsFilter) apply(query *gorm.DB) *gorm.DB{
+	if f.UserID != "" {
+		query = query.Where("subs.user_id = ?", f.UserID)
+	}
+	if f.ServiceName != "" {
+		query = query.Where("subs.service_name = ?", f.ServiceName)
+	}
+	if f.ProviderID != "" {
+		query = query.Where("subs.provider_id = ?", f.ProviderID)
+	}
+	if f.PlanID != "" {
+		query = query.Where("subs.plan_id = ?", f.PlanID)
+	}
+	if f.Category != "" {
+		query = query.Where("subs.category = ?", f.Category)
+	}
+	if f.Tag != "" {
+		query = query.Where("subs.id IN (SELECT sub_tags.sub_id FROM sub_tags JOIN tags ON tags.id = sub_tags.tag_id WHERE tags.name = ?)", f.Tag)
+	}
+	if f.TenantID != "" {
+		query = query.Where("subs.tenant_id = ?", f.TenantID)
+	}
+	for key, value := range f.Metadata {
+		query = query.Where("subs.metadata->>? = ?", key, value)
+	}
+	for _, key := range f.MetadataKeys {
+		query = query.Where("subs.metadata->? IS NOT NULL", key)
+	}
+	return query
+}
+
+// preloadBilling loads what the billing engine needs besides the sub: the seat history of
+// seat-based subs, the pricing and priced usage of usage-based ones, the discounts and the
+// tax settings
+func preloadBilling(db *gorm.DB) *gorm.DB{
+	return db.Preload("SeatChanges", func(db *gorm.DB) *gorm.DB{ return db.Order("effective_date") }).
+		Preload("Pricing").
+		Preload("UsageCharges", func(db *gorm.DB) *gorm.DB{ return db.Order("period_start") }).
+		Preload("Discounts", func(db *gorm.DB) *gorm.DB{ return db.Order("start_date, created_at") }).
+		Preload("Tax")
+}
+
 func (r *SubsRepo) CreateSubRepo (subs *models.Sub) error{
-	return	r.db.Create(subs).Error
+	return r.db.Transaction(func(tx *gorm.DB) error{
+		if err := tx.Create(subs).Error; err != nil{
+			return err
+		}
+		return writeStoredSubEvent(tx, models.EventSubCreated, subs.ID)
+	})
+}
+
+// CreateSubsRepo inserts all subs in one transaction, none of them is stored if one fails
+func (r *SubsRepo) CreateSubsRepo(subs []models.Sub) error{
+	return r.db.Transaction(func(tx *gorm.DB) error{
+		for i := range subs {
+			if err := tx.Create(&subs[i]).Error; err != nil{
+				return err
+			}
+			if err := writeStoredSubEvent(tx, models.EventSubCreated, subs[i].ID); err != nil{
+				return err
+			}
+		}
+		return nil
+	})
 }
 
 func (r *SubsRepo) GetSubRepoById(id string) (*models.Sub, error){
 	var sub models.Sub
-	if err := r.db.First(&sub, "id=?", id).Error; err != nil{
+	if err := preloadBilling(r.db.Preload("Tags")).First(&sub, "id=?", id).Error; err != nil{
 		return nil, err
 	}
 	return &sub, nil
 }
 
-func (r *SubsRepo) ListAllSubsRepo() ([]models.Sub, error){
+func (r *SubsRepo) ListAllSubsRepo(filter SubsFilter) ([]models.Sub, error){
 	var subs []models.Sub
-	query := r.db
+	query := filter.apply(preloadBilling(r.db.Preload("Tags")))
 
 	if err := query.Find(&subs).Error; err != nil{
 		return nil, err
@@ -40,35 +174,244 @@ func (r *SubsRepo) ListAllSubsRepo() ([]models.Sub, error){
 	return subs, nil
 }
 
+// UpdateSubRepo saves the sub and replaces its tags when they are set
 func (r *SubsRepo) UpdateSubRepo(sub *models.Sub) error{
-	return r.db.Save(sub).Error
+	return r.db.Transaction(func(tx *gorm.DB) error{
+		if err := tx.Omit("Tags", "SeatChanges", "Pricing", "UsageCharges", "Discounts", "Tax").Save(sub).Error; err != nil{
+			return err
+		}
+		if sub.Tags != nil {
+			if err := tx.Model(sub).Association("Tags").Replace(sub.Tags); err != nil{
+				return err
+			}
+		}
+		return writeStoredSubEvent(tx, models.EventSubUpdated, sub.ID)
+	})
+}
+
+func (r *SubsRepo) SetSubTagsRepo(sub *models.Sub, tags []models.Tag) error{
+	return r.db.Transaction(func(tx *gorm.DB) error{
+		if err := tx.Model(sub).Association("Tags").Replace(tags); err != nil{
+			return err
+		}
+		return writeStoredSubEvent(tx, models.EventSubUpdated, sub.ID)
+	})
+}
+
+func (r *SubsRepo) SetSubCategoryRepo(id, category string) error{
+	return r.db.Transaction(func(tx *gorm.DB) error{
+		if err := tx.Model(&models.Sub{}).Where("id = ?", id).Update("category", category).Error; err != nil{
+			return err
+		}
+		return writeStoredSubEvent(tx, models.EventSubUpdated, id)
+	})
+}
+
+// BackfillCategoryRepo copies the catalog provider category to subs that have none
+func (r *SubsRepo) BackfillCategoryRepo() error{
+	return r.db.Exec(`
+		UPDATE subs SET category = p.category
+		FROM catalog_providers p
+		WHERE subs.provider_id = p.id AND (subs.category IS NULL OR subs.category = '')`,
+	).Error
 }
 
 func (r *SubsRepo) DeleteSubRepo(id string) error{
-	return r.db.Delete(&models.Sub{}, "id=?", id).Error
+	return r.db.Transaction(func(tx *gorm.DB) error{
+		var sub models.Sub
+		if err := tx.Preload("Tags").First(&sub, "id = ?", id).Error; err != nil{
+			return err
+		}
+		if err := writeSubEvent(tx, models.EventSubDeleted, &sub); err != nil{
+			return err
+		}
+		if err := tx.Exec("DELETE FROM sub_tags WHERE sub_id = ?", id).Error; err != nil{
+			return err
+		}
+		if err := tx.Delete(&models.PriceChange{}, "sub_id = ?", id).Error; err != nil{
+			return err
+		}
+		if err := tx.Delete(&models.Payment{}, "sub_id = ?", id).Error; err != nil{
+			return err
+		}
+		if err := tx.Delete(&models.Discrepancy{}, "sub_id = ?", id).Error; err != nil{
+			return err
+		}
+		if err := tx.Delete(&models.SeatChange{}, "sub_id = ?", id).Error; err != nil{
+			return err
+		}
+		if err := tx.Delete(&models.SeatAssignment{}, "sub_id = ?", id).Error; err != nil{
+			return err
+		}
+		if err := tx.Delete(&models.SubMember{}, "sub_id = ?", id).Error; err != nil{
+			return err
+		}
+		if err := tx.Delete(&models.SubSplit{}, "sub_id = ?", id).Error; err != nil{
+			return err
+		}
+		if err := tx.Delete(&models.UsageRecord{}, "sub_id = ?", id).Error; err != nil{
+			return err
+		}
+		if err := tx.Delete(&models.UsageCharge{}, "sub_id = ?", id).Error; err != nil{
+			return err
+		}
+		if err := tx.Delete(&models.SubPricing{}, "sub_id = ?", id).Error; err != nil{
+			return err
+		}
+		if err := tx.Delete(&models.Discount{}, "sub_id = ?", id).Error; err != nil{
+			return err
+		}
+		if err := tx.Delete(&models.SubTax{}, "sub_id = ?", id).Error; err != nil{
+			return err
+		}
+		if err := tx.Delete(&models.Reminder{}, "sub_id = ?", id).Error; err != nil{
+			return err
+		}
+		// deliveries of the sub's earlier events, the deleted event is queued by the relay after the commit
+		err := tx.Where("event_id IN (SELECT id::text FROM outbox_events WHERE aggregate_type = ? AND aggregate_id = ?)", models.AggregateSubscription, id).
+			Delete(&models.WebhookDelivery{}).Error
+		if err != nil{
+			return err
+		}
+		// statement candidates accepted as the sub can be accepted again
+		err = tx.Model(&models.StatementCandidate{}).Where("sub_id = ?", id).
+			Updates(map[string]interface{}{"sub_id": nil, "status": models.CandidatePending}).Error
+		if err != nil{
+			return err
+		}
+		if err := tx.Model(&models.StatementCandidate{}).Where("matched_sub_id = ?", id).Update("matched_sub_id", nil).Error; err != nil{
+			return err
+		}
+		// budget alerts reference their budget, not the subs, and stay as the record of the
+		// crossed threshold
+		return tx.Delete(&models.Sub{}, "id=?", id).Error
+	})
 }
 
-func (r *SubsRepo) GetTotalCostRepo(startDate, endDate time.Time, userID, serviceName string) (int, error) {
-	var total int64
-	query := r.db.Model(&models.Sub{})
+func (r *SubsRepo) GetTotalCostRepo(startDate, endDate time.Time, filter SubsFilter) (CostTotal, error) {
+	var total CostTotal
+	query := filter.apply(r.db.Model(&models.Sub{}))
+
+	// open-ended subs are stored with a zero end_date
+	query = query.Where(`
+		subs.start_date <= ? 
+		AND (`+subOpenEndedSQL+` OR subs.end_date >= ?)`,
+		endDate, startDate,
+	)
+
+	end := "'" + endDate.Format("2006-01-02") + "'::timestamp"
+	costs := query.Select(subCostSQL(startDate, endDate) + " AS gross, " + subNetCostSQL(startDate, endDate) + " AS net, " +
+		fmt.Sprintf(subTaxRateSQL, end) + " AS tax_rate, " + subTaxInclusiveSQL + " AS tax_inclusive")
+	err := r.db.Table("(?) AS costs", costs).
+		Select("COALESCE(SUM(gross), 0) AS gross, COALESCE(SUM(net), 0) AS net, " +
+			"COALESCE(SUM(" + taxSQL("net", "tax_rate", "tax_inclusive") + "), 0) AS tax, " +
+			"COALESCE(SUM(" + taxNetSQL("net", "tax_rate", "tax_inclusive") + "), 0) AS tax_net").
+		Scan(&total).Error
+	if err != nil {
+		return CostTotal{}, err
+	}
+
+	return total, nil
+}
+
+// paidQuery joins the filtered subs with their payments dated in the months [startDate, endDate]
+func (r *SubsRepo) paidQuery(startDate, endDate time.Time, filter SubsFilter) *gorm.DB{
+	return filter.apply(r.db.Model(&models.Sub{})).
+		Joins("JOIN payments ON payments.sub_id = subs.id").
+		Where("payments.date >= ? AND payments.date < ?", startDate, endDate.AddDate(0, 1, 0))
+}
+
+// GetTotalPaidRepo is GetTotalCostRepo on an "actual paid" basis: it sums the recorded
+// payments instead of the list prices, per currency
+func (r *SubsRepo) GetTotalPaidRepo(startDate, endDate time.Time, filter SubsFilter) ([]PaidTotal, error) {
+	totals := []PaidTotal{}
+	err := r.paidQuery(startDate, endDate, filter).
+		Select(`payments.currency AS currency,
+			SUM(payments.amount) AS paid,
+			SUM(CASE payments.status WHEN 'refunded' THEN payments.amount WHEN 'partially_refunded' THEN payments.refunded_amount ELSE 0 END) AS refunded,
+			SUM(CASE payments.status WHEN 'disputed' THEN payments.amount ELSE 0 END) AS disputed,
+			SUM(` + paymentNetSQL + `) AS net`).
+		Group("payments.currency").
+		Order("payments.currency").
+		Scan(&totals).Error
+	if err != nil {
+		return nil, err
+	}
+	return totals, nil
+}
 
-	if userID != "" {
-		query = query.Where("user_id = ?", userID)
+// GetTotalPaidByCategoryRepo breaks the net paid amount down by subscription category
+func (r *SubsRepo) GetTotalPaidByCategoryRepo(startDate, endDate time.Time, filter SubsFilter) ([]CostGroup, error) {
+	groups := []CostGroup{}
+	err := r.paidQuery(startDate, endDate, filter).
+		Select("subs.category AS key, SUM(" + paymentNetSQL + ") AS total_cost").
+		Group("subs.category").
+		Order("total_cost DESC").
+		Scan(&groups).Error
+	if err != nil {
+		return nil, err
 	}
-	if serviceName != "" {
-		query = query.Where("service_name = ?", serviceName)
+	return groups, nil
+}
+
+// GetTotalPaidByTagRepo breaks the net paid amount down by tag, like GetTotalCostByTagRepo
+func (r *SubsRepo) GetTotalPaidByTagRepo(startDate, endDate time.Time, filter SubsFilter) ([]CostGroup, error) {
+	groups := []CostGroup{}
+	err := r.paidQuery(startDate, endDate, filter).
+		Joins("LEFT JOIN sub_tags ON sub_tags.sub_id = subs.id").
+		Joins("LEFT JOIN tags ON tags.id = sub_tags.tag_id").
+		Select("COALESCE(tags.name, '') AS key, SUM(" + paymentNetSQL + ") AS total_cost").
+		Group("COALESCE(tags.name, '')").
+		Order("total_cost DESC").
+		Scan(&groups).Error
+	if err != nil {
+		return nil, err
 	}
+	return groups, nil
+}
+
+// GetTotalCostByCategoryRepo breaks the total cost down by subscription category
+func (r *SubsRepo) GetTotalCostByCategoryRepo(startDate, endDate time.Time, filter SubsFilter) ([]CostGroup, error) {
+	groups := []CostGroup{}
+	query := filter.apply(r.db.Model(&models.Sub{}))
 
+	// open-ended subs are stored with a zero end_date
 	query = query.Where(`
-		start_date <= ? 
-		AND (end_date IS NULL OR end_date >= ?)`,
+		subs.start_date <= ? 
+		AND (`+subOpenEndedSQL+` OR subs.end_date >= ?)`,
 		endDate, startDate,
 	)
 
-	if err := query.Select("SUM(price)").Scan(&total).Error; err != nil {
-		return 0, err
+	err := query.Select("subs.category AS key, SUM("+subNetCostSQL(startDate, endDate)+") AS total_cost").
+		Group("subs.category").
+		Order("total_cost DESC").
+		Scan(&groups).Error
+	if err != nil {
+		return nil, err
 	}
-
-	return int(total), nil
+	return groups, nil
 }
 
+// GetTotalCostByTagRepo breaks the total cost down by tag, a sub with several tags
+// counts towards each of them and untagged subs are grouped under an empty key
+func (r *SubsRepo) GetTotalCostByTagRepo(startDate, endDate time.Time, filter SubsFilter) ([]CostGroup, error) {
+	groups := []CostGroup{}
+	query := filter.apply(r.db.Model(&models.Sub{})).
+		Joins("LEFT JOIN sub_tags ON sub_tags.sub_id = subs.id").
+		Joins("LEFT JOIN tags ON tags.id = sub_tags.tag_id")
+
+	query = query.Where(`
+		subs.start_date <= ? 
+		AND (`+subOpenEndedSQL+` OR subs.end_date >= ?)`,
+		endDate, startDate,
+	)
+
+	err := query.Select("COALESCE(tags.name, '') AS key, SUM("+subNetCostSQL(startDate, endDate)+") AS total_cost").
+		Group("COALESCE(tags.name, '')").
+		Order("total_cost DESC").
+		Scan(&groups).Error
+	if err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
@@ -0,0 +1,100 @@
+package repo
+
+import (
+	"online-subs-api/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TagRepo struct{
+	db *gorm.DB
+}
+
+func NewTagRepo(db *gorm.DB) *TagRepo{
+	return &TagRepo{
+		db: db,
+	}
+}
+
+func (r *TagRepo) CreateTagRepo(tag *models.Tag) error{
+	return r.db.Create(tag).Error
+}
+
+// GetOrCreateTagsRepo returns the tags with the given names, creating the missing ones
+func (r *TagRepo) GetOrCreateTagsRepo(names []string, newID func() (string, error)) ([]models.Tag, error){
+	tags := []models.Tag{}
+	err := r.db.Transaction(func(tx *gorm.DB) error{
+		for _, name := range names {
+			id, err := newID()
+			if err != nil {
+				return err
+			}
+			tag := models.Tag{ID: id, Name: name}
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&tag).Error; err != nil{
+				return err
+			}
+			if err := tx.First(&tag, "name = ?", name).Error; err != nil{
+				return err
+			}
+			tags = append(tags, tag)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+func (r *TagRepo) ListTagsRepo() ([]models.Tag, error){
+	var tags []models.Tag
+	if err := r.db.Order("name").Find(&tags).Error; err != nil{
+		return nil, err
+	}
+	return tags, nil
+}
+
+func (r *TagRepo) DeleteTagRepo(id string) error{
+	return r.db.Transaction(func(tx *gorm.DB) error{
+		if err := tx.Exec("DELETE FROM sub_tags WHERE tag_id = ?", id).Error; err != nil{
+			return err
+		}
+		return tx.Delete(&models.Tag{}, "id=?", id).Error
+	})
+}
+
+func (r *TagRepo) CreateCategoryRepo(category *models.Category) error{
+	return r.db.Create(category).Error
+}
+
+// EnsureCategoryRepo creates the category if it does not exist yet
+func (r *TagRepo) EnsureCategoryRepo(name string) error{
+	return ensureCategory(r.db, name)
+}
+
+func (r *TagRepo) GetCategoryRepo(name string) (*models.Category, error){
+	var category models.Category
+	if err := r.db.First(&category, "name = ?", name).Error; err != nil{
+		return nil, err
+	}
+	return &category, nil
+}
+
+func (r *TagRepo) ListCategoriesRepo() ([]models.Category, error){
+	var categories []models.Category
+	if err := r.db.Order("name").Find(&categories).Error; err != nil{
+		return nil, err
+	}
+	return categories, nil
+}
+
+// DeleteCategoryRepo removes the category and clears it from the subscriptions using it
+func (r *TagRepo) DeleteCategoryRepo(name string) error{
+	return r.db.Transaction(func(tx *gorm.DB) error{
+		if err := tx.Model(&models.Sub{}).Where("category = ?", name).Update("category", "").Error; err != nil{
+			return err
+		}
+		return tx.Delete(&models.Category{}, "name = ?", name).Error
+	})
+}
@@ -0,0 +1,61 @@
+package repo
+
+import (
+	"online-subs-api/models"
+
+	"gorm.io/gorm"
+)
+
+func (r *SubsRepo) CreateTaxRateRepo(rate *models.TaxRate) error{
+	return r.db.Create(rate).Error
+}
+
+// ListTaxRatesRepo returns the rates of all jurisdictions, oldest first per jurisdiction
+func (r *SubsRepo) ListTaxRatesRepo() ([]models.TaxRate, error){
+	var rates []models.TaxRate
+	if err := r.db.Order("jurisdiction, valid_from").Find(&rates).Error; err != nil{
+		return nil, err
+	}
+	return rates, nil
+}
+
+func (r *SubsRepo) DeleteTaxRateRepo(id string) error{
+	result := r.db.Delete(&models.TaxRate{}, "id = ?", id)
+	if result.Error != nil{
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *SubsRepo) SetSubTaxRepo(tax *models.SubTax) error{
+	return r.db.Transaction(func(tx *gorm.DB) error{
+		if err := tx.Save(tax).Error; err != nil{
+			return err
+		}
+		return writeStoredSubEvent(tx, models.EventSubUpdated, tax.SubID)
+	})
+}
+
+func (r *SubsRepo) GetSubTaxRepo(subID string) (*models.SubTax, error){
+	var tax models.SubTax
+	if err := r.db.First(&tax, "sub_id = ?", subID).Error; err != nil{
+		return nil, err
+	}
+	return &tax, nil
+}
+
+func (r *SubsRepo) DeleteSubTaxRepo(subID string) error{
+	return r.db.Transaction(func(tx *gorm.DB) error{
+		result := tx.Delete(&models.SubTax{}, "sub_id = ?", subID)
+		if result.Error != nil{
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return writeStoredSubEvent(tx, models.EventSubUpdated, subID)
+	})
+}
@@ -0,0 +1,37 @@
+package repo
+
+import (
+	"online-subs-api/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TenantRepo struct{
+	db *gorm.DB
+}
+
+func NewTenantRepo(db *gorm.DB) *TenantRepo{
+	return &TenantRepo{
+		db: db,
+	}
+}
+
+func (r *TenantRepo) SetSchemaRepo(schema *models.TenantSchema) error{
+	return r.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "tenant_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"schema", "updated_at"}),
+	}).Create(schema).Error
+}
+
+func (r *TenantRepo) GetSchemaRepo(tenantID string) (*models.TenantSchema, error){
+	var schema models.TenantSchema
+	if err := r.db.First(&schema, "tenant_id = ?", tenantID).Error; err != nil{
+		return nil, err
+	}
+	return &schema, nil
+}
+
+func (r *TenantRepo) DeleteSchemaRepo(tenantID string) error{
+	return r.db.Delete(&models.TenantSchema{}, "tenant_id = ?", tenantID).Error
+}
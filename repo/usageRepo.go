@@ -0,0 +1,108 @@
+package repo
+
+import (
+	"online-subs-api/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UsageMonth is the quantity recorded for a sub in one calendar month
+type UsageMonth struct{
+	Month		time.Time
+	Units		int64
+}
+
+func (r *SubsRepo) GetPricingRepo(subID string) (*models.SubPricing, error){
+	var pricing models.SubPricing
+	if err := r.db.First(&pricing, "sub_id = ?", subID).Error; err != nil{
+		return nil, err
+	}
+	return &pricing, nil
+}
+
+// SetPricingRepo stores the pricing and replaces the priced usage of the sub in one transaction
+func (r *SubsRepo) SetPricingRepo(pricing *models.SubPricing, charges []models.UsageCharge) error{
+	return r.db.Transaction(func(tx *gorm.DB) error{
+		if err := tx.Save(pricing).Error; err != nil{
+			return err
+		}
+		if err := replaceUsageCharges(tx, pricing.SubID, charges); err != nil{
+			return err
+		}
+		return writeStoredSubEvent(tx, models.EventSubUpdated, pricing.SubID)
+	})
+}
+
+// DeletePricingRepo makes the sub flat again, its usage records are kept
+func (r *SubsRepo) DeletePricingRepo(subID string) error{
+	return r.db.Transaction(func(tx *gorm.DB) error{
+		if err := tx.Delete(&models.UsageCharge{}, "sub_id = ?", subID).Error; err != nil{
+			return err
+		}
+		result := tx.Delete(&models.SubPricing{}, "sub_id = ?", subID)
+		if result.Error != nil{
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return writeStoredSubEvent(tx, models.EventSubUpdated, subID)
+	})
+}
+
+// CreateUsageRecordsRepo inserts the records, skipping the ones whose idempotency key was
+// already recorded for the sub, and returns how many were stored
+func (r *SubsRepo) CreateUsageRecordsRepo(records []models.UsageRecord) (int, error){
+	if len(records) == 0 {
+		return 0, nil
+	}
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&records)
+	return int(result.RowsAffected), result.Error
+}
+
+func (r *SubsRepo) ListUsageRecordsRepo(subID string, from, to time.Time) ([]models.UsageRecord, error){
+	var records []models.UsageRecord
+	err := r.db.Where("sub_id = ? AND timestamp >= ? AND timestamp < ?", subID, from, to).
+		Order("timestamp").
+		Find(&records).Error
+	if err != nil{
+		return nil, err
+	}
+	return records, nil
+}
+
+// UsageByMonthRepo sums the recorded quantities of the sub per calendar month (UTC)
+func (r *SubsRepo) UsageByMonthRepo(subID string) ([]UsageMonth, error){
+	var months []UsageMonth
+	err := r.db.Model(&models.UsageRecord{}).
+		Select("date_trunc('month', timestamp AT TIME ZONE 'UTC') AS month, SUM(quantity) AS units").
+		Where("sub_id = ?", subID).
+		Group("month").
+		Order("month").
+		Scan(&months).Error
+	if err != nil{
+		return nil, err
+	}
+	return months, nil
+}
+
+func (r *SubsRepo) ReplaceUsageChargesRepo(subID string, charges []models.UsageCharge) error{
+	return r.db.Transaction(func(tx *gorm.DB) error{
+		if err := replaceUsageCharges(tx, subID, charges); err != nil{
+			return err
+		}
+		return writeStoredSubEvent(tx, models.EventSubUpdated, subID)
+	})
+}
+
+func replaceUsageCharges(tx *gorm.DB, subID string, charges []models.UsageCharge) error{
+	if err := tx.Delete(&models.UsageCharge{}, "sub_id = ?", subID).Error; err != nil{
+		return err
+	}
+	if len(charges) == 0 {
+		return nil
+	}
+	return tx.Create(&charges).Error
+}
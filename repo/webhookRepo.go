@@ -0,0 +1,118 @@
+package repo
+
+import (
+	"online-subs-api/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type WebhookRepo struct{
+	db *gorm.DB
+}
+
+func NewWebhookRepo(db *gorm.DB) *WebhookRepo{
+	return &WebhookRepo{
+		db: db,
+	}
+}
+
+func (r *WebhookRepo) CreateEndpointRepo(endpoint *models.WebhookEndpoint) error{
+	return r.db.Create(endpoint).Error
+}
+
+func (r *WebhookRepo) GetEndpointRepo(id string) (*models.WebhookEndpoint, error){
+	var endpoint models.WebhookEndpoint
+	if err := r.db.First(&endpoint, "id = ?", id).Error; err != nil{
+		return nil, err
+	}
+	return &endpoint, nil
+}
+
+func (r *WebhookRepo) ListEndpointsRepo(tenantID string) ([]models.WebhookEndpoint, error){
+	var endpoints []models.WebhookEndpoint
+	query := r.db.Order("created_at")
+	if tenantID != "" {
+		query = query.Where("tenant_id = ?", tenantID)
+	}
+	if err := query.Find(&endpoints).Error; err != nil{
+		return nil, err
+	}
+	return endpoints, nil
+}
+
+// ListActiveEndpointsRepo returns the endpoints that receive the tenant's events
+func (r *WebhookRepo) ListActiveEndpointsRepo(tenantID string) ([]models.WebhookEndpoint, error){
+	var endpoints []models.WebhookEndpoint
+	if err := r.db.Where("tenant_id = ? AND active", tenantID).Find(&endpoints).Error; err != nil{
+		return nil, err
+	}
+	return endpoints, nil
+}
+
+func (r *WebhookRepo) UpdateEndpointRepo(endpoint *models.WebhookEndpoint) error{
+	return r.db.Save(endpoint).Error
+}
+
+func (r *WebhookRepo) DeleteEndpointRepo(id string) error{
+	return r.db.Transaction(func(tx *gorm.DB) error{
+		if err := tx.Delete(&models.WebhookDelivery{}, "endpoint_id = ?", id).Error; err != nil{
+			return err
+		}
+		return tx.Delete(&models.WebhookEndpoint{}, "id = ?", id).Error
+	})
+}
+
+// CreateDeliveryRepo queues the delivery unless the endpoint already got this event,
+// created reports whether this call inserted it
+func (r *WebhookRepo) CreateDeliveryRepo(delivery *models.WebhookDelivery) (bool, error){
+	result := r.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "endpoint_id"}, {Name: "event_id"}},
+		DoNothing: true,
+	}).Create(delivery)
+	if result.Error != nil{
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *WebhookRepo) GetDeliveryRepo(id string) (*models.WebhookDelivery, error){
+	var delivery models.WebhookDelivery
+	if err := r.db.First(&delivery, "id = ?", id).Error; err != nil{
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// ListDueDeliveriesRepo returns pending deliveries whose next attempt is due, oldest first
+func (r *WebhookRepo) ListDueDeliveriesRepo(now time.Time, limit int) ([]models.WebhookDelivery, error){
+	var deliveries []models.WebhookDelivery
+	err := r.db.Where("status = ? AND next_attempt_at <= ?", models.DeliveryPending, now).
+		Order("created_at").
+		Limit(limit).
+		Find(&deliveries).Error
+	if err != nil{
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+func (r *WebhookRepo) ListDeliveriesRepo(endpointID, status string, limit int) ([]models.WebhookDelivery, error){
+	var deliveries []models.WebhookDelivery
+	query := r.db.Order("created_at DESC").Limit(limit)
+	if endpointID != "" {
+		query = query.Where("endpoint_id = ?", endpointID)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Find(&deliveries).Error; err != nil{
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+func (r *WebhookRepo) UpdateDeliveryRepo(delivery *models.WebhookDelivery) error{
+	return r.db.Save(delivery).Error
+}
@@ -5,11 +5,115 @@ import (
 	"online-subs-api/handlers"
 )
 
-func Routes(mux *http.ServeMux, subsHandler *handlers.SubsHandler){
+func Routes(mux *http.ServeMux, subsHandler *handlers.SubsHandler, catalogHandler *handlers.CatalogHandler, tagHandler *handlers.TagHandler, tenantHandler *handlers.TenantHandler, reportHandler *handlers.ReportHandler, feedHandler *handlers.FeedHandler, budgetHandler *handlers.BudgetHandler, reminderHandler *handlers.ReminderHandler, webhookHandler *handlers.WebhookHandler, outboxHandler *handlers.OutboxHandler, streamHandler *handlers.StreamHandler, appImportHandler *handlers.AppImportHandler, statementHandler *handlers.StatementHandler, paymentHandler *handlers.PaymentHandler){
 	mux.HandleFunc("/subs/create", subsHandler.CreateSubHandler)
 	mux.HandleFunc("/subs/getById", subsHandler.GetSubHandlerByID)
 	mux.HandleFunc("/subs/listAll", subsHandler.ListAllSubsHandler)
 	mux.HandleFunc("/subs/update", subsHandler.UpdateSubHandler)
 	mux.HandleFunc("/subs/delete", subsHandler.DeleteSubHandler)
 	mux.HandleFunc("/subs/total-cost", subsHandler.GetTotalCostHandler)
+	mux.HandleFunc("/subs/upcoming", subsHandler.UpcomingChargesHandler)
+	mux.HandleFunc("/subs/import", subsHandler.ImportSubsHandler)
+	mux.HandleFunc("/subs/export", subsHandler.ExportSubsHandler)
+	mux.HandleFunc("/subs/stream", streamHandler.StreamSubsHandler)
+	mux.HandleFunc("/subs/suggest", subsHandler.SuggestServiceNamesHandler)
+	mux.HandleFunc("/subs/resolve", subsHandler.ResolveServiceNameHandler)
+	mux.HandleFunc("/subs/tags/set", subsHandler.SetSubTagsHandler)
+	mux.HandleFunc("/subs/category/set", subsHandler.SetSubCategoryHandler)
+	mux.HandleFunc("/subs/price-changes/create", subsHandler.CreatePriceChangeHandler)
+	mux.HandleFunc("/subs/price-changes/listAll", subsHandler.ListPriceChangesHandler)
+	mux.HandleFunc("/subs/price-changes/delete", subsHandler.DeletePriceChangeHandler)
+	mux.HandleFunc("/subs/plan-change", subsHandler.ChangePlanHandler)
+	mux.HandleFunc("/subs/seats/create", subsHandler.CreateSeatChangeHandler)
+	mux.HandleFunc("/subs/seats/listAll", subsHandler.ListSeatChangesHandler)
+	mux.HandleFunc("/subs/seats/delete", subsHandler.DeleteSeatChangeHandler)
+	mux.HandleFunc("/subs/seats/assign", subsHandler.AssignSeatHandler)
+	mux.HandleFunc("/subs/seats/unassign", subsHandler.UnassignSeatHandler)
+	mux.HandleFunc("/subs/seats/assignments", subsHandler.ListSeatAssignmentsHandler)
+	mux.HandleFunc("/subs/seats/utilization", subsHandler.SeatUtilizationHandler)
+	mux.HandleFunc("/subs/pricing/set", subsHandler.SetPricingHandler)
+	mux.HandleFunc("/subs/pricing/get", subsHandler.GetPricingHandler)
+	mux.HandleFunc("/subs/pricing/delete", subsHandler.DeletePricingHandler)
+	mux.HandleFunc("/subs/usage/record", subsHandler.RecordUsageHandler)
+	mux.HandleFunc("/subs/usage/listAll", subsHandler.ListUsageHandler)
+	mux.HandleFunc("/subs/usage/summary", subsHandler.UsageSummaryHandler)
+	mux.HandleFunc("/subs/discounts/create", subsHandler.CreateDiscountHandler)
+	mux.HandleFunc("/subs/discounts/listAll", subsHandler.ListDiscountsHandler)
+	mux.HandleFunc("/subs/discounts/delete", subsHandler.DeleteDiscountHandler)
+	mux.HandleFunc("/subs/tax/set", subsHandler.SetSubTaxHandler)
+	mux.HandleFunc("/subs/tax/get", subsHandler.GetSubTaxHandler)
+	mux.HandleFunc("/subs/tax/delete", subsHandler.DeleteSubTaxHandler)
+	mux.HandleFunc("/taxes/rates/create", subsHandler.CreateTaxRateHandler)
+	mux.HandleFunc("/taxes/rates/listAll", subsHandler.ListTaxRatesHandler)
+	mux.HandleFunc("/taxes/rates/delete", subsHandler.DeleteTaxRateHandler)
+	mux.HandleFunc("/subs/members/set", subsHandler.SetSplitHandler)
+	mux.HandleFunc("/subs/members/get", subsHandler.GetSplitHandler)
+	mux.HandleFunc("/subs/members/delete", subsHandler.DeleteSplitHandler)
+	mux.HandleFunc("/subs/payments/create", paymentHandler.CreatePaymentHandler)
+	mux.HandleFunc("/subs/payments/listAll", paymentHandler.ListPaymentsHandler)
+	mux.HandleFunc("/subs/payments/update", paymentHandler.UpdatePaymentHandler)
+	mux.HandleFunc("/subs/payments/delete", paymentHandler.DeletePaymentHandler)
+	mux.HandleFunc("/subs/payments/import", paymentHandler.ImportPaymentsHandler)
+
+	mux.HandleFunc("/catalog/listAll", catalogHandler.ListCatalogHandler)
+	mux.HandleFunc("/catalog/providers/getById", catalogHandler.GetProviderHandler)
+	mux.HandleFunc("/admin/catalog/providers/create", catalogHandler.CreateProviderHandler)
+	mux.HandleFunc("/admin/catalog/providers/update", catalogHandler.UpdateProviderHandler)
+	mux.HandleFunc("/admin/catalog/providers/delete", catalogHandler.DeleteProviderHandler)
+	mux.HandleFunc("/admin/catalog/plans/create", catalogHandler.CreatePlanHandler)
+	mux.HandleFunc("/admin/catalog/plans/update", catalogHandler.UpdatePlanHandler)
+	mux.HandleFunc("/admin/catalog/plans/delete", catalogHandler.DeletePlanHandler)
+
+	mux.HandleFunc("/tags/create", tagHandler.CreateTagHandler)
+	mux.HandleFunc("/tags/listAll", tagHandler.ListTagsHandler)
+	mux.HandleFunc("/tags/delete", tagHandler.DeleteTagHandler)
+	mux.HandleFunc("/categories/create", tagHandler.CreateCategoryHandler)
+	mux.HandleFunc("/categories/listAll", tagHandler.ListCategoriesHandler)
+	mux.HandleFunc("/categories/delete", tagHandler.DeleteCategoryHandler)
+
+	mux.HandleFunc("/tenants/metadata-schema/set", tenantHandler.SetMetadataSchemaHandler)
+	mux.HandleFunc("/tenants/metadata-schema/get", tenantHandler.GetMetadataSchemaHandler)
+	mux.HandleFunc("/tenants/metadata-schema/delete", tenantHandler.DeleteMetadataSchemaHandler)
+
+	mux.HandleFunc("/reports/spending", reportHandler.SpendingReportHandler)
+	mux.HandleFunc("/reports/forecast", reportHandler.ForecastHandler)
+	mux.HandleFunc("/reports/settlement", reportHandler.SettlementHandler)
+	mux.HandleFunc("/reports/vat", reportHandler.VATSummaryHandler)
+	mux.HandleFunc("/reports/vat/export", reportHandler.VATExportHandler)
+	mux.HandleFunc("/reports/discrepancies", paymentHandler.ListDiscrepanciesHandler)
+	mux.HandleFunc("/admin/reconciliation/run", paymentHandler.RunReconciliationHandler)
+
+	mux.HandleFunc("/feeds/create", feedHandler.CreateFeedHandler)
+	mux.HandleFunc("/feeds/rotate", feedHandler.RotateFeedHandler)
+	mux.HandleFunc("/feeds/delete", feedHandler.DeleteFeedHandler)
+	mux.HandleFunc("/feeds/calendar.ics", feedHandler.CalendarFeedHandler)
+
+	mux.HandleFunc("/budgets/create", budgetHandler.CreateBudgetHandler)
+	mux.HandleFunc("/budgets/getById", budgetHandler.GetBudgetHandler)
+	mux.HandleFunc("/budgets/listAll", budgetHandler.ListBudgetsHandler)
+	mux.HandleFunc("/budgets/update", budgetHandler.UpdateBudgetHandler)
+	mux.HandleFunc("/budgets/delete", budgetHandler.DeleteBudgetHandler)
+	mux.HandleFunc("/budgets/alerts", budgetHandler.ListBudgetAlertsHandler)
+
+	mux.HandleFunc("/reminders/listAll", reminderHandler.ListRemindersHandler)
+	mux.HandleFunc("/admin/reminders/run", reminderHandler.RunRemindersHandler)
+
+	mux.HandleFunc("/webhooks/create", webhookHandler.CreateWebhookHandler)
+	mux.HandleFunc("/webhooks/listAll", webhookHandler.ListWebhooksHandler)
+	mux.HandleFunc("/webhooks/update", webhookHandler.UpdateWebhookHandler)
+	mux.HandleFunc("/webhooks/delete", webhookHandler.DeleteWebhookHandler)
+	mux.HandleFunc("/webhooks/deliveries", webhookHandler.ListDeliveriesHandler)
+	mux.HandleFunc("/webhooks/deliveries/redeliver", webhookHandler.RedeliverHandler)
+
+	mux.HandleFunc("/admin/outbox/status", outboxHandler.OutboxStatusHandler)
+
+	mux.HandleFunc("/imports/apps", appImportHandler.ListAppsHandler)
+	mux.HandleFunc("/imports/preview", appImportHandler.PreviewImportHandler)
+	mux.HandleFunc("/imports/getById", appImportHandler.GetImportPreviewHandler)
+	mux.HandleFunc("/imports/commit", appImportHandler.CommitImportHandler)
+
+	mux.HandleFunc("/statements/import", statementHandler.ImportStatementHandler)
+	mux.HandleFunc("/statements/candidates", statementHandler.ListCandidatesHandler)
+	mux.HandleFunc("/statements/candidates/accept", statementHandler.AcceptCandidatesHandler)
+	mux.HandleFunc("/statements/candidates/dismiss", statementHandler.DismissCandidateHandler)
 }
\ No newline at end of file
@@ -0,0 +1,442 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"online-subs-api/models"
+	"online-subs-api/repo"
+	"online-subs-api/utils"
+	"strings"
+	"time"
+)
+
+const importPreviewTTL = 24 * time.Hour
+
+// appCategories maps the category names used by other apps to ours
+var appCategories = map[string]string{
+	"streaming": "entertainment", "video": "entertainment", "tv": "entertainment", "movies": "entertainment",
+	"games": "entertainment", "gaming": "entertainment", "news": "entertainment",
+	"audio": "music", "podcasts": "music",
+	"software": "productivity", "utilities": "productivity", "tools": "productivity", "office": "productivity", "work": "productivity",
+	"storage": "cloud", "hosting": "cloud", "cloud storage": "cloud",
+	"development": "dev tools", "developer tools": "dev tools", "developer": "dev tools",
+	"learning": "education", "books": "education",
+	"ai": "ai tools",
+}
+
+type AppImportOptions struct{
+	App				string
+	UserID			string
+	TenantID		string
+	// Currency is the currency prices are kept in, Rates gives the value of one unit of
+	// another currency in it, e.g. {"USD": 92.5}
+	Currency		string
+	Rates			map[string]float64
+}
+
+// AppImportRow is one previewed subscription. DuplicateOf is the ID of a stored sub
+// with the same service and an overlapping period, DuplicateRow an earlier row of the file.
+type AppImportRow struct{
+	Row				int				`json:"row"`
+	Source			AppRecord		`json:"source"`
+	Sub				*models.Sub		`json:"subscription,omitempty"`
+	Warnings		[]string		`json:"warnings,omitempty"`
+	Errors			[]string		`json:"errors,omitempty"`
+	DuplicateOf		string			`json:"duplicate_of,omitempty"`
+	DuplicateRow	int				`json:"duplicate_row,omitempty"`
+}
+
+type AppImportPreview struct{
+	models.ImportPreview
+	Total			int				`json:"total"`
+	Valid			int				`json:"valid"`
+	Invalid			int				`json:"invalid"`
+	Duplicates		int				`json:"duplicates"`
+	Rows			[]AppImportRow	`json:"rows"`
+}
+
+// AppImportCommit picks the rows to import, all valid rows that are not duplicates by default
+type AppImportCommit struct{
+	Rows				[]int		`json:"rows"`
+	IncludeDuplicates	bool		`json:"include_duplicates"`
+}
+
+type AppImportSkip struct{
+	Row				int			`json:"row"`
+	Reason			string		`json:"reason"`
+}
+
+type AppImportResult struct{
+	PreviewID		string				`json:"preview_id"`
+	Imported		int					`json:"imported"`
+	Skipped			[]AppImportSkip		`json:"skipped"`
+	Subs			[]models.Sub		`json:"subscriptions"`
+}
+
+type AppImportService struct{
+	importRepo *repo.ImportRepo
+	subsService *SubsService
+}
+
+func NewAppImportService(importRepo *repo.ImportRepo, subsService *SubsService) *AppImportService{
+	return &AppImportService{importRepo: importRepo, subsService: subsService}
+}
+
+// cycleToPeriod maps an app cycle to a billing period. Cycles we can't bill are converted
+// to a monthly price, factor is what the app price is multiplied with.
+func cycleToPeriod(count int, unit string) (period string, factor float64, exact bool){
+	months := 0
+	switch unit {
+	case "month":
+		months = count
+	case "year":
+		months = count * 12
+	}
+	switch months {
+	case 1:
+		return models.BillingMonthly, 1, true
+	case 3:
+		return models.BillingQuarterly, 1, true
+	case 12:
+		return models.BillingYearly, 1, true
+	}
+
+	days := map[string]float64{"day": 1, "week": 7, "month": 365.25 / 12, "year": 365.25}[unit]
+	return models.BillingMonthly, (365.25 / 12) / (days * float64(count)), false
+}
+
+// mapCategory returns our category for the app's one, or "" with the name to keep as a tag
+func (s *AppImportService) mapCategory(name string) (string, string){
+	name = normalizeLabel(name)
+	if name == "" {
+		return "", ""
+	}
+	if mapped, ok := appCategories[name]; ok {
+		name = mapped
+	}
+	if _, err := s.subsService.tagRepo.GetCategoryRepo(name); err != nil {
+		return "", name
+	}
+	return name, ""
+}
+
+// toSub maps a parsed record, Tags holds the tag names until the row is committed
+func (s *AppImportService) toSub(record AppRecord, opts AppImportOptions, row *AppImportRow){
+	sub := &models.Sub{
+		ServiceName: record.Name,
+		UserID: opts.UserID,
+		TenantID: opts.TenantID,
+		Metadata: models.JSONMap{"imported_from": opts.App},
+	}
+
+	period, factor, exact := cycleToPeriod(record.CycleCount, record.CycleUnit)
+	sub.BillingPeriod = period
+	if !exact {
+		row.Warnings = append(row.Warnings, fmt.Sprintf("billed every %d %s, converted to a monthly price", record.CycleCount, record.CycleUnit))
+	}
+
+	amount := record.Amount * factor
+	if record.Currency != "" {
+		sub.Metadata["currency"] = record.Currency
+		sub.Metadata["original_price"] = record.Amount
+		if opts.Currency != "" && record.Currency != opts.Currency {
+			if rate, ok := opts.Rates[record.Currency]; ok {
+				amount *= rate
+			} else {
+				row.Warnings = append(row.Warnings, fmt.Sprintf("no rate for %s, price kept unconverted", record.Currency))
+			}
+		}
+	}
+	sub.Price = int(math.Round(amount))
+	if record.Notes != "" {
+		sub.Metadata["notes"] = record.Notes
+	}
+
+	tags := []string{}
+	category, tag := s.mapCategory(record.Category)
+	sub.Category = category
+	if tag != "" {
+		tags = append(tags, tag)
+		row.Warnings = append(row.Warnings, fmt.Sprintf("unknown category %q kept as a tag", tag))
+	}
+
+	now := monthStart(time.Now())
+	start := now
+	if record.StartDate != nil {
+		start = monthStart(*record.StartDate)
+	} else {
+		row.Warnings = append(row.Warnings, "no start date, starting this month")
+	}
+	end := ""
+	if record.EndDate != nil {
+		end = monthStart(*record.EndDate).Format("01-2006")
+	} else if record.Cancelled {
+		end = now.Format("01-2006")
+		if start.After(now) {
+			end = start.Format("01-2006")
+		}
+		row.Warnings = append(row.Warnings, "cancelled without an end date, ending "+end)
+	}
+
+	row.Errors = append(row.Errors, record.Errors...)
+	if len(row.Errors) == 0 {
+		if err := s.subsService.validateNewSub(sub, start.Format("01-2006"), end, ""); err != nil {
+			row.Errors = append(row.Errors, err.Error())
+		}
+	}
+	if len(row.Errors) > 0 {
+		return
+	}
+	for _, name := range tags {
+		sub.Tags = append(sub.Tags, models.Tag{Name: name})
+	}
+	row.Sub = sub
+}
+
+// overlaps is true when both subs are for the same service and run in a common month
+func overlaps(a, b *models.Sub) bool{
+	if !strings.EqualFold(strings.TrimSpace(a.ServiceName), strings.TrimSpace(b.ServiceName)) {
+		return false
+	}
+	if end, ok := subEndMonth(a); ok && end.Before(monthStart(b.StartDate)) {
+		return false
+	}
+	if end, ok := subEndMonth(b); ok && end.Before(monthStart(a.StartDate)) {
+		return false
+	}
+	return true
+}
+
+// markDuplicates flags rows matching a stored sub of the user or an earlier row
+func markDuplicates(rows []AppImportRow, existing []models.Sub){
+	for i := range rows {
+		row := &rows[i]
+		row.DuplicateOf, row.DuplicateRow = "", 0
+		if row.Sub == nil {
+			continue
+		}
+		for j := range existing {
+			if overlaps(row.Sub, &existing[j]) {
+				row.DuplicateOf = existing[j].ID
+				break
+			}
+		}
+		for j := 0; j < i && row.DuplicateOf == ""; j++ {
+			if rows[j].Sub != nil && overlaps(row.Sub, rows[j].Sub) {
+				row.DuplicateRow = rows[j].Row
+				break
+			}
+		}
+	}
+}
+
+func (row *AppImportRow) duplicate() bool{
+	return row.DuplicateOf != "" || row.DuplicateRow != 0
+}
+
+func (p *AppImportPreview) count(){
+	p.Total, p.Valid, p.Invalid, p.Duplicates = len(p.Rows), 0, 0, 0
+	for i := range p.Rows {
+		switch {
+		case p.Rows[i].Sub == nil:
+			p.Invalid++
+		case p.Rows[i].duplicate():
+			p.Duplicates++
+		default:
+			p.Valid++
+		}
+	}
+}
+
+// PreviewService parses an export of another app and stores the mapped rows, nothing
+// is imported until the preview is committed
+func (s *AppImportService) PreviewService(r io.Reader, opts AppImportOptions) (*AppImportPreview, error){
+	parser, ok := appParsers[opts.App]
+	if !ok {
+		return nil, fmt.Errorf("app must be one of %s", strings.Join(AppParserNames(), ", "))
+	}
+	if !validateUUID(opts.UserID) {
+		utils.ErrorLogger.Println("Invalid user_id format:", opts.UserID)
+		return nil, errors.New("invalid user_id format")
+	}
+	if opts.TenantID != "" && !validateTenantID(opts.TenantID) {
+		utils.ErrorLogger.Println("Invalid tenant_id format:", opts.TenantID)
+		return nil, errors.New("invalid tenant_id format")
+	}
+	opts.Currency = strings.ToUpper(strings.TrimSpace(opts.Currency))
+	for currency, rate := range opts.Rates {
+		if rate <= 0 {
+			return nil, fmt.Errorf("rate for %s must be positive", currency)
+		}
+		if upper := strings.ToUpper(currency); upper != currency {
+			opts.Rates[upper] = rate
+		}
+	}
+
+	records, err := parser.Parse(r)
+	if err != nil {
+		utils.ErrorLogger.Printf("Failed to parse %s export: %v", opts.App, err)
+		return nil, err
+	}
+	if len(records) > maxImportRows {
+		return nil, fmt.Errorf("imports are limited to %d rows", maxImportRows)
+	}
+
+	preview := &AppImportPreview{Rows: []AppImportRow{}}
+	for i, record := range records {
+		row := AppImportRow{Row: i + 1, Source: record}
+		s.toSub(record, opts, &row)
+		preview.Rows = append(preview.Rows, row)
+	}
+
+	existing, err := s.subsService.subsRepo.ListAllSubsRepo(repo.SubsFilter{UserID: opts.UserID})
+	if err != nil {
+		utils.ErrorLogger.Println("Failed to load subscriptions for duplicate check:", err)
+		return nil, err
+	}
+	markDuplicates(preview.Rows, existing)
+	preview.count()
+
+	rows, err := json.Marshal(preview.Rows)
+	if err != nil {
+		return nil, err
+	}
+	id, err := utils.NewUUID()
+	if err != nil {
+		utils.ErrorLogger.Println("Failed to generate UUID:", err)
+		return nil, err
+	}
+	now := time.Now()
+	preview.ImportPreview = models.ImportPreview{
+		ID: id,
+		App: opts.App,
+		UserID: opts.UserID,
+		TenantID: opts.TenantID,
+		Rows: string(rows),
+		CreatedAt: now,
+		ExpiresAt: now.Add(importPreviewTTL),
+	}
+	if err := s.importRepo.DeleteExpiredPreviewsRepo(now); err != nil {
+		utils.WarningLogger.Println("Failed to delete expired import previews:", err)
+	}
+	if err := s.importRepo.CreatePreviewRepo(&preview.ImportPreview); err != nil {
+		utils.ErrorLogger.Println("Failed to store import preview:", err)
+		return nil, err
+	}
+	utils.InfoLogger.Printf("Previewed %d %s subscriptions for user %s (preview %s)", preview.Total, opts.App, opts.UserID, id)
+	return preview, nil
+}
+
+func (s *AppImportService) loadPreview(id string) (*AppImportPreview, error){
+	if !validateUUID(id) {
+		utils.ErrorLogger.Println("Invalid ID format:", id)
+		return nil, errors.New("invalid id format")
+	}
+	stored, err := s.importRepo.GetPreviewRepo(id)
+	if err != nil {
+		utils.ErrorLogger.Println("Import preview not found:", id, "error:", err)
+		return nil, errors.New("import preview not found")
+	}
+	preview := &AppImportPreview{ImportPreview: *stored}
+	if err := json.Unmarshal([]byte(stored.Rows), &preview.Rows); err != nil {
+		return nil, err
+	}
+	preview.count()
+	return preview, nil
+}
+
+func (s *AppImportService) GetPreviewService(id string) (*AppImportPreview, error){
+	return s.loadPreview(id)
+}
+
+// CommitService imports the selected rows of a preview in one transaction. Duplicates are
+// checked again since the subs may have changed since the preview.
+func (s *AppImportService) CommitService(id string, req AppImportCommit) (*AppImportResult, error){
+	preview, err := s.loadPreview(id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if preview.CommittedAt != nil {
+		return nil, errors.New("import preview was already committed")
+	}
+	if !preview.ExpiresAt.After(now) {
+		return nil, errors.New("import preview has expired")
+	}
+
+	selected := map[int]bool{}
+	for _, row := range req.Rows {
+		if row < 1 || row > len(preview.Rows) {
+			return nil, fmt.Errorf("row %d is not in the preview", row)
+		}
+		selected[row] = true
+	}
+
+	existing, err := s.subsService.subsRepo.ListAllSubsRepo(repo.SubsFilter{UserID: preview.UserID})
+	if err != nil {
+		utils.ErrorLogger.Println("Failed to load subscriptions for duplicate check:", err)
+		return nil, err
+	}
+	markDuplicates(preview.Rows, existing)
+
+	result := &AppImportResult{PreviewID: id, Skipped: []AppImportSkip{}, Subs: []models.Sub{}}
+	for _, row := range preview.Rows {
+		if len(selected) > 0 && !selected[row.Row] {
+			continue
+		}
+		switch {
+		case row.Sub == nil:
+			result.Skipped = append(result.Skipped, AppImportSkip{Row: row.Row, Reason: strings.Join(row.Errors, "; ")})
+			continue
+		case row.DuplicateOf != "" && !req.IncludeDuplicates:
+			result.Skipped = append(result.Skipped, AppImportSkip{Row: row.Row, Reason: "duplicate of subscription " + row.DuplicateOf})
+			continue
+		case row.DuplicateRow != 0 && !req.IncludeDuplicates:
+			result.Skipped = append(result.Skipped, AppImportSkip{Row: row.Row, Reason: fmt.Sprintf("duplicate of row %d", row.DuplicateRow)})
+			continue
+		}
+
+		sub := *row.Sub
+		if len(sub.Tags) > 0 {
+			names := []string{}
+			for _, tag := range sub.Tags {
+				names = append(names, tag.Name)
+			}
+			if sub.Tags, err = s.subsService.tagsByName(names); err != nil {
+				return nil, err
+			}
+		}
+		if sub.ID, err = utils.NewUUID(); err != nil {
+			utils.ErrorLogger.Println("Failed to generate UUID:", err)
+			return nil, err
+		}
+		result.Subs = append(result.Subs, sub)
+	}
+
+	claimed, err := s.importRepo.ClaimPreviewRepo(id, now)
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		return nil, errors.New("import preview was already committed or has expired")
+	}
+	if len(result.Subs) > 0 {
+		if err := s.subsService.subsRepo.CreateSubsRepo(result.Subs); err != nil {
+			utils.ErrorLogger.Println("App import rolled back:", err)
+			if err := s.importRepo.ReleasePreviewRepo(id); err != nil {
+				utils.ErrorLogger.Println("Failed to release import preview:", id, "error:", err)
+			}
+			return nil, fmt.Errorf("import rolled back: %v", err)
+		}
+	}
+
+	for i := range result.Subs {
+		s.subsService.emit(EventSubCreated, &result.Subs[i])
+	}
+	result.Imported = len(result.Subs)
+	utils.InfoLogger.Printf("Committed import preview %s: %d imported, %d skipped", id, result.Imported, len(result.Skipped))
+	return result, nil
+}
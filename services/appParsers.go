@@ -0,0 +1,462 @@
+package services
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// AppRecord is a subscription as exported by another tracking app, before it is mapped
+// to a models.Sub. Cycles are a count of days, weeks, months or years.
+type AppRecord struct{
+	Name			string			`json:"name"`
+	Amount			float64			`json:"amount"`
+	Currency		string			`json:"currency,omitempty"`
+	CycleCount		int				`json:"cycle_count"`
+	CycleUnit		string			`json:"cycle_unit"`
+	Category		string			`json:"category,omitempty"`
+	StartDate		*time.Time		`json:"start_date,omitempty"`
+	EndDate			*time.Time		`json:"end_date,omitempty"`
+	Cancelled		bool			`json:"cancelled,omitempty"`
+	Notes			string			`json:"notes,omitempty"`
+	Errors			[]string		`json:"-"`
+}
+
+// AppParser reads the export file of one app
+type AppParser interface{
+	Name() string
+	Parse(r io.Reader) ([]AppRecord, error)
+}
+
+var appParsers = map[string]AppParser{}
+
+// RegisterAppParser makes a parser available to the app importer under its name
+func RegisterAppParser(parser AppParser){
+	appParsers[parser.Name()] = parser
+}
+
+// AppParserNames lists the registered parsers
+func AppParserNames() []string{
+	names := []string{}
+	for name := range appParsers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init(){
+	RegisterAppParser(bobbyParser{})
+	RegisterAppParser(trackMySubsParser{})
+	RegisterAppParser(subscriptionsManagerParser{})
+}
+
+// appFields lists the keys an app uses for each field, keys are compared after
+// normalizeKey so "firstBill", "first_bill" and "First Bill" are the same
+type appFields struct{
+	name, amount, currency, cycle, cycleCount, cycleUnit, start, end, category, cancelled, notes []string
+}
+
+// appItem is one exported subscription with normalized keys
+type appItem map[string]interface{}
+
+func normalizeKey(key string) string{
+	return strings.Map(func(r rune) rune{
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, key)
+}
+
+func (item appItem) get(keys []string) interface{}{
+	for _, key := range keys {
+		if value, ok := item[normalizeKey(key)]; ok && value != nil && value != "" {
+			return value
+		}
+	}
+	return nil
+}
+
+func (item appItem) text(keys []string) string{
+	switch v := item.get(keys).(type) {
+	case nil:
+		return ""
+	case string:
+		return strings.TrimSpace(v)
+	case map[string]interface{}:
+		// nested objects such as {"name": "Music", "color": ...}
+		return appItem(normalizeItem(v)).text([]string{"name", "title", "value"})
+	default:
+		return strings.TrimSpace(fmt.Sprint(v))
+	}
+}
+
+func normalizeItem(raw map[string]interface{}) map[string]interface{}{
+	item := map[string]interface{}{}
+	for key, value := range raw {
+		item[normalizeKey(key)] = value
+	}
+	return item
+}
+
+// toRecord maps an item with the app's field names, problems with single fields are
+// kept on the record so the rest of the file can still be previewed
+func (item appItem) toRecord(fields appFields, layouts []string) AppRecord{
+	record := AppRecord{
+		Name: item.text(fields.name),
+		Currency: strings.ToUpper(item.text(fields.currency)),
+		Category: item.text(fields.category),
+		Notes: item.text(fields.notes),
+	}
+
+	amount, symbolCurrency, err := parseAmount(item.get(fields.amount))
+	if err != nil {
+		record.Errors = append(record.Errors, err.Error())
+	}
+	record.Amount = amount
+	if record.Currency == "" {
+		record.Currency = symbolCurrency
+	}
+
+	if cycle := item.get(fields.cycle); cycle != nil {
+		record.CycleCount, record.CycleUnit, err = parseCycle(cycle)
+	} else if unit := item.text(fields.cycleUnit); unit != "" {
+		count := 1
+		if c := item.text(fields.cycleCount); c != "" {
+			count, _ = strconv.Atoi(strings.TrimSuffix(c, ".0"))
+		}
+		record.CycleCount, record.CycleUnit, err = parseCycle(fmt.Sprintf("%d %s", count, unit))
+	} else {
+		record.CycleCount, record.CycleUnit = 1, "month"
+	}
+	if err != nil {
+		record.Errors = append(record.Errors, err.Error())
+	}
+
+	if value := item.get(fields.start); value != nil {
+		if record.StartDate, err = parseAppDate(value, layouts); err != nil {
+			record.Errors = append(record.Errors, "start date: "+err.Error())
+		}
+	}
+	if value := item.get(fields.end); value != nil {
+		if record.EndDate, err = parseAppDate(value, layouts); err != nil {
+			record.Errors = append(record.Errors, "end date: "+err.Error())
+		}
+	}
+
+	switch v := item.get(fields.cancelled).(type) {
+	case bool:
+		record.Cancelled = v
+	case string:
+		switch strings.ToLower(strings.TrimSpace(v)) {
+		case "true", "yes", "1", "cancelled", "canceled", "inactive", "paused", "archived":
+			record.Cancelled = true
+		}
+	}
+	return record
+}
+
+var currencySymbols = map[string]string{"$": "USD", "€": "EUR", "£": "GBP", "₽": "RUB", "₸": "KZT", "¥": "JPY", "₹": "INR"}
+
+// parseAmount reads 9.99, "9,99", "$9.99" or "1 299,00 ₽", the currency is set when
+// the value carries a symbol
+func parseAmount(value interface{}) (float64, string, error){
+	switch v := value.(type) {
+	case nil:
+		return 0, "", errors.New("missing price")
+	case float64:
+		return v, "", nil
+	case string:
+		currency := ""
+		for symbol, code := range currencySymbols {
+			if strings.Contains(v, symbol) {
+				currency = code
+				v = strings.ReplaceAll(v, symbol, "")
+			}
+		}
+		v = strings.Map(func(r rune) rune{
+			if unicode.IsSpace(r) {
+				return -1
+			}
+			return r
+		}, v)
+		// "1,299.00" uses a thousands separator, "9,99" a decimal comma
+		if strings.Contains(v, ".") {
+			v = strings.ReplaceAll(v, ",", "")
+		} else {
+			v = strings.ReplaceAll(v, ",", ".")
+		}
+		amount, err := strconv.ParseFloat(v, 64)
+		if err != nil || amount < 0 || math.IsInf(amount, 0) || math.IsNaN(amount) {
+			return 0, currency, fmt.Errorf("invalid price %q", value)
+		}
+		return amount, currency, nil
+	}
+	return 0, "", fmt.Errorf("invalid price %v", value)
+}
+
+var cycleWords = map[string][2]interface{}{
+	"daily": {1, "day"}, "weekly": {1, "week"}, "biweekly": {2, "week"}, "fortnightly": {2, "week"},
+	"monthly": {1, "month"}, "bimonthly": {2, "month"}, "quarterly": {3, "month"},
+	"semiannually": {6, "month"}, "semiannual": {6, "month"}, "halfyearly": {6, "month"},
+	"yearly": {1, "year"}, "annually": {1, "year"}, "annual": {1, "year"},
+}
+
+var cyclePattern = regexp.MustCompile(`^(?:every\s*)?(\d+)?\s*(day|week|month|year)s?$`)
+
+// parseCycle reads "monthly", "every 3 months", "1 year" or {"count": 1, "unit": "MONTH"}
+func parseCycle(value interface{}) (int, string, error){
+	if raw, ok := value.(map[string]interface{}); ok {
+		item := appItem(normalizeItem(raw))
+		count := item.text([]string{"count", "value", "duration", "interval", "frequency"})
+		unit := item.text([]string{"unit", "period", "type"})
+		if count == "" {
+			count = "1"
+		}
+		value = count + " " + unit
+	}
+
+	text := strings.ToLower(strings.TrimSpace(fmt.Sprint(value)))
+	if word, ok := cycleWords[strings.NewReplacer("-", "", " ", "", "_", "").Replace(text)]; ok {
+		return word[0].(int), word[1].(string), nil
+	}
+	text = strings.TrimSuffix(strings.TrimSuffix(text, "ly"), ".0")
+	if m := cyclePattern.FindStringSubmatch(strings.Join(strings.Fields(text), " ")); m != nil {
+		count := 1
+		if m[1] != "" {
+			count, _ = strconv.Atoi(m[1])
+		}
+		if count > 0 {
+			return count, m[2], nil
+		}
+	}
+	return 1, "month", fmt.Errorf("unknown billing cycle %q", fmt.Sprint(value))
+}
+
+var (
+	monthFirstLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02", "02.01.2006", "01/02/2006", "2006/01/02", "Jan 2, 2006", "2 Jan 2006"}
+	dayFirstLayouts   = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02", "02.01.2006", "02/01/2006", "2006/01/02", "Jan 2, 2006", "2 Jan 2006"}
+)
+
+var slashDate = regexp.MustCompile(`^(\d{1,2})/(\d{1,2})/\d{2,4}`)
+
+// dateLayouts picks the layouts of a whole file: slashed dates are read day first when one
+// of them has a day above 12 in front (25/06/2024) and none has it in the middle, so
+// 05/06/2024 is the 5th of June in a European statement and May 6th in an American one
+func dateLayouts(items []appItem, keys ...[]string) []string{
+	dayFirst := false
+	for _, item := range items {
+		for _, k := range keys {
+			value, ok := item.get(k).(string)
+			if !ok {
+				continue
+			}
+			m := slashDate.FindStringSubmatch(strings.TrimSpace(value))
+			if m == nil {
+				continue
+			}
+			first, _ := strconv.Atoi(m[1])
+			second, _ := strconv.Atoi(m[2])
+			if second > 12 {
+				return monthFirstLayouts
+			}
+			if first > 12 {
+				dayFirst = true
+			}
+		}
+	}
+	if dayFirst {
+		return dayFirstLayouts
+	}
+	return monthFirstLayouts
+}
+
+// parseAppDate reads a date in one of the layouts or a unix timestamp in seconds or milliseconds
+func parseAppDate(value interface{}, layouts []string) (*time.Time, error){
+	var t time.Time
+	switch v := value.(type) {
+	case float64:
+		if v > 1e11 {
+			t = time.UnixMilli(int64(v)).UTC()
+		} else {
+			t = time.Unix(int64(v), 0).UTC()
+		}
+		return &t, nil
+	case string:
+		v = strings.TrimSpace(v)
+		for _, layout := range layouts {
+			if parsed, err := time.Parse(layout, v); err == nil {
+				t = parsed.UTC()
+				return &t, nil
+			}
+		}
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return parseAppDate(float64(n), layouts)
+		}
+	}
+	return nil, fmt.Errorf("unrecognized date %v", value)
+}
+
+// decodeJSONItems accepts a bare array or an object holding the list under one of the keys
+func decodeJSONItems(r io.Reader, keys ...string) ([]appItem, error){
+	var raw interface{}
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, errors.New("invalid JSON: " + err.Error())
+	}
+	list, ok := raw.([]interface{})
+	if object, isObject := raw.(map[string]interface{}); isObject {
+		list, ok = appItem(normalizeItem(object)).get(keys).([]interface{})
+	}
+	if !ok {
+		return nil, fmt.Errorf("expected a list of subscriptions under %s", strings.Join(keys, ", "))
+	}
+
+	items := []appItem{}
+	for i, entry := range list {
+		object, ok := entry.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("subscription %d is not an object", i+1)
+		}
+		items = append(items, normalizeItem(object))
+	}
+	return items, nil
+}
+
+// decodeCSVItems reads a CSV with a header row, the delimiter is detected from the header
+func decodeCSVItems(r io.Reader) ([]appItem, error){
+	data, err := io.ReadAll(io.LimitReader(r, 10<<20))
+	if err != nil {
+		return nil, err
+	}
+	text := strings.TrimPrefix(string(data), "\ufeff")
+	header, _, _ := strings.Cut(text, "\n")
+
+	reader := csv.NewReader(strings.NewReader(text))
+	reader.FieldsPerRecord = -1
+	if strings.Count(header, ";") > strings.Count(header, ",") {
+		reader.Comma = ';'
+	}
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, errors.New("invalid CSV: " + err.Error())
+	}
+	if len(records) == 0 {
+		return nil, errors.New("empty CSV file")
+	}
+
+	items := []appItem{}
+	for _, record := range records[1:] {
+		item := appItem{}
+		for i, column := range records[0] {
+			if i < len(record) {
+				item[normalizeKey(column)] = record[i]
+			}
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func itemsToRecords(items []appItem, fields appFields) []AppRecord{
+	records := []AppRecord{}
+	layouts := dateLayouts(items, fields.start, fields.end)
+	for _, item := range items {
+		records = append(records, item.toRecord(fields, layouts))
+	}
+	return records
+}
+
+// bobbyParser reads the JSON backup of Bobby:
+// {"subscriptions": [{"title": "Netflix", "price": 9.99, "currency": "USD",
+// "cycle": {"count": 1, "unit": "month"}, "firstBill": "2024-03-15", "category": "Entertainment"}]}
+type bobbyParser struct{}
+
+func (bobbyParser) Name() string{ return "bobby" }
+
+func (bobbyParser) Parse(r io.Reader) ([]AppRecord, error){
+	items, err := decodeJSONItems(r, "subscriptions", "items", "data")
+	if err != nil {
+		return nil, err
+	}
+	return itemsToRecords(items, appFields{
+		name: []string{"title", "name"},
+		amount: []string{"price", "amount", "cost"},
+		currency: []string{"currency", "currencyCode"},
+		cycle: []string{"cycle", "billingCycle"},
+		cycleCount: []string{"cycleCount", "every"},
+		cycleUnit: []string{"cycleUnit", "cycleType"},
+		start: []string{"firstBill", "firstPayment", "startDate"},
+		end: []string{"expires", "endDate"},
+		category: []string{"category", "group"},
+		cancelled: []string{"cancelled", "archived"},
+		notes: []string{"note", "notes", "description"},
+	}), nil
+}
+
+// trackMySubsParser reads the CSV export of TrackMySubs:
+// Name,Cost,Currency,Billing Cycle,Start Date,Next Payment,Category,Status,Notes
+type trackMySubsParser struct{}
+
+func (trackMySubsParser) Name() string{ return "trackmysubs" }
+
+func (trackMySubsParser) Parse(r io.Reader) ([]AppRecord, error){
+	items, err := decodeCSVItems(r)
+	if err != nil {
+		return nil, err
+	}
+	return itemsToRecords(items, appFields{
+		name: []string{"Name", "Subscription"},
+		amount: []string{"Cost", "Price", "Amount"},
+		currency: []string{"Currency"},
+		cycle: []string{"Billing Cycle", "Frequency", "Cycle"},
+		start: []string{"Start Date", "Signup Date", "Next Payment"},
+		end: []string{"End Date", "Cancellation Date", "Cancelled On"},
+		category: []string{"Category", "Folder"},
+		cancelled: []string{"Status", "Cancelled"},
+		notes: []string{"Notes"},
+	}), nil
+}
+
+// subscriptionsManagerParser reads the JSON backup of Subscriptions Manager:
+// {"subscriptions": [{"name": "Spotify", "price": "5.99", "currency": "EUR",
+// "billingPeriod": {"duration": 1, "unit": "MONTH"}, "startDate": 1709251200000,
+// "category": {"name": "Music"}, "active": true}]}
+type subscriptionsManagerParser struct{}
+
+func (subscriptionsManagerParser) Name() string{ return "subscriptions_manager" }
+
+func (subscriptionsManagerParser) Parse(r io.Reader) ([]AppRecord, error){
+	items, err := decodeJSONItems(r, "subscriptions", "subs", "items")
+	if err != nil {
+		return nil, err
+	}
+	// inactive subscriptions are exported with "active": false
+	for _, item := range items {
+		if active, ok := item["active"].(bool); ok {
+			item["cancelled"] = !active
+		}
+	}
+	return itemsToRecords(items, appFields{
+		name: []string{"name", "title"},
+		amount: []string{"price", "amount"},
+		currency: []string{"currency", "currencyCode"},
+		cycle: []string{"billingPeriod", "period", "recurrence"},
+		cycleCount: []string{"periodCount", "duration"},
+		cycleUnit: []string{"periodUnit", "unit"},
+		start: []string{"startDate", "firstPayment", "paymentDate"},
+		end: []string{"endDate", "cancellationDate"},
+		category: []string{"category", "categoryName"},
+		cancelled: []string{"cancelled"},
+		notes: []string{"notes", "description"},
+	}), nil
+}
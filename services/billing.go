@@ -0,0 +1,321 @@
+package services
+
+import (
+	"math"
+	"online-subs-api/models"
+	"online-subs-api/repo"
+	"time"
+)
+
+// The billing engine works on whole months: subs start and end on the 1st of a month,
+// the end month is still billed and a monthly/quarterly/yearly sub is charged every
+// 1/3/12 months counted from the end of its trial (or from its start date without one).
+
+// ProjectedCharge is a single expected charge of a subscription, Amount is net of discounts
+type ProjectedCharge struct{
+	SubID			string				`json:"sub_id"`
+	UserID			string				`json:"user_id"`
+	ServiceName		string				`json:"service_name"`
+	Date			time.Time			`json:"date"`
+	Gross			int					`json:"gross"`
+	Amount			int					`json:"amount"`
+	// TaxBreakdown splits Amount into net, tax and the gross that is charged
+	TaxBreakdown	repo.TaxBreakdown	`json:"tax_breakdown"`
+}
+
+func periodMonths(period string) int{
+	switch period {
+	case models.BillingYearly:
+		return 12
+	case models.BillingQuarterly:
+		return 3
+	}
+	return 1
+}
+
+func monthStart(t time.Time) time.Time{
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+func monthsBetween(from, to time.Time) int{
+	return (to.Year()-from.Year())*12 + int(to.Month()) - int(from.Month())
+}
+
+// subEndMonth returns the last billed month, ok is false for open-ended subs
+func subEndMonth(sub *models.Sub) (time.Time, bool){
+	if sub.EndDate.IsZero() || sub.EndDate.Year() <= 1 {
+		return time.Time{}, false
+	}
+	return monthStart(sub.EndDate), true
+}
+
+// billingAnchor is the month of the first charge
+func billingAnchor(sub *models.Sub) time.Time{
+	anchor := monthStart(sub.StartDate)
+	if sub.TrialEndDate != nil && monthStart(*sub.TrialEndDate).After(anchor) {
+		anchor = monthStart(*sub.TrialEndDate)
+	}
+	return anchor
+}
+
+// chargeMonths lists the months in [from, to] in which the sub is charged
+func chargeMonths(sub *models.Sub, from, to time.Time) []time.Time{
+	months := []time.Time{}
+	anchor := billingAnchor(sub)
+	step := periodMonths(sub.BillingPeriod)
+	from, to = monthStart(from), monthStart(to)
+
+	if end, ok := subEndMonth(sub); ok && end.Before(to) {
+		to = end
+	}
+	if anchor.After(to) {
+		return months
+	}
+
+	// first charge at or after from
+	first := anchor
+	if first.Before(from) {
+		skipped := (monthsBetween(anchor, from) + step - 1) / step
+		first = anchor.AddDate(0, skipped*step, 0)
+	}
+
+	for month := first; !month.After(to); month = month.AddDate(0, step, 0) {
+		months = append(months, month)
+	}
+	return months
+}
+
+// nextChargeDate returns the first charge on or after the given day, ok is false when
+// the sub has no charges left
+func nextChargeDate(sub *models.Sub, after time.Time) (time.Time, bool){
+	from := monthStart(after)
+	if after.After(from) {
+		from = from.AddDate(0, 1, 0)
+	}
+	to := from
+	if anchor := billingAnchor(sub); anchor.After(to) {
+		to = anchor
+	}
+	months := chargeMonths(sub, from, to.AddDate(0, periodMonths(sub.BillingPeriod), 0))
+	if len(months) == 0 {
+		return time.Time{}, false
+	}
+	return months[0], true
+}
+
+// unitPriceAt returns the sub's price in the given month, taking scheduled price changes
+// into account; changes must be sorted by effective date
+func unitPriceAt(sub *models.Sub, changes []models.PriceChange, month time.Time) int{
+	price := sub.Price
+	for _, change := range changes {
+		if monthStart(change.EffectiveDate).After(month) {
+			break
+		}
+		price = change.Price
+	}
+	return price
+}
+
+// seatsAt returns the seats of a seat-based sub on the given day, the seats of its first
+// change before that and 1 for subs without seat changes
+func seatsAt(sub *models.Sub, day time.Time) int{
+	if len(sub.SeatChanges) == 0 {
+		return 1
+	}
+	seats := sub.SeatChanges[0].Seats
+	for _, change := range sub.SeatChanges {
+		if change.EffectiveDate.After(day) {
+			break
+		}
+		seats = change.Seats
+	}
+	return seats
+}
+
+// usageBased is true for subs priced by recorded usage instead of their price
+func usageBased(sub *models.Sub) bool{
+	return sub.Pricing != nil && sub.Pricing.Model != models.PricingFlat
+}
+
+// usageAmount sums the priced usage of the billing periods starting in the months [from, to]
+func usageAmount(sub *models.Sub, from, to time.Time) int{
+	amount := 0
+	for _, charge := range sub.UsageCharges {
+		if month := monthStart(charge.PeriodStart); !month.Before(from) && !month.After(to) {
+			amount += charge.Amount
+		}
+	}
+	return amount
+}
+
+// chargeOrdinal numbers the charges counted from the from month (1 for the first charge at
+// or after it) and returns the number of the last charge at or before the month
+func chargeOrdinal(sub *models.Sub, from, month time.Time) int{
+	anchor := billingAnchor(sub)
+	step := periodMonths(sub.BillingPeriod)
+	last := int(math.Floor(float64(monthsBetween(anchor, monthStart(month))) / float64(step)))
+	first := max(int(math.Ceil(float64(monthsBetween(anchor, monthStart(from))) / float64(step))), 0)
+	return last - first + 1
+}
+
+// discountApplies is true when the charge of the month falls under the discount
+func discountApplies(sub *models.Sub, discount *models.Discount, month time.Time) bool{
+	start := monthStart(discount.StartDate)
+	if monthStart(month).Before(start) {
+		return false
+	}
+	if discount.Duration == models.DiscountForever {
+		return true
+	}
+	n := chargeOrdinal(sub, start, month)
+	return n >= 1 && n <= discount.Periods
+}
+
+// discountAt is the discount on a gross charge of the sub in the given month, percentage
+// discounts are taken off the gross charge and the total never exceeds it
+func discountAt(sub *models.Sub, month time.Time, gross int) int{
+	discount := 0.0
+	for i := range sub.Discounts {
+		d := &sub.Discounts[i]
+		if !discountApplies(sub, d, month) {
+			continue
+		}
+		if d.Kind == models.DiscountPercentage {
+			discount += math.Round(float64(gross) * d.Percentage / 100)
+		} else {
+			discount += float64(d.Amount)
+		}
+	}
+	return max(min(int(discount), gross), 0)
+}
+
+// adjustmentMonth is the charge a mid-period adjustment of the billing period starting in
+// periodStart is booked in: the next one, or the period's own charge when the sub ends
+// before the next
+func adjustmentMonth(sub *models.Sub, periodStart time.Time) time.Time{
+	next := periodStart.AddDate(0, periodMonths(sub.BillingPeriod), 0)
+	if end, ok := subEndMonth(sub); ok && next.After(end) {
+		return periodStart
+	}
+	return next
+}
+
+// bookedMonth is the charge an adjustment stored for the next charge (month) lands in: that
+// one, or the charge of the period before when the sub has since ended before it
+func bookedMonth(sub *models.Sub, month time.Time) time.Time{
+	month = monthStart(month)
+	if end, ok := subEndMonth(sub); ok && month.After(end) {
+		return month.AddDate(0, -periodMonths(sub.BillingPeriod), 0)
+	}
+	return month
+}
+
+// seatProration is the prorated difference of a seat change, Month is the charge it is booked in
+type seatProration struct{
+	Month		time.Time
+	Amount		int
+}
+
+// seatProrations prices the prorated seat changes that fall inside a billing period: the
+// difference to the seats before, at the period's unit price, for the rest of the period.
+// Usage-based subs are not prorated.
+func seatProrations(sub *models.Sub, changes []models.PriceChange) []seatProration{
+	prorations := []seatProration{}
+	if usageBased(sub) {
+		return prorations
+	}
+	anchor := billingAnchor(sub)
+	step := periodMonths(sub.BillingPeriod)
+	for _, change := range sub.SeatChanges {
+		day := change.EffectiveDate.UTC()
+		if !change.Prorate || day.Before(anchor) {
+			continue
+		}
+		periodStart := anchor.AddDate(0, monthsBetween(anchor, day)/step*step, 0)
+		if !day.After(periodStart) {
+			continue
+		}
+		periodEnd := periodStart.AddDate(0, step, 0)
+		before := seatsAt(sub, day.Add(-time.Nanosecond))
+		share := periodEnd.Sub(day).Hours() / periodEnd.Sub(periodStart).Hours()
+		amount := float64((change.Seats - before) * unitPriceAt(sub, changes, periodStart)) * share
+		prorations = append(prorations, seatProration{Month: adjustmentMonth(sub, periodStart), Amount: int(math.Round(amount))})
+	}
+	return prorations
+}
+
+// adjustmentsIn sums the plan change credits and charges and the seat prorations booked in
+// the months [from, to]
+func adjustmentsIn(sub *models.Sub, changes []models.PriceChange, from, to time.Time) int{
+	in := func(month time.Time) bool{
+		month = monthStart(month)
+		return !month.Before(from) && !month.After(to)
+	}
+	amount := 0
+	for _, change := range changes {
+		if change.AdjustmentMonth != nil && in(bookedMonth(sub, *change.AdjustmentMonth)) {
+			amount += change.Charge - change.Credit
+		}
+	}
+	for _, proration := range seatProrations(sub, changes) {
+		if in(proration.Month) {
+			amount += proration.Amount
+		}
+	}
+	return amount
+}
+
+// priceAt returns the charge of the sub in the given month, net of discounts
+func priceAt(sub *models.Sub, changes []models.PriceChange, month time.Time) int{
+	gross := grossPriceAt(sub, changes, month)
+	return gross - discountAt(sub, month, gross)
+}
+
+// grossPriceAt returns the charge of the sub in the given month before discounts: the unit
+// price times the seats on the 1st of the month, plus the seat prorations and plan change
+// credits and charges booked in it. For a usage-based sub the base fee and the usage of the
+// period replace the unit price and seats.
+func grossPriceAt(sub *models.Sub, changes []models.PriceChange, month time.Time) int{
+	month = monthStart(month)
+	amount := unitPriceAt(sub, changes, month) * seatsAt(sub, month)
+	if usageBased(sub) {
+		amount = sub.Pricing.BaseFee + usageAmount(sub, month, month)
+	}
+	return max(amount+adjustmentsIn(sub, changes, month, month), 0)
+}
+
+// totalCostOf is what the sub counts for in the total cost of the months [start, end], like
+// GetTotalCostRepo: one billing period at the end, its price and seats (or base fee and the
+// priced usage of that period), plus the adjustments in the range, before and after the
+// discounts at the end
+func totalCostOf(sub *models.Sub, changes []models.PriceChange, start, end time.Time) (int, int){
+	start, end = monthStart(start), monthStart(end)
+	cost := unitPriceAt(sub, changes, end) * seatsAt(sub, end)
+	if usageBased(sub) {
+		period := usagePeriodStart(sub, end)
+		cost = sub.Pricing.BaseFee + usageAmount(sub, period, period)
+	}
+	cost += adjustmentsIn(sub, changes, start, end)
+	return cost, cost - discountAt(sub, end, cost)
+}
+
+// projectCharges expands the subs into their expected charges between from and to
+func projectCharges(subs []models.Sub, changes map[string][]models.PriceChange, from, to time.Time) []ProjectedCharge{
+	charges := []ProjectedCharge{}
+	for i := range subs {
+		sub := &subs[i]
+		for _, month := range chargeMonths(sub, from, to) {
+			gross := grossPriceAt(sub, changes[sub.ID], month)
+			charges = append(charges, ProjectedCharge{
+				SubID: sub.ID,
+				UserID: sub.UserID,
+				ServiceName: sub.ServiceName,
+				Date: month,
+				Gross: gross,
+				Amount: gross - discountAt(sub, month, gross),
+			})
+		}
+	}
+	return charges
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"online-subs-api/models"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day int) time.Time{
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func monthlySub(price int) *models.Sub{
+	return &models.Sub{ID: "sub", UserID: "owner", Price: price, StartDate: date(2025, time.January, 1), BillingPeriod: models.BillingMonthly}
+}
+
+func TestChargeMonths(t *testing.T){
+	trialEnd := date(2025, time.March, 15)
+	tests := []struct{
+		name	string
+		sub		models.Sub
+		from	time.Time
+		to		time.Time
+		want	[]time.Time
+	}{
+		{
+			name: "monthly",
+			sub: models.Sub{StartDate: date(2025, time.January, 10), BillingPeriod: models.BillingMonthly},
+			from: date(2025, time.March, 1), to: date(2025, time.May, 1),
+			want: []time.Time{date(2025, time.March, 1), date(2025, time.April, 1), date(2025, time.May, 1)},
+		},
+		{
+			name: "quarterly from its start month",
+			sub: models.Sub{StartDate: date(2025, time.February, 1), BillingPeriod: models.BillingQuarterly},
+			from: date(2025, time.January, 1), to: date(2025, time.December, 1),
+			want: []time.Time{date(2025, time.February, 1), date(2025, time.May, 1), date(2025, time.August, 1), date(2025, time.November, 1)},
+		},
+		{
+			name: "yearly charged once in the range",
+			sub: models.Sub{StartDate: date(2023, time.June, 1), BillingPeriod: models.BillingYearly},
+			from: date(2025, time.January, 1), to: date(2025, time.December, 1),
+			want: []time.Time{date(2025, time.June, 1)},
+		},
+		{
+			name: "billing starts at the end of the trial",
+			sub: models.Sub{StartDate: date(2025, time.January, 1), TrialEndDate: &trialEnd, BillingPeriod: models.BillingMonthly},
+			from: date(2025, time.January, 1), to: date(2025, time.April, 1),
+			want: []time.Time{date(2025, time.March, 1), date(2025, time.April, 1)},
+		},
+		{
+			name: "no charges after the end",
+			sub: models.Sub{StartDate: date(2025, time.January, 1), EndDate: date(2025, time.April, 20), BillingPeriod: models.BillingMonthly},
+			from: date(2025, time.March, 1), to: date(2025, time.June, 1),
+			want: []time.Time{date(2025, time.March, 1), date(2025, time.April, 1)},
+		},
+		{
+			name: "starts after the range",
+			sub: models.Sub{StartDate: date(2025, time.July, 1), BillingPeriod: models.BillingMonthly},
+			from: date(2025, time.January, 1), to: date(2025, time.June, 1),
+			want: []time.Time{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T){
+			if got := chargeMonths(&tt.sub, tt.from, tt.to); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("chargeMonths() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChargeOrdinal(t *testing.T){
+	monthly := models.Sub{StartDate: date(2025, time.January, 1), BillingPeriod: models.BillingMonthly}
+	quarterly := models.Sub{StartDate: date(2025, time.January, 1), BillingPeriod: models.BillingQuarterly}
+	tests := []struct{
+		name	string
+		sub		models.Sub
+		from	time.Time
+		month	time.Time
+		want	int
+	}{
+		{"first charge", monthly, date(2025, time.March, 1), date(2025, time.March, 1), 1},
+		{"third charge", monthly, date(2025, time.March, 1), date(2025, time.May, 1), 3},
+		{"before from", monthly, date(2025, time.March, 1), date(2025, time.February, 1), 0},
+		{"from before the anchor", monthly, date(2024, time.June, 1), date(2025, time.January, 1), 1},
+		{"quarterly from mid period", quarterly, date(2025, time.February, 1), date(2025, time.April, 1), 1},
+		{"quarterly month between charges", quarterly, date(2025, time.February, 1), date(2025, time.June, 1), 1},
+		{"quarterly second charge", quarterly, date(2025, time.February, 1), date(2025, time.July, 1), 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T){
+			if got := chargeOrdinal(&tt.sub, tt.from, tt.month); got != tt.want {
+				t.Errorf("chargeOrdinal() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
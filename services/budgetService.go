@@ -0,0 +1,262 @@
+package services
+
+import (
+	"errors"
+	"online-subs-api/models"
+	"online-subs-api/repo"
+	"online-subs-api/utils"
+	"sync"
+	"time"
+)
+
+// budgetThresholds are the percentages of a budget that raise an alert once per month
+var budgetThresholds = []int{80, 100}
+
+type BudgetService struct{
+	budgetRepo *repo.BudgetRepo
+	subsRepo *repo.SubsRepo
+	// serializes evaluations so concurrent mutations don't race on the same alert
+	mu sync.Mutex
+}
+
+func NewBudgetService(budgetRepo *repo.BudgetRepo, subsRepo *repo.SubsRepo) *BudgetService{
+	return &BudgetService{budgetRepo: budgetRepo, subsRepo: subsRepo}
+}
+
+// BudgetStatus is a budget with its spend in the current month
+type BudgetStatus struct{
+	models.Budget
+	Period			string		`json:"period"`
+	Spend			int			`json:"spend"`
+	Percent			int			`json:"percent"`
+}
+
+func validateBudget(budget *models.Budget) error{
+	switch budget.Scope {
+	case models.BudgetScopeUser:
+		if !validateUUID(budget.ScopeID) {
+			return errors.New("invalid user_id format in scope_id")
+		}
+	case models.BudgetScopeTenant:
+		if !validateTenantID(budget.ScopeID) {
+			return errors.New("invalid tenant_id format in scope_id")
+		}
+	case models.BudgetScopeCategory:
+		budget.ScopeID = normalizeLabel(budget.ScopeID)
+		if budget.ScopeID == "" {
+			return errors.New("category must not be empty")
+		}
+	default:
+		return errors.New("scope must be user, category or tenant")
+	}
+	if budget.Amount <= 0 {
+		return errors.New("amount must be a postive integer")
+	}
+	return nil
+}
+
+func budgetFilter(budget *models.Budget) repo.SubsFilter{
+	switch budget.Scope {
+	case models.BudgetScopeCategory:
+		return repo.SubsFilter{Category: budget.ScopeID}
+	case models.BudgetScopeTenant:
+		return repo.SubsFilter{TenantID: budget.ScopeID}
+	}
+	return repo.SubsFilter{UserID: budget.ScopeID}
+}
+
+// monthSpend is what the budget's subs cost in the month after discounts, the same sum
+// the total cost returns for a single month range: a user's budget counts their share of
+// the subs they own or are a member of
+func (s *BudgetService) monthSpend(budget *models.Budget, month time.Time) (int, error){
+	if budget.Scope != models.BudgetScopeUser {
+		total, err := s.subsRepo.GetTotalCostRepo(month, month, budgetFilter(budget))
+		return total.Net, err
+	}
+	_, costs, err := userShareCosts(s.subsRepo, month, month, budgetFilter(budget))
+	if err != nil {
+		return 0, err
+	}
+	spend := 0
+	for _, cost := range costs {
+		spend += cost.Net
+	}
+	return spend, nil
+}
+
+func (s *BudgetService) status(budget models.Budget, month time.Time) (*BudgetStatus, error){
+	spend, err := s.monthSpend(&budget, month)
+	if err != nil {
+		return nil, err
+	}
+	return &BudgetStatus{Budget: budget, Period: month.Format("2006-01"), Spend: spend, Percent: spend * 100 / budget.Amount}, nil
+}
+
+// evaluate raises the alerts for every threshold the month's spend has reached, the
+// unique (budget, period, threshold) index makes each of them fire once
+func (s *BudgetService) evaluate(budget models.Budget, month time.Time) error{
+	status, err := s.status(budget, month)
+	if err != nil {
+		return err
+	}
+
+	for _, threshold := range budgetThresholds {
+		if status.Spend*100 < budget.Amount*threshold {
+			continue
+		}
+		id, err := utils.NewUUID()
+		if err != nil {
+			return err
+		}
+		alert := models.BudgetAlert{
+			ID: id,
+			BudgetID: budget.ID,
+			Period: status.Period,
+			Threshold: threshold,
+			Scope: budget.Scope,
+			ScopeID: budget.ScopeID,
+			Spend: status.Spend,
+			Amount: budget.Amount,
+		}
+		created, err := s.budgetRepo.CreateAlertRepo(&alert)
+		if err != nil {
+			return err
+		}
+		if created {
+			utils.WarningLogger.Printf("Budget %s (%s %s) reached %d%%: %d of %d in %s", budget.ID, budget.Scope, budget.ScopeID, threshold, status.Spend, budget.Amount, status.Period)
+		}
+	}
+	return nil
+}
+
+func (s *BudgetService) evaluateAll(budgets []models.Budget, month time.Time){
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, budget := range budgets {
+		if err := s.evaluate(budget, month); err != nil {
+			utils.ErrorLogger.Println("Failed to evaluate budget:", budget.ID, "error:", err)
+		}
+	}
+}
+
+// EvaluateBudgetsService checks every budget against the current month
+func (s *BudgetService) EvaluateBudgetsService() error{
+	budgets, err := s.budgetRepo.ListBudgetsRepo("")
+	if err != nil {
+		utils.ErrorLogger.Println("Failed to list budgets:", err)
+		return err
+	}
+	s.evaluateAll(budgets, monthStart(time.Now()))
+	return nil
+}
+
+// OnSubEvent re-evaluates the budgets a created or updated sub counts towards, deletes
+// only lower the spend
+func (s *BudgetService) OnSubEvent(event SubEvent){
+	if event.Type == EventSubDeleted {
+		return
+	}
+	budgets, err := s.budgetRepo.ListBudgetsForSubRepo(&event.Sub)
+	if err != nil {
+		utils.ErrorLogger.Println("Failed to list budgets for sub:", event.Sub.ID, "error:", err)
+		return
+	}
+	s.evaluateAll(budgets, monthStart(event.At))
+}
+
+// StartDailyEvaluation evaluates all budgets now and then once a day in the background
+func (s *BudgetService) StartDailyEvaluation(){
+	go func(){
+		for {
+			if err := s.EvaluateBudgetsService(); err != nil {
+				utils.ErrorLogger.Println("Daily budget evaluation failed:", err)
+			}
+			now := time.Now().UTC()
+			next := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+			time.Sleep(time.Until(next))
+		}
+	}()
+}
+
+func (s *BudgetService) CreateBudgetService(budget *models.Budget) error{
+	if err := validateBudget(budget); err != nil {
+		utils.ErrorLogger.Println("Invalid budget:", err)
+		return err
+	}
+	id, err := utils.NewUUID()
+	if err != nil {
+		utils.ErrorLogger.Println("Failed to generate UUID:", err)
+		return err
+	}
+	budget.ID = id
+	if err := s.budgetRepo.CreateBudgetRepo(budget); err != nil {
+		return err
+	}
+	s.evaluateAll([]models.Budget{*budget}, monthStart(time.Now()))
+	return nil
+}
+
+func (s *BudgetService) GetBudgetService(id string) (*BudgetStatus, error){
+	if !validateUUID(id) {
+		utils.ErrorLogger.Println("Invalid ID format:", id)
+		return nil, errors.New("invalid id format")
+	}
+	budget, err := s.budgetRepo.GetBudgetRepo(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.status(*budget, monthStart(time.Now()))
+}
+
+func (s *BudgetService) ListBudgetsService(scope string) ([]BudgetStatus, error){
+	budgets, err := s.budgetRepo.ListBudgetsRepo(scope)
+	if err != nil {
+		return nil, err
+	}
+	month := monthStart(time.Now())
+	statuses := []BudgetStatus{}
+	for _, budget := range budgets {
+		status, err := s.status(budget, month)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, *status)
+	}
+	return statuses, nil
+}
+
+// UpdateBudgetService changes the amount, scope and scope_id are fixed
+func (s *BudgetService) UpdateBudgetService(id string, amount int) (*models.Budget, error){
+	if !validateUUID(id) {
+		utils.ErrorLogger.Println("Invalid ID format:", id)
+		return nil, errors.New("invalid id format")
+	}
+	if amount <= 0 {
+		return nil, errors.New("amount must be a postive integer")
+	}
+	budget, err := s.budgetRepo.GetBudgetRepo(id)
+	if err != nil {
+		return nil, err
+	}
+	budget.Amount = amount
+	if err := s.budgetRepo.UpdateBudgetRepo(budget); err != nil {
+		return nil, err
+	}
+	s.evaluateAll([]models.Budget{*budget}, monthStart(time.Now()))
+	return budget, nil
+}
+
+func (s *BudgetService) DeleteBudgetService(id string) error{
+	if !validateUUID(id) {
+		utils.ErrorLogger.Println("Invalid ID format:", id)
+		return errors.New("invalid id format")
+	}
+	return s.budgetRepo.DeleteBudgetRepo(id)
+}
+
+func (s *BudgetService) ListAlertsService(budgetID, period string) ([]models.BudgetAlert, error){
+	if budgetID != "" && !validateUUID(budgetID) {
+		return nil, errors.New("invalid budget_id format")
+	}
+	return s.budgetRepo.ListAlertsRepo(budgetID, period)
+}
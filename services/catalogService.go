@@ -0,0 +1,178 @@
+package services
+
+import (
+	"errors"
+	"online-subs-api/models"
+	"online-subs-api/repo"
+	"online-subs-api/utils"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+type CatalogService struct{
+	catalogRepo *repo.CatalogRepo
+	resolver *NameResolver
+}
+
+func NewCatalogService(catalogRepo *repo.CatalogRepo, resolver *NameResolver) *CatalogService{
+	return &CatalogService{catalogRepo: catalogRepo, resolver: resolver}
+}
+
+// refresh invalidates the alias dictionary once a catalog change succeeded
+func (s *CatalogService) refresh(err error) error{
+	if err == nil {
+		s.resolver.Invalidate()
+	}
+	return err
+}
+
+func validBillingPeriod(period string) bool{
+	switch period {
+	case models.BillingMonthly, models.BillingQuarterly, models.BillingYearly:
+		return true
+	}
+	return false
+}
+
+func cleanAliases(aliases models.StringList) models.StringList{
+	cleaned := models.StringList{}
+	seen := map[string]bool{}
+	for _, a := range aliases {
+		a = strings.TrimSpace(a)
+		key := strings.ToLower(a)
+		if a == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		cleaned = append(cleaned, a)
+	}
+	return cleaned
+}
+
+func (s *CatalogService) validateProvider(provider *models.Provider) error{
+	provider.Name = strings.TrimSpace(provider.Name)
+	if provider.Name == "" {
+		utils.ErrorLogger.Println("Missing provider name")
+		return errors.New("provider name is required")
+	}
+	provider.Category = normalizeLabel(provider.Category)
+	provider.Aliases = cleanAliases(provider.Aliases)
+	return nil
+}
+
+func (s *CatalogService) validatePlan(plan *models.Plan) error{
+	if !validateUUID(plan.ProviderID) {
+		utils.ErrorLogger.Println("Invalid provider_id format:", plan.ProviderID)
+		return errors.New("invalid provider_id format")
+	}
+	if _, err := s.catalogRepo.GetProviderRepoById(plan.ProviderID); err != nil {
+		utils.ErrorLogger.Println("Provider not found:", plan.ProviderID, "error:", err)
+		return errors.New("provider not found")
+	}
+
+	plan.Name = strings.TrimSpace(plan.Name)
+	if plan.Name == "" {
+		utils.ErrorLogger.Println("Missing plan name")
+		return errors.New("plan name is required")
+	}
+	if plan.Price <= 0 {
+		utils.ErrorLogger.Println("Invalid plan price provided:", plan.Price)
+		return errors.New("price must be a postive integer")
+	}
+	if plan.BillingPeriod == "" {
+		plan.BillingPeriod = models.BillingMonthly
+	}
+	if !validBillingPeriod(plan.BillingPeriod) {
+		utils.ErrorLogger.Println("Invalid billing period:", plan.BillingPeriod)
+		return errors.New("billing_period must be one of monthly, quarterly, yearly")
+	}
+	plan.Aliases = cleanAliases(plan.Aliases)
+	return nil
+}
+
+func (s *CatalogService) CreateProviderService(provider *models.Provider) error{
+	if err := s.validateProvider(provider); err != nil {
+		return err
+	}
+
+	id, err := utils.NewUUID()
+	if err != nil {
+		utils.ErrorLogger.Println("Failed to generate UUID:", err)
+		return err
+	}
+	provider.ID = id
+	return s.refresh(s.catalogRepo.CreateProviderRepo(provider))
+}
+
+func (s *CatalogService) GetProviderService(id string) (*models.Provider, error){
+	if !validateUUID(id) {
+		utils.ErrorLogger.Println("Invalid ID format:", id)
+		return nil, errors.New("invalid id format")
+	}
+	return s.catalogRepo.GetProviderRepoById(id)
+}
+
+func (s *CatalogService) ListProvidersService(category string) ([]models.Provider, error){
+	return s.catalogRepo.ListProvidersRepo(category)
+}
+
+func (s *CatalogService) UpdateProviderService(provider *models.Provider) error{
+	if !validateUUID(provider.ID) {
+		utils.ErrorLogger.Println("Invalid ID format:", provider.ID)
+		return errors.New("invalid id format")
+	}
+	if err := s.validateProvider(provider); err != nil {
+		return err
+	}
+	err := s.catalogRepo.UpdateProviderRepo(provider)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return errors.New("provider not found")
+	}
+	return s.refresh(err)
+}
+
+func (s *CatalogService) DeleteProviderService(id string) error{
+	if !validateUUID(id) {
+		utils.ErrorLogger.Println("Invalid ID format:", id)
+		return errors.New("invalid id format")
+	}
+	return s.refresh(s.catalogRepo.DeleteProviderRepo(id))
+}
+
+func (s *CatalogService) CreatePlanService(plan *models.Plan) error{
+	if err := s.validatePlan(plan); err != nil {
+		return err
+	}
+
+	id, err := utils.NewUUID()
+	if err != nil {
+		utils.ErrorLogger.Println("Failed to generate UUID:", err)
+		return err
+	}
+	plan.ID = id
+	return s.refresh(s.catalogRepo.CreatePlanRepo(plan))
+}
+
+func (s *CatalogService) UpdatePlanService(plan *models.Plan) error{
+	if !validateUUID(plan.ID) {
+		utils.ErrorLogger.Println("Invalid ID format:", plan.ID)
+		return errors.New("invalid id format")
+	}
+	if err := s.validatePlan(plan); err != nil {
+		return err
+	}
+	err := s.catalogRepo.UpdatePlanRepo(plan)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return errors.New("plan not found")
+	}
+	return s.refresh(err)
+}
+
+func (s *CatalogService) DeletePlanService(id string) error{
+	if !validateUUID(id) {
+		utils.ErrorLogger.Println("Invalid ID format:", id)
+		return errors.New("invalid id format")
+	}
+	return s.refresh(s.catalogRepo.DeletePlanRepo(id))
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"errors"
+	"online-subs-api/models"
+	"online-subs-api/utils"
+	"strings"
+	"time"
+)
+
+// validateDiscount checks the kind, value and duration of a discount and normalizes Periods,
+// a one-time discount covers a single charge
+func validateDiscount(discount *models.Discount) error{
+	discount.Code = strings.TrimSpace(discount.Code)
+	if len(discount.Code) > 50 {
+		return errors.New("code must be at most 50 characters")
+	}
+
+	switch discount.Kind {
+	case models.DiscountPercentage:
+		if discount.Percentage <= 0 || discount.Percentage > 100 || discount.Amount != 0 {
+			return errors.New("a percentage discount needs a percentage between 0 and 100 and no amount")
+		}
+	case models.DiscountFixed:
+		if discount.Amount <= 0 || discount.Percentage != 0 {
+			return errors.New("a fixed discount needs a postive amount and no percentage")
+		}
+	default:
+		return errors.New("kind must be percentage or fixed")
+	}
+
+	switch discount.Duration {
+	case models.DiscountOnce:
+		discount.Periods = 1
+	case models.DiscountRepeating:
+		if discount.Periods < 1 {
+			return errors.New("a repeating discount needs a postive number of periods")
+		}
+	case models.DiscountForever:
+		discount.Periods = 0
+	default:
+		return errors.New("duration must be once, repeating or forever")
+	}
+	return nil
+}
+
+// CreateDiscountService attaches a discount to the sub from the charge of the given month
+// (MM-YYYY) on, the current month when empty
+func (s *SubsService) CreateDiscountService(discount *models.Discount, startDateStr string) error{
+	if !validateUUID(discount.SubID) {
+		utils.ErrorLogger.Println("Invalid sub_id format:", discount.SubID)
+		return errors.New("invalid sub_id format")
+	}
+	sub, err := s.subsRepo.GetSubRepoById(discount.SubID)
+	if err != nil {
+		utils.ErrorLogger.Println("Subscription not found:", discount.SubID, "error:", err)
+		return errors.New("subscription not found")
+	}
+	if err := validateDiscount(discount); err != nil {
+		utils.ErrorLogger.Println("Invalid discount for sub", discount.SubID, "error:", err)
+		return err
+	}
+
+	discount.StartDate = monthStart(time.Now())
+	if startDateStr != "" {
+		startDate, err := validDate(startDateStr)
+		if err != nil {
+			utils.ErrorLogger.Println("Invalid start date:", startDateStr, "error:", err)
+			return err
+		}
+		discount.StartDate = startDate
+	}
+
+	id, err := utils.NewUUID()
+	if err != nil {
+		utils.ErrorLogger.Println("Failed to generate UUID:", err)
+		return err
+	}
+	discount.ID = id
+	if err := s.subsRepo.CreateDiscountRepo(discount); err != nil {
+		utils.ErrorLogger.Println("Failed to store discount of sub:", discount.SubID, "error:", err)
+		return err
+	}
+	s.emit(EventSubUpdated, sub)
+	return nil
+}
+
+func (s *SubsService) ListDiscountsService(subID string) ([]models.Discount, error){
+	if !validateUUID(subID) {
+		utils.ErrorLogger.Println("Invalid sub_id format:", subID)
+		return nil, errors.New("invalid sub_id format")
+	}
+	return s.subsRepo.ListDiscountsRepo(subID)
+}
+
+func (s *SubsService) DeleteDiscountService(subID, id string) error{
+	if !validateUUID(subID) || !validateUUID(id) {
+		utils.ErrorLogger.Println("Invalid ID format:", subID, id)
+		return errors.New("invalid id format")
+	}
+	if err := s.subsRepo.DeleteDiscountRepo(subID, id); err != nil {
+		return errors.New("discount not found")
+	}
+	if sub, err := s.subsRepo.GetSubRepoById(subID); err == nil {
+		s.emit(EventSubUpdated, sub)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package services
+
+import (
+	"online-subs-api/models"
+	"testing"
+	"time"
+)
+
+func TestDiscountAt(t *testing.T){
+	percentage := func(p float64, duration string, periods int, start time.Time) models.Discount{
+		return models.Discount{Kind: models.DiscountPercentage, Percentage: p, Duration: duration, Periods: periods, StartDate: start}
+	}
+	fixed := func(amount int, duration string, periods int, start time.Time) models.Discount{
+		return models.Discount{Kind: models.DiscountFixed, Amount: amount, Duration: duration, Periods: periods, StartDate: start}
+	}
+	tests := []struct{
+		name		string
+		period		string
+		discounts	[]models.Discount
+		month		time.Time
+		gross		int
+		want		int
+	}{
+		{"percentage forever", models.BillingMonthly, []models.Discount{percentage(20, models.DiscountForever, 0, date(2025, time.January, 1))}, date(2025, time.March, 1), 1000, 200},
+		{"before the start", models.BillingMonthly, []models.Discount{fixed(300, models.DiscountForever, 0, date(2025, time.February, 1))}, date(2025, time.January, 1), 1000, 0},
+		{"repeating first period", models.BillingMonthly, []models.Discount{fixed(300, models.DiscountRepeating, 2, date(2025, time.February, 1))}, date(2025, time.February, 1), 1000, 300},
+		{"repeating last period", models.BillingMonthly, []models.Discount{fixed(300, models.DiscountRepeating, 2, date(2025, time.February, 1))}, date(2025, time.March, 1), 1000, 300},
+		{"repeating over", models.BillingMonthly, []models.Discount{fixed(300, models.DiscountRepeating, 2, date(2025, time.February, 1))}, date(2025, time.April, 1), 1000, 0},
+		{"once", models.BillingMonthly, []models.Discount{percentage(50, models.DiscountOnce, 1, date(2025, time.March, 1))}, date(2025, time.March, 1), 999, 500},
+		{"never more than the charge", models.BillingMonthly, []models.Discount{fixed(1500, models.DiscountForever, 0, date(2025, time.January, 1))}, date(2025, time.March, 1), 1000, 1000},
+		{"stacked", models.BillingMonthly, []models.Discount{
+			percentage(10, models.DiscountForever, 0, date(2025, time.January, 1)),
+			fixed(100, models.DiscountForever, 0, date(2025, time.January, 1)),
+		}, date(2025, time.March, 1), 1000, 200},
+		{"quarterly counts the next charge", models.BillingQuarterly, []models.Discount{fixed(300, models.DiscountOnce, 1, date(2025, time.February, 1))}, date(2025, time.April, 1), 3000, 300},
+		{"quarterly not the charge before", models.BillingQuarterly, []models.Discount{fixed(300, models.DiscountOnce, 1, date(2025, time.February, 1))}, date(2025, time.March, 1), 3000, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T){
+			sub := &models.Sub{StartDate: date(2025, time.January, 1), BillingPeriod: tt.period, Discounts: tt.discounts}
+			if got := discountAt(sub, tt.month, tt.gross); got != tt.want {
+				t.Errorf("discountAt() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"online-subs-api/models"
+	"online-subs-api/utils"
+	"time"
+)
+
+const (
+	EventSubCreated = models.EventSubCreated
+	EventSubUpdated = models.EventSubUpdated
+	EventSubDeleted = models.EventSubDeleted
+	// lifecycle events, detected by the webhook scheduler on the charge and end dates
+	EventSubRenewed = "subscription.renewed"
+	EventSubEnded   = "subscription.ended"
+	// written to the outbox when a budget crosses a threshold
+	EventBudgetAlert = models.EventBudgetAlert
+)
+
+var eventTypes = []string{EventSubCreated, EventSubUpdated, EventSubDeleted, EventSubRenewed, EventSubEnded, EventBudgetAlert}
+
+// SubEvent is passed to the listeners once a subscription mutation is stored
+type SubEvent struct{
+	Type			string
+	Sub				models.Sub
+	At				time.Time
+}
+
+type SubListener func(event SubEvent)
+
+// AddListener registers a callback for subscription mutations, listeners are added at
+// startup and run in registration order on the request goroutine
+func (s *SubsService) AddListener(listener SubListener){
+	s.listeners = append(s.listeners, listener)
+}
+
+func (s *SubsService) emit(eventType string, sub *models.Sub){
+	event := SubEvent{Type: eventType, Sub: *sub, At: time.Now().UTC()}
+	for _, listener := range s.listeners {
+		func(){
+			// a failing listener must not fail the mutation that was already stored
+			defer func(){
+				if r := recover(); r != nil {
+					utils.ErrorLogger.Println("Subscription listener panicked on", eventType, "error:", r)
+				}
+			}()
+			listener(event)
+		}()
+	}
+}
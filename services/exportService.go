@@ -0,0 +1,149 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"online-subs-api/models"
+	"online-subs-api/repo"
+	"online-subs-api/utils"
+	"slices"
+	"strings"
+	"time"
+)
+
+// exportColumns are the columns that can be selected for an export, besides "metadata.<key>"
+var exportColumns = map[string]func(sub *models.Sub) interface{}{
+	"id": func(sub *models.Sub) interface{}{ return sub.ID },
+	"service_name": func(sub *models.Sub) interface{}{ return sub.ServiceName },
+	"service_name_input": func(sub *models.Sub) interface{}{ return sub.ServiceNameInput },
+	"name_confidence": func(sub *models.Sub) interface{}{ return sub.NameConfidence },
+	"price": func(sub *models.Sub) interface{}{ return sub.Price },
+	"billing_period": func(sub *models.Sub) interface{}{ return sub.BillingPeriod },
+	"user_id": func(sub *models.Sub) interface{}{ return sub.UserID },
+	"start_date": func(sub *models.Sub) interface{}{ return exportDate(&sub.StartDate) },
+	"end_date": func(sub *models.Sub) interface{}{
+		if _, ok := subEndMonth(sub); !ok {
+			return nil
+		}
+		return exportDate(&sub.EndDate)
+	},
+	"trial_end_date": func(sub *models.Sub) interface{}{ return exportDate(sub.TrialEndDate) },
+	"next_charge_date": func(sub *models.Sub) interface{}{ return exportDate(sub.NextChargeDate) },
+	"category": func(sub *models.Sub) interface{}{ return sub.Category },
+	"tags": func(sub *models.Sub) interface{}{
+		names := []string{}
+		for _, tag := range sub.Tags {
+			names = append(names, tag.Name)
+		}
+		return strings.Join(names, ";")
+	},
+	"tenant_id": func(sub *models.Sub) interface{}{ return sub.TenantID },
+	"provider_id": func(sub *models.Sub) interface{}{ return exportOptional(sub.ProviderID) },
+	"plan_id": func(sub *models.Sub) interface{}{ return exportOptional(sub.PlanID) },
+	"metadata": func(sub *models.Sub) interface{}{
+		if len(sub.Metadata) == 0 {
+			return nil
+		}
+		b, _ := json.Marshal(sub.Metadata)
+		return string(b)
+	},
+}
+
+// DefaultExportColumns are exported when no columns are selected, in this order
+var DefaultExportColumns = []string{
+	"id", "service_name", "price", "billing_period", "user_id", "start_date", "end_date", "trial_end_date",
+	"next_charge_date", "category", "tags", "tenant_id", "provider_id", "plan_id",
+}
+
+func exportDate(t *time.Time) interface{}{
+	if t == nil {
+		return nil
+	}
+	return t.Format("2006-01-02")
+}
+
+func exportOptional(value *string) interface{}{
+	if value == nil {
+		return nil
+	}
+	return *value
+}
+
+// metadataColumn exports a single metadata value, numbers stay numbers and nested
+// values are written as JSON
+func metadataColumn(key string) func(sub *models.Sub) interface{}{
+	return func(sub *models.Sub) interface{}{
+		switch v := sub.Metadata[key].(type) {
+		case nil:
+			return nil
+		case string, float64:
+			return v
+		case bool:
+			return fmt.Sprint(v)
+		default:
+			b, _ := json.Marshal(v)
+			return string(b)
+		}
+	}
+}
+
+// SubsExport is a validated export, Write streams it to a table writer
+type SubsExport struct{
+	subsRepo	*repo.SubsRepo
+	filter		repo.SubsFilter
+	columns		[]string
+	values		[]func(sub *models.Sub) interface{}
+}
+
+// PrepareExportService checks the filters and the comma separated column list before
+// anything is written, so errors can still be reported with a status code
+func (s *SubsService) PrepareExportService(filter repo.SubsFilter, columnsSpec string) (*SubsExport, error){
+	if err := validateFilter(filter); err != nil {
+		return nil, err
+	}
+
+	export := &SubsExport{subsRepo: s.subsRepo, filter: filter}
+	columns := DefaultExportColumns
+	if strings.TrimSpace(columnsSpec) != "" {
+		columns = strings.Split(columnsSpec, ",")
+	}
+	for _, column := range columns {
+		column = strings.TrimSpace(column)
+		value, ok := exportColumns[column]
+		if key, isMeta := strings.CutPrefix(column, "metadata."); isMeta && key != "" {
+			value, ok = metadataColumn(key), true
+		}
+		if !ok {
+			utils.ErrorLogger.Println("Unknown export column:", column)
+			return nil, fmt.Errorf("unknown column %q", column)
+		}
+		if slices.Contains(export.columns, column) {
+			continue
+		}
+		export.columns = append(export.columns, column)
+		export.values = append(export.values, value)
+	}
+	return export, nil
+}
+
+// Write streams the header and one row per sub and returns the number of rows written
+func (e *SubsExport) Write(w utils.TableWriter) (int, error){
+	if err := w.WriteHeader(e.columns); err != nil {
+		return 0, err
+	}
+	count := 0
+	now := time.Now()
+	row := make([]interface{}, len(e.columns))
+	err := e.subsRepo.EachSubRepo(e.filter, func(sub *models.Sub) error{
+		setNextChargeDate(sub, now)
+		for i, value := range e.values {
+			row[i] = value(sub)
+		}
+		count++
+		return w.WriteRow(row)
+	})
+	if err != nil {
+		return count, err
+	}
+	return count, w.Close()
+}
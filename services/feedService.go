@@ -0,0 +1,231 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"online-subs-api/models"
+	"online-subs-api/repo"
+	"online-subs-api/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type FeedService struct{
+	feedRepo *repo.FeedRepo
+	subsRepo *repo.SubsRepo
+}
+
+func NewFeedService(feedRepo *repo.FeedRepo, subsRepo *repo.SubsRepo) *FeedService{
+	return &FeedService{feedRepo: feedRepo, subsRepo: subsRepo}
+}
+
+// FeedToken is returned on create and rotate, the only times the token is visible
+type FeedToken struct{
+	Feed			*models.CalendarFeed	`json:"feed"`
+	Token			string					`json:"token"`
+	URL				string					`json:"url"`
+}
+
+func validateFeedScope(scope, scopeID string) error{
+	switch scope {
+	case models.FeedScopeUser:
+		if !validateUUID(scopeID) {
+			return errors.New("invalid user_id format")
+		}
+	case models.FeedScopeTenant:
+		if !validateTenantID(scopeID) {
+			return errors.New("invalid tenant_id format")
+		}
+	default:
+		return errors.New("scope must be user or tenant")
+	}
+	return nil
+}
+
+func newFeedToken(feed *models.CalendarFeed) (*FeedToken, error){
+	token, err := utils.NewToken()
+	if err != nil {
+		utils.ErrorLogger.Println("Failed to generate feed token:", err)
+		return nil, err
+	}
+	feed.TokenHash = utils.HashToken(token)
+	feed.RotatedAt = time.Now().UTC()
+	return &FeedToken{Feed: feed, Token: token, URL: "/feeds/calendar.ics?token=" + token}, nil
+}
+
+func (s *FeedService) CreateFeedService(scope, scopeID string) (*FeedToken, error){
+	if err := validateFeedScope(scope, scopeID); err != nil {
+		utils.ErrorLogger.Println("Invalid feed scope:", scope, scopeID, "error:", err)
+		return nil, err
+	}
+	if _, err := s.feedRepo.GetFeedRepo(scope, scopeID); err == nil {
+		return nil, errors.New("feed already exists, rotate it to get a new token")
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	id, err := utils.NewUUID()
+	if err != nil {
+		utils.ErrorLogger.Println("Failed to generate UUID:", err)
+		return nil, err
+	}
+	feed := &models.CalendarFeed{ID: id, Scope: scope, ScopeID: scopeID}
+	result, err := newFeedToken(feed)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.feedRepo.CreateFeedRepo(feed); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// RotateFeedService issues a new token, calendars subscribed with the old URL stop syncing
+func (s *FeedService) RotateFeedService(scope, scopeID string) (*FeedToken, error){
+	if err := validateFeedScope(scope, scopeID); err != nil {
+		return nil, err
+	}
+	feed, err := s.feedRepo.GetFeedRepo(scope, scopeID)
+	if err != nil {
+		utils.ErrorLogger.Println("Feed not found:", scope, scopeID, "error:", err)
+		return nil, errors.New("feed not found")
+	}
+	result, err := newFeedToken(feed)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.feedRepo.RotateFeedRepo(feed); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *FeedService) DeleteFeedService(scope, scopeID string) error{
+	if err := validateFeedScope(scope, scopeID); err != nil {
+		return err
+	}
+	return s.feedRepo.DeleteFeedRepo(scope, scopeID)
+}
+
+// FeedCalendarService renders the feed the token belongs to
+func (s *FeedService) FeedCalendarService(token string) (*utils.ICalendar, error){
+	feed, err := s.feedRepo.GetFeedByTokenRepo(utils.HashToken(token))
+	if err != nil {
+		return nil, errors.New("feed not found")
+	}
+
+	filter := repo.SubsFilter{UserID: feed.ScopeID}
+	if feed.Scope == models.FeedScopeTenant {
+		filter = repo.SubsFilter{TenantID: feed.ScopeID}
+	}
+	subs, err := s.subsRepo.ListAllSubsRepo(filter)
+	if err != nil {
+		utils.ErrorLogger.Println("Failed to load subscriptions for feed:", feed.ID, "error:", err)
+		return nil, err
+	}
+	ids := []string{}
+	for _, sub := range subs {
+		ids = append(ids, sub.ID)
+	}
+	changes, err := s.subsRepo.ListPriceChangesBySubRepo(ids)
+	if err != nil {
+		utils.ErrorLogger.Println("Failed to load price changes for feed:", feed.ID, "error:", err)
+		return nil, err
+	}
+	rates, err := s.subsRepo.ListTaxRatesRepo()
+	if err != nil {
+		utils.ErrorLogger.Println("Failed to load tax rates for feed:", feed.ID, "error:", err)
+		return nil, err
+	}
+	byJurisdiction := ratesByJurisdiction(rates)
+
+	calendar := &utils.ICalendar{Name: fmt.Sprintf("Subscriptions (%s %s)", feed.Scope, feed.ScopeID)}
+	for i := range subs {
+		calendar.Events = append(calendar.Events, subCalendarEvents(&subs[i], changes[subs[i].ID], byJurisdiction)...)
+	}
+	return calendar, nil
+}
+
+func billingRRule(sub *models.Sub) string{
+	rule := fmt.Sprintf("FREQ=MONTHLY;INTERVAL=%d", periodMonths(sub.BillingPeriod))
+	if sub.BillingPeriod == models.BillingYearly {
+		rule = "FREQ=YEARLY"
+	}
+	return rule
+}
+
+// subCalendarEvents describes a sub as recurring renewal events, one series per price
+// segment, plus one-off trial end and end events. UIDs only depend on the sub id and the
+// segment start, so edits of the sub update the events in place. Amounts include tax.
+func subCalendarEvents(sub *models.Sub, changes []models.PriceChange, rates map[string][]models.TaxRate) []utils.ICalEvent{
+	events := []utils.ICalEvent{}
+	uid := func(kind string) string{
+		return fmt.Sprintf("sub-%s-%s@online-subs-api", sub.ID, kind)
+	}
+	end, ended := subEndMonth(sub)
+
+	// a new series starts with every price change after the first charge
+	starts := []time.Time{billingAnchor(sub)}
+	for _, change := range changes {
+		if month := monthStart(change.EffectiveDate); month.After(starts[len(starts)-1]) {
+			starts = append(starts, month)
+		}
+	}
+
+	for i, start := range starts {
+		first, ok := nextChargeDate(sub, start)
+		if !ok || (ended && first.After(end)) {
+			break
+		}
+		last, bounded := time.Time{}, ended
+		if ended {
+			last = end
+		}
+		if i+1 < len(starts) {
+			next := starts[i+1].AddDate(0, -1, 0)
+			if !bounded || next.Before(last) {
+				last, bounded = next, true
+			}
+		}
+		if bounded && first.After(last) {
+			continue
+		}
+
+		rule := billingRRule(sub)
+		if bounded {
+			months := chargeMonths(sub, first, last)
+			rule += ";UNTIL=" + months[len(months)-1].Format("20060102")
+		}
+		kind := "billing"
+		if i > 0 {
+			kind = "billing-" + start.Format("200601")
+		}
+		price := chargedAt(sub, changes, rates, first)
+		events = append(events, utils.ICalEvent{
+			UID: uid(kind),
+			Summary: fmt.Sprintf("%s renewal: %d", sub.ServiceName, price),
+			Description: fmt.Sprintf("%s (%s) is charged %d", sub.ServiceName, sub.BillingPeriod, price),
+			Date: first,
+			RRule: rule,
+		})
+	}
+
+	if sub.TrialEndDate != nil {
+		events = append(events, utils.ICalEvent{
+			UID: uid("trial-end"),
+			Summary: sub.ServiceName + " trial ends",
+			Description: fmt.Sprintf("First charge of %d for %s", chargedAt(sub, changes, rates, monthStart(*sub.TrialEndDate)), sub.ServiceName),
+			Date: monthStart(*sub.TrialEndDate),
+		})
+	}
+	if ended {
+		events = append(events, utils.ICalEvent{
+			UID: uid("end"),
+			Summary: sub.ServiceName + " ends",
+			Description: fmt.Sprintf("%s is billed for the last time in %s", sub.ServiceName, end.Format("01-2006")),
+			Date: end.AddDate(0, 1, -1),
+		})
+	}
+	return events
+}
@@ -0,0 +1,269 @@
+package services
+
+import (
+	"fmt"
+	"online-subs-api/models"
+	"online-subs-api/repo"
+	"online-subs-api/utils"
+	"sort"
+	"strings"
+	"time"
+)
+
+const maxForecastMonths = 60
+
+// ForecastOverride is a what-if change applied on top of the current subscriptions:
+//   - "cancel" stops the matching subs, the last charge is the month before From
+//   - "add" adds a new sub of Quantity x Price from From on
+//   - "change_price" sets a new price for the matching subs from From on
+//
+// Subs are matched by SubID, or by ServiceName (case insensitive) and optionally UserID.
+type ForecastOverride struct{
+	Action			string		`json:"action"`
+	SubID			string		`json:"sub_id,omitempty"`
+	UserID			string		`json:"user_id,omitempty"`
+	ServiceName		string		`json:"service_name,omitempty"`
+	From			string		`json:"from"`
+	Price			int			`json:"price,omitempty"`
+	Quantity		int			`json:"quantity,omitempty"`
+	BillingPeriod	string		`json:"billing_period,omitempty"`
+}
+
+type ForecastRequest struct{
+	Start			string				`json:"start"`
+	Months			int					`json:"months"`
+	Filter			repo.SubsFilter		`json:"-"`
+	Overrides		[]ForecastOverride	`json:"overrides"`
+}
+
+type ForecastLine struct{
+	UserID			string		`json:"user_id"`
+	ServiceName		string		`json:"service_name"`
+	Amount			int			`json:"amount"`
+}
+
+// ForecastMonth sums the charges of one month, TaxBreakdown splits Total into net, tax and
+// the gross that is charged
+type ForecastMonth struct{
+	Month			string				`json:"month"`
+	Lines			[]ForecastLine		`json:"lines"`
+	GrossTotal		int					`json:"gross_total"`
+	Total			int					`json:"total"`
+	TaxBreakdown	repo.TaxBreakdown	`json:"tax_breakdown"`
+	BaselineTotal	int					`json:"baseline_total"`
+}
+
+type Forecast struct{
+	Start			string				`json:"start"`
+	End				string				`json:"end"`
+	Months			[]ForecastMonth		`json:"months"`
+	Totals			[]ForecastLine		`json:"totals"`
+	GrossTotal		int					`json:"gross_total"`
+	Total			int					`json:"total"`
+	TaxBreakdown	repo.TaxBreakdown	`json:"tax_breakdown"`
+	BaselineTotal	int					`json:"baseline_total"`
+	Overrides		[]ForecastOverride	`json:"overrides"`
+}
+
+func (o ForecastOverride) matches(sub *models.Sub) bool{
+	if o.SubID != "" {
+		return sub.ID == o.SubID
+	}
+	if o.UserID != "" && sub.UserID != o.UserID {
+		return false
+	}
+	return strings.EqualFold(strings.TrimSpace(o.ServiceName), sub.ServiceName)
+}
+
+// applyOverrides returns copies of the subs and price changes with the what-if list applied
+func applyOverrides(subs []models.Sub, changes map[string][]models.PriceChange, overrides []ForecastOverride) ([]models.Sub, map[string][]models.PriceChange, error){
+	subs = append([]models.Sub{}, subs...)
+	patched := map[string][]models.PriceChange{}
+	for id, list := range changes {
+		patched[id] = append([]models.PriceChange{}, list...)
+	}
+
+	for i, o := range overrides {
+		from, err := validDate(o.From)
+		if err != nil {
+			return nil, nil, fmt.Errorf("override %d: invalid from: %v", i+1, err)
+		}
+		if o.SubID == "" && o.ServiceName == "" {
+			return nil, nil, fmt.Errorf("override %d: sub_id or service_name is required", i+1)
+		}
+
+		switch o.Action {
+		case "cancel":
+			for j := range subs {
+				if !o.matches(&subs[j]) {
+					continue
+				}
+				if end, ok := subEndMonth(&subs[j]); !ok || end.After(from.AddDate(0, -1, 0)) {
+					subs[j].EndDate = from.AddDate(0, -1, 0)
+				}
+			}
+		case "change_price":
+			if o.Price <= 0 {
+				return nil, nil, fmt.Errorf("override %d: price must be a postive integer", i+1)
+			}
+			for j := range subs {
+				if !o.matches(&subs[j]) {
+					continue
+				}
+				list := append(patched[subs[j].ID], models.PriceChange{SubID: subs[j].ID, EffectiveDate: from, Price: o.Price})
+				sort.SliceStable(list, func(a, b int) bool{ return list[a].EffectiveDate.Before(list[b].EffectiveDate) })
+				patched[subs[j].ID] = list
+			}
+		case "add":
+			if o.Price <= 0 || o.ServiceName == "" {
+				return nil, nil, fmt.Errorf("override %d: add needs service_name and a postive price", i+1)
+			}
+			quantity := max(o.Quantity, 1)
+			period := o.BillingPeriod
+			if period == "" {
+				period = models.BillingMonthly
+			}
+			if !validBillingPeriod(period) {
+				return nil, nil, fmt.Errorf("override %d: invalid billing_period", i+1)
+			}
+			subs = append(subs, models.Sub{
+				ID: fmt.Sprintf("what-if-%d", i+1),
+				ServiceName: o.ServiceName,
+				UserID: o.UserID,
+				Price: o.Price * quantity,
+				StartDate: from,
+				BillingPeriod: period,
+			})
+		default:
+			return nil, nil, fmt.Errorf("override %d: action must be cancel, add or change_price", i+1)
+		}
+	}
+	return subs, patched, nil
+}
+
+// ForecastService projects month-by-month charges of the active subs, per user and service
+func (s *ReportService) ForecastService(req ForecastRequest) (*Forecast, error){
+	start := monthStart(time.Now()).AddDate(0, 1, 0)
+	if req.Start != "" {
+		var err error
+		if start, err = validDate(req.Start); err != nil {
+			utils.ErrorLogger.Println("Invalid forecast start:", req.Start, "error:", err)
+			return nil, err
+		}
+	}
+	if req.Months == 0 {
+		req.Months = 12
+	}
+	if req.Months < 1 || req.Months > maxForecastMonths {
+		return nil, fmt.Errorf("months must be between 1 and %d", maxForecastMonths)
+	}
+	end := start.AddDate(0, req.Months-1, 0)
+
+	if err := validateFilter(req.Filter); err != nil {
+		return nil, err
+	}
+
+	subs, err := s.subsRepo.ListActiveSubsRepo(start, req.Filter)
+	if err != nil {
+		utils.ErrorLogger.Println("Failed to load subscriptions for forecast:", err)
+		return nil, err
+	}
+	ids := []string{}
+	for _, sub := range subs {
+		ids = append(ids, sub.ID)
+	}
+	changes, err := s.subsRepo.ListPriceChangesBySubRepo(ids)
+	if err != nil {
+		utils.ErrorLogger.Println("Failed to load price changes for forecast:", err)
+		return nil, err
+	}
+
+	splits, err := s.subsRepo.ListSplitsBySubRepo(ids)
+	if err != nil {
+		utils.ErrorLogger.Println("Failed to load splits for forecast:", err)
+		return nil, err
+	}
+	rates, err := s.subsRepo.ListTaxRatesRepo()
+	if err != nil {
+		utils.ErrorLogger.Println("Failed to load tax rates for forecast:", err)
+		return nil, err
+	}
+	byJurisdiction := ratesByJurisdiction(rates)
+
+	// subs added for a user's forecast belong to that user
+	for i := range req.Overrides {
+		if req.Overrides[i].Action == "add" && req.Overrides[i].UserID == "" {
+			req.Overrides[i].UserID = req.Filter.UserID
+		}
+	}
+	whatIfSubs, whatIfChanges, err := applyOverrides(subs, changes, req.Overrides)
+	if err != nil {
+		utils.ErrorLogger.Println("Invalid forecast override:", err)
+		return nil, err
+	}
+	if req.Overrides == nil {
+		req.Overrides = []ForecastOverride{}
+	}
+
+	forecast := &Forecast{
+		Start: start.Format("2006-01"),
+		End: end.Format("2006-01"),
+		Months: []ForecastMonth{},
+		Totals: []ForecastLine{},
+		Overrides: req.Overrides,
+	}
+
+	index := map[string]int{}
+	for month := start; !month.After(end); month = month.AddDate(0, 1, 0) {
+		index[month.Format("2006-01")] = len(forecast.Months)
+		forecast.Months = append(forecast.Months, ForecastMonth{Month: month.Format("2006-01"), Lines: []ForecastLine{}})
+	}
+
+	for _, charge := range shareCharges(projectCharges(subs, changes, start, end), subs, splits, req.Filter.UserID) {
+		forecast.Months[index[charge.Date.Format("2006-01")]].BaselineTotal += charge.Amount
+		forecast.BaselineTotal += charge.Amount
+	}
+
+	// amounts per user and service, for each month and over the whole horizon, shared subs
+	// count for every user with their share
+	lines := make([]map[[2]string]int, len(forecast.Months))
+	for i := range lines {
+		lines[i] = map[[2]string]int{}
+	}
+	totals := map[[2]string]int{}
+
+	whatIf := shareCharges(projectCharges(whatIfSubs, whatIfChanges, start, end), whatIfSubs, splits, req.Filter.UserID)
+	for _, charge := range taxCharges(whatIf, whatIfSubs, byJurisdiction) {
+		i := index[charge.Date.Format("2006-01")]
+		key := [2]string{charge.UserID, charge.ServiceName}
+		lines[i][key] += charge.Amount
+		totals[key] += charge.Amount
+		forecast.Months[i].GrossTotal += charge.Gross
+		forecast.Months[i].Total += charge.Amount
+		forecast.GrossTotal += charge.Gross
+		forecast.Total += charge.Amount
+		addTax(&forecast.Months[i].TaxBreakdown, charge.TaxBreakdown)
+		addTax(&forecast.TaxBreakdown, charge.TaxBreakdown)
+	}
+
+	for i := range forecast.Months {
+		forecast.Months[i].Lines = sortedLines(lines[i])
+	}
+	forecast.Totals = sortedLines(totals)
+
+	return forecast, nil
+}
+
+func sortedLines(amounts map[[2]string]int) []ForecastLine{
+	lines := []ForecastLine{}
+	for key, amount := range amounts {
+		lines = append(lines, ForecastLine{UserID: key[0], ServiceName: key[1], Amount: amount})
+	}
+	sort.Slice(lines, func(i, j int) bool{
+		if lines[i].UserID != lines[j].UserID {
+			return lines[i].UserID < lines[j].UserID
+		}
+		return lines[i].ServiceName < lines[j].ServiceName
+	})
+	return lines
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"online-subs-api/models"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestApplyOverrides(t *testing.T){
+	spotify := *monthlySub(1000)
+	spotify.ServiceName = "Spotify"
+	tests := []struct{
+		name		string
+		overrides	[]ForecastOverride
+		want		map[string]int
+		wantErr		bool
+	}{
+		{
+			name: "no overrides",
+			want: map[string]int{"2025-01": 1000, "2025-02": 1000, "2025-03": 1000, "2025-04": 1000},
+		},
+		{
+			name: "cancel by service name",
+			overrides: []ForecastOverride{{Action: "cancel", ServiceName: "spotify", From: "03-2025"}},
+			want: map[string]int{"2025-01": 1000, "2025-02": 1000},
+		},
+		{
+			name: "change price",
+			overrides: []ForecastOverride{{Action: "change_price", SubID: "sub", From: "03-2025", Price: 1500}},
+			want: map[string]int{"2025-01": 1000, "2025-02": 1000, "2025-03": 1500, "2025-04": 1500},
+		},
+		{
+			name: "add seats",
+			overrides: []ForecastOverride{{Action: "add", ServiceName: "Copilot", From: "02-2025", Price: 1900, Quantity: 5}},
+			want: map[string]int{"2025-01": 1000, "2025-02": 10500, "2025-03": 10500, "2025-04": 10500},
+		},
+		{
+			name: "quarterly addition",
+			overrides: []ForecastOverride{{Action: "add", ServiceName: "Backup", From: "01-2025", Price: 300, BillingPeriod: models.BillingQuarterly}},
+			want: map[string]int{"2025-01": 1300, "2025-02": 1000, "2025-03": 1000, "2025-04": 1300},
+		},
+		{name: "unknown action", overrides: []ForecastOverride{{Action: "pause", SubID: "sub", From: "03-2025"}}, wantErr: true},
+		{name: "invalid from", overrides: []ForecastOverride{{Action: "cancel", SubID: "sub", From: "2025-03"}}, wantErr: true},
+		{name: "add without a price", overrides: []ForecastOverride{{Action: "add", ServiceName: "Copilot", From: "02-2025"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T){
+			subs, changes, err := applyOverrides([]models.Sub{spotify}, map[string][]models.PriceChange{}, tt.overrides)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("applyOverrides() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			got := map[string]int{}
+			for _, charge := range projectCharges(subs, changes, date(2025, time.January, 1), date(2025, time.April, 1)) {
+				got[charge.Date.Format("2006-01")] += charge.Amount
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("projected charges = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
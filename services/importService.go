@@ -0,0 +1,273 @@
+package services
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"online-subs-api/models"
+	"online-subs-api/utils"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const maxImportRows = 10000
+
+// importFields are the Sub fields a CSV column can be mapped to, besides "metadata.<key>"
+var importFields = []string{
+	"service_name", "price", "user_id", "start_date", "end_date", "trial_end_date", "billing_period",
+	"category", "tags", "tenant_id", "provider_id", "plan_id",
+}
+
+// ImportOptions configures a CSV import. Mapping maps a field to the header of its column,
+// unmapped fields are looked up by their own name. The defaults fill empty user_id and
+// tenant_id cells.
+type ImportOptions struct{
+	Mapping				map[string]string
+	Delimiter			rune
+	DryRun				bool
+	DefaultUserID		string
+	DefaultTenantID		string
+}
+
+// ImportRowResult is the outcome of one data row, Row counts from 1 for the first data row
+type ImportRowResult struct{
+	Row				int				`json:"row"`
+	Sub				*models.Sub		`json:"subscription,omitempty"`
+	Errors			[]string		`json:"errors,omitempty"`
+}
+
+type ImportReport struct{
+	DryRun			bool				`json:"dry_run"`
+	Total			int					`json:"total"`
+	Valid			int					`json:"valid"`
+	Invalid			int					`json:"invalid"`
+	Imported		int					`json:"imported"`
+	Rows			[]ImportRowResult	`json:"rows"`
+}
+
+// ParseImportMapping reads "field=Column,field=Column" as used by the CLI
+func ParseImportMapping(spec string) (map[string]string, error){
+	mapping := map[string]string{}
+	for _, pair := range strings.Split(spec, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		field, column, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid mapping %q, expected field=Column", pair)
+		}
+		mapping[strings.TrimSpace(field)] = strings.TrimSpace(column)
+	}
+	return mapping, nil
+}
+
+func validateImportMapping(mapping map[string]string) error{
+	for field := range mapping {
+		if key, ok := strings.CutPrefix(field, "metadata."); ok && key != "" {
+			continue
+		}
+		if !slices.Contains(importFields, field) {
+			return fmt.Errorf("unknown field %q in mapping", field)
+		}
+	}
+	return nil
+}
+
+// importDate accepts MM-YYYY as the API does, and YYYY-MM or YYYY-MM-DD as spreadsheets
+// tend to export
+func importDate(value string) string{
+	value = strings.TrimSpace(value)
+	for _, layout := range []string{"2006-01-02", "2006-01", "01/2006", "1/2006"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.Format("01-2006")
+		}
+	}
+	return value
+}
+
+// importPrice reads a whole-unit price, thousands separated by spaces are allowed
+func importPrice(value string) (int, error){
+	value = strings.ReplaceAll(strings.TrimSpace(value), " ", "")
+	price, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("price %q is not a whole number", value)
+	}
+	return price, nil
+}
+
+// importRow is a parsed CSV row before validation
+type importRow struct{
+	sub				*models.Sub
+	tags			[]string
+	startDate		string
+	endDate			string
+	trialEndDate	string
+}
+
+func (s *SubsService) parseImportRow(get func(string) string, metadataFields []string, opts ImportOptions) (*importRow, error){
+	row := &importRow{
+		sub: &models.Sub{
+			ServiceName: strings.TrimSpace(get("service_name")),
+			UserID: strings.TrimSpace(get("user_id")),
+			BillingPeriod: strings.ToLower(strings.TrimSpace(get("billing_period"))),
+			Category: get("category"),
+			TenantID: strings.TrimSpace(get("tenant_id")),
+			ProviderID: optionalString(strings.TrimSpace(get("provider_id"))),
+			PlanID: optionalString(strings.TrimSpace(get("plan_id"))),
+		},
+		startDate: importDate(get("start_date")),
+		endDate: importDate(get("end_date")),
+		trialEndDate: importDate(get("trial_end_date")),
+	}
+	if row.sub.UserID == "" {
+		row.sub.UserID = opts.DefaultUserID
+	}
+	if row.sub.TenantID == "" {
+		row.sub.TenantID = opts.DefaultTenantID
+	}
+	if price := get("price"); strings.TrimSpace(price) != "" {
+		var err error
+		if row.sub.Price, err = importPrice(price); err != nil {
+			return row, err
+		}
+	}
+	for _, tag := range strings.FieldsFunc(get("tags"), func(r rune) bool{ return r == ';' || r == '|' }) {
+		row.tags = append(row.tags, tag)
+	}
+	for _, field := range metadataFields {
+		if value := get(field); value != "" {
+			if row.sub.Metadata == nil {
+				row.sub.Metadata = models.JSONMap{}
+			}
+			row.sub.Metadata[strings.TrimPrefix(field, "metadata.")] = value
+		}
+	}
+	return row, nil
+}
+
+func optionalString(value string) *string{
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+// ImportCSVService validates every row like CreateService does. With DryRun, or when a
+// row is invalid, nothing is written and the report lists the errors per row; otherwise
+// all rows are created in a single transaction.
+func (s *SubsService) ImportCSVService(r io.Reader, opts ImportOptions) (*ImportReport, error){
+	if opts.Mapping == nil {
+		opts.Mapping = map[string]string{}
+	}
+	if err := validateImportMapping(opts.Mapping); err != nil {
+		return nil, err
+	}
+
+	reader := csv.NewReader(r)
+	if opts.Delimiter != 0 {
+		reader.Comma = opts.Delimiter
+	}
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, errors.New("could not read the CSV header: " + err.Error())
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+
+	// field -> column index, a mapping must point at an existing column
+	index := map[string]int{}
+	metadataFields := []string{}
+	for field, column := range opts.Mapping {
+		i, ok := columns[strings.ToLower(column)]
+		if !ok {
+			return nil, fmt.Errorf("column %q mapped to %s is not in the header", column, field)
+		}
+		index[field] = i
+		if strings.HasPrefix(field, "metadata.") {
+			metadataFields = append(metadataFields, field)
+		}
+	}
+	for _, field := range importFields {
+		if _, mapped := index[field]; !mapped {
+			if i, ok := columns[field]; ok {
+				index[field] = i
+			}
+		}
+	}
+
+	report := &ImportReport{DryRun: opts.DryRun, Rows: []ImportRowResult{}}
+	rows := []*importRow{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		report.Total++
+		if report.Total > maxImportRows {
+			return nil, fmt.Errorf("imports are limited to %d rows", maxImportRows)
+		}
+		result := ImportRowResult{Row: report.Total}
+		if err != nil {
+			result.Errors = []string{err.Error()}
+			report.Rows = append(report.Rows, result)
+			report.Invalid++
+			continue
+		}
+
+		get := func(field string) string{
+			if i, ok := index[field]; ok && i < len(record) {
+				return record[i]
+			}
+			return ""
+		}
+		row, err := s.parseImportRow(get, metadataFields, opts)
+		if err == nil {
+			err = s.validateNewSub(row.sub, row.startDate, row.endDate, row.trialEndDate)
+		}
+		if err != nil {
+			result.Errors = []string{err.Error()}
+			report.Invalid++
+		} else {
+			result.Sub = row.sub
+			report.Valid++
+			rows = append(rows, row)
+		}
+		report.Rows = append(report.Rows, result)
+	}
+
+	if opts.DryRun || report.Invalid > 0 || len(rows) == 0 {
+		return report, nil
+	}
+
+	subs := make([]models.Sub, 0, len(rows))
+	for _, row := range rows {
+		if len(row.tags) > 0 {
+			if row.sub.Tags, err = s.tagsByName(row.tags); err != nil {
+				return nil, err
+			}
+		}
+		if row.sub.ID, err = utils.NewUUID(); err != nil {
+			return nil, err
+		}
+		subs = append(subs, *row.sub)
+	}
+	if err := s.subsRepo.CreateSubsRepo(subs); err != nil {
+		utils.ErrorLogger.Println("CSV import rolled back:", err)
+		return nil, fmt.Errorf("import rolled back: %v", err)
+	}
+
+	for i := range subs {
+		report.Rows[i].Sub = &subs[i]
+		s.emit(EventSubCreated, &subs[i])
+	}
+	report.Imported = len(subs)
+	return report, nil
+}
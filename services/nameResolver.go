@@ -0,0 +1,309 @@
+package services
+
+import (
+	"online-subs-api/repo"
+	"online-subs-api/utils"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// names resolved with at least this confidence replace the user's free text
+const autoResolveConfidence = 0.8
+
+// close matches returned with a name that is not resolved
+const maxResolveSuggestions = 3
+
+const dictionaryTTL = 5 * time.Minute
+
+var cyrillicToLatin = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh",
+	'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+	'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts",
+	'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu",
+	'я': "ya", 'і': "i", 'ә': "a", 'ғ': "g", 'қ': "k", 'ң': "n", 'ө': "o", 'ұ': "u",
+	'ү': "u", 'һ': "h",
+}
+
+// spelling differences between transliterations and the original latin names
+var phoneticFolds = strings.NewReplacer(
+	"shch", "sh", "kh", "h", "ks", "x", "ph", "f", "w", "v", "ck", "k", "q", "k", "yu", "u", "ya", "a",
+)
+
+// Resolution is the best catalog match for a free-text service name
+type Resolution struct{
+	Input			string		`json:"input"`
+	CanonicalName	string		`json:"canonical_name"`
+	ProviderID		string		`json:"provider_id,omitempty"`
+	PlanID			string		`json:"plan_id,omitempty"`
+	PlanName		string		`json:"plan_name,omitempty"`
+	Category		string		`json:"category,omitempty"`
+	Confidence		float64		`json:"confidence"`
+	MatchType		string		`json:"match_type"`
+	// close catalog names when the name is not resolved, best first
+	Suggestions		[]Resolution	`json:"suggestions,omitempty"`
+}
+
+type dictionaryEntry struct{
+	normalized	string
+	folded		string
+	resolution	Resolution
+}
+
+// NameResolver matches free-text service names against the catalog aliases
+type NameResolver struct{
+	catalogRepo *repo.CatalogRepo
+
+	mu			sync.RWMutex
+	entries		[]dictionaryEntry
+	loadedAt	time.Time
+}
+
+func NewNameResolver(catalogRepo *repo.CatalogRepo) *NameResolver{
+	return &NameResolver{catalogRepo: catalogRepo}
+}
+
+// normalizeName lowercases the name, spells out "+" and "&" and drops punctuation
+func normalizeName(name string) string{
+	name = strings.ToLower(name)
+	name = strings.NewReplacer("+", " plus ", "&", " and ", "ё", "е").Replace(name)
+
+	var b strings.Builder
+	for _, r := range name {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+		default:
+			b.WriteRune(' ')
+		}
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+func transliterate(name string) string{
+	var b strings.Builder
+	for _, r := range name {
+		if latin, ok := cyrillicToLatin[r]; ok {
+			b.WriteString(latin)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// foldName transliterates a normalized name and folds spelling variants,
+// so "Яндекс Плюс" and "yandex plus" get the same key
+func foldName(normalized string) string{
+	folded := phoneticFolds.Replace(transliterate(normalized))
+	return strings.ReplaceAll(folded, " ", "")
+}
+
+func levenshtein(a, b string) int{
+	ar, br := []rune(a), []rune(b)
+	prev := make([]int, len(br)+1)
+	curr := make([]int, len(br)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ar); i++ {
+		curr[0] = i
+		for j := 1; j <= len(br); j++ {
+			cost := 1
+			if ar[i-1] == br[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(br)]
+}
+
+func similarity(a, b string) float64{
+	longest := max(len([]rune(a)), len([]rune(b)))
+	if longest == 0 {
+		return 0
+	}
+	return 1 - float64(levenshtein(a, b))/float64(longest)
+}
+
+// Invalidate drops the cached alias dictionary after catalog changes
+func (n *NameResolver) Invalidate(){
+	n.mu.Lock()
+	n.entries = nil
+	n.mu.Unlock()
+}
+
+func (n *NameResolver) dictionary() ([]dictionaryEntry, error){
+	n.mu.RLock()
+	if n.entries != nil && time.Since(n.loadedAt) < dictionaryTTL {
+		entries := n.entries
+		n.mu.RUnlock()
+		return entries, nil
+	}
+	n.mu.RUnlock()
+
+	providers, err := n.catalogRepo.ListProvidersRepo("")
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []dictionaryEntry{}
+	add := func(alias string, res Resolution){
+		normalized := normalizeName(alias)
+		if normalized == "" {
+			return
+		}
+		entries = append(entries, dictionaryEntry{normalized: normalized, folded: foldName(normalized), resolution: res})
+	}
+
+	for _, p := range providers {
+		res := Resolution{CanonicalName: p.Name, ProviderID: p.ID, Category: p.Category}
+		add(p.Name, res)
+		for _, alias := range p.Aliases {
+			add(alias, res)
+		}
+
+		for _, plan := range p.Plans {
+			planRes := res
+			planRes.PlanID = plan.ID
+			planRes.PlanName = plan.Name
+			add(plan.Name, planRes)
+			for _, alias := range plan.Aliases {
+				add(alias, planRes)
+			}
+		}
+	}
+
+	n.mu.Lock()
+	n.entries = entries
+	n.loadedAt = time.Now()
+	n.mu.Unlock()
+	return entries, nil
+}
+
+// fuzzyResolves tells whether a typo match is safe to resolve: the names must start with
+// the same letter and differ by at most one edit per five letters, so a short name one
+// letter off ("Motion" for "Notion") is more likely another service and only suggested
+func fuzzyResolves(folded, candidate string) bool{
+	a, b := []rune(folded), []rune(candidate)
+	if len(a) == 0 || len(b) == 0 || a[0] != b[0] {
+		return false
+	}
+	return levenshtein(folded, candidate) <= min(len(a), len(b))/5
+}
+
+// score rates how well the input matches a dictionary entry
+func score(normalized, folded string, entry dictionaryEntry) (float64, string){
+	switch {
+	case normalized == entry.normalized:
+		return 1, "exact"
+	case folded == entry.folded:
+		return 0.95, "transliterated"
+	}
+
+	sim := similarity(folded, entry.folded)
+	if sim < 0.6 {
+		return 0, ""
+	}
+	return min(sim, 0.9), "fuzzy"
+}
+
+// Resolve returns the best catalog match for name; a zero confidence means no match. Typo
+// matches that fuzzyResolves rejects are left out, names resolved with less than
+// autoResolveConfidence come with the closest catalog names as suggestions.
+func (n *NameResolver) Resolve(name string) (Resolution, error){
+	best := Resolution{Input: name, CanonicalName: strings.TrimSpace(name), MatchType: "none"}
+
+	normalized := normalizeName(name)
+	if normalized == "" {
+		return best, nil
+	}
+	folded := foldName(normalized)
+
+	entries, err := n.dictionary()
+	if err != nil {
+		utils.ErrorLogger.Println("Failed to load alias dictionary:", err)
+		return best, err
+	}
+
+	for _, entry := range entries {
+		confidence, matchType := score(normalized, folded, entry)
+		if matchType == "fuzzy" && !fuzzyResolves(folded, entry.folded) {
+			continue
+		}
+		// provider-level matches win ties so plan aliases don't pin a plan by accident
+		if confidence > best.Confidence || (confidence == best.Confidence && confidence > 0 && entry.resolution.PlanID == "" && best.PlanID != "") {
+			best = entry.resolution
+			best.Input = name
+			best.Confidence = confidence
+			best.MatchType = matchType
+		}
+	}
+
+	if best.Confidence < autoResolveConfidence {
+		if best.Suggestions, err = n.Suggest(name, maxResolveSuggestions); err != nil {
+			return best, err
+		}
+	}
+	return best, nil
+}
+
+// Suggest returns up to limit catalog names for autocomplete, best first
+func (n *NameResolver) Suggest(query string, limit int) ([]Resolution, error){
+	normalized := normalizeName(query)
+	if normalized == "" {
+		return []Resolution{}, nil
+	}
+	folded := foldName(normalized)
+
+	entries, err := n.dictionary()
+	if err != nil {
+		return nil, err
+	}
+
+	// keep the best score per canonical name / plan
+	best := map[string]Resolution{}
+	for _, entry := range entries {
+		confidence, matchType := score(normalized, folded, entry)
+		if strings.HasPrefix(entry.normalized, normalized) || strings.HasPrefix(entry.folded, folded) {
+			prefix := 0.7 + 0.3*float64(len(folded))/float64(max(len(entry.folded), 1))
+			if prefix > confidence {
+				confidence, matchType = prefix, "prefix"
+			}
+		}
+		if confidence == 0 {
+			continue
+		}
+
+		key := entry.resolution.ProviderID + "/" + entry.resolution.PlanID
+		if current, ok := best[key]; ok && current.Confidence >= confidence {
+			continue
+		}
+		res := entry.resolution
+		res.Input = query
+		res.Confidence = confidence
+		res.MatchType = matchType
+		best[key] = res
+	}
+
+	suggestions := make([]Resolution, 0, len(best))
+	for _, res := range best {
+		suggestions = append(suggestions, res)
+	}
+	sort.Slice(suggestions, func(i, j int) bool{
+		if suggestions[i].Confidence != suggestions[j].Confidence {
+			return suggestions[i].Confidence > suggestions[j].Confidence
+		}
+		return suggestions[i].CanonicalName+suggestions[i].PlanName < suggestions[j].CanonicalName+suggestions[j].PlanName
+	})
+
+	if limit > 0 && len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions, nil
+}
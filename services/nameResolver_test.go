@@ -0,0 +1,50 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestResolve(t *testing.T){
+	entries := []dictionaryEntry{}
+	for _, alias := range []struct{ name, canonical string }{
+		{"Notion", "Notion"},
+		{"Netflix", "Netflix"},
+		{"нетфликс", "Netflix"},
+		{"Spotify", "Spotify"},
+	} {
+		normalized := normalizeName(alias.name)
+		entries = append(entries, dictionaryEntry{normalized: normalized, folded: foldName(normalized),
+			resolution: Resolution{CanonicalName: alias.canonical, ProviderID: alias.canonical}})
+	}
+	resolver := &NameResolver{entries: entries, loadedAt: time.Now()}
+
+	tests := []struct{
+		name			string
+		want			string
+		wantResolved	bool
+		wantSuggestion	string
+	}{
+		{"notion", "Notion", true, ""},
+		{"Нетфликс", "Netflix", true, ""},
+		{"netflx", "Netflix", true, ""},
+		{"spotfy", "Spotify", true, ""},
+		{"Motion", "Motion", false, "Notion"},
+		{"Notoin", "Notoin", false, "Notion"},
+		{"Bagamol Podcast", "Bagamol Podcast", false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T){
+			res, err := resolver.Resolve(tt.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.CanonicalName != tt.want || (res.Confidence >= autoResolveConfidence) != tt.wantResolved {
+				t.Errorf("Resolve() = %q with confidence %.2f, want %q resolved %v", res.CanonicalName, res.Confidence, tt.want, tt.wantResolved)
+			}
+			if tt.wantSuggestion != "" && (len(res.Suggestions) == 0 || res.Suggestions[0].CanonicalName != tt.wantSuggestion) {
+				t.Errorf("suggestions = %+v, want %q first", res.Suggestions, tt.wantSuggestion)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"online-subs-api/models"
+	"online-subs-api/utils"
+	"os"
+	"strings"
+	"time"
+)
+
+// Notifier delivers reminders over one channel, an error makes the reminder retry later
+type Notifier interface{
+	Name() string
+	Notify(reminder *models.Reminder, sub *models.Sub) error
+}
+
+func reminderText(reminder *models.Reminder) string{
+	date := reminder.EventDate.Format("2006-01-02")
+	switch reminder.Kind {
+	case models.ReminderTrialEnd:
+		return fmt.Sprintf("The %s trial ends on %s, the first charge is %d.", reminder.ServiceName, date, reminder.Price)
+	case models.ReminderEnd:
+		return fmt.Sprintf("Your %s subscription ends on %s.", reminder.ServiceName, date)
+	}
+	return fmt.Sprintf("%s renews on %s for %d.", reminder.ServiceName, date, reminder.Price)
+}
+
+// LogNotifier writes reminders to the application log
+type LogNotifier struct{}
+
+func (LogNotifier) Name() string{
+	return "log"
+}
+
+func (LogNotifier) Notify(reminder *models.Reminder, sub *models.Sub) error{
+	utils.InfoLogger.Printf("Reminder for user %s: %s", reminder.UserID, reminderText(reminder))
+	return nil
+}
+
+// WebhookNotifier POSTs the reminder and its sub as JSON, any non-2xx answer is a failure
+type WebhookNotifier struct{
+	URL string
+	Client *http.Client
+}
+
+func (n *WebhookNotifier) Name() string{
+	return "webhook"
+}
+
+func (n *WebhookNotifier) Notify(reminder *models.Reminder, sub *models.Sub) error{
+	body, err := json.Marshal(map[string]interface{}{
+		"reminder": reminder,
+		"subscription": sub,
+		"message": reminderText(reminder),
+	})
+	if err != nil {
+		return err
+	}
+	resp, err := n.Client.Post(n.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook answered %s", resp.Status)
+	}
+	return nil
+}
+
+// SMTPNotifier emails the reminder to the "email" metadata of the sub, or to the
+// default recipient when the sub has none
+type SMTPNotifier struct{
+	Addr string
+	Auth smtp.Auth
+	From string
+	To string
+}
+
+func (n *SMTPNotifier) Name() string{
+	return "smtp"
+}
+
+func (n *SMTPNotifier) Notify(reminder *models.Reminder, sub *models.Sub) error{
+	to := n.To
+	if email, ok := sub.Metadata["email"].(string); ok && email != "" {
+		to = email
+	}
+	if to == "" {
+		return errors.New("no recipient, set the email metadata or SMTP_TO")
+	}
+	// a single parsed address keeps header injection and extra recipients out of To
+	addr, err := mail.ParseAddress(to)
+	if err != nil {
+		return fmt.Errorf("invalid recipient %q: %w", to, err)
+	}
+
+	text := reminderText(reminder)
+	msg := strings.Join([]string{
+		"From: " + n.From,
+		"To: " + addr.String(),
+		"Subject: " + mime.QEncoding.Encode("utf-8", text),
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=utf-8",
+		"",
+		text,
+		"",
+	}, "\r\n")
+	return smtp.SendMail(n.Addr, n.Auth, n.From, []string{addr.Address}, []byte(msg))
+}
+
+// NotifiersFromEnv builds the notifiers listed in REMINDER_NOTIFIERS (comma separated,
+// default "log"): webhook needs REMINDER_WEBHOOK_URL, smtp needs SMTP_HOST, SMTP_PORT,
+// SMTP_FROM and optionally SMTP_USER, SMTP_PASSWORD and SMTP_TO
+func NotifiersFromEnv() ([]Notifier, error){
+	names := os.Getenv("REMINDER_NOTIFIERS")
+	if names == "" {
+		names = "log"
+	}
+
+	notifiers := []Notifier{}
+	for _, name := range strings.Split(names, ",") {
+		switch strings.TrimSpace(name) {
+		case "log":
+			notifiers = append(notifiers, LogNotifier{})
+		case "webhook":
+			url := os.Getenv("REMINDER_WEBHOOK_URL")
+			if url == "" {
+				return nil, errors.New("REMINDER_WEBHOOK_URL is required for the webhook notifier")
+			}
+			notifiers = append(notifiers, &WebhookNotifier{URL: url, Client: &http.Client{Timeout: 10 * time.Second}})
+		case "smtp":
+			host, from := os.Getenv("SMTP_HOST"), os.Getenv("SMTP_FROM")
+			if host == "" || from == "" {
+				return nil, errors.New("SMTP_HOST and SMTP_FROM are required for the smtp notifier")
+			}
+			port := os.Getenv("SMTP_PORT")
+			if port == "" {
+				port = "587"
+			}
+			var auth smtp.Auth
+			if user := os.Getenv("SMTP_USER"); user != "" {
+				auth = smtp.PlainAuth("", user, os.Getenv("SMTP_PASSWORD"), host)
+			}
+			notifiers = append(notifiers, &SMTPNotifier{Addr: net.JoinHostPort(host, port), Auth: auth, From: from, To: os.Getenv("SMTP_TO")})
+		case "":
+		default:
+			return nil, fmt.Errorf("unknown notifier %q", name)
+		}
+	}
+	return notifiers, nil
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"encoding/json"
+	"online-subs-api/models"
+	"online-subs-api/repo"
+	"online-subs-api/utils"
+	"time"
+)
+
+const (
+	outboxBatchSize = 100
+	outboxPollInterval = 2 * time.Second
+	outboxMaxBackoff = time.Minute
+)
+
+// OutboxMessage is the envelope published to every sink, ID is the same on every
+// redelivery so consumers can drop duplicates
+type OutboxMessage struct{
+	ID				string				`json:"id"`
+	Seq				int64				`json:"seq"`
+	AggregateType	string				`json:"aggregate_type"`
+	AggregateID		string				`json:"aggregate_id"`
+	Type			string				`json:"type"`
+	TenantID		string				`json:"tenant_id"`
+	OccurredAt		time.Time			`json:"occurred_at"`
+	Data			json.RawMessage		`json:"data"`
+}
+
+func newOutboxMessage(event *models.OutboxEvent) OutboxMessage{
+	return OutboxMessage{
+		ID: event.ID,
+		Seq: event.Seq,
+		AggregateType: event.AggregateType,
+		AggregateID: event.AggregateID,
+		Type: event.Type,
+		TenantID: event.TenantID,
+		OccurredAt: event.CreatedAt,
+		Data: json.RawMessage(event.Payload),
+	}
+}
+
+// OutboxSink receives the outbox events in seq order. Publish returning an error makes
+// the relay retry the same event, so a sink sees every event at least once.
+type OutboxSink interface{
+	Name() string
+	Publish(message OutboxMessage) error
+}
+
+// OutboxStatus shows how far each sink got
+type OutboxStatus struct{
+	LastSeq			int64					`json:"last_seq"`
+	Cursors			[]models.OutboxCursor	`json:"cursors"`
+}
+
+// OutboxRelay publishes the outbox to the sinks. Each sink has its own cursor and
+// goroutine, a failing sink retries with backoff without holding the others back.
+// Events are published strictly in seq order, which keeps them ordered per aggregate.
+type OutboxRelay struct{
+	outboxRepo *repo.OutboxRepo
+	sinks []OutboxSink
+	nudges []chan struct{}
+}
+
+func NewOutboxRelay(outboxRepo *repo.OutboxRepo, sinks []OutboxSink) *OutboxRelay{
+	relay := &OutboxRelay{outboxRepo: outboxRepo, sinks: sinks}
+	for range sinks {
+		relay.nudges = append(relay.nudges, make(chan struct{}, 1))
+	}
+	return relay
+}
+
+// Wake makes the sinks look for new events now instead of at the next poll
+func (r *OutboxRelay) Wake(){
+	for _, nudge := range r.nudges {
+		select {
+		case nudge <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// OnSubEvent wakes the relay after a mutation, the event itself is already in the outbox
+func (r *OutboxRelay) OnSubEvent(SubEvent){
+	r.Wake()
+}
+
+// relayBatch publishes the next events to the sink and returns how many it published
+func (r *OutboxRelay) relayBatch(sink OutboxSink) (int, error){
+	cursor, err := r.outboxRepo.GetCursorRepo(sink.Name())
+	if err != nil {
+		return 0, err
+	}
+	events, err := r.outboxRepo.ListOutboxAfterRepo(cursor, outboxBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range events {
+		if err := sink.Publish(newOutboxMessage(&events[i])); err != nil {
+			return i, err
+		}
+		// a crash between publish and this update republishes the event, consumers dedupe on the id
+		if err := r.outboxRepo.SetCursorRepo(sink.Name(), events[i].Seq); err != nil {
+			return i, err
+		}
+	}
+	return len(events), nil
+}
+
+func (r *OutboxRelay) run(sink OutboxSink, nudge chan struct{}){
+	backoff := time.Duration(0)
+	for {
+		published, err := r.relayBatch(sink)
+		switch {
+		case err != nil:
+			backoff = min(max(2*backoff, time.Second), outboxMaxBackoff)
+			utils.WarningLogger.Printf("Outbox sink %s failed, retrying in %s: %v", sink.Name(), backoff, err)
+			time.Sleep(backoff)
+			continue
+		case published == outboxBatchSize:
+			backoff = 0
+			continue
+		}
+		backoff = 0
+
+		select {
+		case <-nudge:
+		case <-time.After(outboxPollInterval):
+		}
+	}
+}
+
+// Start runs one relay goroutine per sink
+func (r *OutboxRelay) Start(){
+	for i, sink := range r.sinks {
+		go r.run(sink, r.nudges[i])
+	}
+}
+
+func (r *OutboxRelay) StatusService() (*OutboxStatus, error){
+	lastSeq, err := r.outboxRepo.LastSeqRepo()
+	if err != nil {
+		return nil, err
+	}
+	cursors, err := r.outboxRepo.ListCursorsRepo()
+	if err != nil {
+		return nil, err
+	}
+	return &OutboxStatus{LastSeq: lastSeq, Cursors: cursors}, nil
+}
@@ -0,0 +1,209 @@
+package services
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StdoutSink prints every event as one JSON line
+type StdoutSink struct{
+	Out io.Writer
+}
+
+func (s *StdoutSink) Name() string{
+	return "stdout"
+}
+
+func (s *StdoutSink) Publish(message OutboxMessage) error{
+	return json.NewEncoder(s.Out).Encode(message)
+}
+
+// FileSink appends every event as one JSON line to a file and syncs it to disk
+type FileSink struct{
+	Path string
+	mu sync.Mutex
+}
+
+func (s *FileSink) Name() string{
+	return "file"
+}
+
+func (s *FileSink) Publish(message OutboxMessage) error{
+	line, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.OpenFile(s.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+// WebhookSink hands the events to the webhook deliveries, which are unique per endpoint
+// and event id
+type WebhookSink struct{
+	Webhooks *WebhookService
+}
+
+func (s *WebhookSink) Name() string{
+	return "webhooks"
+}
+
+func (s *WebhookSink) Publish(message OutboxMessage) error{
+	return s.Webhooks.Enqueue(WebhookEvent{
+		ID: message.ID,
+		Type: message.Type,
+		TenantID: message.TenantID,
+		OccurredAt: message.OccurredAt,
+		Data: message.Data,
+	})
+}
+
+// NATSSink publishes to "<prefix>.<event type>" over the plain NATS protocol. The event
+// id is sent as Nats-Msg-Id, so JetStream streams drop redeliveries. Every publish waits
+// for the server's PONG, which confirms the message was processed.
+type NATSSink struct{
+	URL string
+	SubjectPrefix string
+	conn net.Conn
+	reader *bufio.Reader
+}
+
+func (s *NATSSink) Name() string{
+	return "nats"
+}
+
+func (s *NATSSink) connect() error{
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		return err
+	}
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "4222")
+	}
+	conn, err := net.DialTimeout("tcp", host, 5*time.Second)
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	reader := bufio.NewReader(conn)
+
+	info, err := reader.ReadString('\n')
+	if err != nil || !strings.HasPrefix(info, "INFO") {
+		conn.Close()
+		return fmt.Errorf("unexpected NATS greeting %q: %v", info, err)
+	}
+	options := map[string]interface{}{"verbose": false, "pedantic": false, "headers": true, "name": "online-subs-api"}
+	if u.User != nil {
+		options["user"] = u.User.Username()
+		options["pass"], _ = u.User.Password()
+	}
+	connect, _ := json.Marshal(options)
+	if _, err := fmt.Fprintf(conn, "CONNECT %s\r\n", connect); err != nil {
+		conn.Close()
+		return err
+	}
+	s.conn, s.reader = conn, reader
+	return nil
+}
+
+func (s *NATSSink) close(){
+	if s.conn != nil {
+		s.conn.Close()
+	}
+	s.conn, s.reader = nil, nil
+}
+
+func (s *NATSSink) Publish(message OutboxMessage) error{
+	if s.conn == nil {
+		if err := s.connect(); err != nil {
+			return err
+		}
+	}
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	headers := "NATS/1.0\r\nNats-Msg-Id: " + message.ID + "\r\n\r\n"
+	subject := s.SubjectPrefix + "." + message.Type
+
+	s.conn.SetDeadline(time.Now().Add(5 * time.Second))
+	_, err = fmt.Fprintf(s.conn, "HPUB %s %d %d\r\n%s%s\r\nPING\r\n", subject, len(headers), len(headers)+len(body), headers, body)
+	if err != nil {
+		s.close()
+		return err
+	}
+	for {
+		line, err := s.reader.ReadString('\n')
+		if err != nil {
+			s.close()
+			return err
+		}
+		switch {
+		case strings.HasPrefix(line, "PONG"):
+			return nil
+		case strings.HasPrefix(line, "PING"):
+			fmt.Fprint(s.conn, "PONG\r\n")
+		case strings.HasPrefix(line, "-ERR"):
+			s.close()
+			return errors.New("nats: " + strings.TrimSpace(line))
+		}
+	}
+}
+
+// OutboxSinksFromEnv builds the sinks listed in OUTBOX_SINKS (comma separated, default
+// "webhooks"): stdout, file (OUTBOX_FILE), nats (NATS_URL, NATS_SUBJECT_PREFIX) and webhooks
+func OutboxSinksFromEnv(webhooks *WebhookService) ([]OutboxSink, error){
+	names := os.Getenv("OUTBOX_SINKS")
+	if names == "" {
+		names = "webhooks"
+	}
+
+	sinks := []OutboxSink{}
+	for _, name := range strings.Split(names, ",") {
+		switch strings.TrimSpace(name) {
+		case "webhooks":
+			sinks = append(sinks, &WebhookSink{Webhooks: webhooks})
+		case "stdout":
+			sinks = append(sinks, &StdoutSink{Out: os.Stdout})
+		case "file":
+			path := os.Getenv("OUTBOX_FILE")
+			if path == "" {
+				return nil, errors.New("OUTBOX_FILE is required for the file sink")
+			}
+			sinks = append(sinks, &FileSink{Path: path})
+		case "nats":
+			natsURL := os.Getenv("NATS_URL")
+			if natsURL == "" {
+				return nil, errors.New("NATS_URL is required for the nats sink")
+			}
+			prefix := os.Getenv("NATS_SUBJECT_PREFIX")
+			if prefix == "" {
+				prefix = "subs.events"
+			}
+			sinks = append(sinks, &NATSSink{URL: natsURL, SubjectPrefix: prefix})
+		case "":
+		default:
+			return nil, fmt.Errorf("unknown outbox sink %q", name)
+		}
+	}
+	return sinks, nil
+}
@@ -0,0 +1,272 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"online-subs-api/models"
+	"online-subs-api/repo"
+	"online-subs-api/utils"
+	"regexp"
+	"strings"
+	"time"
+)
+
+type PaymentImportRow struct{
+	Row				int					`json:"row"`
+	Payment			*models.Payment		`json:"payment,omitempty"`
+	Errors			[]string			`json:"errors,omitempty"`
+}
+
+type PaymentImportReport struct{
+	Total			int					`json:"total"`
+	Invalid			int					`json:"invalid"`
+	Imported		int					`json:"imported"`
+	Rows			[]PaymentImportRow	`json:"rows"`
+}
+
+type PaymentService struct{
+	paymentRepo *repo.PaymentRepo
+	subsRepo *repo.SubsRepo
+}
+
+func NewPaymentService(paymentRepo *repo.PaymentRepo, subsRepo *repo.SubsRepo) *PaymentService{
+	return &PaymentService{paymentRepo: paymentRepo, subsRepo: subsRepo}
+}
+
+var currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// parseDay reads a YYYY-MM-DD day, e.g. when a payment was charged
+func parseDay(dateStr string) (time.Time, error){
+	date, err := time.Parse("2006-01-02", strings.TrimSpace(dateStr))
+	if err != nil {
+		return time.Time{}, errors.New("invalid date, expected YYYY-MM-DD")
+	}
+	return date, nil
+}
+
+// validatePayment checks the payment, known caches the subs that were already looked up
+func (s *PaymentService) validatePayment(payment *models.Payment, known map[string]bool) error{
+	if !validateUUID(payment.SubID) {
+		utils.ErrorLogger.Println("Invalid sub_id format:", payment.SubID)
+		return errors.New("invalid sub_id format")
+	}
+	exists, checked := known[payment.SubID]
+	if !checked {
+		_, err := s.subsRepo.GetSubRepoById(payment.SubID)
+		exists = err == nil
+		known[payment.SubID] = exists
+	}
+	if !exists {
+		utils.ErrorLogger.Println("Subscription not found:", payment.SubID)
+		return errors.New("subscription not found")
+	}
+	if payment.Amount <= 0 {
+		utils.ErrorLogger.Println("Invalid payment amount:", payment.Amount)
+		return errors.New("amount must be a postive integer")
+	}
+	if len(payment.Reference) > 255 || len(payment.Method) > 64 {
+		return errors.New("reference must be at most 255 and method at most 64 characters")
+	}
+	payment.Currency = strings.ToUpper(strings.TrimSpace(payment.Currency))
+	if payment.Currency != "" && !currencyCode.MatchString(payment.Currency) {
+		return errors.New("currency must be a 3 letter ISO 4217 code")
+	}
+
+	if payment.Status == "" {
+		payment.Status = models.PaymentPaid
+	}
+	switch payment.Status {
+	case models.PaymentPaid, models.PaymentDisputed:
+		payment.RefundedAmount = 0
+	case models.PaymentRefunded:
+		payment.RefundedAmount = payment.Amount
+	case models.PaymentPartiallyRefunded:
+		if payment.RefundedAmount <= 0 || payment.RefundedAmount >= payment.Amount {
+			return errors.New("refunded_amount of a partially refunded payment must be between 0 and the amount")
+		}
+	default:
+		utils.ErrorLogger.Println("Invalid payment status:", payment.Status)
+		return errors.New("status must be paid, refunded, partially_refunded or disputed")
+	}
+	return nil
+}
+
+func (s *PaymentService) CreatePaymentService(payment *models.Payment, dateStr string) error{
+	date, err := parseDay(dateStr)
+	if err != nil {
+		utils.ErrorLogger.Println("Invalid payment date:", dateStr, "error:", err)
+		return err
+	}
+	payment.Date = date
+	payment.Source = models.PaymentSourceManual
+	if err := s.validatePayment(payment, map[string]bool{}); err != nil {
+		return err
+	}
+
+	id, err := utils.NewUUID()
+	if err != nil {
+		utils.ErrorLogger.Println("Failed to generate UUID:", err)
+		return err
+	}
+	payment.ID = id
+	return s.paymentRepo.CreatePaymentRepo(payment)
+}
+
+// UpdatePaymentService changes the given details of a payment, e.g. to record a refund or a
+// dispute; empty fields are kept
+func (s *PaymentService) UpdatePaymentService(subID, id string, update *models.Payment, dateStr string) (*models.Payment, error){
+	if !validateUUID(subID) || !validateUUID(id) {
+		utils.ErrorLogger.Println("Invalid ID format:", subID, id)
+		return nil, errors.New("invalid id format")
+	}
+	payment, err := s.paymentRepo.GetPaymentRepo(subID, id)
+	if err != nil {
+		utils.ErrorLogger.Println("Payment not found:", id, "error:", err)
+		return nil, errors.New("payment not found")
+	}
+
+	if dateStr != "" {
+		if payment.Date, err = parseDay(dateStr); err != nil {
+			return nil, err
+		}
+	}
+	if update.Amount != 0 {
+		payment.Amount = update.Amount
+	}
+	if update.Currency != "" {
+		payment.Currency = update.Currency
+	}
+	if update.Method != "" {
+		payment.Method = update.Method
+	}
+	if update.Reference != "" {
+		payment.Reference = update.Reference
+	}
+	if update.Status != "" {
+		payment.Status = update.Status
+	}
+	if update.RefundedAmount != 0 {
+		payment.RefundedAmount = update.RefundedAmount
+	}
+	if err := s.validatePayment(payment, map[string]bool{}); err != nil {
+		return nil, err
+	}
+
+	if err := s.paymentRepo.UpdatePaymentRepo(payment); err != nil {
+		utils.ErrorLogger.Println("Failed to update payment:", id, "error:", err)
+		return nil, err
+	}
+	return payment, nil
+}
+
+func (s *PaymentService) ListPaymentsService(subID string) ([]models.Payment, error){
+	if !validateUUID(subID) {
+		utils.ErrorLogger.Println("Invalid sub_id format:", subID)
+		return nil, errors.New("invalid sub_id format")
+	}
+	return s.paymentRepo.ListPaymentsRepo(subID)
+}
+
+func (s *PaymentService) DeletePaymentService(subID, id string) error{
+	if !validateUUID(subID) || !validateUUID(id) {
+		utils.ErrorLogger.Println("Invalid ID format:", subID, id)
+		return errors.New("invalid id format")
+	}
+	if err := s.paymentRepo.DeletePaymentRepo(subID, id); err != nil {
+		utils.ErrorLogger.Println("Failed to delete payment:", id, "error:", err)
+		return errors.New("payment not found")
+	}
+	return nil
+}
+
+var paymentImportFields = appFields{
+	name: []string{"sub_id", "subscription_id"},
+	amount: []string{"amount", "price", "paid"},
+	start: []string{"date", "paid_at", "payment_date"},
+	notes: []string{"reference", "ref", "transaction_id"},
+	currency: []string{"currency"},
+}
+
+var (
+	paymentMethodFields = []string{"method", "payment_method"}
+	paymentStatusFields = []string{"status"}
+	paymentRefundFields = []string{"refunded_amount", "refunded", "refund"}
+)
+
+// ImportPaymentsService reads a CSV with date, amount, reference and sub_id columns and
+// optionally currency, method, status and refunded_amount. The
+// sub_id column can be left out when all rows belong to subID. Nothing is stored unless
+// every row is valid.
+func (s *PaymentService) ImportPaymentsService(r io.Reader, subID string) (*PaymentImportReport, error){
+	items, err := decodeCSVItems(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) > maxImportRows {
+		return nil, fmt.Errorf("imports are limited to %d rows", maxImportRows)
+	}
+
+	report := &PaymentImportReport{Total: len(items), Rows: []PaymentImportRow{}}
+	payments := []models.Payment{}
+	known := map[string]bool{}
+	for i, item := range items {
+		row := PaymentImportRow{Row: i + 1}
+		payment := models.Payment{
+			SubID: item.text(paymentImportFields.name),
+			Currency: item.text(paymentImportFields.currency),
+			Method: item.text(paymentMethodFields),
+			Reference: item.text(paymentImportFields.notes),
+			Status: strings.ToLower(strings.ReplaceAll(item.text(paymentStatusFields), " ", "_")),
+			Source: models.PaymentSourceImport,
+		}
+		if payment.SubID == "" {
+			payment.SubID = subID
+		}
+
+		err := func() error{
+			date, err := parseDay(item.text(paymentImportFields.start))
+			if err != nil {
+				return err
+			}
+			payment.Date = date
+			amount, _, err := parseAmount(item.get(paymentImportFields.amount))
+			if err != nil {
+				return err
+			}
+			payment.Amount = int(math.Round(amount))
+			if refund := item.get(paymentRefundFields); refund != nil {
+				refunded, _, err := parseAmount(refund)
+				if err != nil {
+					return fmt.Errorf("invalid refunded_amount: %v", err)
+				}
+				payment.RefundedAmount = int(math.Round(refunded))
+			}
+			return s.validatePayment(&payment, known)
+		}()
+		if err != nil {
+			row.Errors = append(row.Errors, err.Error())
+			report.Invalid++
+		} else {
+			if payment.ID, err = utils.NewUUID(); err != nil {
+				return nil, err
+			}
+			payments = append(payments, payment)
+		}
+		report.Rows = append(report.Rows, row)
+	}
+
+	if report.Invalid > 0 {
+		return report, nil
+	}
+	if err := s.paymentRepo.CreatePaymentsRepo(payments); err != nil {
+		utils.ErrorLogger.Println("Payment import rolled back:", err)
+		return nil, fmt.Errorf("import rolled back: %v", err)
+	}
+	for i := range report.Rows {
+		report.Rows[i].Payment = &payments[i]
+	}
+	report.Imported = len(payments)
+	return report, nil
+}
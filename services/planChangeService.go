@@ -0,0 +1,217 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"online-subs-api/models"
+	"online-subs-api/utils"
+	"os"
+	"time"
+)
+
+// PlanChangeRequest moves a subscription to a new price (or catalog plan) on a given day
+type PlanChangeRequest struct{
+	Date			string		`json:"date"`
+	Price			int			`json:"price,omitempty"`
+	PlanID			*string		`json:"plan_id,omitempty"`
+	Policy			string		`json:"proration_policy,omitempty"`
+}
+
+// PlanChange is the new price segment with the billing period the change falls in. Net is
+// added to the next charge, or to the charge of that period when the sub ends before.
+type PlanChange struct{
+	Change			models.PriceChange	`json:"change"`
+	PeriodStart		time.Time			`json:"period_start"`
+	PeriodEnd		time.Time			`json:"period_end"`
+	PeriodDays		int					`json:"period_days"`
+	RemainingDays	int					`json:"remaining_days"`
+	Net				int					`json:"net"`
+	DryRun			bool				`json:"dry_run"`
+}
+
+// defaultProrationPolicy is PRORATION_POLICY, immediate when unset
+func defaultProrationPolicy() string{
+	if policy := os.Getenv("PRORATION_POLICY"); policy != "" {
+		return policy
+	}
+	return models.ProrationImmediate
+}
+
+// priceOnDay is the unit price in effect on the day, including immediate plan changes made
+// earlier in the same billing period
+func priceOnDay(sub *models.Sub, changes []models.PriceChange, periodStart, day time.Time) int{
+	price := unitPriceAt(sub, changes, periodStart)
+	var latest time.Time
+	for _, change := range changes {
+		if change.ChangedAt == nil || change.Policy != models.ProrationImmediate {
+			continue
+		}
+		if change.ChangedAt.After(periodStart) && !change.ChangedAt.After(day) && !change.ChangedAt.Before(latest) {
+			price = change.Price
+			latest = *change.ChangedAt
+		}
+	}
+	return price
+}
+
+// planChange opens the new price segment for a change on the given day:
+//   - immediate: the new price from the next charge on, the rest of the current period is
+//     credited at the old price and charged at the new one, by day, with the next charge
+//   - next_renewal: the new price and plan from the next charge on, nothing changes before
+//   - none: the new price for the whole current period, without proration
+//
+// Changes before the first charge or on the first day of a period simply start there.
+func planChange(sub *models.Sub, changes []models.PriceChange, day time.Time, price int, policy string) *PlanChange{
+	step := periodMonths(sub.BillingPeriod)
+	anchor := billingAnchor(sub)
+	changedAt := day
+	change := models.PriceChange{SubID: sub.ID, Price: price, ChangedAt: &changedAt, Policy: policy, PlanFrom: &changedAt}
+
+	if day.Before(anchor) {
+		change.EffectiveDate = monthStart(day)
+		change.PreviousPrice = unitPriceAt(sub, changes, anchor)
+		change.Policy = models.ProrationNone
+		return &PlanChange{Change: change, PeriodStart: anchor, PeriodEnd: anchor.AddDate(0, step, 0)}
+	}
+
+	periodStart := anchor.AddDate(0, monthsBetween(anchor, day)/step*step, 0)
+	periodEnd := periodStart.AddDate(0, step, 0)
+	result := &PlanChange{
+		PeriodStart: periodStart,
+		PeriodEnd: periodEnd,
+		PeriodDays: int(math.Round(periodEnd.Sub(periodStart).Hours() / 24)),
+		RemainingDays: int(math.Round(periodEnd.Sub(day).Hours() / 24)),
+	}
+	change.PreviousPrice = priceOnDay(sub, changes, periodStart, day)
+
+	switch {
+	case day.Equal(periodStart):
+		change.EffectiveDate = periodStart
+	case policy == models.ProrationNone:
+		change.EffectiveDate = periodStart
+		result.Net = (price - change.PreviousPrice) * seatsAt(sub, day)
+	case policy == models.ProrationNextRenewal:
+		change.EffectiveDate = periodEnd
+		change.PlanFrom = &periodEnd
+	default:
+		seats := seatsAt(sub, day)
+		share := float64(result.RemainingDays) / float64(result.PeriodDays)
+		change.EffectiveDate = periodEnd
+		change.Credit = int(math.Round(float64(change.PreviousPrice*seats) * share))
+		change.Charge = int(math.Round(float64(price*seats) * share))
+		month := adjustmentMonth(sub, periodStart)
+		change.AdjustmentMonth = &month
+		result.Net = change.Charge - change.Credit
+	}
+	result.Change = change
+	return result
+}
+
+// ChangePlanService moves the sub to a new price from the given YYYY-MM-DD day, following
+// the proration policy (PRORATION_POLICY by default). A plan_id takes the catalog plan's
+// price unless a price is given. With dryRun nothing is stored.
+func (s *SubsService) ChangePlanService(subID string, req PlanChangeRequest, dryRun bool) (*PlanChange, error){
+	if !validateUUID(subID) {
+		utils.ErrorLogger.Println("Invalid sub_id format:", subID)
+		return nil, errors.New("invalid sub_id format")
+	}
+	sub, err := s.subsRepo.GetSubRepoById(subID)
+	if err != nil {
+		utils.ErrorLogger.Println("Subscription not found:", subID, "error:", err)
+		return nil, errors.New("subscription not found")
+	}
+
+	day, err := parseDay(req.Date)
+	if err != nil {
+		utils.ErrorLogger.Println("Invalid plan change date:", req.Date, "error:", err)
+		return nil, err
+	}
+	if end, ok := subEndMonth(sub); ok && !day.Before(end.AddDate(0, 1, 0)) {
+		return nil, errors.New("subscription has ended before the change")
+	}
+
+	if req.PlanID != nil {
+		plan, err := s.catalogRepo.GetPlanRepoById(*req.PlanID)
+		if err != nil {
+			utils.ErrorLogger.Println("Catalog plan not found:", *req.PlanID, "error:", err)
+			return nil, errors.New("catalog plan not found")
+		}
+		if plan.BillingPeriod != sub.BillingPeriod {
+			return nil, errors.New("the new plan must have the same billing period")
+		}
+		if req.Price == 0 {
+			req.Price = plan.Price
+		}
+	}
+	if req.Price <= 0 {
+		utils.ErrorLogger.Println("Invalid price provided:", req.Price)
+		return nil, errors.New("price must be a postive integer")
+	}
+
+	if req.Policy == "" {
+		req.Policy = defaultProrationPolicy()
+	}
+	switch req.Policy {
+	case models.ProrationImmediate, models.ProrationNextRenewal, models.ProrationNone:
+	default:
+		utils.ErrorLogger.Println("Invalid proration policy:", req.Policy)
+		return nil, errors.New("proration_policy must be immediate, next_renewal or none")
+	}
+
+	changes, err := s.subsRepo.ListPriceChangesRepo(subID)
+	if err != nil {
+		return nil, err
+	}
+	result := planChange(sub, changes, day, req.Price, req.Policy)
+	result.Change.PlanID = req.PlanID
+	result.DryRun = dryRun
+	if req.PlanID == nil {
+		result.Change.PlanFrom = nil
+	} else {
+		result.Change.PlanApplied = !result.Change.PlanFrom.After(time.Now())
+	}
+	if dryRun {
+		return result, nil
+	}
+
+	id, err := utils.NewUUID()
+	if err != nil {
+		utils.ErrorLogger.Println("Failed to generate UUID:", err)
+		return nil, err
+	}
+	result.Change.ID = id
+	if err := s.subsRepo.CreatePlanChangeRepo(&result.Change); err != nil {
+		utils.ErrorLogger.Println("Failed to store plan change of sub:", subID, "error:", err)
+		return nil, err
+	}
+	if result.Change.PlanApplied {
+		sub.PlanID = req.PlanID
+		s.emit(EventSubUpdated, sub)
+	}
+	return result, nil
+}
+
+// ApplyPlanChangesService moves the subs to the catalog plans of the plan changes that have
+// taken effect since, next_renewal changes and changes made for a later day
+func (s *SubsService) ApplyPlanChangesService() error{
+	subs, err := s.subsRepo.ApplyDuePlanChangesRepo(time.Now())
+	for i := range subs {
+		s.emit(EventSubUpdated, &subs[i])
+	}
+	if err != nil {
+		utils.ErrorLogger.Println("Failed to apply due plan changes:", err)
+	}
+	return err
+}
+
+// StartPlanChangeScheduler applies the due plan changes now and then once a day in the background
+func (s *SubsService) StartPlanChangeScheduler(){
+	go func(){
+		for {
+			s.ApplyPlanChangesService()
+			now := time.Now().UTC()
+			next := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+			time.Sleep(time.Until(next))
+		}
+	}()
+}
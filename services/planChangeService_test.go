@@ -0,0 +1,88 @@
+package services
+
+import (
+	"online-subs-api/models"
+	"testing"
+	"time"
+)
+
+func TestPlanChange(t *testing.T){
+	trialEnd := date(2025, time.March, 1)
+	tests := []struct{
+		name			string
+		trialEnd		*time.Time
+		end				time.Time
+		day				time.Time
+		policy			string
+		wantEffective	time.Time
+		wantCredit		int
+		wantCharge		int
+		wantNet			int
+		wantAdjustment	*time.Time
+		wantPlanFrom	time.Time
+	}{
+		{
+			name: "immediate", day: date(2025, time.March, 12), policy: models.ProrationImmediate,
+			wantEffective: date(2025, time.April, 1), wantCredit: 645, wantCharge: 968, wantNet: 323,
+			wantAdjustment: &[]time.Time{date(2025, time.April, 1)}[0], wantPlanFrom: date(2025, time.March, 12),
+		},
+		{
+			name: "immediate on a sub ending in the period", end: date(2025, time.March, 31), day: date(2025, time.March, 12), policy: models.ProrationImmediate,
+			wantEffective: date(2025, time.April, 1), wantCredit: 645, wantCharge: 968, wantNet: 323,
+			wantAdjustment: &[]time.Time{date(2025, time.March, 1)}[0], wantPlanFrom: date(2025, time.March, 12),
+		},
+		{
+			name: "next renewal", day: date(2025, time.March, 12), policy: models.ProrationNextRenewal,
+			wantEffective: date(2025, time.April, 1), wantPlanFrom: date(2025, time.April, 1),
+		},
+		{
+			name: "none", day: date(2025, time.March, 12), policy: models.ProrationNone,
+			wantEffective: date(2025, time.March, 1), wantNet: 500, wantPlanFrom: date(2025, time.March, 12),
+		},
+		{
+			name: "first day of a period", day: date(2025, time.March, 1), policy: models.ProrationImmediate,
+			wantEffective: date(2025, time.March, 1), wantPlanFrom: date(2025, time.March, 1),
+		},
+		{
+			name: "during the trial", trialEnd: &trialEnd, day: date(2025, time.February, 10), policy: models.ProrationImmediate,
+			wantEffective: date(2025, time.February, 1), wantPlanFrom: date(2025, time.February, 10),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T){
+			sub := monthlySub(1000)
+			sub.TrialEndDate = tt.trialEnd
+			sub.EndDate = tt.end
+			result := planChange(sub, nil, tt.day, 1500, tt.policy)
+			got := result.Change
+
+			if !got.EffectiveDate.Equal(tt.wantEffective) {
+				t.Errorf("effective date = %v, want %v", got.EffectiveDate, tt.wantEffective)
+			}
+			if got.Credit != tt.wantCredit || got.Charge != tt.wantCharge || result.Net != tt.wantNet {
+				t.Errorf("credit, charge, net = %d, %d, %d, want %d, %d, %d", got.Credit, got.Charge, result.Net, tt.wantCredit, tt.wantCharge, tt.wantNet)
+			}
+			if (got.AdjustmentMonth == nil) != (tt.wantAdjustment == nil) || (got.AdjustmentMonth != nil && !got.AdjustmentMonth.Equal(*tt.wantAdjustment)) {
+				t.Errorf("adjustment month = %v, want %v", got.AdjustmentMonth, tt.wantAdjustment)
+			}
+			if got.PlanFrom == nil || !got.PlanFrom.Equal(tt.wantPlanFrom) {
+				t.Errorf("plan from = %v, want %v", got.PlanFrom, tt.wantPlanFrom)
+			}
+		})
+	}
+}
+
+func TestPlanChangeCharges(t *testing.T){
+	sub := monthlySub(1000)
+	changes := []models.PriceChange{planChange(sub, nil, date(2025, time.March, 12), 1500, models.ProrationImmediate).Change}
+	for month, want := range map[time.Time]int{
+		date(2025, time.February, 1): 1000,
+		date(2025, time.March, 1): 1000,
+		date(2025, time.April, 1): 1823,
+		date(2025, time.May, 1): 1500,
+	} {
+		if got := grossPriceAt(sub, changes, month); got != want {
+			t.Errorf("grossPriceAt(%s) = %d, want %d", month.Format("2006-01"), got, want)
+		}
+	}
+}
@@ -0,0 +1,249 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"online-subs-api/models"
+	"online-subs-api/repo"
+	"online-subs-api/utils"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ReconciliationConfig sets the trailing months the daily job checks and how many days
+// into a month a charge may come before it counts as missed
+type ReconciliationConfig struct{
+	Months			int
+	GraceDays		int
+}
+
+// ReconciliationConfigFromEnv reads RECONCILE_MONTHS (12) and RECONCILE_GRACE_DAYS (5)
+func ReconciliationConfigFromEnv() ReconciliationConfig{
+	return ReconciliationConfig{
+		Months: max(envInt("RECONCILE_MONTHS", 12), 1),
+		GraceDays: envInt("RECONCILE_GRACE_DAYS", 5),
+	}
+}
+
+type ReconciliationRun struct{
+	Start			string				`json:"start"`
+	End				string				`json:"end"`
+	Subs			int					`json:"subs"`
+	Payments		int					`json:"payments"`
+	Open			int					`json:"open"`
+	ByKind			map[string]int		`json:"by_kind"`
+}
+
+type ReconciliationService struct{
+	paymentRepo *repo.PaymentRepo
+	subsRepo *repo.SubsRepo
+	config ReconciliationConfig
+
+	mu sync.Mutex
+}
+
+func NewReconciliationService(paymentRepo *repo.PaymentRepo, subsRepo *repo.SubsRepo, config ReconciliationConfig) *ReconciliationService{
+	return &ReconciliationService{paymentRepo: paymentRepo, subsRepo: subsRepo, config: config}
+}
+
+func discrepancy(sub *models.Sub, kind string, month time.Time, payments []models.Payment, expected, actual int) models.Discrepancy{
+	ids := models.StringList{}
+	for _, payment := range payments {
+		ids = append(ids, payment.ID)
+	}
+	key := fmt.Sprintf("%s|%s|%s", sub.ID, kind, month.Format("2006-01"))
+	if kind == models.DiscrepancyAfterEnd || kind == models.DiscrepancyUnexpected {
+		key += "|" + ids[0]
+	}
+	return models.Discrepancy{
+		Key: key,
+		SubID: sub.ID,
+		UserID: sub.UserID,
+		TenantID: sub.TenantID,
+		ServiceName: sub.ServiceName,
+		Kind: kind,
+		Month: month,
+		PaymentIDs: ids,
+		Expected: expected,
+		Actual: actual,
+		Status: models.DiscrepancyOpen,
+	}
+}
+
+// reconcileSub compares the expected charges of the sub in [from, to] with its payments.
+// A month of the sub's charges is checked for a missing payment once its grace period has
+// passed; several payments adding up to the price are a split payment, not a double charge.
+// Payments are counted with their amount after refunds and compared with the charge
+// including tax.
+func reconcileSub(sub *models.Sub, changes []models.PriceChange, rates map[string][]models.TaxRate, payments []models.Payment, from, to, now time.Time, graceDays int) []models.Discrepancy{
+	found := []models.Discrepancy{}
+	expected := map[time.Time]int{}
+	for _, month := range chargeMonths(sub, from, to) {
+		expected[month] = chargedAt(sub, changes, rates, month)
+	}
+	paid := map[time.Time][]models.Payment{}
+	for _, payment := range payments {
+		// refunded payments count as not paid
+		if payment.NetAmount() == 0 {
+			continue
+		}
+		month := monthStart(payment.Date)
+		paid[month] = append(paid[month], payment)
+	}
+	end, ended := subEndMonth(sub)
+
+	for month, price := range expected {
+		if len(paid[month]) == 0 && month.AddDate(0, 0, graceDays).Before(now) {
+			found = append(found, discrepancy(sub, models.DiscrepancyMissed, month, nil, price, 0))
+		}
+	}
+
+	for month, list := range paid {
+		price, isExpected := expected[month]
+		if !isExpected {
+			kind := models.DiscrepancyUnexpected
+			if ended && month.After(end) {
+				kind = models.DiscrepancyAfterEnd
+			}
+			for _, payment := range list {
+				found = append(found, discrepancy(sub, kind, month, []models.Payment{payment}, 0, payment.NetAmount()))
+			}
+			continue
+		}
+
+		total, allFull := 0, true
+		for _, payment := range list {
+			total += payment.NetAmount()
+			allFull = allFull && payment.NetAmount() == price
+		}
+		switch {
+		case total == price:
+		case len(list) > 1 && allFull:
+			found = append(found, discrepancy(sub, models.DiscrepancyDouble, month, list, price, total))
+		default:
+			found = append(found, discrepancy(sub, models.DiscrepancyAmountMismatch, month, list, price, total))
+		}
+	}
+
+	sort.Slice(found, func(i, j int) bool{ return found[i].Key < found[j].Key })
+	return found
+}
+
+// RunReconciliationService reconciles the subs matching the filter between two MM-YYYY
+// months, by default the trailing months of the config up to the current month
+func (s *ReconciliationService) RunReconciliationService(startStr, endStr string, filter repo.SubsFilter) (*ReconciliationRun, error){
+	now := time.Now().UTC()
+	to := monthStart(now)
+	from := to.AddDate(0, 1-s.config.Months, 0)
+	var err error
+	if startStr != "" {
+		if from, err = validDate(startStr); err != nil {
+			utils.ErrorLogger.Println("Invalid start date:", startStr, "error:", err)
+			return nil, err
+		}
+	}
+	if endStr != "" {
+		if to, err = validDate(endStr); err != nil {
+			utils.ErrorLogger.Println("Invalid end date:", endStr, "error:", err)
+			return nil, err
+		}
+	}
+	if to.Before(from) {
+		return nil, errors.New("end must not be before start")
+	}
+	if err := validateFilter(filter); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subs, err := s.subsRepo.ListAllSubsRepo(filter)
+	if err != nil {
+		utils.ErrorLogger.Println("Failed to load subscriptions for reconciliation:", err)
+		return nil, err
+	}
+	ids := []string{}
+	for _, sub := range subs {
+		ids = append(ids, sub.ID)
+	}
+	changes, err := s.subsRepo.ListPriceChangesBySubRepo(ids)
+	if err != nil {
+		return nil, err
+	}
+	payments, err := s.paymentRepo.ListPaymentsBySubRepo(ids, from, to.AddDate(0, 1, 0))
+	if err != nil {
+		return nil, err
+	}
+	rates, err := s.subsRepo.ListTaxRatesRepo()
+	if err != nil {
+		return nil, err
+	}
+	byJurisdiction := ratesByJurisdiction(rates)
+
+	run := &ReconciliationRun{Start: from.Format("2006-01"), End: to.Format("2006-01"), Subs: len(subs), ByKind: map[string]int{}}
+	found := []models.Discrepancy{}
+	for i := range subs {
+		run.Payments += len(payments[subs[i].ID])
+		for _, d := range reconcileSub(&subs[i], changes[subs[i].ID], byJurisdiction, payments[subs[i].ID], from, to, now, s.config.GraceDays) {
+			if d.ID, err = utils.NewUUID(); err != nil {
+				return nil, err
+			}
+			d.DetectedAt = now
+			found = append(found, d)
+			run.ByKind[d.Kind]++
+		}
+	}
+	run.Open = len(found)
+
+	if err := s.paymentRepo.SaveDiscrepanciesRepo(found, ids, from, to.AddDate(0, 1, 0), now); err != nil {
+		utils.ErrorLogger.Println("Failed to store discrepancies:", err)
+		return nil, err
+	}
+	utils.InfoLogger.Printf("Reconciled %d subscriptions from %s to %s: %d open discrepancies", run.Subs, run.Start, run.End, run.Open)
+	return run, nil
+}
+
+// StartDailyReconciliation reconciles all subscriptions now and then once a day in the background
+func (s *ReconciliationService) StartDailyReconciliation(){
+	go func(){
+		for {
+			if _, err := s.RunReconciliationService("", "", repo.SubsFilter{}); err != nil {
+				utils.ErrorLogger.Println("Daily reconciliation failed:", err)
+			}
+			now := time.Now().UTC()
+			next := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+			time.Sleep(time.Until(next))
+		}
+	}()
+}
+
+func (s *ReconciliationService) ListDiscrepanciesService(filter repo.DiscrepancyFilter, startStr, endStr string) ([]models.Discrepancy, error){
+	if filter.UserID != "" && !validateUUID(filter.UserID) {
+		return nil, errors.New("invalid user_id format")
+	}
+	if filter.SubID != "" && !validateUUID(filter.SubID) {
+		return nil, errors.New("invalid sub_id format")
+	}
+	switch filter.Kind {
+	case "", models.DiscrepancyMissed, models.DiscrepancyDouble, models.DiscrepancyAmountMismatch, models.DiscrepancyAfterEnd, models.DiscrepancyUnexpected:
+	default:
+		return nil, errors.New("kind must be one of missed, double, amount_mismatch, after_end, unexpected")
+	}
+	if filter.Status != "" && filter.Status != models.DiscrepancyOpen && filter.Status != models.DiscrepancyResolved {
+		return nil, errors.New("status must be open or resolved")
+	}
+	var err error
+	if startStr != "" {
+		if filter.From, err = validDate(startStr); err != nil {
+			return nil, err
+		}
+	}
+	if endStr != "" {
+		if filter.To, err = validDate(endStr); err != nil {
+			return nil, err
+		}
+	}
+	return s.paymentRepo.ListDiscrepanciesRepo(filter)
+}
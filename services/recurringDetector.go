@@ -0,0 +1,216 @@
+package services
+
+import (
+	"math"
+	"online-subs-api/models"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// cadenceRule describes a billing rhythm: charges Days apart give or take Tolerance days.
+// Expected is the number of intervals after which a series counts as well established.
+type cadenceRule struct{
+	cadence			string
+	days			float64
+	tolerance		float64
+	expected		int
+}
+
+var cadenceRules = []cadenceRule{
+	{models.CadenceWeekly, 7, 2, 6},
+	{models.CadenceMonthly, 365.25 / 12, 4, 5},
+	{models.CadenceQuarterly, 365.25 / 4, 8, 3},
+	{models.CadenceYearly, 365.25, 15, 1},
+}
+
+// amounts within this ratio of the smallest charge of a band belong to the same series,
+// so price increases don't split a subscription in two
+const amountBandRatio = 1.25
+
+// RecurringCharge is a series of charges to one merchant at a regular cadence
+type RecurringCharge struct{
+	Merchant		string
+	Currency		string
+	Cadence			string
+	Charges			[]models.StatementCharge
+	Amount			float64
+	NextExpected	time.Time
+	Active			bool
+	Confidence		float64
+}
+
+var merchantPrefixes = []string{
+	"paypal *", "paypal*", "pp*", "sq *", "sq*", "sp *", "tst* ", "pos ", "card ", "visa ", "mc ", "debit ", "purchase ",
+	"direct debit ", "dd ", "payment to ", "recurring ", "online ",
+}
+
+var merchantNoise = map[string]bool{
+	"www": true, "com": true, "net": true, "org": true, "io": true, "ru": true, "kz": true, "inc": true, "ltd": true, "llc": true,
+	"gmbh": true, "bv": true, "sarl": true, "subscription": true, "payment": true, "purchase": true, "bill": true, "help": true,
+}
+
+// normalizeMerchant reduces a statement description to a merchant key: processor
+// prefixes, reference numbers, domains and legal suffixes are dropped
+// ("PAYPAL *NETFLIX.COM 866-579-7172 CA" -> "netflix")
+func normalizeMerchant(description string) string{
+	text := strings.ToLower(strings.TrimSpace(description))
+	for stripped := true; stripped; {
+		stripped = false
+		for _, prefix := range merchantPrefixes {
+			if strings.HasPrefix(text, prefix) {
+				text, stripped = strings.TrimSpace(strings.TrimPrefix(text, prefix)), true
+			}
+		}
+	}
+
+	tokens := []string{}
+	for _, field := range strings.Fields(text) {
+		digits := 0
+		for _, r := range field {
+			if unicode.IsDigit(r) {
+				digits++
+			}
+		}
+		// references, card numbers, dates and phone numbers
+		if digits >= 3 || float64(digits) > 0.3*float64(len(field)) {
+			continue
+		}
+		for _, token := range strings.FieldsFunc(field, func(r rune) bool{ return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+			if merchantNoise[token] || len([]rune(token)) < 2 || (len(tokens) > 0 && len([]rune(token)) == 2) {
+				continue
+			}
+			tokens = append(tokens, token)
+		}
+		if len(tokens) >= 3 {
+			break
+		}
+	}
+	if len(tokens) > 3 {
+		tokens = tokens[:3]
+	}
+	return strings.Join(tokens, " ")
+}
+
+// detectRecurring groups the debits by merchant and currency, splits each group into
+// bands of similar amounts and scores the bands that follow a known cadence. asOf is the
+// end of the statement, a series is active when its next charge isn't overdue by then.
+func detectRecurring(transactions []BankTransaction, asOf time.Time) []RecurringCharge{
+	clusters := map[[2]string][]models.StatementCharge{}
+	for _, t := range transactions {
+		if t.Amount >= 0 {
+			continue
+		}
+		merchant := normalizeMerchant(t.Description)
+		if merchant == "" {
+			continue
+		}
+		key := [2]string{merchant, t.Currency}
+		clusters[key] = append(clusters[key], models.StatementCharge{Date: t.Date, Amount: -t.Amount, Description: t.Description})
+	}
+
+	found := []RecurringCharge{}
+	for key, charges := range clusters {
+		sort.Slice(charges, func(i, j int) bool{ return charges[i].Amount < charges[j].Amount })
+		for start := 0; start < len(charges); {
+			end := start + 1
+			for end < len(charges) && charges[end].Amount <= charges[start].Amount*amountBandRatio {
+				end++
+			}
+			band := append([]models.StatementCharge{}, charges[start:end]...)
+			start = end
+
+			if series, ok := scoreSeries(band, asOf); ok {
+				series.Merchant, series.Currency = key[0], key[1]
+				found = append(found, series)
+			}
+		}
+	}
+
+	sort.Slice(found, func(i, j int) bool{
+		if found[i].Confidence != found[j].Confidence {
+			return found[i].Confidence > found[j].Confidence
+		}
+		return found[i].Merchant < found[j].Merchant
+	})
+	return found
+}
+
+func scoreSeries(charges []models.StatementCharge, asOf time.Time) (RecurringCharge, bool){
+	if len(charges) < 2 {
+		return RecurringCharge{}, false
+	}
+	sort.Slice(charges, func(i, j int) bool{ return charges[i].Date.Before(charges[j].Date) })
+
+	intervals := []float64{}
+	for i := 1; i < len(charges); i++ {
+		intervals = append(intervals, charges[i].Date.Sub(charges[i-1].Date).Hours()/24)
+	}
+	sorted := append([]float64{}, intervals...)
+	sort.Float64s(sorted)
+	median := sorted[len(sorted)/2]
+	if len(sorted)%2 == 0 {
+		median = (sorted[len(sorted)/2-1] + sorted[len(sorted)/2]) / 2
+	}
+
+	var rule *cadenceRule
+	for i := range cadenceRules {
+		if math.Abs(median-cadenceRules[i].days) <= cadenceRules[i].tolerance {
+			rule = &cadenceRules[i]
+			break
+		}
+	}
+	if rule == nil {
+		return RecurringCharge{}, false
+	}
+
+	regular := 0
+	for _, interval := range intervals {
+		if math.Abs(interval-rule.days) <= rule.tolerance {
+			regular++
+		}
+	}
+	regularity := float64(regular) / float64(len(intervals))
+	if regularity < 0.5 {
+		return RecurringCharge{}, false
+	}
+
+	mean := 0.0
+	for _, charge := range charges {
+		mean += charge.Amount
+	}
+	mean /= float64(len(charges))
+	variance := 0.0
+	for _, charge := range charges {
+		variance += (charge.Amount - mean) * (charge.Amount - mean)
+	}
+	stability := math.Max(0, 1-5*math.Sqrt(variance/float64(len(charges)))/mean)
+	established := math.Min(1, float64(len(intervals))/float64(rule.expected))
+
+	last := charges[len(charges)-1]
+	next := last.Date.AddDate(0, 0, 7)
+	switch rule.cadence {
+	case models.CadenceMonthly:
+		next = last.Date.AddDate(0, 1, 0)
+	case models.CadenceQuarterly:
+		next = last.Date.AddDate(0, 3, 0)
+	case models.CadenceYearly:
+		next = last.Date.AddDate(1, 0, 0)
+	}
+	active := !asOf.After(next.AddDate(0, 0, int(2*rule.tolerance)))
+	recency := 0.3
+	if active {
+		recency = 1
+	}
+
+	confidence := 0.4*regularity + 0.3*established + 0.2*stability + 0.1*recency
+	return RecurringCharge{
+		Cadence: rule.cadence,
+		Charges: charges,
+		Amount: last.Amount,
+		NextExpected: next,
+		Active: active,
+		Confidence: math.Round(confidence*100) / 100,
+	}, true
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"online-subs-api/models"
+	"testing"
+	"time"
+)
+
+func TestNormalizeMerchant(t *testing.T){
+	tests := []struct{
+		description	string
+		want		string
+	}{
+		{"PAYPAL *NETFLIX.COM 866-579-7172 CA", "netflix"},
+		{"DD Spotify UK Ltd", "spotify"},
+		{"SQ *BLUE BOTTLE COFFEE 12345", "blue bottle coffee"},
+		{"  Card  github.com/billing ", "github billing"},
+		{"12345 67890", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T){
+			if got := normalizeMerchant(tt.description); got != tt.want {
+				t.Errorf("normalizeMerchant(%q) = %q, want %q", tt.description, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectRecurring(t *testing.T){
+	monthly := func(description string, amounts ...float64) []BankTransaction{
+		list := []BankTransaction{}
+		for i, amount := range amounts {
+			list = append(list, BankTransaction{Date: date(2025, time.January, 15).AddDate(0, i, 0), Amount: -amount, Currency: "EUR", Description: description})
+		}
+		return list
+	}
+	type series struct{
+		merchant	string
+		cadence		string
+		charges		int
+		amount		float64
+		active		bool
+	}
+	asOf := date(2025, time.June, 30)
+	tests := []struct{
+		name			string
+		transactions	[]BankTransaction
+		want			[]series
+	}{
+		{
+			name: "monthly",
+			transactions: monthly("NETFLIX.COM 866-579-7172", 15.99, 15.99, 15.99, 15.99, 15.99, 15.99),
+			want: []series{{"netflix", models.CadenceMonthly, 6, 15.99, true}},
+		},
+		{
+			name: "a price increase stays one series",
+			transactions: monthly("Spotify AB", 9.99, 9.99, 9.99, 10.99, 10.99, 10.99),
+			want: []series{{"spotify", models.CadenceMonthly, 6, 10.99, true}},
+		},
+		{
+			name: "lapsed",
+			transactions: monthly("FITNESS CLUB", 30, 30, 30),
+			want: []series{{"fitness club", models.CadenceMonthly, 3, 30, false}},
+		},
+		{
+			name: "credits are ignored",
+			transactions: []BankTransaction{
+				{Date: date(2025, time.January, 25), Amount: 3000, Currency: "EUR", Description: "SALARY"},
+				{Date: date(2025, time.February, 25), Amount: 3000, Currency: "EUR", Description: "SALARY"},
+				{Date: date(2025, time.March, 25), Amount: 3000, Currency: "EUR", Description: "SALARY"},
+			},
+			want: []series{},
+		},
+		{
+			name: "irregular",
+			transactions: []BankTransaction{
+				{Date: date(2025, time.January, 3), Amount: -4.5, Currency: "EUR", Description: "COFFEE SHOP"},
+				{Date: date(2025, time.January, 5), Amount: -4.5, Currency: "EUR", Description: "COFFEE SHOP"},
+				{Date: date(2025, time.February, 20), Amount: -4.5, Currency: "EUR", Description: "COFFEE SHOP"},
+				{Date: date(2025, time.April, 1), Amount: -4.5, Currency: "EUR", Description: "COFFEE SHOP"},
+			},
+			want: []series{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T){
+			found := detectRecurring(tt.transactions, asOf)
+			if len(found) != len(tt.want) {
+				t.Fatalf("detectRecurring() found %d series, want %d: %+v", len(found), len(tt.want), found)
+			}
+			for i, want := range tt.want {
+				got := series{found[i].Merchant, found[i].Cadence, len(found[i].Charges), found[i].Amount, found[i].Active}
+				if got != want {
+					t.Errorf("series %d = %+v, want %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+package services
+
+import (
+	"errors"
+	"online-subs-api/models"
+	"online-subs-api/repo"
+	"online-subs-api/utils"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const reminderBatchSize = 100
+
+// ReminderConfig sets how many days ahead each kind of reminder fires, how often the
+// scheduler runs and how many delivery attempts a reminder gets
+type ReminderConfig struct{
+	RenewalLeadDays		int
+	TrialEndLeadDays	int
+	EndLeadDays			int
+	Interval			time.Duration
+	MaxAttempts			int
+}
+
+func envInt(name string, fallback int) int{
+	if value, err := strconv.Atoi(os.Getenv(name)); err == nil && value >= 0 {
+		return value
+	}
+	return fallback
+}
+
+// ReminderConfigFromEnv reads REMINDER_RENEWAL_LEAD_DAYS (3), REMINDER_TRIAL_LEAD_DAYS (3),
+// REMINDER_END_LEAD_DAYS (7), REMINDER_INTERVAL_MINUTES (60) and REMINDER_MAX_ATTEMPTS (5)
+func ReminderConfigFromEnv() ReminderConfig{
+	return ReminderConfig{
+		RenewalLeadDays: envInt("REMINDER_RENEWAL_LEAD_DAYS", 3),
+		TrialEndLeadDays: envInt("REMINDER_TRIAL_LEAD_DAYS", 3),
+		EndLeadDays: envInt("REMINDER_END_LEAD_DAYS", 7),
+		Interval: time.Duration(max(envInt("REMINDER_INTERVAL_MINUTES", 60), 1)) * time.Minute,
+		MaxAttempts: max(envInt("REMINDER_MAX_ATTEMPTS", 5), 1),
+	}
+}
+
+type ReminderService struct{
+	reminderRepo *repo.ReminderRepo
+	subsRepo *repo.SubsRepo
+	notifiers map[string]Notifier
+	channels []string
+	config ReminderConfig
+	mu sync.Mutex
+}
+
+func NewReminderService(reminderRepo *repo.ReminderRepo, subsRepo *repo.SubsRepo, notifiers []Notifier, config ReminderConfig) *ReminderService{
+	s := &ReminderService{reminderRepo: reminderRepo, subsRepo: subsRepo, notifiers: map[string]Notifier{}, config: config}
+	for _, notifier := range notifiers {
+		s.notifiers[notifier.Name()] = notifier
+		s.channels = append(s.channels, notifier.Name())
+	}
+	return s
+}
+
+// ReminderRun sums up one scheduler pass
+type ReminderRun struct{
+	Created			int		`json:"created"`
+	Sent			int		`json:"sent"`
+	Retrying		int		`json:"retrying"`
+	Failed			int		`json:"failed"`
+}
+
+func dayStart(t time.Time) time.Time{
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// inWindow is true when the date falls within the next leadDays days, today included
+func inWindow(date, today time.Time, leadDays int) bool{
+	return !date.Before(today) && !date.After(today.AddDate(0, 0, leadDays))
+}
+
+// dueReminders lists the reminders the sub needs today, before dedup, with the charge
+// including tax
+func (s *ReminderService) dueReminders(sub *models.Sub, changes []models.PriceChange, rates map[string][]models.TaxRate, today time.Time) []models.Reminder{
+	reminders := []models.Reminder{}
+	add := func(kind string, date time.Time, price int){
+		reminders = append(reminders, models.Reminder{
+			SubID: sub.ID,
+			Kind: kind,
+			EventDate: date,
+			UserID: sub.UserID,
+			TenantID: sub.TenantID,
+			ServiceName: sub.ServiceName,
+			Price: price,
+		})
+	}
+
+	if next, ok := nextChargeDate(sub, today); ok && inWindow(next, today, s.config.RenewalLeadDays) {
+		add(models.ReminderRenewal, next, chargedAt(sub, changes, rates, next))
+	}
+	if sub.TrialEndDate != nil {
+		trialEnd := monthStart(*sub.TrialEndDate)
+		if inWindow(trialEnd, today, s.config.TrialEndLeadDays) {
+			add(models.ReminderTrialEnd, trialEnd, chargedAt(sub, changes, rates, trialEnd))
+		}
+	}
+	if end, ok := subEndMonth(sub); ok {
+		lastDay := end.AddDate(0, 1, -1)
+		if inWindow(lastDay, today, s.config.EndLeadDays) {
+			add(models.ReminderEnd, lastDay, 0)
+		}
+	}
+	return reminders
+}
+
+// scan creates the reminders that became due, one per notifier channel
+func (s *ReminderService) scan(now time.Time) (int, error){
+	today := dayStart(now)
+	subs, err := s.subsRepo.ListActiveSubsRepo(monthStart(today), repo.SubsFilter{})
+	if err != nil {
+		return 0, err
+	}
+	ids := []string{}
+	for _, sub := range subs {
+		ids = append(ids, sub.ID)
+	}
+	changes, err := s.subsRepo.ListPriceChangesBySubRepo(ids)
+	if err != nil {
+		return 0, err
+	}
+	rates, err := s.subsRepo.ListTaxRatesRepo()
+	if err != nil {
+		return 0, err
+	}
+	byJurisdiction := ratesByJurisdiction(rates)
+
+	created := 0
+	for i := range subs {
+		for _, reminder := range s.dueReminders(&subs[i], changes[subs[i].ID], byJurisdiction, today) {
+			for _, channel := range s.channels {
+				id, err := utils.NewUUID()
+				if err != nil {
+					return created, err
+				}
+				reminder.ID = id
+				reminder.Channel = channel
+				reminder.Status = models.ReminderPending
+				reminder.NextAttemptAt = now
+				ok, err := s.reminderRepo.CreateReminderRepo(&reminder)
+				if err != nil {
+					return created, err
+				}
+				if ok {
+					created++
+				}
+			}
+		}
+	}
+	return created, nil
+}
+
+// deliver sends the due reminders, failures back off exponentially (1, 2, 4... minutes)
+// until MaxAttempts is reached
+func (s *ReminderService) deliver(now time.Time, run *ReminderRun) error{
+	reminders, err := s.reminderRepo.ListDueRemindersRepo(now, reminderBatchSize)
+	if err != nil {
+		return err
+	}
+
+	for i := range reminders {
+		reminder := &reminders[i]
+		notifier, ok := s.notifiers[reminder.Channel]
+		if !ok {
+			err = errors.New("notifier " + reminder.Channel + " is not configured")
+		} else if sub, subErr := s.subsRepo.GetSubRepoById(reminder.SubID); subErr != nil {
+			err = errors.New("subscription not found")
+		} else {
+			err = notifier.Notify(reminder, sub)
+		}
+
+		reminder.Attempts++
+		switch {
+		case err == nil:
+			sentAt := time.Now().UTC()
+			reminder.Status = models.ReminderSent
+			reminder.SentAt = &sentAt
+			reminder.LastError = ""
+			run.Sent++
+		case reminder.Attempts >= s.config.MaxAttempts:
+			reminder.Status = models.ReminderFailed
+			reminder.LastError = err.Error()
+			run.Failed++
+		default:
+			reminder.LastError = err.Error()
+			reminder.NextAttemptAt = now.Add(time.Duration(1<<(reminder.Attempts-1)) * time.Minute)
+			run.Retrying++
+		}
+		if err != nil {
+			utils.WarningLogger.Printf("Reminder %s via %s failed (attempt %d): %v", reminder.ID, reminder.Channel, reminder.Attempts, err)
+		}
+		if err := s.reminderRepo.UpdateReminderRepo(reminder); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RunRemindersService runs one scheduler pass: create the due reminders, then deliver them
+func (s *ReminderService) RunRemindersService() (*ReminderRun, error){
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	run := &ReminderRun{}
+	created, err := s.scan(now)
+	run.Created = created
+	if err != nil {
+		utils.ErrorLogger.Println("Failed to create reminders:", err)
+		return run, err
+	}
+	if err := s.deliver(now, run); err != nil {
+		utils.ErrorLogger.Println("Failed to deliver reminders:", err)
+		return run, err
+	}
+	return run, nil
+}
+
+// StartScheduler runs the reminder pass every config.Interval in the background
+func (s *ReminderService) StartScheduler(){
+	go func(){
+		for {
+			if run, err := s.RunRemindersService(); err == nil && run.Created+run.Sent+run.Failed > 0 {
+				utils.InfoLogger.Printf("Reminders: %d created, %d sent, %d retrying, %d failed", run.Created, run.Sent, run.Retrying, run.Failed)
+			}
+			time.Sleep(s.config.Interval)
+		}
+	}()
+}
+
+func (s *ReminderService) ListRemindersService(subID, status string) ([]models.Reminder, error){
+	if subID != "" && !validateUUID(subID) {
+		return nil, errors.New("invalid sub_id format")
+	}
+	switch status {
+	case "", models.ReminderPending, models.ReminderSent, models.ReminderFailed:
+	default:
+		return nil, errors.New("status must be pending, sent or failed")
+	}
+	return s.reminderRepo.ListRemindersRepo(subID, status)
+}
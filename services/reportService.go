@@ -0,0 +1,159 @@
+package services
+
+import (
+	"errors"
+	"online-subs-api/repo"
+	"online-subs-api/utils"
+	"slices"
+	"strings"
+)
+
+type ReportService struct{
+	reportRepo *repo.ReportRepo
+	subsRepo *repo.SubsRepo
+}
+
+func NewReportService(reportRepo *repo.ReportRepo, subsRepo *repo.SubsRepo) *ReportService{
+	return &ReportService{reportRepo: reportRepo, subsRepo: subsRepo}
+}
+
+// ReportGroup is the spend of one combination of the requested dimensions, Amount is net of
+// discounts and Gross before them, TaxBreakdown splits Amount into net, tax and gross
+type ReportGroup struct{
+	UserID			*string				`json:"user_id,omitempty"`
+	ServiceName		*string				`json:"service_name,omitempty"`
+	Category		*string				`json:"category,omitempty"`
+	Gross			int					`json:"gross"`
+	Amount			int					`json:"amount"`
+	TaxBreakdown	repo.TaxBreakdown	`json:"tax_breakdown"`
+}
+
+// ReportPeriod is one month ("2025-07") or year ("2025") of the time series
+type ReportPeriod struct{
+	Period			string				`json:"period"`
+	Groups			[]ReportGroup		`json:"groups,omitempty"`
+	GrossSubtotal	int					`json:"gross_subtotal"`
+	Subtotal		int					`json:"subtotal"`
+	TaxSubtotal		repo.TaxBreakdown	`json:"tax_subtotal"`
+}
+
+type SpendingReport struct{
+	Start			string				`json:"start"`
+	End				string				`json:"end"`
+	Interval		string				`json:"interval"`
+	GroupBy			[]string			`json:"group_by"`
+	Series			[]ReportPeriod		`json:"series"`
+	GroupTotals		[]ReportGroup		`json:"group_totals,omitempty"`
+	GrossTotal		int					`json:"gross_total"`
+	GrandTotal		int					`json:"grand_total"`
+	TaxTotal		repo.TaxBreakdown	`json:"tax_total"`
+}
+
+// parseGroupBy splits "user,service" into known dimensions, dropping duplicates
+func parseGroupBy(groupBy string) ([]string, error){
+	dims := []string{}
+	for _, dim := range strings.Split(groupBy, ",") {
+		dim = strings.TrimSpace(dim)
+		if dim == "" || slices.Contains(dims, dim) {
+			continue
+		}
+		if _, ok := repo.ReportDimensions[dim]; !ok {
+			return nil, errors.New("group_by must be a combination of user, service, category")
+		}
+		dims = append(dims, dim)
+	}
+	return dims, nil
+}
+
+// SpendingReportService builds the spend time series between two MM-YYYY months (inclusive)
+func (s *ReportService) SpendingReportService(startStr, endStr, interval, groupBy string, filter repo.SubsFilter) (*SpendingReport, error){
+	start, err := validDate(startStr)
+	if err != nil {
+		utils.ErrorLogger.Println("Invalid start date:", startStr, "error:", err)
+		return nil, err
+	}
+	end, err := validDate(endStr)
+	if err != nil {
+		utils.ErrorLogger.Println("Invalid end date:", endStr, "error:", err)
+		return nil, err
+	}
+	if end.Before(start) {
+		return nil, errors.New("end must not be before start")
+	}
+
+	if interval == "" {
+		interval = "month"
+	}
+	if interval != "month" && interval != "year" {
+		utils.ErrorLogger.Println("Invalid interval:", interval)
+		return nil, errors.New("interval must be month or year")
+	}
+
+	dims, err := parseGroupBy(groupBy)
+	if err != nil {
+		utils.ErrorLogger.Println("Invalid group_by:", groupBy)
+		return nil, err
+	}
+	if err := validateFilter(filter); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.reportRepo.GetSpendingReportRepo(start, end, interval, dims, filter)
+	if err != nil {
+		utils.ErrorLogger.Println("Failed to build spending report:", err)
+		return nil, err
+	}
+
+	layout := "2006-01"
+	if interval == "year" {
+		layout = "2006"
+	}
+
+	report := &SpendingReport{
+		Start: start.Format("2006-01"),
+		End: end.Format("2006-01"),
+		Interval: interval,
+		GroupBy: dims,
+		Series: []ReportPeriod{},
+	}
+	periods := map[string]int{}
+
+	// list every period of the range, including the ones without any spend
+	for month := start; !month.After(end); month = month.AddDate(0, 1, 0) {
+		key := month.Format(layout)
+		if _, ok := periods[key]; !ok {
+			periods[key] = len(report.Series)
+			report.Series = append(report.Series, ReportPeriod{Period: key})
+		}
+	}
+
+	for _, row := range rows {
+		tax := repo.TaxBreakdown{Net: row.TaxNet, Tax: row.Tax, Gross: row.TaxNet + row.Tax}
+		group := ReportGroup{UserID: row.UserID, ServiceName: row.ServiceName, Category: row.Category, Gross: row.Gross, Amount: row.Amount, TaxBreakdown: tax}
+
+		switch {
+		case row.PeriodGrouped != 0 && (row.DimsGrouped != 0 || len(dims) == 0):
+			report.GrossTotal = row.Gross
+			report.GrandTotal = row.Amount
+			report.TaxTotal = tax
+		case row.PeriodGrouped != 0:
+			report.GroupTotals = append(report.GroupTotals, group)
+		default:
+			key := row.Period.Format(layout)
+			i, ok := periods[key]
+			if !ok {
+				i = len(report.Series)
+				periods[key] = i
+				report.Series = append(report.Series, ReportPeriod{Period: key})
+			}
+			if row.DimsGrouped != 0 || len(dims) == 0 {
+				report.Series[i].GrossSubtotal = row.Gross
+				report.Series[i].Subtotal = row.Amount
+				report.Series[i].TaxSubtotal = tax
+			} else {
+				report.Series[i].Groups = append(report.Series[i].Groups, group)
+			}
+		}
+	}
+	return report, nil
+}
@@ -0,0 +1,231 @@
+package services
+
+import (
+	"errors"
+	"online-subs-api/models"
+	"online-subs-api/utils"
+	"strings"
+	"time"
+)
+
+// SeatMonth is the seat usage of a subscription at the end of a month. UnusedCost is the
+// monthly cost of the unassigned seats.
+type SeatMonth struct{
+	Month			string		`json:"month"`
+	Seats			int			`json:"seats"`
+	Assigned		int			`json:"assigned"`
+	Unused			int			`json:"unused"`
+	Utilization		float64		`json:"utilization"`
+	UnusedCost		int			`json:"unused_cost"`
+}
+
+type SeatUtilization struct{
+	SubID			string					`json:"sub_id"`
+	ServiceName		string					`json:"service_name"`
+	UnitPrice		int						`json:"unit_price"`
+	Months			[]SeatMonth				`json:"months"`
+	Assignments		[]models.SeatAssignment	`json:"assignments"`
+}
+
+func (s *SubsService) CreateSeatChangeService(change *models.SeatChange, effectiveDateStr string) error{
+	if !validateUUID(change.SubID) {
+		utils.ErrorLogger.Println("Invalid sub_id format:", change.SubID)
+		return errors.New("invalid sub_id format")
+	}
+	sub, err := s.subsRepo.GetSubRepoById(change.SubID)
+	if err != nil {
+		utils.ErrorLogger.Println("Subscription not found:", change.SubID, "error:", err)
+		return errors.New("subscription not found")
+	}
+	if change.Seats < 0 {
+		utils.ErrorLogger.Println("Invalid seats provided:", change.Seats)
+		return errors.New("seats must not be negative")
+	}
+
+	effectiveDate, err := parseDay(effectiveDateStr)
+	if err != nil {
+		utils.ErrorLogger.Println("Invalid effective date:", effectiveDateStr, "error:", err)
+		return err
+	}
+	change.EffectiveDate = effectiveDate
+
+	id, err := utils.NewUUID()
+	if err != nil {
+		utils.ErrorLogger.Println("Failed to generate UUID:", err)
+		return err
+	}
+	change.ID = id
+	if err := s.subsRepo.CreateSeatChangeRepo(change); err != nil {
+		utils.ErrorLogger.Println("Failed to store seat change of sub:", change.SubID, "error:", err)
+		return err
+	}
+	s.emit(EventSubUpdated, sub)
+	return nil
+}
+
+func (s *SubsService) ListSeatChangesService(subID string) ([]models.SeatChange, error){
+	if !validateUUID(subID) {
+		utils.ErrorLogger.Println("Invalid sub_id format:", subID)
+		return nil, errors.New("invalid sub_id format")
+	}
+	return s.subsRepo.ListSeatChangesRepo(subID)
+}
+
+func (s *SubsService) DeleteSeatChangeService(subID, id string) error{
+	if !validateUUID(subID) || !validateUUID(id) {
+		utils.ErrorLogger.Println("Invalid ID format:", subID, id)
+		return errors.New("invalid id format")
+	}
+	if err := s.subsRepo.DeleteSeatChangeRepo(subID, id); err != nil {
+		return errors.New("seat change not found")
+	}
+	if sub, err := s.subsRepo.GetSubRepoById(subID); err == nil {
+		s.emit(EventSubUpdated, sub)
+	}
+	return nil
+}
+
+// AssignSeatService gives a seat of the sub to the assignee (e.g. an email) from the given
+// day, today when empty
+func (s *SubsService) AssignSeatService(assignment *models.SeatAssignment, dateStr string) error{
+	if !validateUUID(assignment.SubID) {
+		utils.ErrorLogger.Println("Invalid sub_id format:", assignment.SubID)
+		return errors.New("invalid sub_id format")
+	}
+	if _, err := s.subsRepo.GetSubRepoById(assignment.SubID); err != nil {
+		return errors.New("subscription not found")
+	}
+	assignment.Assignee = strings.TrimSpace(assignment.Assignee)
+	if assignment.Assignee == "" || len(assignment.Assignee) > 255 {
+		return errors.New("assignee is required and must be at most 255 characters")
+	}
+	if assignment.UserID != nil && !validateUUID(*assignment.UserID) {
+		return errors.New("invalid user_id format")
+	}
+
+	assignment.AssignedAt = dayStart(time.Now())
+	if dateStr != "" {
+		date, err := parseDay(dateStr)
+		if err != nil {
+			return err
+		}
+		assignment.AssignedAt = date
+	}
+
+	existing, err := s.subsRepo.ListSeatAssignmentsRepo(assignment.SubID)
+	if err != nil {
+		return err
+	}
+	for _, other := range existing {
+		if strings.EqualFold(other.Assignee, assignment.Assignee) && other.UnassignedAt == nil {
+			return errors.New("assignee already has a seat")
+		}
+	}
+
+	id, err := utils.NewUUID()
+	if err != nil {
+		utils.ErrorLogger.Println("Failed to generate UUID:", err)
+		return err
+	}
+	assignment.ID = id
+	return s.subsRepo.CreateSeatAssignmentRepo(assignment)
+}
+
+// UnassignSeatService frees the seat from the given day on, today when empty
+func (s *SubsService) UnassignSeatService(subID, id, dateStr string) (*models.SeatAssignment, error){
+	if !validateUUID(subID) || !validateUUID(id) {
+		utils.ErrorLogger.Println("Invalid ID format:", subID, id)
+		return nil, errors.New("invalid id format")
+	}
+	assignment, err := s.subsRepo.GetSeatAssignmentRepo(subID, id)
+	if err != nil {
+		return nil, errors.New("seat assignment not found")
+	}
+	if assignment.UnassignedAt != nil {
+		return nil, errors.New("seat is already unassigned")
+	}
+
+	date := dayStart(time.Now())
+	if dateStr != "" {
+		if date, err = parseDay(dateStr); err != nil {
+			return nil, err
+		}
+	}
+	if date.Before(assignment.AssignedAt) {
+		return nil, errors.New("seat cannot be unassigned before it was assigned")
+	}
+	assignment.UnassignedAt = &date
+
+	if err := s.subsRepo.UpdateSeatAssignmentRepo(assignment); err != nil {
+		utils.ErrorLogger.Println("Failed to unassign seat:", id, "error:", err)
+		return nil, err
+	}
+	return assignment, nil
+}
+
+func (s *SubsService) ListSeatAssignmentsService(subID string) ([]models.SeatAssignment, error){
+	if !validateUUID(subID) {
+		utils.ErrorLogger.Println("Invalid sub_id format:", subID)
+		return nil, errors.New("invalid sub_id format")
+	}
+	return s.subsRepo.ListSeatAssignmentsRepo(subID)
+}
+
+// SeatUtilizationService compares the seats with the assigned users at the end of each month
+// between two MM-YYYY months
+func (s *SubsService) SeatUtilizationService(subID, startStr, endStr string) (*SeatUtilization, error){
+	if !validateUUID(subID) {
+		utils.ErrorLogger.Println("Invalid sub_id format:", subID)
+		return nil, errors.New("invalid sub_id format")
+	}
+	start, err := validDate(startStr)
+	if err != nil {
+		utils.ErrorLogger.Println("Invalid start date:", startStr, "error:", err)
+		return nil, err
+	}
+	end, err := validDate(endStr)
+	if err != nil {
+		utils.ErrorLogger.Println("Invalid end date:", endStr, "error:", err)
+		return nil, err
+	}
+	if end.Before(start) {
+		return nil, errors.New("end must not be before start")
+	}
+	if monthsBetween(start, end) >= maxForecastMonths {
+		return nil, errors.New("the range is limited to 60 months")
+	}
+
+	sub, err := s.subsRepo.GetSubRepoById(subID)
+	if err != nil {
+		return nil, errors.New("subscription not found")
+	}
+	if len(sub.SeatChanges) == 0 {
+		return nil, errors.New("subscription has no seats")
+	}
+	changes, err := s.subsRepo.ListPriceChangesRepo(subID)
+	if err != nil {
+		return nil, err
+	}
+	assignments, err := s.subsRepo.ListSeatAssignmentsRepo(subID)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &SeatUtilization{SubID: sub.ID, ServiceName: sub.ServiceName, UnitPrice: unitPriceAt(sub, changes, end), Months: []SeatMonth{}, Assignments: assignments}
+	for month := start; !month.After(end); month = month.AddDate(0, 1, 0) {
+		last := month.AddDate(0, 1, -1)
+		line := SeatMonth{Month: month.Format("2006-01"), Seats: seatsAt(sub, last)}
+		for _, assignment := range assignments {
+			if !assignment.AssignedAt.After(last) && (assignment.UnassignedAt == nil || assignment.UnassignedAt.After(last)) {
+				line.Assigned++
+			}
+		}
+		line.Unused = max(line.Seats-line.Assigned, 0)
+		if line.Seats > 0 {
+			line.Utilization = float64(line.Assigned) / float64(line.Seats)
+		}
+		line.UnusedCost = line.Unused * unitPriceAt(sub, changes, month) / periodMonths(sub.BillingPeriod)
+		report.Months = append(report.Months, line)
+	}
+	return report, nil
+}
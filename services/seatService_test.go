@@ -0,0 +1,35 @@
+package services
+
+import (
+	"online-subs-api/models"
+	"testing"
+	"time"
+)
+
+func TestGrossPriceAtSeatProration(t *testing.T){
+	seats := []models.SeatChange{
+		{EffectiveDate: date(2025, time.January, 1), Seats: 10},
+		{EffectiveDate: date(2025, time.March, 16), Seats: 14, Prorate: true},
+	}
+	tests := []struct{
+		name	string
+		end		time.Time
+		month	time.Time
+		want	int
+	}{
+		{"month of the change", time.Time{}, date(2025, time.March, 1), 10000},
+		{"booked with the next charge", time.Time{}, date(2025, time.April, 1), 14*1000 + 2065},
+		{"after", time.Time{}, date(2025, time.May, 1), 14000},
+		{"booked in the last charge", date(2025, time.March, 31), date(2025, time.March, 1), 10000 + 2065},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T){
+			sub := monthlySub(1000)
+			sub.EndDate = tt.end
+			sub.SeatChanges = seats
+			if got := grossPriceAt(sub, nil, tt.month); got != tt.want {
+				t.Errorf("grossPriceAt() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
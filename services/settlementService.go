@@ -0,0 +1,173 @@
+package services
+
+import (
+	"errors"
+	"online-subs-api/repo"
+	"online-subs-api/utils"
+	"sort"
+)
+
+// SettlementSub is what a shared sub was charged in the period and who pays which part
+type SettlementSub struct{
+	SubID			string			`json:"sub_id"`
+	ServiceName		string			`json:"service_name"`
+	PaidBy			string			`json:"paid_by"`
+	SplitRule		string			`json:"split_rule"`
+	Charged			int				`json:"charged"`
+	Shares			[]MemberShare	`json:"shares"`
+}
+
+// SettlementBalance is positive when the user paid more than their share and is owed money
+type SettlementBalance struct{
+	UserID			string		`json:"user_id"`
+	Paid			int			`json:"paid"`
+	Share			int			`json:"share"`
+	Balance			int			`json:"balance"`
+}
+
+type SettlementTransfer struct{
+	From			string		`json:"from"`
+	To				string		`json:"to"`
+	Amount			int			`json:"amount"`
+}
+
+type Settlement struct{
+	Start			string					`json:"start"`
+	End				string					`json:"end"`
+	Subs			[]SettlementSub			`json:"subs"`
+	Balances		[]SettlementBalance		`json:"balances"`
+	Transfers		[]SettlementTransfer	`json:"transfers"`
+}
+
+// settleBalances turns the balances into transfers from the users who owe money to the
+// ones who are owed, largest amounts first, which keeps the number of transfers low
+func settleBalances(balances []SettlementBalance) []SettlementTransfer{
+	type party struct{
+		userID string
+		amount int
+	}
+	debtors, creditors := []party{}, []party{}
+	for _, balance := range balances {
+		switch {
+		case balance.Balance < 0:
+			debtors = append(debtors, party{balance.UserID, -balance.Balance})
+		case balance.Balance > 0:
+			creditors = append(creditors, party{balance.UserID, balance.Balance})
+		}
+	}
+	byAmount := func(list []party) func(i, j int) bool{
+		return func(i, j int) bool{
+			if list[i].amount != list[j].amount {
+				return list[i].amount > list[j].amount
+			}
+			return list[i].userID < list[j].userID
+		}
+	}
+	sort.Slice(debtors, byAmount(debtors))
+	sort.Slice(creditors, byAmount(creditors))
+
+	transfers := []SettlementTransfer{}
+	for i, j := 0, 0; i < len(debtors) && j < len(creditors); {
+		amount := min(debtors[i].amount, creditors[j].amount)
+		transfers = append(transfers, SettlementTransfer{From: debtors[i].userID, To: creditors[j].userID, Amount: amount})
+		debtors[i].amount -= amount
+		creditors[j].amount -= amount
+		if debtors[i].amount == 0 {
+			i++
+		}
+		if creditors[j].amount == 0 {
+			j++
+		}
+	}
+	return transfers
+}
+
+// SettlementService computes who owes whom for the shared subs charged between two MM-YYYY
+// months. The owner of a sub pays its charges, the members owe their share to the owner.
+// filter.UserID selects the subs the user owns or is a member of.
+func (s *ReportService) SettlementService(startStr, endStr string, filter repo.SubsFilter) (*Settlement, error){
+	start, err := validDate(startStr)
+	if err != nil {
+		utils.ErrorLogger.Println("Invalid start date:", startStr, "error:", err)
+		return nil, err
+	}
+	end, err := validDate(endStr)
+	if err != nil {
+		utils.ErrorLogger.Println("Invalid end date:", endStr, "error:", err)
+		return nil, err
+	}
+	if end.Before(start) {
+		return nil, errors.New("end must not be before start")
+	}
+	if err := validateFilter(filter); err != nil {
+		return nil, err
+	}
+
+	subs, err := s.subsRepo.ListUserSubsRepo(start, end, filter, true)
+	if err != nil {
+		utils.ErrorLogger.Println("Failed to load shared subscriptions:", err)
+		return nil, err
+	}
+	ids := []string{}
+	for _, sub := range subs {
+		ids = append(ids, sub.ID)
+	}
+	changes, err := s.subsRepo.ListPriceChangesBySubRepo(ids)
+	if err != nil {
+		return nil, err
+	}
+	splits, err := s.subsRepo.ListSplitsBySubRepo(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	settlement := &Settlement{Start: start.Format("2006-01"), End: end.Format("2006-01"), Subs: []SettlementSub{}, Balances: []SettlementBalance{}}
+	balances := map[string]*SettlementBalance{}
+	balance := func(userID string) *SettlementBalance{
+		if balances[userID] == nil {
+			balances[userID] = &SettlementBalance{UserID: userID}
+		}
+		return balances[userID]
+	}
+
+	sort.Slice(subs, func(i, j int) bool{ return subs[i].ServiceName < subs[j].ServiceName })
+	for i := range subs {
+		sub := &subs[i]
+		split := splits[sub.ID]
+		// the split may have been deleted since the subs were listed
+		if split == nil {
+			continue
+		}
+		line := SettlementSub{SubID: sub.ID, ServiceName: sub.ServiceName, PaidBy: sub.UserID, SplitRule: split.Rule, Shares: []MemberShare{}}
+		shares := map[string]int{}
+		order := []string{}
+
+		for _, month := range chargeMonths(sub, start, end) {
+			price := priceAt(sub, changes[sub.ID], month)
+			line.Charged += price
+			balance(sub.UserID).Paid += price
+			for _, share := range splitShares(sub, split, price) {
+				if _, ok := shares[share.UserID]; !ok {
+					order = append(order, share.UserID)
+				}
+				shares[share.UserID] += share.Amount
+				balance(share.UserID).Share += share.Amount
+			}
+		}
+		if line.Charged == 0 {
+			continue
+		}
+		for _, userID := range order {
+			line.Shares = append(line.Shares, MemberShare{UserID: userID, Amount: shares[userID]})
+		}
+		settlement.Subs = append(settlement.Subs, line)
+	}
+
+	for _, b := range balances {
+		b.Balance = b.Paid - b.Share
+		settlement.Balances = append(settlement.Balances, *b)
+	}
+	sort.Slice(settlement.Balances, func(i, j int) bool{ return settlement.Balances[i].UserID < settlement.Balances[j].UserID })
+	settlement.Transfers = settleBalances(settlement.Balances)
+	return settlement, nil
+}
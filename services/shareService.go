@@ -0,0 +1,297 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"online-subs-api/models"
+	"online-subs-api/repo"
+	"online-subs-api/utils"
+	"sort"
+	"time"
+)
+
+const maxSubMembers = 50
+
+// SplitRequest sets how a subscription is shared, the owner is added when not listed
+type SplitRequest struct{
+	Rule			string			`json:"split_rule"`
+	Members			[]SplitMember	`json:"members"`
+}
+
+type SplitMember struct{
+	UserID			string		`json:"user_id"`
+	Share			int			`json:"share,omitempty"`
+}
+
+// MemberShare is what one user pays of a charge
+type MemberShare struct{
+	UserID			string		`json:"user_id"`
+	Amount			int			`json:"amount"`
+}
+
+// SubSplitView is a split with the shares of the current price
+type SubSplitView struct{
+	models.SubSplit
+	Price			int				`json:"price"`
+	Shares			[]MemberShare	`json:"shares"`
+}
+
+// splitShares divides a charge of the sub between its owner and members:
+//   - equal: the same amount for everyone, the owner pays the rounding remainder
+//   - percentage: members pay their percentage (rounded down), the owner the rest
+//   - fixed: members pay their amount in the listed order while the price lasts, the owner the rest
+//
+// Without a split the owner pays everything.
+func splitShares(sub *models.Sub, split *models.SubSplit, price int) []MemberShare{
+	if split == nil || len(split.Members) == 0 {
+		return []MemberShare{{UserID: sub.UserID, Amount: price}}
+	}
+
+	owner := MemberShare{UserID: sub.UserID}
+	members := []MemberShare{}
+	for _, member := range split.Members {
+		if member.UserID == sub.UserID && split.Rule == models.SplitEqual {
+			continue
+		}
+		members = append(members, MemberShare{UserID: member.UserID, Amount: member.Share})
+	}
+
+	left := price
+	switch split.Rule {
+	case models.SplitEqual:
+		each := price / (len(members) + 1)
+		for i := range members {
+			members[i].Amount = each
+		}
+		left -= each * len(members)
+	case models.SplitPercentage:
+		for i := range members {
+			members[i].Amount = price * members[i].Amount / 100
+			left -= members[i].Amount
+		}
+	case models.SplitFixed:
+		for i := range members {
+			members[i].Amount = min(members[i].Amount, left)
+			left -= members[i].Amount
+		}
+	}
+
+	// an owner listed as a member pays the listed share and the rest
+	shares := []MemberShare{}
+	for _, member := range members {
+		if member.UserID == sub.UserID {
+			owner.Amount += member.Amount
+			continue
+		}
+		shares = append(shares, member)
+	}
+	owner.Amount += left
+	return append([]MemberShare{owner}, shares...)
+}
+
+// shareOf is the user's part of a charge of the sub
+func shareOf(sub *models.Sub, split *models.SubSplit, price int, userID string) int{
+	for _, share := range splitShares(sub, split, price) {
+		if share.UserID == userID {
+			return share.Amount
+		}
+	}
+	return 0
+}
+
+// currentPrice is the charge of the sub in the current month, with its price changes, plan
+// changes, seats, usage base fee and discounts
+func (s *SubsService) currentPrice(sub *models.Sub) (int, error){
+	changes, err := s.subsRepo.ListPriceChangesBySubRepo([]string{sub.ID})
+	if err != nil {
+		utils.ErrorLogger.Println("Failed to load price changes of sub:", sub.ID, "error:", err)
+		return 0, err
+	}
+	return priceAt(sub, changes[sub.ID], monthStart(time.Now())), nil
+}
+
+func (s *SubsService) SetSplitService(subID string, req SplitRequest) (*SubSplitView, error){
+	if !validateUUID(subID) {
+		utils.ErrorLogger.Println("Invalid sub_id format:", subID)
+		return nil, errors.New("invalid sub_id format")
+	}
+	sub, err := s.subsRepo.GetSubRepoById(subID)
+	if err != nil {
+		utils.ErrorLogger.Println("Subscription not found:", subID)
+		return nil, errors.New("subscription not found")
+	}
+
+	switch req.Rule {
+	case models.SplitEqual, models.SplitPercentage, models.SplitFixed:
+	default:
+		utils.ErrorLogger.Println("Invalid split rule:", req.Rule)
+		return nil, errors.New("split_rule must be equal, percentage or fixed")
+	}
+	if len(req.Members) == 0 || len(req.Members) > maxSubMembers {
+		return nil, fmt.Errorf("a shared subscription needs between 1 and %d members", maxSubMembers)
+	}
+
+	split := &models.SubSplit{SubID: subID, Rule: req.Rule, Members: []models.SubMember{}}
+	seen := map[string]bool{}
+	total := 0
+	for i, member := range req.Members {
+		if !validateUUID(member.UserID) {
+			return nil, fmt.Errorf("member %d: invalid user_id format", i+1)
+		}
+		if seen[member.UserID] {
+			return nil, fmt.Errorf("member %d: user %s is listed twice", i+1, member.UserID)
+		}
+		seen[member.UserID] = true
+
+		switch req.Rule {
+		case models.SplitEqual:
+			member.Share = 0
+		case models.SplitPercentage:
+			if member.Share < 1 || member.Share > 100 {
+				return nil, fmt.Errorf("member %d: share must be a percentage between 1 and 100", i+1)
+			}
+		case models.SplitFixed:
+			if member.Share <= 0 {
+				return nil, fmt.Errorf("member %d: share must be a postive amount", i+1)
+			}
+		}
+		total += member.Share
+
+		id, err := utils.NewUUID()
+		if err != nil {
+			utils.ErrorLogger.Println("Failed to generate UUID:", err)
+			return nil, err
+		}
+		split.Members = append(split.Members, models.SubMember{ID: id, SubID: subID, UserID: member.UserID, Share: member.Share, Position: i})
+	}
+	if req.Rule == models.SplitPercentage && total > 100 {
+		return nil, errors.New("the percentages add up to more than 100")
+	}
+	price, err := s.currentPrice(sub)
+	if err != nil {
+		return nil, err
+	}
+	if req.Rule == models.SplitFixed && total > price {
+		return nil, errors.New("the fixed shares add up to more than the price")
+	}
+
+	if err := s.subsRepo.SetSplitRepo(split); err != nil {
+		utils.ErrorLogger.Println("Failed to save split of sub:", subID, "error:", err)
+		return nil, err
+	}
+	s.emit(EventSubUpdated, sub)
+	return &SubSplitView{SubSplit: *split, Price: price, Shares: splitShares(sub, split, price)}, nil
+}
+
+func (s *SubsService) GetSplitService(subID string) (*SubSplitView, error){
+	if !validateUUID(subID) {
+		utils.ErrorLogger.Println("Invalid sub_id format:", subID)
+		return nil, errors.New("invalid sub_id format")
+	}
+	sub, err := s.subsRepo.GetSubRepoById(subID)
+	if err != nil {
+		return nil, errors.New("subscription not found")
+	}
+	split, err := s.subsRepo.GetSplitRepo(subID)
+	if err != nil {
+		return nil, errors.New("subscription is not shared")
+	}
+	price, err := s.currentPrice(sub)
+	if err != nil {
+		return nil, err
+	}
+	return &SubSplitView{SubSplit: *split, Price: price, Shares: splitShares(sub, split, price)}, nil
+}
+
+func (s *SubsService) DeleteSplitService(subID string) error{
+	if !validateUUID(subID) {
+		utils.ErrorLogger.Println("Invalid sub_id format:", subID)
+		return errors.New("invalid sub_id format")
+	}
+	if err := s.subsRepo.DeleteSplitRepo(subID); err != nil {
+		utils.ErrorLogger.Println("Failed to delete split of sub:", subID, "error:", err)
+		return errors.New("subscription is not shared")
+	}
+	if sub, err := s.subsRepo.GetSubRepoById(subID); err == nil {
+		s.emit(EventSubUpdated, sub)
+	}
+	return nil
+}
+
+// shareCharges splits the charges of shared subs into the shares of their users, before and
+// after discounts alike. With a userID only the shares of that user are kept.
+func shareCharges(charges []ProjectedCharge, subs []models.Sub, splits map[string]*models.SubSplit, userID string) []ProjectedCharge{
+	index := map[string]int{}
+	for i := range subs {
+		index[subs[i].ID] = i
+	}
+	shared := []ProjectedCharge{}
+	for _, charge := range charges {
+		sub := &subs[index[charge.SubID]]
+		gross := splitShares(sub, splits[sub.ID], charge.Gross)
+		for i, share := range splitShares(sub, splits[sub.ID], charge.Amount) {
+			if userID != "" && share.UserID != userID {
+				continue
+			}
+			part := charge
+			part.UserID = share.UserID
+			part.Gross = gross[i].Amount
+			part.Amount = share.Amount
+			shared = append(shared, part)
+		}
+	}
+	return shared
+}
+
+// userShareCosts lists the subs of the user's total cost, owned or shared, with the user's
+// share of their price before and after discounts and the tax in it
+func userShareCosts(subsRepo *repo.SubsRepo, start, end time.Time, filter repo.SubsFilter) ([]models.Sub, []repo.CostTotal, error){
+	subs, err := subsRepo.ListUserSubsRepo(start, end, filter, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	ids := []string{}
+	for _, sub := range subs {
+		ids = append(ids, sub.ID)
+	}
+	splits, err := subsRepo.ListSplitsBySubRepo(ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	changes, err := subsRepo.ListPriceChangesBySubRepo(ids)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rates, err := subsRepo.ListTaxRatesRepo()
+	if err != nil {
+		return nil, nil, err
+	}
+	byJurisdiction := ratesByJurisdiction(rates)
+
+	costs := make([]repo.CostTotal, len(subs))
+	for i := range subs {
+		gross, net := totalCostOf(&subs[i], changes[subs[i].ID], start, end)
+		costs[i].Gross = shareOf(&subs[i], splits[subs[i].ID], gross, filter.UserID)
+		costs[i].Net = shareOf(&subs[i], splits[subs[i].ID], net, filter.UserID)
+		tax := taxOf(&subs[i], byJurisdiction, end, costs[i].Net)
+		costs[i].Tax = tax.Tax
+		costs[i].TaxNet = tax.Net
+	}
+	return subs, costs, nil
+}
+
+// sortedCostGroups orders a breakdown like the SQL ones, highest total first
+func sortedCostGroups(totals map[string]int) []repo.CostGroup{
+	groups := []repo.CostGroup{}
+	for key, total := range totals {
+		groups = append(groups, repo.CostGroup{Key: key, TotalCost: total})
+	}
+	sort.Slice(groups, func(i, j int) bool{
+		if groups[i].TotalCost != groups[j].TotalCost {
+			return groups[i].TotalCost > groups[j].TotalCost
+		}
+		return groups[i].Key < groups[j].Key
+	})
+	return groups
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"online-subs-api/models"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSplitShares(t *testing.T){
+	split := func(rule string, members ...models.SubMember) *models.SubSplit{
+		return &models.SubSplit{SubID: "sub", Rule: rule, Members: members}
+	}
+	member := func(userID string, share int) models.SubMember{
+		return models.SubMember{UserID: userID, Share: share}
+	}
+	tests := []struct{
+		name	string
+		split	*models.SubSplit
+		price	int
+		want	[]MemberShare
+	}{
+		{"not shared", nil, 1000, []MemberShare{{"owner", 1000}}},
+		{"no members", split(models.SplitEqual), 1000, []MemberShare{{"owner", 1000}}},
+		{"equal, owner pays the remainder", split(models.SplitEqual, member("a", 0), member("b", 0)), 1000,
+			[]MemberShare{{"owner", 334}, {"a", 333}, {"b", 333}}},
+		{"equal with the owner listed", split(models.SplitEqual, member("owner", 0), member("a", 0)), 1000,
+			[]MemberShare{{"owner", 500}, {"a", 500}}},
+		{"percentage rounds down", split(models.SplitPercentage, member("a", 25), member("b", 30)), 999,
+			[]MemberShare{{"owner", 451}, {"a", 249}, {"b", 299}}},
+		{"fixed in the listed order", split(models.SplitFixed, member("a", 400), member("b", 800)), 1000,
+			[]MemberShare{{"owner", 0}, {"a", 400}, {"b", 600}}},
+		{"fixed with the owner listed", split(models.SplitFixed, member("owner", 300), member("a", 900)), 1000,
+			[]MemberShare{{"owner", 300}, {"a", 700}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T){
+			if got := splitShares(monthlySub(tt.price), tt.split, tt.price); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitShares() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShareCharges(t *testing.T){
+	subs := []models.Sub{*monthlySub(1000)}
+	splits := map[string]*models.SubSplit{"sub": {SubID: "sub", Rule: models.SplitEqual, Members: []models.SubMember{{UserID: "a"}}}}
+	charges := []ProjectedCharge{{SubID: "sub", UserID: "owner", Date: date(2025, time.March, 1), Gross: 1000, Amount: 801}}
+
+	got := shareCharges(charges, subs, splits, "")
+	want := []ProjectedCharge{
+		{SubID: "sub", UserID: "owner", Date: date(2025, time.March, 1), Gross: 500, Amount: 401},
+		{SubID: "sub", UserID: "a", Date: date(2025, time.March, 1), Gross: 500, Amount: 400},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("shareCharges() = %v, want %v", got, want)
+	}
+	if got := shareCharges(charges, subs, splits, "a"); !reflect.DeepEqual(got, want[1:]) {
+		t.Errorf("shareCharges() of a member = %v, want %v", got, want[1:])
+	}
+}
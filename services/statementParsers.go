@@ -0,0 +1,328 @@
+package services
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Statement formats accepted by the bank statement import
+const (
+	StatementCSV     = "csv"
+	StatementOFX     = "ofx"
+	StatementCAMT053 = "camt053"
+)
+
+const maxStatementSize = 20 << 20
+
+// BankTransaction is one booked statement line, Amount is negative for debits
+type BankTransaction struct{
+	Date			time.Time
+	Amount			float64
+	Currency		string
+	Description		string
+}
+
+// ParseStatement reads a statement in the given format, an empty format is detected from
+// the content. QFX files are OFX.
+func ParseStatement(format string, r io.Reader) ([]BankTransaction, string, error){
+	data, err := io.ReadAll(io.LimitReader(r, maxStatementSize+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(data) > maxStatementSize {
+		return nil, "", errors.New("statement is too large")
+	}
+
+	format = strings.ToLower(strings.TrimSpace(format))
+	switch format {
+	case "":
+		format = detectStatementFormat(data)
+	case "qfx":
+		format = StatementOFX
+	case "camt", "camt.053":
+		format = StatementCAMT053
+	}
+
+	var transactions []BankTransaction
+	switch format {
+	case StatementCSV:
+		transactions, err = parseStatementCSV(data)
+	case StatementOFX:
+		transactions, err = parseOFX(data)
+	case StatementCAMT053:
+		transactions, err = parseCAMT053(data)
+	default:
+		return nil, "", fmt.Errorf("format must be one of %s, %s, %s", StatementCSV, StatementOFX, StatementCAMT053)
+	}
+	return transactions, format, err
+}
+
+func detectStatementFormat(data []byte) string{
+	head := strings.ToUpper(string(data[:min(len(data), 4096)]))
+	switch {
+	case strings.Contains(head, "OFXHEADER") || strings.Contains(head, "<OFX>"):
+		return StatementOFX
+	case strings.Contains(head, "CAMT.053") || strings.Contains(head, "<BKTOCSTMRSTMT"):
+		return StatementCAMT053
+	}
+	return StatementCSV
+}
+
+// parseSignedAmount reads amounts like "-9.99", "(9.99)" or "9,99-"
+func parseSignedAmount(value string) (float64, error){
+	value = strings.TrimSpace(value)
+	negative := false
+	if strings.HasPrefix(value, "(") && strings.HasSuffix(value, ")") {
+		negative, value = true, value[1:len(value)-1]
+	}
+	if strings.HasPrefix(value, "-") || strings.HasPrefix(value, "−") {
+		negative, value = true, strings.TrimLeft(value, "-−")
+	}
+	if strings.HasSuffix(value, "-") {
+		negative, value = true, strings.TrimSuffix(value, "-")
+	}
+	value = strings.TrimPrefix(value, "+")
+	amount, _, err := parseAmount(value)
+	if negative {
+		amount = -amount
+	}
+	return amount, err
+}
+
+var statementCSVFields = appFields{
+	name: []string{"Description", "Payee", "Merchant", "Name", "Details", "Narrative", "Counterparty", "Transaction Description", "Memo", "Назначение платежа", "Описание"},
+	amount: []string{"Amount", "Transaction Amount", "Sum", "Value", "Сумма", "Сумма операции"},
+	currency: []string{"Currency", "Валюта", "Валюта операции"},
+	start: []string{"Date", "Booking Date", "Transaction Date", "Posted Date", "Posting Date", "Value Date", "Дата", "Дата операции"},
+}
+
+var (
+	debitColumns  = []string{"Debit", "Debit Amount", "Withdrawal", "Withdrawals", "Paid Out", "Money Out", "Расход", "Списание"}
+	creditColumns = []string{"Credit", "Credit Amount", "Deposit", "Deposits", "Paid In", "Money In", "Приход", "Зачисление"}
+	typeColumns   = []string{"Type", "Debit/Credit", "Direction", "CdtDbtInd"}
+)
+
+// parseStatementCSV reads bank CSV exports by their usual column names. Amounts come from a
+// signed amount column, a debit/credit type column or separate debit and credit columns.
+func parseStatementCSV(data []byte) ([]BankTransaction, error){
+	items, err := decodeCSVItems(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	transactions := []BankTransaction{}
+	layouts := dateLayouts(items, statementCSVFields.start)
+	for i, item := range items {
+		line := i + 2
+		date, err := parseAppDate(item.get(statementCSVFields.start), layouts)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: date: %v", line, err)
+		}
+
+		var amount float64
+		switch {
+		case item.text(statementCSVFields.amount) != "":
+			if amount, err = parseSignedAmount(item.text(statementCSVFields.amount)); err != nil {
+				return nil, fmt.Errorf("line %d: %v", line, err)
+			}
+			kind := strings.ToLower(item.text(typeColumns))
+			if amount > 0 && (strings.HasPrefix(kind, "d") || kind == "withdrawal" || kind == "расход") {
+				amount = -amount
+			}
+		case item.text(debitColumns) != "":
+			if amount, err = parseSignedAmount(item.text(debitColumns)); err != nil {
+				return nil, fmt.Errorf("line %d: %v", line, err)
+			}
+			if amount > 0 {
+				amount = -amount
+			}
+		case item.text(creditColumns) != "":
+			if amount, err = parseSignedAmount(item.text(creditColumns)); err != nil {
+				return nil, fmt.Errorf("line %d: %v", line, err)
+			}
+		default:
+			return nil, fmt.Errorf("line %d: no amount, expected an Amount or Debit/Credit column", line)
+		}
+
+		transactions = append(transactions, BankTransaction{
+			Date: *date,
+			Amount: amount,
+			Currency: strings.ToUpper(item.text(statementCSVFields.currency)),
+			Description: item.text(statementCSVFields.name),
+		})
+	}
+	return transactions, nil
+}
+
+var (
+	ofxTransactionPattern = regexp.MustCompile(`(?i)<STMTTRN>`)
+	ofxBlockEndPattern    = regexp.MustCompile(`(?i)</STMTTRN>|</BANKTRANLIST>`)
+	ofxFieldPattern       = regexp.MustCompile(`(?i)<([A-Z0-9.]+)>([^<\r\n]*)`)
+	ofxCurrencyPattern    = regexp.MustCompile(`(?i)<CURDEF>\s*([A-Z]{3})`)
+)
+
+// parseOFX reads OFX 1.x (SGML, leaf elements without closing tags) and OFX 2.x (XML)
+// statements, QFX included
+func parseOFX(data []byte) ([]BankTransaction, error){
+	text := string(data)
+	currency := ""
+	if m := ofxCurrencyPattern.FindStringSubmatch(text); m != nil {
+		currency = strings.ToUpper(m[1])
+	}
+
+	transactions := []BankTransaction{}
+	// SGML files may not close STMTTRN, a block ends at the next one at the latest
+	starts := ofxTransactionPattern.FindAllStringIndex(text, -1)
+	for i, start := range starts {
+		block := text[start[1]:]
+		if i+1 < len(starts) {
+			block = text[start[1]:starts[i+1][0]]
+		}
+		if end := ofxBlockEndPattern.FindStringIndex(block); end != nil {
+			block = block[:end[0]]
+		}
+
+		fields := map[string]string{}
+		for _, m := range ofxFieldPattern.FindAllStringSubmatch(block, -1) {
+			fields[strings.ToUpper(m[1])] = strings.TrimSpace(xmlUnescape(m[2]))
+		}
+
+		posted := fields["DTPOSTED"]
+		if len(posted) < 8 {
+			return nil, fmt.Errorf("transaction %q: invalid DTPOSTED %q", fields["FITID"], posted)
+		}
+		date, err := time.Parse("20060102", posted[:8])
+		if err != nil {
+			return nil, fmt.Errorf("transaction %q: invalid DTPOSTED %q", fields["FITID"], posted)
+		}
+		amount, err := parseSignedAmount(fields["TRNAMT"])
+		if err != nil {
+			return nil, fmt.Errorf("transaction %q: %v", fields["FITID"], err)
+		}
+
+		description := fields["NAME"]
+		if description == "" || (fields["MEMO"] != "" && len(description) < 4) {
+			description = strings.TrimSpace(description + " " + fields["MEMO"])
+		}
+		transactions = append(transactions, BankTransaction{Date: date, Amount: amount, Currency: currency, Description: description})
+	}
+	if len(transactions) == 0 && !strings.Contains(strings.ToUpper(text), "<OFX>") {
+		return nil, errors.New("not an OFX statement")
+	}
+	return transactions, nil
+}
+
+func xmlUnescape(text string) string{
+	return strings.NewReplacer("&amp;", "&", "&lt;", "<", "&gt;", ">", "&quot;", `"`, "&apos;", "'").Replace(text)
+}
+
+// CAMT.053 (ISO 20022 bank to customer statement), namespaces are ignored so all
+// versions of the schema are read
+type camtDocument struct{
+	Statements	[]camtStatement	`xml:"BkToCstmrStmt>Stmt"`
+}
+
+type camtStatement struct{
+	Entries		[]camtEntry		`xml:"Ntry"`
+}
+
+type camtAmount struct{
+	Value		string			`xml:",chardata"`
+	Currency	string			`xml:"Ccy,attr"`
+}
+
+type camtDate struct{
+	Date		string			`xml:"Dt"`
+	DateTime	string			`xml:"DtTm"`
+}
+
+// camtStatus is plain text up to version 8 and holds a code element from version 9 on
+type camtStatus struct{
+	Text		string			`xml:",chardata"`
+	Code		string			`xml:"Cd"`
+}
+
+type camtEntry struct{
+	Amount			camtAmount		`xml:"Amt"`
+	CreditDebit		string			`xml:"CdtDbtInd"`
+	Status			camtStatus		`xml:"Sts"`
+	BookingDate		camtDate		`xml:"BookgDt"`
+	ValueDate		camtDate		`xml:"ValDt"`
+	Info			string			`xml:"AddtlNtryInf"`
+	Details			[]camtDetails	`xml:"NtryDtls>TxDtls"`
+}
+
+type camtDetails struct{
+	Creditor		string			`xml:"RltdPties>Cdtr>Nm"`
+	CreditorParty	string			`xml:"RltdPties>Cdtr>Pty>Nm"`
+	Remittance		[]string		`xml:"RmtInf>Ustrd"`
+}
+
+func (d camtDate) parse() (time.Time, bool){
+	for _, value := range []string{d.Date, d.DateTime} {
+		if len(value) >= 10 {
+			if t, err := time.Parse("2006-01-02", value[:10]); err == nil {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+func parseCAMT053(data []byte) ([]BankTransaction, error){
+	var document camtDocument
+	if err := xml.Unmarshal(data, &document); err != nil {
+		return nil, errors.New("invalid CAMT.053 XML: " + err.Error())
+	}
+	if len(document.Statements) == 0 {
+		return nil, errors.New("not a CAMT.053 statement")
+	}
+
+	transactions := []BankTransaction{}
+	for _, statement := range document.Statements {
+		for _, entry := range statement.Entries {
+			status := strings.ToUpper(strings.TrimSpace(entry.Status.Text + entry.Status.Code))
+			if status != "" && status != "BOOK" {
+				continue
+			}
+			date, ok := entry.BookingDate.parse()
+			if !ok {
+				if date, ok = entry.ValueDate.parse(); !ok {
+					return nil, errors.New("entry without a booking date")
+				}
+			}
+			amount, err := parseSignedAmount(entry.Amount.Value)
+			if err != nil {
+				return nil, err
+			}
+			if strings.EqualFold(entry.CreditDebit, "DBIT") {
+				amount = -amount
+			}
+
+			description := entry.Info
+			for _, details := range entry.Details {
+				switch {
+				case details.Creditor != "":
+					description = details.Creditor
+				case details.CreditorParty != "":
+					description = details.CreditorParty
+				case description == "":
+					description = strings.Join(details.Remittance, " ")
+				}
+			}
+			transactions = append(transactions, BankTransaction{
+				Date: date,
+				Amount: amount,
+				Currency: strings.ToUpper(entry.Amount.Currency),
+				Description: strings.TrimSpace(description),
+			})
+		}
+	}
+	return transactions, nil
+}
@@ -0,0 +1,309 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"online-subs-api/models"
+	"online-subs-api/repo"
+	"online-subs-api/utils"
+	"strings"
+	"unicode"
+)
+
+// defaultMinConfidence keeps one-off purchases that happen to repeat out of the proposals
+const defaultMinConfidence = 0.6
+
+type StatementAnalysis struct{
+	Import			models.StatementImport			`json:"import"`
+	Candidates		[]models.StatementCandidate		`json:"candidates"`
+}
+
+// StatementAccept lists the candidates to turn into subscriptions, candidates of
+// subscriptions the user already tracks are skipped unless IncludeTracked is set
+type StatementAccept struct{
+	IDs				[]string		`json:"ids"`
+	IncludeTracked	bool			`json:"include_tracked"`
+}
+
+type StatementSkip struct{
+	ID				string			`json:"id"`
+	Reason			string			`json:"reason"`
+}
+
+type StatementAcceptResult struct{
+	Subs			[]models.Sub		`json:"subscriptions"`
+	Skipped			[]StatementSkip		`json:"skipped"`
+}
+
+type StatementService struct{
+	statementRepo *repo.StatementRepo
+	subsService *SubsService
+}
+
+func NewStatementService(statementRepo *repo.StatementRepo, subsService *SubsService) *StatementService{
+	return &StatementService{statementRepo: statementRepo, subsService: subsService}
+}
+
+func titleCase(text string) string{
+	words := strings.Fields(text)
+	for i, word := range words {
+		runes := []rune(word)
+		runes[0] = unicode.ToUpper(runes[0])
+		words[i] = string(runes)
+	}
+	return strings.Join(words, " ")
+}
+
+// cadencePrice maps a cadence to the billing period and price of the subscription,
+// weekly charges are billed as a monthly price
+func cadencePrice(cadence string, amount float64) (string, int){
+	switch cadence {
+	case models.CadenceWeekly:
+		return models.BillingMonthly, int(math.Round(amount * 52 / 12))
+	case models.CadenceQuarterly:
+		return models.BillingQuarterly, int(math.Round(amount))
+	case models.CadenceYearly:
+		return models.BillingYearly, int(math.Round(amount))
+	}
+	return models.BillingMonthly, int(math.Round(amount))
+}
+
+// toCandidate names the series after the catalog provider when the merchant resolves to one
+func (s *StatementService) toCandidate(series RecurringCharge, statement *models.StatementImport) (models.StatementCandidate, error){
+	id, err := utils.NewUUID()
+	if err != nil {
+		return models.StatementCandidate{}, err
+	}
+	period, price := cadencePrice(series.Cadence, series.Amount)
+	candidate := models.StatementCandidate{
+		ID: id,
+		ImportID: statement.ID,
+		UserID: statement.UserID,
+		TenantID: statement.TenantID,
+		Merchant: series.Merchant,
+		ServiceName: titleCase(series.Merchant),
+		Currency: series.Currency,
+		Amount: series.Amount,
+		Cadence: series.Cadence,
+		Price: price,
+		BillingPeriod: period,
+		Occurrences: len(series.Charges),
+		FirstDate: series.Charges[0].Date,
+		LastDate: series.Charges[len(series.Charges)-1].Date,
+		NextExpected: series.NextExpected,
+		Active: series.Active,
+		Confidence: series.Confidence,
+		Status: models.CandidatePending,
+		Charges: series.Charges,
+	}
+
+	res, err := s.subsService.resolver.Resolve(series.Merchant)
+	if err != nil {
+		utils.WarningLogger.Println("Could not resolve merchant:", series.Merchant, "error:", err)
+	}
+	if err == nil && res.Confidence >= autoResolveConfidence {
+		candidate.ServiceName = res.CanonicalName
+		candidate.ProviderID = &res.ProviderID
+		candidate.Category = res.Category
+	}
+	return candidate, nil
+}
+
+// candidateSub is the subscription a candidate becomes, inactive series end with their last charge
+func candidateSub(candidate *models.StatementCandidate) *models.Sub{
+	sub := &models.Sub{
+		ServiceName: candidate.ServiceName,
+		UserID: candidate.UserID,
+		TenantID: candidate.TenantID,
+		ProviderID: candidate.ProviderID,
+		Price: candidate.Price,
+		BillingPeriod: candidate.BillingPeriod,
+		Category: candidate.Category,
+		StartDate: monthStart(candidate.FirstDate),
+		Metadata: models.JSONMap{"imported_from": "bank_statement", "merchant": candidate.Merchant},
+	}
+	if candidate.Currency != "" {
+		sub.Metadata["currency"] = candidate.Currency
+	}
+	if !candidate.Active {
+		sub.EndDate = monthStart(candidate.LastDate)
+	}
+	return sub
+}
+
+// AnalyzeStatementService parses a bank statement, detects the recurring charges and
+// stores them as pending candidates of the user
+func (s *StatementService) AnalyzeStatementService(r io.Reader, format, userID, tenantID string, minConfidence float64) (*StatementAnalysis, error){
+	if !validateUUID(userID) {
+		utils.ErrorLogger.Println("Invalid user_id format:", userID)
+		return nil, errors.New("invalid user_id format")
+	}
+	if tenantID != "" && !validateTenantID(tenantID) {
+		utils.ErrorLogger.Println("Invalid tenant_id format:", tenantID)
+		return nil, errors.New("invalid tenant_id format")
+	}
+	if minConfidence == 0 {
+		minConfidence = defaultMinConfidence
+	}
+	if minConfidence < 0 || minConfidence > 1 {
+		return nil, errors.New("min_confidence must be between 0 and 1")
+	}
+
+	transactions, format, err := ParseStatement(format, r)
+	if err != nil {
+		utils.ErrorLogger.Println("Failed to parse statement:", err)
+		return nil, err
+	}
+	if len(transactions) == 0 {
+		return nil, errors.New("statement has no transactions")
+	}
+
+	id, err := utils.NewUUID()
+	if err != nil {
+		utils.ErrorLogger.Println("Failed to generate UUID:", err)
+		return nil, err
+	}
+	statement := models.StatementImport{
+		ID: id,
+		UserID: userID,
+		TenantID: tenantID,
+		Format: format,
+		Transactions: len(transactions),
+		FromDate: transactions[0].Date,
+		ToDate: transactions[0].Date,
+	}
+	for _, t := range transactions {
+		if t.Date.Before(statement.FromDate) {
+			statement.FromDate = t.Date
+		}
+		if t.Date.After(statement.ToDate) {
+			statement.ToDate = t.Date
+		}
+	}
+
+	existing, err := s.subsService.subsRepo.ListAllSubsRepo(repo.SubsFilter{UserID: userID})
+	if err != nil {
+		utils.ErrorLogger.Println("Failed to load subscriptions of user:", userID, "error:", err)
+		return nil, err
+	}
+
+	candidates := []models.StatementCandidate{}
+	for _, series := range detectRecurring(transactions, statement.ToDate) {
+		if series.Confidence < minConfidence {
+			continue
+		}
+		candidate, err := s.toCandidate(series, &statement)
+		if err != nil {
+			return nil, err
+		}
+		sub := candidateSub(&candidate)
+		for i := range existing {
+			if overlaps(sub, &existing[i]) {
+				candidate.MatchedSubID = &existing[i].ID
+				break
+			}
+		}
+		candidates = append(candidates, candidate)
+	}
+
+	if err := s.statementRepo.CreateImportRepo(&statement, candidates); err != nil {
+		utils.ErrorLogger.Println("Failed to store statement import:", err)
+		return nil, err
+	}
+	utils.InfoLogger.Printf("Analysed %s statement of user %s: %d transactions, %d candidates", format, userID, len(transactions), len(candidates))
+	return &StatementAnalysis{Import: statement, Candidates: candidates}, nil
+}
+
+func (s *StatementService) ListCandidatesService(userID, importID, status string) ([]models.StatementCandidate, error){
+	if !validateUUID(userID) {
+		utils.ErrorLogger.Println("Invalid user_id format:", userID)
+		return nil, errors.New("invalid user_id format")
+	}
+	if importID != "" && !validateUUID(importID) {
+		return nil, errors.New("invalid import_id format")
+	}
+	if status != "" && status != models.CandidatePending && status != models.CandidateAccepted && status != models.CandidateDismissed {
+		return nil, errors.New("status must be one of pending, accepted, dismissed")
+	}
+	return s.statementRepo.ListCandidatesRepo(userID, importID, status)
+}
+
+// AcceptCandidatesService turns the candidates into subscriptions in one transaction,
+// candidates that can't be accepted are reported as skipped
+func (s *StatementService) AcceptCandidatesService(req StatementAccept) (*StatementAcceptResult, error){
+	if len(req.IDs) == 0 {
+		return nil, errors.New("ids must list at least one candidate")
+	}
+	for _, id := range req.IDs {
+		if !validateUUID(id) {
+			return nil, fmt.Errorf("invalid candidate id %q", id)
+		}
+	}
+	stored, err := s.statementRepo.GetCandidatesRepo(req.IDs)
+	if err != nil {
+		return nil, err
+	}
+	byID := map[string]*models.StatementCandidate{}
+	for i := range stored {
+		byID[stored[i].ID] = &stored[i]
+	}
+
+	result := &StatementAcceptResult{Subs: []models.Sub{}, Skipped: []StatementSkip{}}
+	accepted := []models.StatementCandidate{}
+	for _, id := range req.IDs {
+		candidate, ok := byID[id]
+		switch {
+		case !ok:
+			result.Skipped = append(result.Skipped, StatementSkip{ID: id, Reason: "candidate not found"})
+			continue
+		case candidate.Status != models.CandidatePending:
+			result.Skipped = append(result.Skipped, StatementSkip{ID: id, Reason: "candidate is " + candidate.Status})
+			continue
+		case candidate.MatchedSubID != nil && !req.IncludeTracked:
+			result.Skipped = append(result.Skipped, StatementSkip{ID: id, Reason: "already tracked as subscription " + *candidate.MatchedSubID})
+			continue
+		}
+		delete(byID, id)
+
+		sub := candidateSub(candidate)
+		end := ""
+		if !sub.EndDate.IsZero() {
+			end = sub.EndDate.Format("01-2006")
+		}
+		if err := s.subsService.validateNewSub(sub, sub.StartDate.Format("01-2006"), end, ""); err != nil {
+			result.Skipped = append(result.Skipped, StatementSkip{ID: id, Reason: err.Error()})
+			continue
+		}
+		if sub.ID, err = utils.NewUUID(); err != nil {
+			utils.ErrorLogger.Println("Failed to generate UUID:", err)
+			return nil, err
+		}
+		accepted = append(accepted, *candidate)
+		result.Subs = append(result.Subs, *sub)
+	}
+
+	if len(result.Subs) > 0 {
+		if err := s.statementRepo.AcceptCandidatesRepo(accepted, result.Subs); err != nil {
+			utils.ErrorLogger.Println("Failed to accept candidates:", err)
+			return nil, err
+		}
+	}
+	for i := range result.Subs {
+		s.subsService.emit(EventSubCreated, &result.Subs[i])
+	}
+	return result, nil
+}
+
+func (s *StatementService) DismissCandidateService(id string) error{
+	if !validateUUID(id) {
+		utils.ErrorLogger.Println("Invalid ID format:", id)
+		return errors.New("invalid id format")
+	}
+	if err := s.statementRepo.SetCandidateStatusRepo(id, models.CandidateDismissed); err != nil {
+		utils.ErrorLogger.Println("Failed to dismiss candidate:", id, "error:", err)
+		return errors.New("pending candidate not found")
+	}
+	return nil
+}
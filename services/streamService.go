@@ -0,0 +1,174 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"online-subs-api/repo"
+	"online-subs-api/utils"
+	"sync"
+	"time"
+)
+
+const (
+	streamBufferSize = 256
+	streamPollInterval = 2 * time.Second
+)
+
+// StreamFilter narrows a stream down to the subs of a user, tenant or service
+type StreamFilter struct{
+	UserID			string
+	ServiceName		string
+	TenantID		string
+}
+
+// streamedSub holds the fields of an event payload the filters look at
+type streamedSub struct{
+	UserID			string		`json:"user_id"`
+	ServiceName		string		`json:"service_name"`
+	TenantID		string		`json:"tenant_id"`
+}
+
+func (f StreamFilter) matches(message OutboxMessage) bool{
+	var sub streamedSub
+	if err := json.Unmarshal(message.Data, &sub); err != nil {
+		return false
+	}
+	return (f.UserID == "" || f.UserID == sub.UserID) &&
+		(f.ServiceName == "" || f.ServiceName == sub.ServiceName) &&
+		(f.TenantID == "" || f.TenantID == sub.TenantID)
+}
+
+// StreamSubscription receives live events on Events, the channel is closed when the
+// subscriber falls too far behind and has to resume with Last-Event-ID
+type StreamSubscription struct{
+	Events <-chan OutboxMessage
+	events chan OutboxMessage
+	filter StreamFilter
+}
+
+// StreamService fans the subscription events out to SSE clients. Events come from the
+// outbox, so their seq is the SSE event id and clients can resume after it.
+type StreamService struct{
+	outboxRepo *repo.OutboxRepo
+	mu sync.Mutex
+	subscribers map[*StreamSubscription]struct{}
+	lastSeq int64
+	nudge chan struct{}
+}
+
+func NewStreamService(outboxRepo *repo.OutboxRepo) *StreamService{
+	return &StreamService{
+		outboxRepo: outboxRepo,
+		subscribers: map[*StreamSubscription]struct{}{},
+		nudge: make(chan struct{}, 1),
+	}
+}
+
+// OnSubEvent wakes the stream after a mutation, the event is read back from the outbox
+func (s *StreamService) OnSubEvent(SubEvent){
+	select {
+	case s.nudge <- struct{}{}:
+	default:
+	}
+}
+
+// Start broadcasts the outbox events committed from now on
+func (s *StreamService) Start() error{
+	lastSeq, err := s.outboxRepo.LastSeqRepo()
+	if err != nil {
+		return err
+	}
+	s.lastSeq = lastSeq
+
+	go func(){
+		for {
+			s.broadcastNew()
+			select {
+			case <-s.nudge:
+			case <-time.After(streamPollInterval):
+			}
+		}
+	}()
+	return nil
+}
+
+func (s *StreamService) broadcastNew(){
+	for {
+		events, err := s.outboxRepo.ListOutboxAfterRepo(s.lastSeq, outboxBatchSize)
+		if err != nil {
+			utils.ErrorLogger.Println("Failed to read outbox for the stream:", err)
+			return
+		}
+
+		s.mu.Lock()
+		for i := range events {
+			message := newOutboxMessage(&events[i])
+			for sub := range s.subscribers {
+				if !sub.filter.matches(message) {
+					continue
+				}
+				select {
+				case sub.events <- message:
+				default:
+					// slow client, it reconnects and replays from its last event id
+					delete(s.subscribers, sub)
+					close(sub.events)
+				}
+			}
+			s.lastSeq = events[i].Seq
+		}
+		s.mu.Unlock()
+
+		if len(events) < outboxBatchSize {
+			return
+		}
+	}
+}
+
+// Subscribe registers a live subscriber first and then loads the events after
+// lastEventID, so nothing committed in between is missed. Live events may repeat the
+// tail of the backlog, callers skip seqs they have already sent.
+func (s *StreamService) Subscribe(filter StreamFilter, lastEventID int64) (*StreamSubscription, []OutboxMessage, error){
+	if filter.UserID != "" && !validateUUID(filter.UserID) {
+		return nil, nil, errors.New("invalid user_id format")
+	}
+	if filter.TenantID != "" && !validateTenantID(filter.TenantID) {
+		return nil, nil, errors.New("invalid tenant_id format")
+	}
+
+	events := make(chan OutboxMessage, streamBufferSize)
+	sub := &StreamSubscription{Events: events, events: events, filter: filter}
+	s.mu.Lock()
+	s.subscribers[sub] = struct{}{}
+	s.mu.Unlock()
+
+	backlog := []OutboxMessage{}
+	if lastEventID <= 0 {
+		return sub, backlog, nil
+	}
+	for seq := lastEventID; ; {
+		batch, err := s.outboxRepo.ListOutboxAfterRepo(seq, outboxBatchSize)
+		if err != nil {
+			s.Unsubscribe(sub)
+			return nil, nil, err
+		}
+		for i := range batch {
+			if message := newOutboxMessage(&batch[i]); filter.matches(message) {
+				backlog = append(backlog, message)
+			}
+			seq = batch[i].Seq
+		}
+		if len(batch) < outboxBatchSize {
+			return sub, backlog, nil
+		}
+	}
+}
+
+func (s *StreamService) Unsubscribe(sub *StreamSubscription){
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.subscribers[sub]; ok {
+		delete(s.subscribers, sub)
+		close(sub.events)
+	}
+}
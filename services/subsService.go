@@ -10,14 +10,26 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
+)
+
+// bases of the total cost: list prices of the billed subs or the recorded payments
+const (
+	TotalBasisExpected = "expected"
+	TotalBasisActual   = "actual"
 )
 
 type SubsService struct{
 	subsRepo *repo.SubsRepo
+	catalogRepo *repo.CatalogRepo
+	tagRepo *repo.TagRepo
+	tenantRepo *repo.TenantRepo
+	resolver *NameResolver
+	listeners []SubListener
 }
 
-func NewSubsService(subsRepo *repo.SubsRepo) *SubsService{
-	return &SubsService{subsRepo: subsRepo}
+func NewSubsService(subsRepo *repo.SubsRepo, catalogRepo *repo.CatalogRepo, tagRepo *repo.TagRepo, tenantRepo *repo.TenantRepo, resolver *NameResolver) *SubsService{
+	return &SubsService{subsRepo: subsRepo, catalogRepo: catalogRepo, tagRepo: tagRepo, tenantRepo: tenantRepo, resolver: resolver}
 }
 
 func validateUUID(id string) bool{
@@ -45,12 +57,264 @@ func validDate(date string) (time.Time, error){
 	return time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC), nil
 }
 
-func (s *SubsService) CreateService(sub *models.Sub, startDateStr, endDateStr string) error{
+// hasControlChars reports characters such as CR and LF, which must never reach mail headers
+func hasControlChars(value string) bool{
+	return strings.IndexFunc(value, unicode.IsControl) >= 0
+}
+
+// applyCatalog fills the canonical service name, default price and billing period
+// from the referenced catalog plan or provider
+func (s *SubsService) applyCatalog(sub *models.Sub) error{
+	if hasControlChars(sub.ServiceName) {
+		utils.ErrorLogger.Printf("Rejected service name with control characters: %q", sub.ServiceName)
+		return errors.New("service_name must not contain control characters")
+	}
+	if sub.PlanID != nil {
+		if !validateUUID(*sub.PlanID) {
+			utils.ErrorLogger.Println("Invalid plan_id format:", *sub.PlanID)
+			return errors.New("invalid plan_id format")
+		}
+		plan, err := s.catalogRepo.GetPlanRepoById(*sub.PlanID)
+		if err != nil {
+			utils.ErrorLogger.Println("Plan not found:", *sub.PlanID, "error:", err)
+			return errors.New("plan not found")
+		}
+		if sub.ProviderID != nil && *sub.ProviderID != plan.ProviderID {
+			utils.ErrorLogger.Println("Plan", plan.ID, "does not belong to provider", *sub.ProviderID)
+			return errors.New("plan does not belong to provider")
+		}
+		sub.ProviderID = &plan.ProviderID
+		if sub.Price == 0 {
+			sub.Price = plan.Price
+		}
+		if sub.BillingPeriod == "" {
+			sub.BillingPeriod = plan.BillingPeriod
+		}
+	}
+
+	if sub.ProviderID != nil {
+		if !validateUUID(*sub.ProviderID) {
+			utils.ErrorLogger.Println("Invalid provider_id format:", *sub.ProviderID)
+			return errors.New("invalid provider_id format")
+		}
+		provider, err := s.catalogRepo.GetProviderRepoById(*sub.ProviderID)
+		if err != nil {
+			utils.ErrorLogger.Println("Provider not found:", *sub.ProviderID, "error:", err)
+			return errors.New("provider not found")
+		}
+		sub.ServiceName = provider.Name
+		sub.NameConfidence = 1
+		if sub.Category == "" {
+			sub.Category = provider.Category
+		}
+	} else {
+		s.resolveServiceName(sub)
+	}
+
+	if sub.BillingPeriod == "" {
+		sub.BillingPeriod = models.BillingMonthly
+	}
+	if !validBillingPeriod(sub.BillingPeriod) {
+		utils.ErrorLogger.Println("Invalid billing period:", sub.BillingPeriod)
+		return errors.New("billing_period must be one of monthly, quarterly, yearly")
+	}
+	if strings.TrimSpace(sub.ServiceName) == "" {
+		utils.ErrorLogger.Println("Missing service name")
+		return errors.New("service_name or a catalog plan_id/provider_id is required")
+	}
+
+	if err := s.validateCategory(sub); err != nil {
+		return err
+	}
+	return s.resolveTags(sub)
+}
+
+func (s *SubsService) validateCategory(sub *models.Sub) error{
+	sub.Category = normalizeLabel(sub.Category)
+	if sub.Category == "" {
+		return nil
+	}
+	if _, err := s.tagRepo.GetCategoryRepo(sub.Category); err != nil {
+		utils.ErrorLogger.Println("Unknown category:", sub.Category, "error:", err)
+		return errors.New("unknown category")
+	}
+	return nil
+}
+
+// resolveTags swaps the tag names given in the request for stored tags, creating new ones
+func (s *SubsService) resolveTags(sub *models.Sub) error{
+	if sub.Tags == nil {
+		return nil
+	}
+	names := []string{}
+	for _, tag := range sub.Tags {
+		names = append(names, tag.Name)
+	}
+
+	tags, err := s.tagsByName(names)
+	if err != nil {
+		return err
+	}
+	sub.Tags = tags
+	return nil
+}
+
+func (s *SubsService) tagsByName(names []string) ([]models.Tag, error){
+	unique := []string{}
+	seen := map[string]bool{}
+	for _, name := range names {
+		name = normalizeLabel(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		unique = append(unique, name)
+	}
+
+	tags, err := s.tagRepo.GetOrCreateTagsRepo(unique, utils.NewUUID)
+	if err != nil {
+		utils.ErrorLogger.Println("Failed to resolve tags:", names, "error:", err)
+		return nil, err
+	}
+	return tags, nil
+}
+
+func (s *SubsService) SetSubTagsService(id string, names []string) (*models.Sub, error){
+	if !validateUUID(id) {
+		utils.ErrorLogger.Println("Invalid ID format:", id)
+		return nil, errors.New("invalid id format")
+	}
+	sub, err := s.subsRepo.GetSubRepoById(id)
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := s.tagsByName(names)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.subsRepo.SetSubTagsRepo(sub, tags); err != nil {
+		return nil, err
+	}
+	sub.Tags = tags
+	s.emit(EventSubUpdated, sub)
+	return sub, nil
+}
+
+func (s *SubsService) SetSubCategoryService(id, category string) (*models.Sub, error){
+	if !validateUUID(id) {
+		utils.ErrorLogger.Println("Invalid ID format:", id)
+		return nil, errors.New("invalid id format")
+	}
+	sub, err := s.subsRepo.GetSubRepoById(id)
+	if err != nil {
+		return nil, err
+	}
+
+	sub.Category = category
+	if err := s.validateCategory(sub); err != nil {
+		return nil, err
+	}
+	if err := s.subsRepo.SetSubCategoryRepo(id, sub.Category); err != nil {
+		return nil, err
+	}
+	s.emit(EventSubUpdated, sub)
+	return sub, nil
+}
+
+// resolveServiceName replaces the free-text service name with its canonical
+// catalog name when the match is confident enough
+func (s *SubsService) resolveServiceName(sub *models.Sub){
+	sub.ServiceNameInput = sub.ServiceName
+	res, err := s.resolver.Resolve(sub.ServiceName)
+	if err != nil {
+		utils.WarningLogger.Println("Could not resolve service name:", sub.ServiceName, "error:", err)
+	}
+	sub.NameConfidence = res.Confidence
+
+	if res.Confidence >= autoResolveConfidence {
+		utils.InfoLogger.Printf("Resolved service name %q to %q (%s, confidence %.2f)", sub.ServiceName, res.CanonicalName, res.MatchType, res.Confidence)
+		sub.ServiceName = res.CanonicalName
+		sub.ProviderID = &res.ProviderID
+		if sub.Category == "" {
+			sub.Category = res.Category
+		}
+		return
+	}
+	sub.ServiceName = strings.TrimSpace(sub.ServiceName)
+}
+
+func (s *SubsService) ResolveNameService(name string) (Resolution, error){
+	if strings.TrimSpace(name) == "" {
+		return Resolution{}, errors.New("missing name")
+	}
+	return s.resolver.Resolve(name)
+}
+
+func (s *SubsService) SuggestService(query string, limit int) ([]Resolution, error){
+	if limit <= 0 || limit > 50 {
+		limit = 10
+	}
+	return s.resolver.Suggest(query, limit)
+}
+
+// setTrialEnd parses the optional MM-YYYY trial end, the sub is first charged in that month
+func setTrialEnd(sub *models.Sub, trialEndStr string) error{
+	sub.TrialEndDate = nil
+	if trialEndStr == "" {
+		return nil
+	}
+	trialEnd, err := validDate(trialEndStr)
+	if err != nil {
+		utils.ErrorLogger.Println("Invalid trial end date:", trialEndStr, "error:", err)
+		return err
+	}
+	if trialEnd.Before(sub.StartDate) {
+		utils.ErrorLogger.Println("Trial ends before start:", trialEndStr)
+		return errors.New("trial_end_date must not be before start_date")
+	}
+	sub.TrialEndDate = &trialEnd
+	return nil
+}
+
+func (s *SubsService) CreateService(sub *models.Sub, startDateStr, endDateStr, trialEndStr string) error{
+	if err := s.validateNewSub(sub, startDateStr, endDateStr, trialEndStr); err != nil {
+		return err
+	}
+
+	id, err := utils.NewUUID()
+	if err != nil {
+		utils.ErrorLogger.Println("Failed to generate UUID:", err)
+		return err
+	}
+	sub.ID = id
+	if err := s.subsRepo.CreateSubRepo(sub); err != nil {
+		return err
+	}
+	s.emit(EventSubCreated, sub)
+	return nil
+}
+
+// validateNewSub runs the create validation and fills in catalog defaults and dates
+func (s *SubsService) validateNewSub(sub *models.Sub, startDateStr, endDateStr, trialEndStr string) error{
 	if !validateUUID(sub.UserID){
 		utils.ErrorLogger.Println("Invalid user_id format:", sub.UserID)
 		return errors.New("invalid user_id format")
 	}
 
+	if sub.TenantID != "" && !validateTenantID(sub.TenantID){
+		utils.ErrorLogger.Println("Invalid tenant_id format:", sub.TenantID)
+		return errors.New("invalid tenant_id format")
+	}
+
+	if err := s.applyCatalog(sub); err != nil {
+		return err
+	}
+
+	if err := validateMetadata(s.tenantRepo, sub.TenantID, sub.Metadata); err != nil {
+		return err
+	}
+
 	if sub.Price <= 0{
 		utils.ErrorLogger.Println("Invalid price provided:", sub.Price)
 		return errors.New("price must be a postive integer")
@@ -72,13 +336,7 @@ func (s *SubsService) CreateService(sub *models.Sub, startDateStr, endDateStr st
 		sub.EndDate = endDate
 	}
 
-	id, err := utils.NewUUID()
-	if err != nil {
-		utils.ErrorLogger.Println("Failed to generate UUID:", err)
-		return err
-	}
-	sub.ID = id
-	return s.subsRepo.CreateSubRepo(sub)
+	return setTrialEnd(sub, trialEndStr)
 }
 
 func (s *SubsService) GetServiceByID(id string) (*models.Sub, error){
@@ -86,19 +344,68 @@ func (s *SubsService) GetServiceByID(id string) (*models.Sub, error){
 		utils.ErrorLogger.Println("Invalid ID format:", id)
 		return nil, errors.New("invalid id format")
 	}
-	return s.subsRepo.GetSubRepoById(id)
+	sub, err := s.subsRepo.GetSubRepoById(id)
+	if err != nil {
+		return nil, err
+	}
+	setNextChargeDate(sub, time.Now())
+	return sub, nil
 }
 
-func (s *SubsService) ListAllSubsService() ([]models.Sub, error){
-	return s.subsRepo.ListAllSubsRepo()
+func validateFilter(filter repo.SubsFilter) error{
+	if filter.UserID != "" && !validateUUID(filter.UserID) {
+		utils.ErrorLogger.Println("Invalid user_id filter:", filter.UserID)
+		return errors.New("invalid user_id format")
+	}
+	if filter.ProviderID != "" && !validateUUID(filter.ProviderID) {
+		utils.ErrorLogger.Println("Invalid provider_id filter:", filter.ProviderID)
+		return errors.New("invalid provider_id format")
+	}
+	if filter.PlanID != "" && !validateUUID(filter.PlanID) {
+		utils.ErrorLogger.Println("Invalid plan_id filter:", filter.PlanID)
+		return errors.New("invalid plan_id format")
+	}
+	if filter.TenantID != "" && !validateTenantID(filter.TenantID) {
+		utils.ErrorLogger.Println("Invalid tenant_id filter:", filter.TenantID)
+		return errors.New("invalid tenant_id format")
+	}
+	return nil
 }
 
-func (s *SubsService) UpdateSubService(sub *models.Sub, startDateStr, endDateStr string) error{
+func (s *SubsService) ListAllSubsService(filter repo.SubsFilter) ([]models.Sub, error){
+	if err := validateFilter(filter); err != nil {
+		return nil, err
+	}
+	subs, err := s.subsRepo.ListAllSubsRepo(filter)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for i := range subs {
+		setNextChargeDate(&subs[i], now)
+	}
+	return subs, nil
+}
+
+func (s *SubsService) UpdateSubService(sub *models.Sub, startDateStr, endDateStr, trialEndStr string) error{
 	if !validateUUID(sub.UserID){
 		utils.ErrorLogger.Println("Invalid user_id format:", sub.UserID)
 		return errors.New("invalid user_id format")
 	}
 
+	if sub.TenantID != "" && !validateTenantID(sub.TenantID){
+		utils.ErrorLogger.Println("Invalid tenant_id format:", sub.TenantID)
+		return errors.New("invalid tenant_id format")
+	}
+
+	if err := s.applyCatalog(sub); err != nil {
+		return err
+	}
+
+	if err := validateMetadata(s.tenantRepo, sub.TenantID, sub.Metadata); err != nil {
+		return err
+	}
+
 	if sub.Price <= 0{
 		utils.ErrorLogger.Println("Invalid price provided:", sub.Price)
 		return errors.New("price must be a postive integer")
@@ -120,13 +427,28 @@ func (s *SubsService) UpdateSubService(sub *models.Sub, startDateStr, endDateStr
 		sub.EndDate = endDate
 	}
 
+	if err := setTrialEnd(sub, trialEndStr); err != nil {
+		return err
+	}
+
 	// id, err := utils.NewUUID()
 	// if err != nil {
 	// 	utils.ErrorLogger.Println("Failed to generate UUID:", err)
 	// 	return err
 	// }
 	// sub.ID = id
-	return s.subsRepo.UpdateSubRepo(sub)
+	if err := s.subsRepo.UpdateSubRepo(sub); err != nil {
+		return err
+	}
+	// the billing periods of usage-based subs follow the start date and billing period
+	if pricing, err := s.subsRepo.GetPricingRepo(sub.ID); err == nil {
+		sub.Pricing = pricing
+		if err := s.repriceUsage(sub); err != nil {
+			return err
+		}
+	}
+	s.emit(EventSubUpdated, sub)
+	return nil
 }
 
 func (s *SubsService) DeleteSubService(id string) error{
@@ -134,26 +456,183 @@ func (s *SubsService) DeleteSubService(id string) error{
 		utils.ErrorLogger.Println("Invalid ID format:", id)
 		return errors.New("invalid id format")
 	}
-	return s.subsRepo.DeleteSubRepo(id)
+	sub, err := s.subsRepo.GetSubRepoById(id)
+	if err != nil {
+		utils.ErrorLogger.Println("Subscription not found:", id, "error:", err)
+		return errors.New("subscription not found")
+	}
+	if err := s.subsRepo.DeleteSubRepo(id); err != nil {
+		return err
+	}
+	s.emit(EventSubDeleted, sub)
+	return nil
 }
 
 
-func (s *SubsService) GetTotalCostService(startStr, endStr, userID, serviceName string) (int, error) {
+func (s *SubsService) GetTotalCostService(startStr, endStr string, filter repo.SubsFilter) (repo.CostTotal, error) {
 	start, err := validDate(startStr)
 	if err != nil {
 		utils.ErrorLogger.Println("Invalid start date:", start, "error:", err)
-		return 0, err
+		return repo.CostTotal{}, err
 	}
 	end, err := validDate(endStr)
 	if err != nil {
 		utils.ErrorLogger.Println("Invalid end date:", end, "error:", err)
-		return 0, err
+		return repo.CostTotal{}, err
+	}
+
+	if err := validateFilter(filter); err != nil {
+		return repo.CostTotal{}, err
+	}
+
+	// a user's total counts their share of the subs they own or are a member of
+	if filter.UserID != "" {
+		_, costs, err := userShareCosts(s.subsRepo, start, end, filter)
+		if err != nil {
+			return repo.CostTotal{}, err
+		}
+		var total repo.CostTotal
+		for _, cost := range costs {
+			total.Gross += cost.Gross
+			total.Net += cost.Net
+			total.Tax += cost.Tax
+			total.TaxNet += cost.TaxNet
+		}
+		return total, nil
+	}
+
+	return s.subsRepo.GetTotalCostRepo(start, end, filter)
+}
+
+// GetTotalPaidService sums the recorded payments between two months, per currency
+func (s *SubsService) GetTotalPaidService(startStr, endStr string, filter repo.SubsFilter) ([]repo.PaidTotal, error) {
+	start, err := validDate(startStr)
+	if err != nil {
+		utils.ErrorLogger.Println("Invalid start date:", startStr, "error:", err)
+		return nil, err
+	}
+	end, err := validDate(endStr)
+	if err != nil {
+		utils.ErrorLogger.Println("Invalid end date:", endStr, "error:", err)
+		return nil, err
+	}
+
+	if err := validateFilter(filter); err != nil {
+		return nil, err
 	}
 
-	if userID != "" && !validateUUID(userID) {
-		utils.ErrorLogger.Println("Invalid usedID:", userID, "error:", err)
-		return 0, errors.New("invalid user_id format")
+	return s.subsRepo.GetTotalPaidRepo(start, end, filter)
+}
+
+// GetTotalCostBreakdownService groups the total cost by "category" or "tag", on the
+// "expected" (list price) or "actual" (net paid) basis
+func (s *SubsService) GetTotalCostBreakdownService(startStr, endStr, groupBy, basis string, filter repo.SubsFilter) ([]repo.CostGroup, error) {
+	start, err := validDate(startStr)
+	if err != nil {
+		utils.ErrorLogger.Println("Invalid start date:", startStr, "error:", err)
+		return nil, err
+	}
+	end, err := validDate(endStr)
+	if err != nil {
+		utils.ErrorLogger.Println("Invalid end date:", endStr, "error:", err)
+		return nil, err
 	}
 
-	return s.subsRepo.GetTotalCostRepo(start, end, userID, serviceName)
+	if err := validateFilter(filter); err != nil {
+		return nil, err
+	}
+
+	actual := basis == TotalBasisActual
+	if filter.UserID != "" && !actual && (groupBy == "category" || groupBy == "tag") {
+		subs, costs, err := userShareCosts(s.subsRepo, start, end, filter)
+		if err != nil {
+			return nil, err
+		}
+		totals := map[string]int{}
+		for i, sub := range subs {
+			if groupBy == "category" {
+				totals[sub.Category] += costs[i].Net
+				continue
+			}
+			if len(sub.Tags) == 0 {
+				totals[""] += costs[i].Net
+			}
+			for _, tag := range sub.Tags {
+				totals[tag.Name] += costs[i].Net
+			}
+		}
+		return sortedCostGroups(totals), nil
+	}
+
+	switch {
+	case groupBy == "category" && actual:
+		return s.subsRepo.GetTotalPaidByCategoryRepo(start, end, filter)
+	case groupBy == "category":
+		return s.subsRepo.GetTotalCostByCategoryRepo(start, end, filter)
+	case groupBy == "tag" && actual:
+		return s.subsRepo.GetTotalPaidByTagRepo(start, end, filter)
+	case groupBy == "tag":
+		return s.subsRepo.GetTotalCostByTagRepo(start, end, filter)
+	}
+	utils.ErrorLogger.Println("Invalid group_by:", groupBy)
+	return nil, errors.New("group_by must be category or tag")
+}
+
+func (s *SubsService) CreatePriceChangeService(change *models.PriceChange, effectiveDateStr string) error{
+	if !validateUUID(change.SubID) {
+		utils.ErrorLogger.Println("Invalid sub_id format:", change.SubID)
+		return errors.New("invalid sub_id format")
+	}
+	sub, err := s.subsRepo.GetSubRepoById(change.SubID)
+	if err != nil {
+		utils.ErrorLogger.Println("Subscription not found:", change.SubID, "error:", err)
+		return errors.New("subscription not found")
+	}
+	if change.Price <= 0 {
+		utils.ErrorLogger.Println("Invalid price provided:", change.Price)
+		return errors.New("price must be a postive integer")
+	}
+
+	effectiveDate, err := validDate(effectiveDateStr)
+	if err != nil {
+		utils.ErrorLogger.Println("Invalid effective date:", effectiveDateStr, "error:", err)
+		return err
+	}
+	change.EffectiveDate = effectiveDate
+
+	id, err := utils.NewUUID()
+	if err != nil {
+		utils.ErrorLogger.Println("Failed to generate UUID:", err)
+		return err
+	}
+	change.ID = id
+	if err := s.subsRepo.CreatePriceChangeRepo(change); err != nil {
+		utils.ErrorLogger.Println("Failed to store price change of sub:", change.SubID, "error:", err)
+		return err
+	}
+	s.emit(EventSubUpdated, sub)
+	return nil
+}
+
+func (s *SubsService) ListPriceChangesService(subID string) ([]models.PriceChange, error){
+	if !validateUUID(subID) {
+		utils.ErrorLogger.Println("Invalid sub_id format:", subID)
+		return nil, errors.New("invalid sub_id format")
+	}
+	return s.subsRepo.ListPriceChangesRepo(subID)
+}
+
+func (s *SubsService) DeletePriceChangeService(id string) error{
+	if !validateUUID(id) {
+		utils.ErrorLogger.Println("Invalid ID format:", id)
+		return errors.New("invalid id format")
+	}
+	change, err := s.subsRepo.DeletePriceChangeRepo(id)
+	if err != nil || change == nil {
+		return err
+	}
+	if sub, err := s.subsRepo.GetSubRepoById(change.SubID); err == nil {
+		s.emit(EventSubUpdated, sub)
+	}
+	return nil
 }
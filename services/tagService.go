@@ -0,0 +1,73 @@
+package services
+
+import (
+	"errors"
+	"online-subs-api/models"
+	"online-subs-api/repo"
+	"online-subs-api/utils"
+	"strings"
+)
+
+type TagService struct{
+	tagRepo *repo.TagRepo
+}
+
+func NewTagService(tagRepo *repo.TagRepo) *TagService{
+	return &TagService{tagRepo: tagRepo}
+}
+
+// normalizeLabel makes tag and category names case and whitespace insensitive
+func normalizeLabel(name string) string{
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
+
+func (s *TagService) CreateTagService(tag *models.Tag) error{
+	tag.Name = normalizeLabel(tag.Name)
+	if tag.Name == "" {
+		utils.ErrorLogger.Println("Missing tag name")
+		return errors.New("tag name is required")
+	}
+
+	id, err := utils.NewUUID()
+	if err != nil {
+		utils.ErrorLogger.Println("Failed to generate UUID:", err)
+		return err
+	}
+	tag.ID = id
+	return s.tagRepo.CreateTagRepo(tag)
+}
+
+func (s *TagService) ListTagsService() ([]models.Tag, error){
+	return s.tagRepo.ListTagsRepo()
+}
+
+func (s *TagService) DeleteTagService(id string) error{
+	if !validateUUID(id) {
+		utils.ErrorLogger.Println("Invalid ID format:", id)
+		return errors.New("invalid id format")
+	}
+	return s.tagRepo.DeleteTagRepo(id)
+}
+
+func (s *TagService) CreateCategoryService(category *models.Category) error{
+	category.Name = normalizeLabel(category.Name)
+	if category.Name == "" {
+		utils.ErrorLogger.Println("Missing category name")
+		return errors.New("category name is required")
+	}
+	category.Description = strings.TrimSpace(category.Description)
+	return s.tagRepo.CreateCategoryRepo(category)
+}
+
+func (s *TagService) ListCategoriesService() ([]models.Category, error){
+	return s.tagRepo.ListCategoriesRepo()
+}
+
+func (s *TagService) DeleteCategoryService(name string) error{
+	name = normalizeLabel(name)
+	if _, err := s.tagRepo.GetCategoryRepo(name); err != nil {
+		utils.ErrorLogger.Println("Category not found:", name, "error:", err)
+		return errors.New("category not found")
+	}
+	return s.tagRepo.DeleteCategoryRepo(name)
+}
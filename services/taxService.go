@@ -0,0 +1,206 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"online-subs-api/models"
+	"online-subs-api/repo"
+	"online-subs-api/utils"
+	"regexp"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// jurisdictionCode is an ISO 3166-1 country code, optionally with a subdivision ("US-CA")
+var jurisdictionCode = regexp.MustCompile(`^[A-Z]{2}(-[A-Z0-9]{1,3})?$`)
+
+// SubTaxRequest sets how a subscription is taxed, rate overrides the jurisdiction's rate.
+// Prices include the tax unless inclusive is false.
+type SubTaxRequest struct{
+	Jurisdiction	string		`json:"jurisdiction,omitempty"`
+	Rate			*float64	`json:"rate,omitempty"`
+	Inclusive		*bool		`json:"inclusive,omitempty"`
+}
+
+func validTaxRate(rate float64) bool{
+	return rate >= 0 && rate <= 100
+}
+
+// taxSplit splits an amount at the rate (in percent): the tax is taken out of tax-inclusive
+// amounts and added on top of the others
+func taxSplit(amount int, rate float64, inclusive bool) repo.TaxBreakdown{
+	if inclusive {
+		net := int(math.Round(float64(amount) * 100 / (100 + rate)))
+		return repo.TaxBreakdown{Net: net, Tax: amount - net, Gross: amount}
+	}
+	tax := int(math.Round(float64(amount) * rate / 100))
+	return repo.TaxBreakdown{Net: amount, Tax: tax, Gross: amount + tax}
+}
+
+// ratesByJurisdiction groups the rates, which are sorted by valid_from, per jurisdiction
+func ratesByJurisdiction(rates []models.TaxRate) map[string][]models.TaxRate{
+	grouped := map[string][]models.TaxRate{}
+	for _, rate := range rates {
+		grouped[rate.Jurisdiction] = append(grouped[rate.Jurisdiction], rate)
+	}
+	return grouped
+}
+
+// taxRateAt returns the tax rate of the sub in the month: its own rate, else the latest rate
+// of its jurisdiction valid by then and 0 for untaxed subs
+func taxRateAt(sub *models.Sub, rates map[string][]models.TaxRate, month time.Time) float64{
+	if sub.Tax == nil {
+		return 0
+	}
+	if sub.Tax.Rate != nil {
+		return *sub.Tax.Rate
+	}
+	rate := 0.0
+	for _, r := range rates[sub.Tax.Jurisdiction] {
+		if monthStart(r.ValidFrom).After(monthStart(month)) {
+			break
+		}
+		rate = r.Rate
+	}
+	return rate
+}
+
+// taxOf splits an amount charged by the sub in the month, amounts of untaxed subs have no tax
+func taxOf(sub *models.Sub, rates map[string][]models.TaxRate, month time.Time, amount int) repo.TaxBreakdown{
+	inclusive := sub.Tax == nil || sub.Tax.Inclusive
+	return taxSplit(amount, taxRateAt(sub, rates, month), inclusive)
+}
+
+// chargedAt is what the sub is charged in the month: its price after discounts with the tax
+// added on top of tax-exclusive prices
+func chargedAt(sub *models.Sub, changes []models.PriceChange, rates map[string][]models.TaxRate, month time.Time) int{
+	return taxOf(sub, rates, month, priceAt(sub, changes, month)).Gross
+}
+
+// taxCharges fills the tax breakdown of the projected charges (or of the shares of them) at
+// the rate in effect in the month of each charge
+func taxCharges(charges []ProjectedCharge, subs []models.Sub, rates map[string][]models.TaxRate) []ProjectedCharge{
+	index := map[string]int{}
+	for i := range subs {
+		index[subs[i].ID] = i
+	}
+	for i := range charges {
+		charges[i].TaxBreakdown = taxOf(&subs[index[charges[i].SubID]], rates, charges[i].Date, charges[i].Amount)
+	}
+	return charges
+}
+
+func addTax(total *repo.TaxBreakdown, tax repo.TaxBreakdown){
+	total.Net += tax.Net
+	total.Tax += tax.Tax
+	total.Gross += tax.Gross
+}
+
+// CreateTaxRateService adds the rate of a jurisdiction from the month validFromStr (MM-YYYY) on
+func (s *SubsService) CreateTaxRateService(rate *models.TaxRate, validFromStr string) error{
+	rate.Jurisdiction = strings.ToUpper(strings.TrimSpace(rate.Jurisdiction))
+	if !jurisdictionCode.MatchString(rate.Jurisdiction) {
+		utils.ErrorLogger.Println("Invalid jurisdiction:", rate.Jurisdiction)
+		return errors.New("jurisdiction must be a country code like DE, optionally with a region like US-CA")
+	}
+	if !validTaxRate(rate.Rate) {
+		utils.ErrorLogger.Println("Invalid tax rate:", rate.Rate)
+		return errors.New("rate must be a percentage between 0 and 100")
+	}
+	rate.Name = strings.TrimSpace(rate.Name)
+
+	validFrom, err := validDate(validFromStr)
+	if err != nil {
+		utils.ErrorLogger.Println("Invalid valid_from date:", validFromStr, "error:", err)
+		return err
+	}
+	rate.ValidFrom = validFrom
+
+	id, err := utils.NewUUID()
+	if err != nil {
+		utils.ErrorLogger.Println("Failed to generate UUID:", err)
+		return err
+	}
+	rate.ID = id
+	if err := s.subsRepo.CreateTaxRateRepo(rate); err != nil {
+		utils.ErrorLogger.Println("Failed to create tax rate:", err)
+		return errors.New("a rate of this jurisdiction already starts in that month")
+	}
+	return nil
+}
+
+func (s *SubsService) ListTaxRatesService() ([]models.TaxRate, error){
+	return s.subsRepo.ListTaxRatesRepo()
+}
+
+func (s *SubsService) DeleteTaxRateService(id string) error{
+	if !validateUUID(id) {
+		utils.ErrorLogger.Println("Invalid ID format:", id)
+		return errors.New("invalid id format")
+	}
+	if err := s.subsRepo.DeleteTaxRateRepo(id); err != nil {
+		return errors.New("tax rate not found")
+	}
+	return nil
+}
+
+// SetSubTaxService taxes the sub at the rate of a jurisdiction or at its own rate
+func (s *SubsService) SetSubTaxService(subID string, req SubTaxRequest) (*models.SubTax, error){
+	if !validateUUID(subID) {
+		utils.ErrorLogger.Println("Invalid sub_id format:", subID)
+		return nil, errors.New("invalid sub_id format")
+	}
+	sub, err := s.subsRepo.GetSubRepoById(subID)
+	if err != nil {
+		utils.ErrorLogger.Println("Subscription not found:", subID, "error:", err)
+		return nil, errors.New("subscription not found")
+	}
+
+	tax := &models.SubTax{SubID: subID, Jurisdiction: strings.ToUpper(strings.TrimSpace(req.Jurisdiction)), Rate: req.Rate, Inclusive: req.Inclusive == nil || *req.Inclusive}
+	if tax.Jurisdiction == "" && tax.Rate == nil {
+		return nil, errors.New("jurisdiction or rate is required")
+	}
+	if tax.Jurisdiction != "" && !jurisdictionCode.MatchString(tax.Jurisdiction) {
+		utils.ErrorLogger.Println("Invalid jurisdiction:", tax.Jurisdiction)
+		return nil, errors.New("jurisdiction must be a country code like DE, optionally with a region like US-CA")
+	}
+	if tax.Rate != nil && !validTaxRate(*tax.Rate) {
+		utils.ErrorLogger.Println("Invalid tax rate:", *tax.Rate)
+		return nil, errors.New("rate must be a percentage between 0 and 100")
+	}
+
+	if err := s.subsRepo.SetSubTaxRepo(tax); err != nil {
+		utils.ErrorLogger.Println("Failed to save tax settings of sub", subID, "error:", err)
+		return nil, err
+	}
+	s.emit(EventSubUpdated, sub)
+	return tax, nil
+}
+
+func (s *SubsService) GetSubTaxService(subID string) (*models.SubTax, error){
+	if !validateUUID(subID) {
+		utils.ErrorLogger.Println("Invalid sub_id format:", subID)
+		return nil, errors.New("invalid sub_id format")
+	}
+	tax, err := s.subsRepo.GetSubTaxRepo(subID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("subscription has no tax settings")
+	}
+	return tax, err
+}
+
+func (s *SubsService) DeleteSubTaxService(subID string) error{
+	if !validateUUID(subID) {
+		utils.ErrorLogger.Println("Invalid sub_id format:", subID)
+		return errors.New("invalid sub_id format")
+	}
+	if err := s.subsRepo.DeleteSubTaxRepo(subID); err != nil {
+		return errors.New("tax settings not found")
+	}
+	if sub, err := s.subsRepo.GetSubRepoById(subID); err == nil {
+		s.emit(EventSubUpdated, sub)
+	}
+	return nil
+}
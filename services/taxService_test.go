@@ -0,0 +1,81 @@
+package services
+
+import (
+	"online-subs-api/models"
+	"online-subs-api/repo"
+	"testing"
+	"time"
+)
+
+func TestTaxSplit(t *testing.T){
+	tests := []struct{
+		name		string
+		amount		int
+		rate		float64
+		inclusive	bool
+		want		repo.TaxBreakdown
+	}{
+		{"inclusive", 1190, 19, true, repo.TaxBreakdown{Net: 1000, Tax: 190, Gross: 1190}},
+		{"exclusive", 1000, 19, false, repo.TaxBreakdown{Net: 1000, Tax: 190, Gross: 1190}},
+		{"untaxed", 1000, 0, true, repo.TaxBreakdown{Net: 1000, Tax: 0, Gross: 1000}},
+		{"exclusive rounds the tax", 999, 7, false, repo.TaxBreakdown{Net: 999, Tax: 70, Gross: 1069}},
+		{"inclusive rounds the net", 1000, 7, true, repo.TaxBreakdown{Net: 935, Tax: 65, Gross: 1000}},
+		{"nothing charged", 0, 19, false, repo.TaxBreakdown{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T){
+			if got := taxSplit(tt.amount, tt.rate, tt.inclusive); got != tt.want {
+				t.Errorf("taxSplit() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTaxRateAt(t *testing.T){
+	own := 7.0
+	rates := ratesByJurisdiction([]models.TaxRate{
+		{Jurisdiction: "DE", Rate: 19, ValidFrom: date(2007, time.January, 1)},
+		{Jurisdiction: "DE", Rate: 16, ValidFrom: date(2020, time.July, 1)},
+		{Jurisdiction: "DE", Rate: 19, ValidFrom: date(2021, time.January, 1)},
+	})
+	tests := []struct{
+		name	string
+		tax		*models.SubTax
+		month	time.Time
+		want	float64
+	}{
+		{"untaxed", nil, date(2020, time.August, 1), 0},
+		{"own rate", &models.SubTax{Jurisdiction: "DE", Rate: &own}, date(2020, time.August, 1), 7},
+		{"jurisdiction rate", &models.SubTax{Jurisdiction: "DE"}, date(2020, time.June, 1), 19},
+		{"rate change", &models.SubTax{Jurisdiction: "DE"}, date(2020, time.August, 1), 16},
+		{"unknown jurisdiction", &models.SubTax{Jurisdiction: "FR"}, date(2020, time.August, 1), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T){
+			sub := monthlySub(1000)
+			sub.Tax = tt.tax
+			if got := taxRateAt(sub, rates, tt.month); got != tt.want {
+				t.Errorf("taxRateAt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTaxCharges(t *testing.T){
+	sub := monthlySub(1000)
+	sub.Tax = &models.SubTax{Jurisdiction: "DE"}
+	rates := ratesByJurisdiction([]models.TaxRate{
+		{Jurisdiction: "DE", Rate: 19, ValidFrom: date(2007, time.January, 1)},
+		{Jurisdiction: "DE", Rate: 16, ValidFrom: date(2025, time.March, 1)},
+	})
+	charges := taxCharges(projectCharges([]models.Sub{*sub}, nil, date(2025, time.February, 1), date(2025, time.March, 1)), []models.Sub{*sub}, rates)
+	want := []repo.TaxBreakdown{{Net: 1000, Tax: 190, Gross: 1190}, {Net: 1000, Tax: 160, Gross: 1160}}
+	if len(charges) != len(want) {
+		t.Fatalf("got %d charges, want %d", len(charges), len(want))
+	}
+	for i, charge := range charges {
+		if charge.TaxBreakdown != want[i] {
+			t.Errorf("%s: tax breakdown = %+v, want %+v", charge.Date.Format("2006-01"), charge.TaxBreakdown, want[i])
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"online-subs-api/models"
+	"online-subs-api/repo"
+	"online-subs-api/utils"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+var tenantIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+type TenantService struct{
+	tenantRepo *repo.TenantRepo
+}
+
+func NewTenantService(tenantRepo *repo.TenantRepo) *TenantService{
+	return &TenantService{tenantRepo: tenantRepo}
+}
+
+func validateTenantID(tenantID string) bool{
+	return tenantIDPattern.MatchString(tenantID)
+}
+
+// validateMetadata checks metadata against the tenant's JSON Schema, tenants without a schema
+// accept anything but an email the smtp notifier could not send to
+func validateMetadata(tenantRepo *repo.TenantRepo, tenantID string, metadata models.JSONMap) error{
+	if email, ok := metadata["email"].(string); ok && email != "" {
+		if hasControlChars(email) {
+			utils.ErrorLogger.Printf("Rejected email metadata with control characters: %q", email)
+			return errors.New("metadata email must not contain control characters")
+		}
+		if _, err := mail.ParseAddress(email); err != nil {
+			utils.ErrorLogger.Println("Invalid email metadata:", email, "error:", err)
+			return errors.New("metadata email must be a valid email address")
+		}
+	}
+	if tenantID == "" {
+		return nil
+	}
+	schema, err := tenantRepo.GetSchemaRepo(tenantID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		utils.ErrorLogger.Println("Failed to load metadata schema for tenant:", tenantID, "error:", err)
+		return err
+	}
+
+	value := map[string]interface{}{}
+	for k, v := range metadata {
+		value[k] = v
+	}
+	if errs := utils.ValidateJSONSchema(schema.Schema, value); len(errs) > 0 {
+		utils.ErrorLogger.Println("Metadata rejected by tenant schema:", tenantID, errs)
+		return fmt.Errorf("metadata does not match tenant schema: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (s *TenantService) SetMetadataSchemaService(tenantID string, schema models.JSONMap) (*models.TenantSchema, error){
+	if !validateTenantID(tenantID) {
+		utils.ErrorLogger.Println("Invalid tenant_id format:", tenantID)
+		return nil, errors.New("invalid tenant_id format")
+	}
+	if len(schema) == 0 {
+		return nil, errors.New("schema must be a non-empty JSON object")
+	}
+	if err := utils.CheckJSONSchema(schema); err != nil {
+		utils.ErrorLogger.Println("Invalid metadata schema for tenant:", tenantID, "error:", err)
+		return nil, err
+	}
+
+	tenantSchema := &models.TenantSchema{TenantID: tenantID, Schema: schema}
+	if err := s.tenantRepo.SetSchemaRepo(tenantSchema); err != nil {
+		return nil, err
+	}
+	return tenantSchema, nil
+}
+
+func (s *TenantService) GetMetadataSchemaService(tenantID string) (*models.TenantSchema, error){
+	if !validateTenantID(tenantID) {
+		utils.ErrorLogger.Println("Invalid tenant_id format:", tenantID)
+		return nil, errors.New("invalid tenant_id format")
+	}
+	return s.tenantRepo.GetSchemaRepo(tenantID)
+}
+
+func (s *TenantService) DeleteMetadataSchemaService(tenantID string) error{
+	if !validateTenantID(tenantID) {
+		utils.ErrorLogger.Println("Invalid tenant_id format:", tenantID)
+		return errors.New("invalid tenant_id format")
+	}
+	return s.tenantRepo.DeleteSchemaRepo(tenantID)
+}
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"online-subs-api/models"
+	"online-subs-api/repo"
+	"online-subs-api/utils"
+	"os"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// testDB opens TEST_DATABASE_URL in a transaction that is rolled back after the test, tests
+// needing Postgres are skipped without it
+func testDB(t *testing.T) *gorm.DB{
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	if err != nil {
+		t.Fatal("failed to connect to the test database:", err)
+	}
+	err = db.AutoMigrate(&models.Sub{}, &models.Tag{}, &models.PriceChange{}, &models.OutboxEvent{}, &models.SubSplit{}, &models.SubMember{},
+		&models.SeatChange{}, &models.SubPricing{}, &models.UsageCharge{}, &models.Discount{}, &models.TaxRate{}, &models.SubTax{})
+	if err != nil {
+		t.Fatal("failed to migrate the test database:", err)
+	}
+	tx := db.Begin()
+	t.Cleanup(func(){ tx.Rollback() })
+	return tx
+}
+
+func newID(t *testing.T) string{
+	id, err := utils.NewUUID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+// TestTotalCostMatchesRepo checks that the billing engine and the SQL of the total cost
+// agree on flat, seat-based, usage-based, discounted, taxed and plan-changed subs, and pins
+// the one period rule of usage-based subs
+func TestTotalCostMatchesRepo(t *testing.T){
+	db := testDB(t)
+	subsRepo := repo.NewSubsRepo(db)
+	userID := newID(t)
+	exclusive := 19.0
+
+	subs := []models.Sub{
+		{ServiceName: "flat", Price: 1000, StartDate: date(2025, time.January, 1), BillingPeriod: models.BillingMonthly,
+			Tax: &models.SubTax{Rate: &exclusive}},
+		{ServiceName: "quarterly", Price: 3000, StartDate: date(2025, time.February, 1), EndDate: date(2025, time.September, 30), BillingPeriod: models.BillingQuarterly,
+			Discounts: []models.Discount{{Kind: models.DiscountPercentage, Percentage: 20, Duration: models.DiscountForever, StartDate: date(2025, time.February, 1)}}},
+		{ServiceName: "seats", Price: 1000, StartDate: date(2025, time.January, 1), BillingPeriod: models.BillingMonthly,
+			SeatChanges: []models.SeatChange{
+				{EffectiveDate: date(2025, time.January, 1), Seats: 10},
+				{EffectiveDate: date(2025, time.March, 16), Seats: 14, Prorate: true},
+			},
+			Discounts: []models.Discount{{Kind: models.DiscountFixed, Amount: 500, Duration: models.DiscountRepeating, Periods: 2, StartDate: date(2025, time.March, 1)}}},
+		{ServiceName: "usage", StartDate: date(2025, time.January, 1), BillingPeriod: models.BillingMonthly,
+			Pricing: &models.SubPricing{Model: models.PricingPerUnit, UnitPrice: 1, UnitSize: 1, BaseFee: 500},
+			UsageCharges: []models.UsageCharge{
+				{PeriodStart: date(2025, time.February, 1), Units: 1200, Amount: 1200},
+				{PeriodStart: date(2025, time.March, 1), Units: 800, Amount: 800},
+			}},
+		{ServiceName: "plan change", Price: 1000, StartDate: date(2025, time.January, 1), BillingPeriod: models.BillingMonthly},
+	}
+	for i := range subs {
+		subs[i].ID = newID(t)
+		subs[i].UserID = userID
+		for j := range subs[i].SeatChanges {
+			subs[i].SeatChanges[j].ID = newID(t)
+		}
+		for j := range subs[i].Discounts {
+			subs[i].Discounts[j].ID = newID(t)
+		}
+		if err := subsRepo.CreateSubRepo(&subs[i]); err != nil {
+			t.Fatal("failed to create sub:", err)
+		}
+	}
+	change := planChange(&subs[4], nil, date(2025, time.March, 12), 1500, models.ProrationImmediate).Change
+	change.ID = newID(t)
+	if err := db.Create(&change).Error; err != nil {
+		t.Fatal("failed to create plan change:", err)
+	}
+	price := models.PriceChange{ID: newID(t), SubID: subs[0].ID, EffectiveDate: date(2025, time.April, 1), Price: 1200}
+	if err := db.Create(&price).Error; err != nil {
+		t.Fatal("failed to create price change:", err)
+	}
+
+	ranges := [][2]time.Time{
+		{date(2025, time.January, 1), date(2025, time.January, 1)},
+		{date(2025, time.March, 1), date(2025, time.March, 1)},
+		{date(2025, time.April, 1), date(2025, time.April, 1)},
+		{date(2025, time.January, 1), date(2025, time.June, 1)},
+		{date(2025, time.July, 1), date(2025, time.December, 1)},
+	}
+	for _, r := range ranges {
+		filter := repo.SubsFilter{UserID: userID}
+		want, err := subsRepo.GetTotalCostRepo(r[0], r[1], filter)
+		if err != nil {
+			t.Fatal("GetTotalCostRepo failed:", err)
+		}
+		_, costs, err := userShareCosts(subsRepo, r[0], r[1], filter)
+		if err != nil {
+			t.Fatal("userShareCosts failed:", err)
+		}
+		var got repo.CostTotal
+		for _, cost := range costs {
+			got.Gross += cost.Gross
+			got.Net += cost.Net
+			got.Tax += cost.Tax
+			got.TaxNet += cost.TaxNet
+		}
+		if got != want {
+			t.Errorf("%s to %s: engine %+v, SQL %+v", r[0].Format("01-2006"), r[1].Format("01-2006"), got, want)
+		}
+	}
+
+	// a usage-based sub counts like a flat one, for one period at the end of the range: its
+	// base fee and the usage of that period, not all the usage in the range
+	for _, tt := range []struct{
+		start	time.Time
+		end		time.Time
+		want	int
+	}{
+		{date(2025, time.January, 1), date(2025, time.January, 1), 500},
+		{date(2025, time.February, 1), date(2025, time.March, 1), 1300},
+		{date(2025, time.January, 1), date(2025, time.June, 1), 500},
+	} {
+		filter := repo.SubsFilter{UserID: userID, ServiceName: "usage"}
+		total, err := subsRepo.GetTotalCostRepo(tt.start, tt.end, filter)
+		if err != nil {
+			t.Fatal("GetTotalCostRepo failed:", err)
+		}
+		gross, _ := totalCostOf(&subs[3], nil, tt.start, tt.end)
+		if total.Gross != tt.want || gross != tt.want {
+			t.Errorf("usage %s to %s: SQL %d, engine %d, want %d", tt.start.Format("01-2006"), tt.end.Format("01-2006"), total.Gross, gross, tt.want)
+		}
+	}
+}
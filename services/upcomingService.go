@@ -0,0 +1,149 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"online-subs-api/models"
+	"online-subs-api/repo"
+	"online-subs-api/utils"
+	"sort"
+	"time"
+)
+
+const (
+	defaultUpcomingDays = 14
+	maxUpcomingDays = 366
+)
+
+// UpcomingDay sums the charges due on one day
+type UpcomingDay struct{
+	Date			string				`json:"date"`
+	Charges			[]ProjectedCharge	`json:"charges"`
+	Total			int					`json:"total"`
+	TaxBreakdown	repo.TaxBreakdown	`json:"tax_breakdown"`
+}
+
+type UpcomingCharges struct{
+	From			string				`json:"from"`
+	To				string				`json:"to"`
+	Days			[]UpcomingDay		`json:"days"`
+	Total			int					`json:"total"`
+	TaxBreakdown	repo.TaxBreakdown	`json:"tax_breakdown"`
+}
+
+// setNextChargeDate fills the computed next charge date of the sub, it stays nil for
+// subs without charges left
+func setNextChargeDate(sub *models.Sub, now time.Time){
+	sub.NextChargeDate = nil
+	if next, ok := nextChargeDate(sub, now); ok {
+		sub.NextChargeDate = &next
+	}
+}
+
+// UpcomingChargesService lists the charges due in [from, from+days), from is a
+// YYYY-MM-DD day and defaults to today
+func (s *SubsService) UpcomingChargesService(fromStr string, days int, filter repo.SubsFilter) (*UpcomingCharges, error){
+	today := time.Now().UTC()
+	from := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+	if fromStr != "" {
+		var err error
+		if from, err = time.Parse("2006-01-02", fromStr); err != nil {
+			utils.ErrorLogger.Println("Invalid from date:", fromStr, "error:", err)
+			return nil, errors.New("from must be in YYYY-MM-DD format")
+		}
+	}
+	if days == 0 {
+		days = defaultUpcomingDays
+	}
+	if days < 1 || days > maxUpcomingDays {
+		return nil, fmt.Errorf("days must be between 1 and %d", maxUpcomingDays)
+	}
+	to := from.AddDate(0, 0, days)
+
+	if err := validateFilter(filter); err != nil {
+		return nil, err
+	}
+
+	subs, err := s.subsRepo.ListActiveSubsRepo(monthStart(from), filter)
+	if err != nil {
+		utils.ErrorLogger.Println("Failed to load subscriptions for upcoming charges:", err)
+		return nil, err
+	}
+	ids := []string{}
+	for _, sub := range subs {
+		ids = append(ids, sub.ID)
+	}
+	changes, err := s.subsRepo.ListPriceChangesBySubRepo(ids)
+	if err != nil {
+		utils.ErrorLogger.Println("Failed to load price changes for upcoming charges:", err)
+		return nil, err
+	}
+
+	projected := projectCharges(subs, changes, from, to)
+	// a user's upcoming charges are their share of the subs they own or are a member of
+	if filter.UserID != "" {
+		splits, err := s.subsRepo.ListSplitsBySubRepo(ids)
+		if err != nil {
+			utils.ErrorLogger.Println("Failed to load splits for upcoming charges:", err)
+			return nil, err
+		}
+		projected = shareCharges(projected, subs, splits, filter.UserID)
+	}
+
+	rates, err := s.subsRepo.ListTaxRatesRepo()
+	if err != nil {
+		utils.ErrorLogger.Println("Failed to load tax rates for upcoming charges:", err)
+		return nil, err
+	}
+	projected = taxCharges(projected, subs, ratesByJurisdiction(rates))
+
+	charges := []ProjectedCharge{}
+	for _, charge := range projected {
+		if !charge.Date.Before(from) && charge.Date.Before(to) {
+			charges = append(charges, charge)
+		}
+	}
+	sort.SliceStable(charges, func(i, j int) bool{
+		if !charges[i].Date.Equal(charges[j].Date) {
+			return charges[i].Date.Before(charges[j].Date)
+		}
+		return charges[i].ServiceName < charges[j].ServiceName
+	})
+
+	upcoming := &UpcomingCharges{
+		From: from.Format("2006-01-02"),
+		To: to.AddDate(0, 0, -1).Format("2006-01-02"),
+		Days: []UpcomingDay{},
+	}
+	for _, charge := range charges {
+		date := charge.Date.Format("2006-01-02")
+		if n := len(upcoming.Days); n == 0 || upcoming.Days[n-1].Date != date {
+			upcoming.Days = append(upcoming.Days, UpcomingDay{Date: date, Charges: []ProjectedCharge{}})
+		}
+		day := &upcoming.Days[len(upcoming.Days)-1]
+		day.Charges = append(day.Charges, charge)
+		day.Total += charge.Amount
+		upcoming.Total += charge.Amount
+		addTax(&day.TaxBreakdown, charge.TaxBreakdown)
+		addTax(&upcoming.TaxBreakdown, charge.TaxBreakdown)
+	}
+	return upcoming, nil
+}
+
+// Calendar turns the upcoming charges into one all-day event per charge, with the gross
+// amount charged like the calendar feeds
+func (u *UpcomingCharges) Calendar() *utils.ICalendar{
+	calendar := &utils.ICalendar{Name: "Upcoming subscription charges"}
+	for _, day := range u.Days {
+		for _, charge := range day.Charges {
+			charged := charge.TaxBreakdown.Gross
+			calendar.Events = append(calendar.Events, utils.ICalEvent{
+				UID: fmt.Sprintf("charge-%s-%s@online-subs-api", charge.SubID, charge.Date.Format("20060102")),
+				Summary: fmt.Sprintf("%s: %d", charge.ServiceName, charged),
+				Description: fmt.Sprintf("Charge of %d for %s", charged, charge.ServiceName),
+				Date: charge.Date,
+			})
+		}
+	}
+	return calendar
+}
@@ -0,0 +1,462 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"online-subs-api/models"
+	"online-subs-api/repo"
+	"online-subs-api/utils"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const maxUsageBatch = 1000
+
+// PricingRequest sets the pricing model of a subscription, "flat" removes usage-based pricing
+type PricingRequest struct{
+	Model			string					`json:"model"`
+	Unit			string					`json:"unit,omitempty"`
+	UnitSize		int						`json:"unit_size,omitempty"`
+	UnitPrice		int						`json:"unit_price,omitempty"`
+	BaseFee			int						`json:"base_fee,omitempty"`
+	Tiers			[]models.PricingTier	`json:"tiers,omitempty"`
+}
+
+// UsageInput is one usage record, Timestamp is RFC 3339 or a day (YYYY-MM-DD)
+type UsageInput struct{
+	Timestamp		string		`json:"timestamp"`
+	Quantity		int64		`json:"quantity"`
+	IdempotencyKey	string		`json:"idempotency_key,omitempty"`
+}
+
+type UsageIngestResult struct{
+	Received		int		`json:"received"`
+	Recorded		int		`json:"recorded"`
+	Duplicates		int		`json:"duplicates"`
+}
+
+// TierUsage is the part of a period's usage priced by one tier
+type TierUsage struct{
+	From			int64		`json:"from"`
+	UpTo			*int64		`json:"up_to,omitempty"`
+	Units			int64		`json:"units"`
+	UnitPrice		int			`json:"unit_price"`
+	FlatFee			int			`json:"flat_fee,omitempty"`
+	Amount			int			`json:"amount"`
+}
+
+// UsagePeriod is the charge of one billing period of a usage-based sub
+type UsagePeriod struct{
+	PeriodStart		string			`json:"period_start"`
+	PeriodEnd		string			`json:"period_end"`
+	Units			int64			`json:"units"`
+	BaseFee			int				`json:"base_fee"`
+	UsageAmount		int				`json:"usage_amount"`
+	Total			int				`json:"total"`
+	Tiers			[]TierUsage		`json:"tiers"`
+}
+
+type UsageSummary struct{
+	SubID			string				`json:"sub_id"`
+	ServiceName		string				`json:"service_name"`
+	Pricing			models.SubPricing	`json:"pricing"`
+	Periods			[]UsagePeriod		`json:"periods"`
+	Units			int64				`json:"units"`
+	Total			int					`json:"total"`
+}
+
+func validPricingModel(model string) bool{
+	switch model {
+	case models.PricingFlat, models.PricingPerUnit, models.PricingTiered, models.PricingVolume:
+		return true
+	}
+	return false
+}
+
+// validatePricing checks the request and turns it into the pricing of the sub
+func validatePricing(subID string, req PricingRequest) (*models.SubPricing, error){
+	if !validPricingModel(req.Model) {
+		return nil, errors.New("model must be flat, per_unit, tiered or volume")
+	}
+	pricing := &models.SubPricing{SubID: subID, Model: req.Model, Unit: strings.TrimSpace(req.Unit), UnitSize: req.UnitSize, BaseFee: req.BaseFee}
+	if req.Model == models.PricingFlat {
+		return pricing, nil
+	}
+
+	if len(pricing.Unit) > 50 {
+		return nil, errors.New("unit must be at most 50 characters")
+	}
+	if pricing.UnitSize == 0 {
+		pricing.UnitSize = 1
+	}
+	if pricing.UnitSize < 0 {
+		return nil, errors.New("unit_size must be a postive integer")
+	}
+	if pricing.BaseFee < 0 {
+		return nil, errors.New("base_fee must not be negative")
+	}
+
+	if req.Model == models.PricingPerUnit {
+		if req.UnitPrice <= 0 {
+			return nil, errors.New("unit_price must be a postive integer")
+		}
+		if len(req.Tiers) > 0 {
+			return nil, errors.New("tiers are only used by tiered and volume pricing")
+		}
+		pricing.UnitPrice = req.UnitPrice
+		return pricing, nil
+	}
+
+	if len(req.Tiers) == 0 {
+		return nil, errors.New("tiered and volume pricing need at least one tier")
+	}
+	var last int64
+	for i, tier := range req.Tiers {
+		if tier.UnitPrice < 0 || tier.FlatFee < 0 {
+			return nil, fmt.Errorf("tier %d: unit_price and flat_fee must not be negative", i+1)
+		}
+		if i == len(req.Tiers)-1 {
+			if tier.UpTo != nil {
+				return nil, errors.New("the last tier must not have up_to")
+			}
+			break
+		}
+		if tier.UpTo == nil || *tier.UpTo <= last {
+			return nil, fmt.Errorf("tier %d: up_to must be set and greater than the previous tier", i+1)
+		}
+		last = *tier.UpTo
+	}
+	pricing.Tiers = req.Tiers
+	return pricing, nil
+}
+
+// unitsCost prices units at a price per size units, rounded half up
+func unitsCost(units int64, price, size int) int{
+	return int((units*int64(price) + int64(size)/2) / int64(size))
+}
+
+// usageCost prices the units used in one billing period:
+//   - per_unit: every unit at the unit price
+//   - tiered: the units of each tier at that tier's price, plus the flat fee of every tier reached
+//   - volume: all units at the price of the tier the total falls into, plus its flat fee
+func usageCost(pricing *models.SubPricing, units int64) (int, []TierUsage){
+	lines := []TierUsage{}
+	if units <= 0 {
+		return 0, lines
+	}
+
+	switch pricing.Model {
+	case models.PricingPerUnit:
+		amount := unitsCost(units, pricing.UnitPrice, pricing.UnitSize)
+		lines = append(lines, TierUsage{From: 1, Units: units, UnitPrice: pricing.UnitPrice, Amount: amount})
+		return amount, lines
+	case models.PricingTiered:
+		total := 0
+		var from int64
+		for _, tier := range pricing.Tiers {
+			if units <= from {
+				break
+			}
+			inTier := units - from
+			if tier.UpTo != nil && *tier.UpTo < units {
+				inTier = *tier.UpTo - from
+			}
+			amount := unitsCost(inTier, tier.UnitPrice, pricing.UnitSize) + tier.FlatFee
+			lines = append(lines, TierUsage{From: from + 1, UpTo: tier.UpTo, Units: inTier, UnitPrice: tier.UnitPrice, FlatFee: tier.FlatFee, Amount: amount})
+			total += amount
+			if tier.UpTo == nil {
+				break
+			}
+			from = *tier.UpTo
+		}
+		return total, lines
+	case models.PricingVolume:
+		var from int64
+		for _, tier := range pricing.Tiers {
+			if tier.UpTo == nil || units <= *tier.UpTo {
+				amount := unitsCost(units, tier.UnitPrice, pricing.UnitSize) + tier.FlatFee
+				lines = append(lines, TierUsage{From: from + 1, UpTo: tier.UpTo, Units: units, UnitPrice: tier.UnitPrice, FlatFee: tier.FlatFee, Amount: amount})
+				return amount, lines
+			}
+			from = *tier.UpTo
+		}
+	}
+	return 0, lines
+}
+
+// usagePeriodStart is the first month of the billing period the month belongs to, usage
+// during a trial counts for the first billed period
+func usagePeriodStart(sub *models.Sub, month time.Time) time.Time{
+	anchor := billingAnchor(sub)
+	month = monthStart(month)
+	if month.Before(anchor) {
+		return anchor
+	}
+	step := periodMonths(sub.BillingPeriod)
+	return anchor.AddDate(0, monthsBetween(anchor, month)/step*step, 0)
+}
+
+// usageCharges prices the monthly usage of the sub per billing period
+func usageCharges(sub *models.Sub, pricing *models.SubPricing, months []repo.UsageMonth) []models.UsageCharge{
+	charges := []models.UsageCharge{}
+	index := map[time.Time]int{}
+	for _, month := range months {
+		start := usagePeriodStart(sub, month.Month)
+		i, ok := index[start]
+		if !ok {
+			i = len(charges)
+			index[start] = i
+			charges = append(charges, models.UsageCharge{SubID: sub.ID, PeriodStart: start})
+		}
+		charges[i].Units += month.Units
+	}
+	for i := range charges {
+		charges[i].Amount, _ = usageCost(pricing, charges[i].Units)
+	}
+	return charges
+}
+
+// parseUsageTime accepts an RFC 3339 timestamp or a day
+func parseUsageTime(value string) (time.Time, error){
+	value = strings.TrimSpace(value)
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), nil
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, errors.New("invalid timestamp, expected RFC 3339 or YYYY-MM-DD")
+}
+
+// repriceUsage recomputes the usage charges of a usage-based sub from its records
+func (s *SubsService) repriceUsage(sub *models.Sub) error{
+	if !usageBased(sub) {
+		return nil
+	}
+	months, err := s.subsRepo.UsageByMonthRepo(sub.ID)
+	if err != nil {
+		utils.ErrorLogger.Println("Failed to load usage of sub", sub.ID, "error:", err)
+		return err
+	}
+	if err := s.subsRepo.ReplaceUsageChargesRepo(sub.ID, usageCharges(sub, sub.Pricing, months)); err != nil {
+		utils.ErrorLogger.Println("Failed to price usage of sub", sub.ID, "error:", err)
+		return err
+	}
+	return nil
+}
+
+// GetPricingService returns the pricing of the sub, flat when it has none
+func (s *SubsService) GetPricingService(subID string) (*models.SubPricing, error){
+	if !validateUUID(subID) {
+		utils.ErrorLogger.Println("Invalid sub_id format:", subID)
+		return nil, errors.New("invalid sub_id format")
+	}
+	if _, err := s.subsRepo.GetSubRepoById(subID); err != nil {
+		return nil, errors.New("subscription not found")
+	}
+	pricing, err := s.subsRepo.GetPricingRepo(subID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &models.SubPricing{SubID: subID, Model: models.PricingFlat}, nil
+	}
+	return pricing, err
+}
+
+// SetPricingService replaces the pricing model of the sub and reprices its recorded usage
+func (s *SubsService) SetPricingService(subID string, req PricingRequest) (*models.SubPricing, error){
+	if !validateUUID(subID) {
+		utils.ErrorLogger.Println("Invalid sub_id format:", subID)
+		return nil, errors.New("invalid sub_id format")
+	}
+	sub, err := s.subsRepo.GetSubRepoById(subID)
+	if err != nil {
+		utils.ErrorLogger.Println("Subscription not found:", subID, "error:", err)
+		return nil, errors.New("subscription not found")
+	}
+	pricing, err := validatePricing(subID, req)
+	if err != nil {
+		utils.ErrorLogger.Println("Invalid pricing for sub", subID, "error:", err)
+		return nil, err
+	}
+
+	if pricing.Model == models.PricingFlat {
+		err := s.subsRepo.DeletePricingRepo(subID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		if err == nil {
+			s.emit(EventSubUpdated, sub)
+		}
+		return pricing, nil
+	}
+
+	months, err := s.subsRepo.UsageByMonthRepo(subID)
+	if err != nil {
+		utils.ErrorLogger.Println("Failed to load usage of sub", subID, "error:", err)
+		return nil, err
+	}
+	if err := s.subsRepo.SetPricingRepo(pricing, usageCharges(sub, pricing, months)); err != nil {
+		utils.ErrorLogger.Println("Failed to save pricing of sub", subID, "error:", err)
+		return nil, err
+	}
+	s.emit(EventSubUpdated, sub)
+	return pricing, nil
+}
+
+func (s *SubsService) DeletePricingService(subID string) error{
+	if !validateUUID(subID) {
+		utils.ErrorLogger.Println("Invalid sub_id format:", subID)
+		return errors.New("invalid sub_id format")
+	}
+	if err := s.subsRepo.DeletePricingRepo(subID); err != nil {
+		return errors.New("pricing not found")
+	}
+	if sub, err := s.subsRepo.GetSubRepoById(subID); err == nil {
+		s.emit(EventSubUpdated, sub)
+	}
+	return nil
+}
+
+// RecordUsageService stores a batch of usage records of a usage-based sub and reprices its
+// billing periods. Records with an idempotency key that was already sent are skipped.
+func (s *SubsService) RecordUsageService(subID string, inputs []UsageInput) (*UsageIngestResult, error){
+	if !validateUUID(subID) {
+		utils.ErrorLogger.Println("Invalid sub_id format:", subID)
+		return nil, errors.New("invalid sub_id format")
+	}
+	sub, err := s.subsRepo.GetSubRepoById(subID)
+	if err != nil {
+		utils.ErrorLogger.Println("Subscription not found:", subID, "error:", err)
+		return nil, errors.New("subscription not found")
+	}
+	if !usageBased(sub) {
+		return nil, errors.New("subscription has no usage-based pricing")
+	}
+	if len(inputs) == 0 || len(inputs) > maxUsageBatch {
+		return nil, fmt.Errorf("records must contain between 1 and %d items", maxUsageBatch)
+	}
+
+	start := monthStart(sub.StartDate)
+	end, hasEnd := subEndMonth(sub)
+	records := []models.UsageRecord{}
+	for i, input := range inputs {
+		timestamp, err := parseUsageTime(input.Timestamp)
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %v", i+1, err)
+		}
+		if timestamp.Before(start) || (hasEnd && !timestamp.Before(end.AddDate(0, 1, 0))) {
+			return nil, fmt.Errorf("record %d: timestamp is outside of the subscription", i+1)
+		}
+		if input.Quantity < 0 {
+			return nil, fmt.Errorf("record %d: quantity must not be negative", i+1)
+		}
+		key := strings.TrimSpace(input.IdempotencyKey)
+		if len(key) > 255 {
+			return nil, fmt.Errorf("record %d: idempotency_key must be at most 255 characters", i+1)
+		}
+
+		id, err := utils.NewUUID()
+		if err != nil {
+			utils.ErrorLogger.Println("Failed to generate UUID:", err)
+			return nil, err
+		}
+		records = append(records, models.UsageRecord{ID: id, SubID: subID, Timestamp: timestamp, Quantity: input.Quantity, IdempotencyKey: key})
+	}
+
+	recorded, err := s.subsRepo.CreateUsageRecordsRepo(records)
+	if err != nil {
+		utils.ErrorLogger.Println("Failed to store usage of sub", subID, "error:", err)
+		return nil, err
+	}
+
+	if err := s.repriceUsage(sub); err != nil {
+		return nil, err
+	}
+	s.emit(EventSubUpdated, sub)
+
+	utils.InfoLogger.Printf("Recorded %d of %d usage records for sub %s", recorded, len(records), subID)
+	return &UsageIngestResult{Received: len(records), Recorded: recorded, Duplicates: len(records) - recorded}, nil
+}
+
+// ListUsageService returns the usage records of the sub between two days (inclusive), the
+// current month when they are empty
+func (s *SubsService) ListUsageService(subID, fromStr, toStr string) ([]models.UsageRecord, error){
+	if !validateUUID(subID) {
+		utils.ErrorLogger.Println("Invalid sub_id format:", subID)
+		return nil, errors.New("invalid sub_id format")
+	}
+	from := monthStart(time.Now())
+	to := from.AddDate(0, 1, -1)
+	var err error
+	if fromStr != "" {
+		if from, err = parseDay(fromStr); err != nil {
+			return nil, err
+		}
+	}
+	if toStr != "" {
+		if to, err = parseDay(toStr); err != nil {
+			return nil, err
+		}
+	}
+	if to.Before(from) {
+		return nil, errors.New("to must not be before from")
+	}
+	return s.subsRepo.ListUsageRecordsRepo(subID, from, to.AddDate(0, 0, 1))
+}
+
+// UsageSummaryService breaks the charges of a usage-based sub between two MM-YYYY months
+// (inclusive) down into the base fee and the usage priced per tier
+func (s *SubsService) UsageSummaryService(subID, startStr, endStr string) (*UsageSummary, error){
+	if !validateUUID(subID) {
+		utils.ErrorLogger.Println("Invalid sub_id format:", subID)
+		return nil, errors.New("invalid sub_id format")
+	}
+	end := monthStart(time.Now())
+	start := end.AddDate(0, -11, 0)
+	var err error
+	if startStr != "" {
+		if start, err = validDate(startStr); err != nil {
+			return nil, err
+		}
+	}
+	if endStr != "" {
+		if end, err = validDate(endStr); err != nil {
+			return nil, err
+		}
+	}
+	if end.Before(start) {
+		return nil, errors.New("end must not be before start")
+	}
+
+	sub, err := s.subsRepo.GetSubRepoById(subID)
+	if err != nil {
+		return nil, errors.New("subscription not found")
+	}
+	if !usageBased(sub) {
+		return nil, errors.New("subscription has no usage-based pricing")
+	}
+
+	units := map[time.Time]int64{}
+	for _, charge := range sub.UsageCharges {
+		units[monthStart(charge.PeriodStart)] = charge.Units
+	}
+
+	summary := &UsageSummary{SubID: sub.ID, ServiceName: sub.ServiceName, Pricing: *sub.Pricing, Periods: []UsagePeriod{}}
+	step := periodMonths(sub.BillingPeriod)
+	for _, month := range chargeMonths(sub, start, end) {
+		amount, tiers := usageCost(sub.Pricing, units[month])
+		summary.Periods = append(summary.Periods, UsagePeriod{
+			PeriodStart: month.Format("2006-01"),
+			PeriodEnd: month.AddDate(0, step, 0).AddDate(0, 0, -1).Format("2006-01-02"),
+			Units: units[month],
+			BaseFee: sub.Pricing.BaseFee,
+			UsageAmount: amount,
+			Total: sub.Pricing.BaseFee + amount,
+			Tiers: tiers,
+		})
+		summary.Units += units[month]
+		summary.Total += sub.Pricing.BaseFee + amount
+	}
+	return summary, nil
+}
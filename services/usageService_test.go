@@ -0,0 +1,39 @@
+package services
+
+import (
+	"online-subs-api/models"
+	"testing"
+)
+
+func TestUsageCost(t *testing.T){
+	upTo := func(n int64) *int64{ return &n }
+	tiers := models.TierList{
+		{UpTo: upTo(1000), UnitPrice: 0},
+		{UpTo: upTo(5000), UnitPrice: 2, FlatFee: 100},
+		{UnitPrice: 1},
+	}
+	tests := []struct{
+		name		string
+		pricing		models.SubPricing
+		units		int64
+		want		int
+		wantLines	int
+	}{
+		{"no usage", models.SubPricing{Model: models.PricingPerUnit, UnitPrice: 2, UnitSize: 1}, 0, 0, 0},
+		{"per unit", models.SubPricing{Model: models.PricingPerUnit, UnitPrice: 2, UnitSize: 1}, 1500, 3000, 1},
+		{"per unit size rounds", models.SubPricing{Model: models.PricingPerUnit, UnitPrice: 5, UnitSize: 1000}, 2500, 13, 1},
+		{"tiered in the free tier", models.SubPricing{Model: models.PricingTiered, UnitSize: 1, Tiers: tiers}, 500, 0, 1},
+		{"tiered across tiers", models.SubPricing{Model: models.PricingTiered, UnitSize: 1, Tiers: tiers}, 6000, 0 + 4000*2 + 100 + 1000, 3},
+		{"tiered at a tier boundary", models.SubPricing{Model: models.PricingTiered, UnitSize: 1, Tiers: tiers}, 5000, 8100, 2},
+		{"volume", models.SubPricing{Model: models.PricingVolume, UnitSize: 1, Tiers: tiers}, 3000, 3000*2 + 100, 1},
+		{"volume in the last tier", models.SubPricing{Model: models.PricingVolume, UnitSize: 1, Tiers: tiers}, 6000, 6000, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T){
+			got, lines := usageCost(&tt.pricing, tt.units)
+			if got != tt.want || len(lines) != tt.wantLines {
+				t.Errorf("usageCost() = %d with %d lines, want %d with %d lines", got, len(lines), tt.want, tt.wantLines)
+			}
+		})
+	}
+}
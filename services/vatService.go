@@ -0,0 +1,177 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"online-subs-api/repo"
+	"online-subs-api/utils"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// VATLine sums the taxed charges of one jurisdiction and rate
+type VATLine struct{
+	Jurisdiction	string		`json:"jurisdiction"`
+	Rate			float64		`json:"rate"`
+	Charges			int			`json:"charges"`
+	Net				int			`json:"net"`
+	Tax				int			`json:"tax"`
+	Gross			int			`json:"gross"`
+}
+
+type VATQuarter struct{
+	Quarter			string				`json:"quarter"`
+	Lines			[]VATLine			`json:"lines"`
+	Total			repo.TaxBreakdown	`json:"total"`
+}
+
+// VATCharge is one expected charge of a taxed subscription, a line of the VAT export
+type VATCharge struct{
+	Date			time.Time	`json:"date"`
+	SubID			string		`json:"sub_id"`
+	UserID			string		`json:"user_id"`
+	ServiceName		string		`json:"service_name"`
+	Jurisdiction	string		`json:"jurisdiction"`
+	Rate			float64		`json:"rate"`
+	Inclusive		bool		`json:"inclusive"`
+	Net				int			`json:"net"`
+	Tax				int			`json:"tax"`
+	Gross			int			`json:"gross"`
+}
+
+// VATSummary is the tax of a year's expected charges per jurisdiction and rate, for the year
+// and per quarter. Untaxed sums the charges of subscriptions without tax settings.
+type VATSummary struct{
+	Year			int					`json:"year"`
+	Lines			[]VATLine			`json:"lines"`
+	Quarters		[]VATQuarter		`json:"quarters"`
+	Total			repo.TaxBreakdown	`json:"total"`
+	Untaxed			int					`json:"untaxed"`
+	Charges			[]VATCharge			`json:"-"`
+}
+
+// VATExportColumns are the columns of the VAT export, one row per VATCharge
+var VATExportColumns = []string{"date", "sub_id", "user_id", "service_name", "jurisdiction", "rate", "prices", "net", "tax", "gross"}
+
+// Row returns the charge's cells in the order of VATExportColumns
+func (c VATCharge) Row() []interface{}{
+	prices := "exclusive"
+	if c.Inclusive {
+		prices = "inclusive"
+	}
+	return []interface{}{c.Date.Format("2006-01-02"), c.SubID, c.UserID, c.ServiceName, c.Jurisdiction, c.Rate, prices, c.Net, c.Tax, c.Gross}
+}
+
+// addVAT adds a charge to the line of its jurisdiction and rate
+func addVAT(lines map[[2]string]*VATLine, charge VATCharge){
+	key := [2]string{charge.Jurisdiction, strconv.FormatFloat(charge.Rate, 'f', -1, 64)}
+	line, ok := lines[key]
+	if !ok {
+		line = &VATLine{Jurisdiction: charge.Jurisdiction, Rate: charge.Rate}
+		lines[key] = line
+	}
+	line.Charges++
+	line.Net += charge.Net
+	line.Tax += charge.Tax
+	line.Gross += charge.Gross
+}
+
+func sortedVATLines(lines map[[2]string]*VATLine) ([]VATLine, repo.TaxBreakdown){
+	sorted := []VATLine{}
+	var total repo.TaxBreakdown
+	for _, line := range lines {
+		sorted = append(sorted, *line)
+		total.Net += line.Net
+		total.Tax += line.Tax
+		total.Gross += line.Gross
+	}
+	sort.Slice(sorted, func(i, j int) bool{
+		if sorted[i].Jurisdiction != sorted[j].Jurisdiction {
+			return sorted[i].Jurisdiction < sorted[j].Jurisdiction
+		}
+		return sorted[i].Rate < sorted[j].Rate
+	})
+	return sorted, total
+}
+
+// VATSummaryService splits the expected charges of the year (YYYY), net of discounts, into
+// net, tax and gross at the rate in effect in the month of each charge
+func (s *ReportService) VATSummaryService(yearStr string, filter repo.SubsFilter) (*VATSummary, error){
+	year, err := strconv.Atoi(yearStr)
+	if err != nil || year < 1 || year > 9999 {
+		utils.ErrorLogger.Println("Invalid year:", yearStr)
+		return nil, errors.New("invalid year, expected YYYY")
+	}
+	if err := validateFilter(filter); err != nil {
+		return nil, err
+	}
+
+	subs, err := s.subsRepo.ListAllSubsRepo(filter)
+	if err != nil {
+		utils.ErrorLogger.Println("Failed to load subscriptions for VAT summary:", err)
+		return nil, err
+	}
+	ids := []string{}
+	for _, sub := range subs {
+		ids = append(ids, sub.ID)
+	}
+	changes, err := s.subsRepo.ListPriceChangesBySubRepo(ids)
+	if err != nil {
+		utils.ErrorLogger.Println("Failed to load price changes for VAT summary:", err)
+		return nil, err
+	}
+	rates, err := s.subsRepo.ListTaxRatesRepo()
+	if err != nil {
+		utils.ErrorLogger.Println("Failed to load tax rates for VAT summary:", err)
+		return nil, err
+	}
+	byJurisdiction := ratesByJurisdiction(rates)
+
+	index := map[string]int{}
+	for i := range subs {
+		index[subs[i].ID] = i
+	}
+
+	summary := &VATSummary{Year: year, Quarters: []VATQuarter{}, Charges: []VATCharge{}}
+	yearLines := map[[2]string]*VATLine{}
+	quarterLines := make([]map[[2]string]*VATLine, 4)
+	for q := range quarterLines {
+		quarterLines[q] = map[[2]string]*VATLine{}
+	}
+
+	start := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	for _, charge := range projectCharges(subs, changes, start, start.AddDate(0, 11, 0)) {
+		sub := &subs[index[charge.SubID]]
+		if sub.Tax == nil {
+			summary.Untaxed += charge.Amount
+			continue
+		}
+		rate := taxRateAt(sub, byJurisdiction, charge.Date)
+		tax := taxSplit(charge.Amount, rate, sub.Tax.Inclusive)
+		line := VATCharge{
+			Date: charge.Date,
+			SubID: sub.ID,
+			UserID: sub.UserID,
+			ServiceName: sub.ServiceName,
+			Jurisdiction: sub.Tax.Jurisdiction,
+			Rate: rate,
+			Inclusive: sub.Tax.Inclusive,
+			Net: tax.Net,
+			Tax: tax.Tax,
+			Gross: tax.Gross,
+		}
+		summary.Charges = append(summary.Charges, line)
+		addVAT(yearLines, line)
+		addVAT(quarterLines[(int(charge.Date.Month())-1)/3], line)
+	}
+
+	summary.Lines, summary.Total = sortedVATLines(yearLines)
+	for q, lines := range quarterLines {
+		quarter := VATQuarter{Quarter: fmt.Sprintf("%d-Q%d", year, q+1)}
+		quarter.Lines, quarter.Total = sortedVATLines(lines)
+		summary.Quarters = append(summary.Quarters, quarter)
+	}
+	sort.SliceStable(summary.Charges, func(i, j int) bool{ return summary.Charges[i].Date.Before(summary.Charges[j].Date) })
+	return summary, nil
+}
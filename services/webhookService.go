@@ -0,0 +1,358 @@
+package services
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"online-subs-api/models"
+	"online-subs-api/repo"
+	"online-subs-api/utils"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	webhookMaxAttempts = 8
+	webhookBatchSize = 50
+	webhookPollInterval = 5 * time.Second
+	webhookScanInterval = time.Hour
+)
+
+// WebhookEvent is the JSON body POSTed to the endpoints
+type WebhookEvent struct{
+	ID				string			`json:"id"`
+	Type			string			`json:"type"`
+	TenantID		string			`json:"tenant_id"`
+	OccurredAt		time.Time		`json:"occurred_at"`
+	Data			interface{}		`json:"data"`
+}
+
+// WebhookEndpointSecret is returned on create, the only time the signing secret is visible
+type WebhookEndpointSecret struct{
+	Endpoint		*models.WebhookEndpoint		`json:"endpoint"`
+	Secret			string						`json:"secret"`
+}
+
+type WebhookService struct{
+	webhookRepo *repo.WebhookRepo
+	subsRepo *repo.SubsRepo
+	client *http.Client
+	nudge chan struct{}
+}
+
+func NewWebhookService(webhookRepo *repo.WebhookRepo, subsRepo *repo.SubsRepo) *WebhookService{
+	return &WebhookService{
+		webhookRepo: webhookRepo,
+		subsRepo: subsRepo,
+		client: &http.Client{Timeout: 10 * time.Second},
+		nudge: make(chan struct{}, 1),
+	}
+}
+
+// SignWebhook computes the X-Webhook-Signature value: the hex HMAC-SHA256 of
+// "<timestamp>.<body>" keyed with the endpoint secret
+func SignWebhook(secret string, timestamp int64, body []byte) string{
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func validateEndpoint(endpoint *models.WebhookEndpoint) error{
+	if endpoint.TenantID != "" && !validateTenantID(endpoint.TenantID) {
+		return errors.New("invalid tenant_id format")
+	}
+	u, err := url.Parse(endpoint.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("url must be an absolute http(s) URL")
+	}
+	for _, eventType := range endpoint.EventTypes {
+		if !slices.Contains(eventTypes, eventType) {
+			return fmt.Errorf("unknown event type %q, use one of %s", eventType, strings.Join(eventTypes, ", "))
+		}
+	}
+	return nil
+}
+
+func (s *WebhookService) CreateEndpointService(endpoint *models.WebhookEndpoint) (*WebhookEndpointSecret, error){
+	if err := validateEndpoint(endpoint); err != nil {
+		utils.ErrorLogger.Println("Invalid webhook endpoint:", err)
+		return nil, err
+	}
+	id, err := utils.NewUUID()
+	if err != nil {
+		utils.ErrorLogger.Println("Failed to generate UUID:", err)
+		return nil, err
+	}
+	token, err := utils.NewToken()
+	if err != nil {
+		utils.ErrorLogger.Println("Failed to generate webhook secret:", err)
+		return nil, err
+	}
+	endpoint.ID = id
+	endpoint.Secret = "whsec_" + token
+	endpoint.Active = true
+	if endpoint.EventTypes == nil {
+		endpoint.EventTypes = models.StringList{}
+	}
+	if err := s.webhookRepo.CreateEndpointRepo(endpoint); err != nil {
+		return nil, err
+	}
+	return &WebhookEndpointSecret{Endpoint: endpoint, Secret: endpoint.Secret}, nil
+}
+
+func (s *WebhookService) ListEndpointsService(tenantID string) ([]models.WebhookEndpoint, error){
+	if tenantID != "" && !validateTenantID(tenantID) {
+		return nil, errors.New("invalid tenant_id format")
+	}
+	return s.webhookRepo.ListEndpointsRepo(tenantID)
+}
+
+// UpdateEndpointService changes url, event types and active flag, the tenant is fixed
+func (s *WebhookService) UpdateEndpointService(id string, update *models.WebhookEndpoint) (*models.WebhookEndpoint, error){
+	if !validateUUID(id) {
+		return nil, errors.New("invalid id format")
+	}
+	endpoint, err := s.webhookRepo.GetEndpointRepo(id)
+	if err != nil {
+		return nil, errors.New("webhook endpoint not found")
+	}
+	endpoint.URL = update.URL
+	endpoint.EventTypes = update.EventTypes
+	endpoint.Active = update.Active
+	if endpoint.EventTypes == nil {
+		endpoint.EventTypes = models.StringList{}
+	}
+	if err := validateEndpoint(endpoint); err != nil {
+		return nil, err
+	}
+	if err := s.webhookRepo.UpdateEndpointRepo(endpoint); err != nil {
+		return nil, err
+	}
+	return endpoint, nil
+}
+
+func (s *WebhookService) DeleteEndpointService(id string) error{
+	if !validateUUID(id) {
+		return errors.New("invalid id format")
+	}
+	return s.webhookRepo.DeleteEndpointRepo(id)
+}
+
+func (s *WebhookService) ListDeliveriesService(endpointID, status string) ([]models.WebhookDelivery, error){
+	if endpointID != "" && !validateUUID(endpointID) {
+		return nil, errors.New("invalid endpoint_id format")
+	}
+	switch status {
+	case "", models.DeliveryPending, models.DeliveryDelivered, models.DeliveryDead:
+	default:
+		return nil, errors.New("status must be pending, delivered or dead")
+	}
+	return s.webhookRepo.ListDeliveriesRepo(endpointID, status, 200)
+}
+
+// RedeliverService queues a delivery again with a fresh set of attempts, typically a dead one
+func (s *WebhookService) RedeliverService(id string) (*models.WebhookDelivery, error){
+	if !validateUUID(id) {
+		return nil, errors.New("invalid id format")
+	}
+	delivery, err := s.webhookRepo.GetDeliveryRepo(id)
+	if err != nil {
+		return nil, errors.New("delivery not found")
+	}
+	delivery.Status = models.DeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = time.Now().UTC()
+	if err := s.webhookRepo.UpdateDeliveryRepo(delivery); err != nil {
+		return nil, err
+	}
+	s.wake()
+	return delivery, nil
+}
+
+func (s *WebhookService) wake(){
+	select {
+	case s.nudge <- struct{}{}:
+	default:
+	}
+}
+
+// Enqueue stores one delivery per active endpoint of the tenant subscribed to the event
+// type. Deliveries are unique per endpoint and event id, so enqueueing twice is harmless.
+func (s *WebhookService) Enqueue(event WebhookEvent) error{
+	endpoints, err := s.webhookRepo.ListActiveEndpointsRepo(event.TenantID)
+	if err != nil {
+		return err
+	}
+
+	var body []byte
+	for _, endpoint := range endpoints {
+		if len(endpoint.EventTypes) > 0 && !slices.Contains(endpoint.EventTypes, event.Type) {
+			continue
+		}
+		if body == nil {
+			if body, err = json.Marshal(event); err != nil {
+				return err
+			}
+		}
+		id, err := utils.NewUUID()
+		if err != nil {
+			return err
+		}
+		delivery := &models.WebhookDelivery{
+			ID: id,
+			EndpointID: endpoint.ID,
+			EventID: event.ID,
+			EventType: event.Type,
+			Payload: string(body),
+			Status: models.DeliveryPending,
+			NextAttemptAt: time.Now().UTC(),
+		}
+		if _, err := s.webhookRepo.CreateDeliveryRepo(delivery); err != nil {
+			return err
+		}
+	}
+	s.wake()
+	return nil
+}
+
+// send makes one delivery attempt, failures back off exponentially (30s, 1m, 2m...) and
+// the delivery is dead-lettered after webhookMaxAttempts
+func (s *WebhookService) send(delivery *models.WebhookDelivery){
+	endpoint, err := s.webhookRepo.GetEndpointRepo(delivery.EndpointID)
+	status := 0
+	if err == nil {
+		status, err = s.post(endpoint, delivery)
+	}
+
+	now := time.Now().UTC()
+	delivery.Attempts++
+	delivery.LastStatusCode = status
+	switch {
+	case err == nil:
+		delivery.Status = models.DeliveryDelivered
+		delivery.DeliveredAt = &now
+		delivery.LastError = ""
+	case delivery.Attempts >= webhookMaxAttempts:
+		delivery.Status = models.DeliveryDead
+		delivery.LastError = err.Error()
+		utils.ErrorLogger.Printf("Webhook delivery %s is dead after %d attempts: %v", delivery.ID, delivery.Attempts, err)
+	default:
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = now.Add(time.Duration(1<<(delivery.Attempts-1)) * 30 * time.Second)
+		utils.WarningLogger.Printf("Webhook delivery %s failed (attempt %d): %v", delivery.ID, delivery.Attempts, err)
+	}
+	if err := s.webhookRepo.UpdateDeliveryRepo(delivery); err != nil {
+		utils.ErrorLogger.Println("Failed to update webhook delivery:", delivery.ID, "error:", err)
+	}
+}
+
+func (s *WebhookService) post(endpoint *models.WebhookEndpoint, delivery *models.WebhookDelivery) (int, error){
+	body := []byte(delivery.Payload)
+	timestamp := time.Now().Unix()
+
+	req, err := http.NewRequest(http.MethodPost, endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "online-subs-api-webhooks")
+	req.Header.Set("X-Webhook-ID", delivery.ID)
+	req.Header.Set("X-Webhook-Event", delivery.EventType)
+	req.Header.Set("X-Webhook-Event-ID", delivery.EventID)
+	req.Header.Set("X-Webhook-Timestamp", strconv.FormatInt(timestamp, 10))
+	req.Header.Set("X-Webhook-Signature", SignWebhook(endpoint.Secret, timestamp, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("endpoint answered %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+func (s *WebhookService) deliverDue(){
+	for {
+		deliveries, err := s.webhookRepo.ListDueDeliveriesRepo(time.Now().UTC(), webhookBatchSize)
+		if err != nil {
+			utils.ErrorLogger.Println("Failed to load webhook deliveries:", err)
+			return
+		}
+		for i := range deliveries {
+			s.send(&deliveries[i])
+		}
+		if len(deliveries) < webhookBatchSize {
+			return
+		}
+	}
+}
+
+// scanLifecycle queues renewed and ended events for yesterday and today. Their ids are
+// derived from the sub and the date, so rescans don't deliver them twice.
+func (s *WebhookService) scanLifecycle(now time.Time){
+	today := dayStart(now)
+	yesterday := today.AddDate(0, 0, -1)
+	subs, err := s.subsRepo.ListActiveSubsRepo(monthStart(yesterday).AddDate(0, -1, 0), repo.SubsFilter{})
+	if err != nil {
+		utils.ErrorLogger.Println("Failed to load subscriptions for lifecycle webhooks:", err)
+		return
+	}
+
+	for i := range subs {
+		sub := &subs[i]
+		for _, date := range []time.Time{yesterday, today} {
+			eventType := ""
+			if next, ok := nextChargeDate(sub, date); ok && next.Equal(date) {
+				eventType = EventSubRenewed
+			}
+			if end, ok := subEndMonth(sub); ok && end.AddDate(0, 1, 0).Equal(date) {
+				eventType = EventSubEnded
+			}
+			if eventType == "" {
+				continue
+			}
+			event := WebhookEvent{
+				ID: fmt.Sprintf("%s.%s.%s", eventType, sub.ID, date.Format("20060102")),
+				Type: eventType,
+				TenantID: sub.TenantID,
+				OccurredAt: date,
+				Data: sub,
+			}
+			if err := s.Enqueue(event); err != nil {
+				utils.ErrorLogger.Println("Failed to queue lifecycle webhook:", event.ID, "error:", err)
+			}
+		}
+	}
+}
+
+// StartWorker delivers queued webhooks in the background, right after they are queued
+// or every few seconds for retries, and scans for renewals and ends every hour
+func (s *WebhookService) StartWorker(){
+	go func(){
+		poll := time.NewTicker(webhookPollInterval)
+		scan := time.NewTicker(webhookScanInterval)
+		s.scanLifecycle(time.Now())
+		for {
+			s.deliverDue()
+			select {
+			case <-s.nudge:
+			case <-poll.C:
+			case <-scan.C:
+				s.scanLifecycle(time.Now())
+			}
+		}
+	}()
+}
@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Minimal RFC 5545 writer: all-day events, optional RRULE, text escaping and
+// line folding at 75 octets.
+
+type ICalEvent struct{
+	UID				string
+	Summary			string
+	Description		string
+	Date			time.Time
+	RRule			string
+	LastModified	time.Time
+}
+
+type ICalendar struct{
+	Name		string
+	Events		[]ICalEvent
+}
+
+func icalEscape(text string) string{
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(text)
+}
+
+// icalFold splits a content line into 75 octet chunks without breaking UTF-8 sequences
+func icalFold(line string) string{
+	var b strings.Builder
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		// continuation lines start with a space
+		limit = 74
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+	return b.String()
+}
+
+func (c *ICalendar) WriteTo(w io.Writer) (int64, error){
+	out := bufio.NewWriter(w)
+	var written int64
+	line := func(format string, args ...interface{}){
+		n, _ := out.WriteString(icalFold(fmt.Sprintf(format, args...)))
+		written += int64(n)
+	}
+
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//online-subs-api//subscriptions//EN")
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:PUBLISH")
+	if c.Name != "" {
+		line("X-WR-CALNAME:%s", icalEscape(c.Name))
+	}
+	for _, event := range c.Events {
+		line("BEGIN:VEVENT")
+		line("UID:%s", event.UID)
+		line("DTSTAMP:%s", stamp)
+		if !event.LastModified.IsZero() {
+			line("LAST-MODIFIED:%s", event.LastModified.UTC().Format("20060102T150405Z"))
+		}
+		line("DTSTART;VALUE=DATE:%s", event.Date.Format("20060102"))
+		line("DTEND;VALUE=DATE:%s", event.Date.AddDate(0, 0, 1).Format("20060102"))
+		if event.RRule != "" {
+			line("RRULE:%s", event.RRule)
+		}
+		line("SUMMARY:%s", icalEscape(event.Summary))
+		if event.Description != "" {
+			line("DESCRIPTION:%s", icalEscape(event.Description))
+		}
+		line("TRANSP:TRANSPARENT")
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+	return written, out.Flush()
+}
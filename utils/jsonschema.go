@@ -0,0 +1,344 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Supports the JSON Schema keywords needed for metadata validation:
+// type, enum, const, properties, required, additionalProperties, items,
+// minItems, maxItems, minLength, maxLength, pattern, format (email, uri, date, date-time),
+// minimum, maximum, exclusiveMinimum and exclusiveMaximum.
+
+var schemaTypes = map[string]bool{
+	"string": true, "number": true, "integer": true, "boolean": true, "object": true, "array": true, "null": true,
+}
+
+// CheckJSONSchema reports keywords with values of the wrong kind, so bad schemas fail on save
+func CheckJSONSchema(schema map[string]interface{}) error{
+	return checkSchema(schema, "#")
+}
+
+func checkSchema(schema map[string]interface{}, path string) error{
+	if t, ok := schema["type"]; ok {
+		types, ok := schemaTypeList(t)
+		if !ok {
+			return fmt.Errorf("%s: type must be a string or an array of strings", path)
+		}
+		for _, name := range types {
+			if !schemaTypes[name] {
+				return fmt.Errorf("%s: unknown type %q", path, name)
+			}
+		}
+	}
+
+	if p, ok := schema["pattern"]; ok {
+		pattern, ok := p.(string)
+		if !ok {
+			return fmt.Errorf("%s: pattern must be a string", path)
+		}
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("%s: invalid pattern: %v", path, err)
+		}
+	}
+
+	for _, key := range []string{"minLength", "maxLength", "minItems", "maxItems", "minimum", "maximum", "exclusiveMinimum", "exclusiveMaximum"} {
+		if v, ok := schema[key]; ok {
+			if _, ok := v.(float64); !ok {
+				return fmt.Errorf("%s: %s must be a number", path, key)
+			}
+		}
+	}
+
+	if e, ok := schema["enum"]; ok {
+		if _, ok := e.([]interface{}); !ok {
+			return fmt.Errorf("%s: enum must be an array", path)
+		}
+	}
+
+	if r, ok := schema["required"]; ok {
+		required, ok := r.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s: required must be an array of strings", path)
+		}
+		for _, name := range required {
+			if _, ok := name.(string); !ok {
+				return fmt.Errorf("%s: required must be an array of strings", path)
+			}
+		}
+	}
+
+	if p, ok := schema["properties"]; ok {
+		properties, ok := p.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: properties must be an object", path)
+		}
+		for name, sub := range properties {
+			subSchema, ok := sub.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("%s/properties/%s: must be an object", path, name)
+			}
+			if err := checkSchema(subSchema, path+"/properties/"+name); err != nil {
+				return err
+			}
+		}
+	}
+
+	if a, ok := schema["additionalProperties"]; ok {
+		switch v := a.(type) {
+		case bool:
+		case map[string]interface{}:
+			if err := checkSchema(v, path+"/additionalProperties"); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("%s: additionalProperties must be a boolean or an object", path)
+		}
+	}
+
+	if i, ok := schema["items"]; ok {
+		items, ok := i.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: items must be an object", path)
+		}
+		if err := checkSchema(items, path+"/items"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func schemaTypeList(t interface{}) ([]string, bool){
+	switch v := t.(type) {
+	case string:
+		return []string{v}, true
+	case []interface{}:
+		types := []string{}
+		for _, item := range v {
+			name, ok := item.(string)
+			if !ok {
+				return nil, false
+			}
+			types = append(types, name)
+		}
+		return types, true
+	}
+	return nil, false
+}
+
+// ValidateJSONSchema validates a decoded JSON value against the schema and
+// returns one message per violation, an empty list means the value is valid
+func ValidateJSONSchema(schema map[string]interface{}, value interface{}) []string{
+	errs := []string{}
+	validateValue(schema, value, "", &errs)
+	return errs
+}
+
+func jsonTypeOf(value interface{}) string{
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case int, int64:
+		return "integer"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return "unknown"
+}
+
+func schemaNumber(schema map[string]interface{}, key string) (float64, bool){
+	v, ok := schema[key].(float64)
+	return v, ok
+}
+
+func toFloat(value interface{}) (float64, bool){
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+// jsonEqual compares two decoded JSON values, numbers by value whatever their Go type
+func jsonEqual(a, b interface{}) bool{
+	x, aNumber := toFloat(a)
+	y, bNumber := toFloat(b)
+	if aNumber || bNumber {
+		return aNumber && bNumber && x == y
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func fieldName(path string) string{
+	if path == "" {
+		return "metadata"
+	}
+	return "metadata" + path
+}
+
+func validateValue(schema map[string]interface{}, value interface{}, path string, errs *[]string){
+	add := func(format string, args ...interface{}){
+		*errs = append(*errs, fieldName(path)+": "+fmt.Sprintf(format, args...))
+	}
+
+	if t, ok := schema["type"]; ok {
+		types, _ := schemaTypeList(t)
+		actual := jsonTypeOf(value)
+		matched := false
+		for _, name := range types {
+			if name == actual || (name == "number" && actual == "integer") {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			add("must be of type %s", strings.Join(types, " or "))
+			return
+		}
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, option := range enum {
+			if jsonEqual(option, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			add("must be one of %v", enum)
+		}
+	}
+
+	if c, ok := schema["const"]; ok && !jsonEqual(c, value) {
+		add("must be %v", c)
+	}
+
+	switch v := value.(type) {
+	case string:
+		validateString(schema, v, add)
+	case map[string]interface{}:
+		validateObject(schema, v, path, errs, add)
+	case []interface{}:
+		if n, ok := schemaNumber(schema, "minItems"); ok && float64(len(v)) < n {
+			add("must have at least %v items", n)
+		}
+		if n, ok := schemaNumber(schema, "maxItems"); ok && float64(len(v)) > n {
+			add("must have at most %v items", n)
+		}
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				validateValue(items, item, fmt.Sprintf("%s[%d]", path, i), errs)
+			}
+		}
+	}
+
+	if number, ok := toFloat(value); ok {
+		if n, ok := schemaNumber(schema, "minimum"); ok && number < n {
+			add("must be >= %v", n)
+		}
+		if n, ok := schemaNumber(schema, "maximum"); ok && number > n {
+			add("must be <= %v", n)
+		}
+		if n, ok := schemaNumber(schema, "exclusiveMinimum"); ok && number <= n {
+			add("must be > %v", n)
+		}
+		if n, ok := schemaNumber(schema, "exclusiveMaximum"); ok && number >= n {
+			add("must be < %v", n)
+		}
+	}
+}
+
+func validateString(schema map[string]interface{}, value string, add func(string, ...interface{})){
+	length := float64(utf8.RuneCountInString(value))
+	if n, ok := schemaNumber(schema, "minLength"); ok && length < n {
+		add("must be at least %v characters", n)
+	}
+	if n, ok := schemaNumber(schema, "maxLength"); ok && length > n {
+		add("must be at most %v characters", n)
+	}
+	if pattern, ok := schema["pattern"].(string); ok {
+		if re, err := regexp.Compile(pattern); err == nil && !re.MatchString(value) {
+			add("must match pattern %s", pattern)
+		}
+	}
+
+	switch schema["format"] {
+	case "email":
+		if _, err := mail.ParseAddress(value); err != nil {
+			add("must be an email address")
+		}
+	case "uri":
+		if u, err := url.Parse(value); err != nil || u.Scheme == "" || u.Host == "" {
+			add("must be an absolute URI")
+		}
+	case "date":
+		if _, err := time.Parse("2006-01-02", value); err != nil {
+			add("must be a date (YYYY-MM-DD)")
+		}
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, value); err != nil {
+			add("must be an RFC 3339 date-time")
+		}
+	}
+}
+
+func validateObject(schema map[string]interface{}, value map[string]interface{}, path string, errs *[]string, add func(string, ...interface{})){
+	if required, ok := schema["required"].([]interface{}); ok {
+		for _, r := range required {
+			name, _ := r.(string)
+			if _, present := value[name]; !present {
+				add("missing required property %q", name)
+			}
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+
+	// sorted keys keep the error order stable between requests
+	keys := make([]string, 0, len(value))
+	for key := range value {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		childPath := path + "." + key
+		if propSchema, ok := properties[key].(map[string]interface{}); ok {
+			validateValue(propSchema, value[key], childPath, errs)
+			continue
+		}
+
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				add("property %q is not allowed", key)
+			}
+		case map[string]interface{}:
+			validateValue(additional, value[key], childPath, errs)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package utils
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// TableWriter streams rows of a table in one export format. Cell values are strings,
+// ints, float64s or nil. WriteHeader is called once before the rows and Close
+// completes the file; nothing is buffered beyond a single row.
+type TableWriter interface{
+	WriteHeader(columns []string) error
+	WriteRow(values []interface{}) error
+	Close() error
+}
+
+// Export formats with their content types
+const (
+	FormatCSV		= "csv"
+	FormatNDJSON	= "ndjson"
+	FormatXLSX		= "xlsx"
+)
+
+var TableContentTypes = map[string]string{
+	FormatCSV: "text/csv; charset=utf-8",
+	FormatNDJSON: "application/x-ndjson",
+	FormatXLSX: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+func NewTableWriter(format string, w io.Writer) (TableWriter, error){
+	switch format {
+	case FormatCSV:
+		return &csvTableWriter{w: csv.NewWriter(w)}, nil
+	case FormatNDJSON:
+		return &ndjsonTableWriter{w: bufio.NewWriter(w)}, nil
+	case FormatXLSX:
+		return &xlsxTableWriter{zip: zip.NewWriter(w)}, nil
+	}
+	return nil, fmt.Errorf("unsupported format %q", format)
+}
+
+func cellString(value interface{}) string{
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
+
+type csvTableWriter struct{
+	w		*csv.Writer
+	record	[]string
+}
+
+func (t *csvTableWriter) WriteHeader(columns []string) error{
+	return t.w.Write(columns)
+}
+
+// csvTextCell prefixes text that spreadsheets would evaluate as a formula with a quote,
+// numbers are written as they are
+func csvTextCell(value string) string{
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+func (t *csvTableWriter) WriteRow(values []interface{}) error{
+	t.record = t.record[:0]
+	for _, value := range values {
+		if text, ok := value.(string); ok {
+			t.record = append(t.record, csvTextCell(text))
+			continue
+		}
+		t.record = append(t.record, cellString(value))
+	}
+	return t.w.Write(t.record)
+}
+
+func (t *csvTableWriter) Close() error{
+	t.w.Flush()
+	return t.w.Error()
+}
+
+// ndjsonTableWriter writes one JSON object per row, keyed by the header columns
+type ndjsonTableWriter struct{
+	w			*bufio.Writer
+	columns		[]string
+}
+
+func (t *ndjsonTableWriter) WriteHeader(columns []string) error{
+	t.columns = columns
+	return nil
+}
+
+func (t *ndjsonTableWriter) WriteRow(values []interface{}) error{
+	t.w.WriteByte('{')
+	for i, value := range values {
+		if i > 0 {
+			t.w.WriteByte(',')
+		}
+		key, _ := json.Marshal(t.columns[i])
+		cell, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		t.w.Write(key)
+		t.w.WriteByte(':')
+		t.w.Write(cell)
+	}
+	t.w.WriteString("}\n")
+	if t.w.Buffered() > 32<<10 {
+		return t.w.Flush()
+	}
+	return nil
+}
+
+func (t *ndjsonTableWriter) Close() error{
+	return t.w.Flush()
+}
+
+// xlsxTableWriter writes a single-sheet SpreadsheetML package. The static parts go
+// first so the worksheet, which is written last, can be streamed into the zip entry;
+// strings are stored inline to avoid a shared string table.
+type xlsxTableWriter struct{
+	zip		*zip.Writer
+	sheet	*bufio.Writer
+}
+
+const xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/><Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/></Types>`
+
+const xlsxRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`
+
+const xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Export" sheetId="1" r:id="rId1"/></sheets></workbook>`
+
+const xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/><Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/></Relationships>`
+
+const xlsxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts><fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills><borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders><cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs><cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs></styleSheet>`
+
+func (t *xlsxTableWriter) WriteHeader(columns []string) error{
+	parts := []struct{ name, body string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRels},
+		{"xl/workbook.xml", xlsxWorkbook},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+		{"xl/styles.xml", xlsxStyles},
+	}
+	for _, part := range parts {
+		f, err := t.zip.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, part.body); err != nil {
+			return err
+		}
+	}
+
+	f, err := t.zip.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	t.sheet = bufio.NewWriter(f)
+	t.sheet.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n" +
+		`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews><sheetData>`)
+
+	t.sheet.WriteString("<row>")
+	for _, column := range columns {
+		t.sheet.WriteString(`<c t="inlineStr" s="1"><is><t>`)
+		xml.EscapeText(t.sheet, []byte(column))
+		t.sheet.WriteString("</t></is></c>")
+	}
+	t.sheet.WriteString("</row>")
+	return nil
+}
+
+func (t *xlsxTableWriter) WriteRow(values []interface{}) error{
+	t.sheet.WriteString("<row>")
+	for _, value := range values {
+		switch v := value.(type) {
+		case nil:
+			t.sheet.WriteString("<c/>")
+		case int, float64:
+			t.sheet.WriteString("<c><v>" + cellString(v) + "</v></c>")
+		default:
+			t.sheet.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`)
+			if err := xml.EscapeText(t.sheet, []byte(cellString(v))); err != nil {
+				return err
+			}
+			t.sheet.WriteString("</t></is></c>")
+		}
+	}
+	_, err := t.sheet.WriteString("</row>")
+	return err
+}
+
+func (t *xlsxTableWriter) Close() error{
+	if t.sheet == nil {
+		if err := t.WriteHeader(nil); err != nil {
+			return err
+		}
+	}
+	t.sheet.WriteString("</sheetData></worksheet>")
+	if err := t.sheet.Flush(); err != nil {
+		return err
+	}
+	return t.zip.Close()
+}